@@ -2,24 +2,43 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"whynoipv6/internal/config"
 	"whynoipv6/internal/core"
+	"whynoipv6/internal/logger"
+	"whynoipv6/internal/metrics"
 	"whynoipv6/internal/postgres"
 	"whynoipv6/internal/rest"
+	"whynoipv6/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
 )
 
 func main() {
-	log.Println("Starting api server")
+	logg := logger.GetLogger().With().Str("service", "api").Logger()
+	// The packages that log with the standard logger write to it too.
+	log.SetFlags(0)
+	log.SetOutput(logWriter{logg})
+	logg.Info().Msg("Starting api server")
+
+	// Stop serving on SIGINT or SIGTERM, after the requests in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Read the application configuration.
 	cfg, err := config.Read()
 	if err != nil {
-		log.Fatalf("Failed to read config: %v", err)
+		logg.Fatal().Err(err).Msg("Failed to read config")
 	}
 
 	// Connect to the database
@@ -28,22 +47,49 @@ func main() {
 	dbSource := cfg.DatabaseSource + "&application_name=api"
 	db, err := postgres.NewPostgreSQL(dbSource, maxRetries, timeout)
 	if err != nil {
-		log.Fatalln("Error connecting to database", err)
+		logg.Fatal().Err(err).Msg("Error connecting to database")
 	}
 	defer db.Close()
 
+	// Export the traces of the requests, if a collector is configured.
+	shutdownTracing, err := tracing.Setup(ctx, "whynoipv6-api", tracing.Config{
+		Endpoint:    cfg.TracingEndpoint,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logg.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	defer shutdownTracing()
+
 	// Initialize the router for handling HTTP requests.
-	router, err := rest.NewRouter()
+	trustedProxies, err := rest.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logg.Fatal().Err(err).Msg("Invalid TRUSTED_PROXIES")
+	}
+	router, err := rest.NewRouter(logg, trustedProxies)
 	if err != nil {
-		log.Fatalf("Failed to create router: %v", err)
+		logg.Fatal().Err(err).Msg("Failed to create router")
 	}
 
-	// Initialize core services for managing various resources.
-	changelogService := core.NewChangelogService(db)
-	domainService := core.NewDomainService(db)
-	countryService := core.NewCountryService(db)
-	campaignService := core.NewCampaignService(db)
-	metricService := core.NewMetricService(db)
+	// Initialize core services for managing various resources, with a span
+	// for every query.
+	traced := postgres.Traced(db)
+	changelogService := core.NewChangelogService(traced)
+	domainService := core.NewDomainService(traced)
+	countryService := core.NewCountryService(traced)
+	campaignService := core.NewCampaignService(traced)
+	metricService := core.NewMetricService(traced)
+	asnService := core.NewASNService(traced)
+	recheckJobService := core.NewRecheckJobService(traced)
+	apiKeyService := core.NewAPIKeyService(traced)
+
+	// Rate limit every request, by API key or by client.
+	rateLimiter := rest.NewRateLimiter(apiKeyService, rest.RateLimits{
+		Anonymous: cfg.RateLimitAnonymous,
+		Key:       cfg.RateLimitKey,
+	})
+	router.Use(rateLimiter.Handler)
+	go rateLimiter.Run(ctx)
 
 	// Message for the / endpoint.
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -52,18 +98,155 @@ func main() {
 		_, _ = w.Write([]byte(`{"message": "ok"}`))
 	})
 
+	// Health, readiness and build info, for load balancers and monitoring.
+	healthHandler := rest.HealthHandler{
+		DB:         db,
+		Metrics:    metricService,
+		MaxDataAge: cfg.ReadyMaxDataAge,
+	}
+	router.Get("/healthz", healthHandler.Healthz)
+	router.Get("/readyz", healthHandler.Readyz)
+	router.Get("/version", healthHandler.Version)
+
+	// Prometheus metrics of the requests, the database pool and the process.
+	metrics.RegisterPool(db)
+	router.Get("/metrics", metrics.Handler().ServeHTTP)
+
 	// Register API endpoints with their respective handlers.
-	router.Mount("/domain", rest.DomainHandler{Repo: domainService}.Routes())
-	router.Mount("/country", rest.CountryHandler{Repo: countryService}.Routes())
-	router.Mount("/changelog", rest.ChangelogHandler{Repo: changelogService}.Routes())
-	router.Mount("/campaign", rest.CampaignHandler{Repo: campaignService}.Routes())
-	router.Mount("/metric", rest.MetricHandler{Repo: metricService}.Routes())
+	domainHandler := rest.DomainHandler{
+		Repo: domainService,
+		Jobs: recheckJobService,
+		DB:   db,
+		RecheckLimits: rest.RecheckLimits{
+			ClientLimit:    cfg.RecheckClientLimit,
+			ClientWindow:   cfg.RecheckClientWindow,
+			DomainInterval: cfg.RecheckDomainInterval,
+		},
+	}
+	countryHandler := rest.CountryHandler{Repo: countryService}
+	changelogHandler := rest.ChangelogHandler{
+		Repo:   changelogService,
+		Stream: rest.NewChangelogStream(changelogService),
+	}
+	campaignHandler := rest.CampaignHandler{Repo: campaignService}
+	metricHandler := rest.MetricHandler{Repo: metricService}
+	badgeHandler := rest.BadgeHandler{Domains: domainService, Campaigns: campaignService}
+	jobHandler := rest.JobHandler{Repo: recheckJobService}
+	feedHandler := rest.FeedHandler{
+		Changelog: changelogService,
+		Domains:   domainService,
+		Countries: countryService,
+		Campaigns: campaignService,
+		SiteURL:   cfg.SiteURL,
+	}
+	exportHandler := rest.ExportHandler{Repo: domainService}
+	adminHandler := rest.AdminHandler{DB: db}
+	graphQLHandler, err := rest.NewGraphQLHandler(rest.GraphQLRepos{
+		Domains:   domainService,
+		Countries: countryService,
+		Campaigns: campaignService,
+		ASNs:      asnService,
+		Changelog: changelogService,
+		Metrics:   metricService,
+	}, rest.GraphQLLimits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	})
+	if err != nil {
+		logg.Fatal().Err(err).Msg("Failed to create GraphQL handler")
+	}
+	// Register the routes with their cache policies. The country and metric
+	// responses are the same for every client, and are kept in memory too.
+	responseCache := rest.NewResponseCache(cfg.ResponseCacheTTL)
+	hotData := rest.CrawlData.WithCache(responseCache)
+	router.With(rest.CrawlData.Handler).Mount("/domain", domainHandler.Routes())
+	router.With(hotData.Handler).Mount("/country", countryHandler.Routes())
+	router.With(rest.Revalidate.Handler).Mount("/changelog", changelogHandler.Routes())
+	router.With(rest.CrawlData.Handler).Mount("/campaign", campaignHandler.Routes())
+	router.With(hotData.Handler).Mount("/metric", metricHandler.Routes())
+	router.With(rest.CrawlData.Handler).Mount("/badge", badgeHandler.Routes())
+	router.Mount("/jobs", jobHandler.Routes())
+	router.With(rest.CrawlData.Handler).Mount("/feed", feedHandler.Routes())
+	router.With(rest.CrawlData.Handler).Mount("/export", exportHandler.Routes())
+	router.With(rest.CrawlData.Handler).Mount("/graphql", graphQLHandler.Routes())
+	router.Mount("/admin", adminHandler.Routes())
+
+	// Register the /v2 API, which wraps every response in the same envelope.
+	router.Route("/v2", func(r chi.Router) {
+		r.NotFound(rest.NotFoundV2)
+		r.With(rest.CrawlData.Handler).Mount("/domain", domainHandler.RoutesV2())
+		r.With(hotData.Handler).Mount("/country", countryHandler.RoutesV2())
+		r.With(rest.Revalidate.Handler).Mount("/changelog", changelogHandler.RoutesV2())
+		r.With(rest.CrawlData.Handler).Mount("/campaign", campaignHandler.RoutesV2())
+		r.With(hotData.Handler).Mount("/metric", metricHandler.RoutesV2())
+	})
+
+	// Document the endpoints, and serve the OpenAPI document and docs page.
+	docs := rest.NewOpenAPI("WhyNoIPv6 API", "1.0.0")
+	docs.Mount("", []rest.Operation{
+		{Method: "GET", Path: "/", Summary: "Health message", Response: map[string]string{}},
+		{Method: "GET", Path: "/metrics", Summary: "Prometheus metrics, in the Prometheus text format"},
+	})
+	docs.Mount("", docs.Operations())
+	docs.Mount("", healthHandler.Operations())
+	docs.Mount("/domain", domainHandler.Operations())
+	docs.Mount("/country", countryHandler.Operations())
+	docs.Mount("/changelog", changelogHandler.Operations())
+	docs.Mount("/campaign", campaignHandler.Operations())
+	docs.Mount("/metric", metricHandler.Operations())
+	docs.Mount("/badge", badgeHandler.Operations())
+	docs.Mount("/jobs", jobHandler.Operations())
+	docs.Mount("/feed", feedHandler.Operations())
+	docs.Mount("/export", exportHandler.Operations())
+	docs.Mount("/graphql", graphQLHandler.Operations())
+	docs.Mount("/admin", adminHandler.Operations())
+	docs.Mount("/v2/domain", domainHandler.OperationsV2())
+	docs.Mount("/v2/country", countryHandler.OperationsV2())
+	docs.Mount("/v2/changelog", changelogHandler.OperationsV2())
+	docs.Mount("/v2/campaign", campaignHandler.OperationsV2())
+	docs.Mount("/v2/metric", metricHandler.OperationsV2())
+	router.With(rest.CrawlData.Handler).Get("/openapi.json", docs.ServeDocument)
+	router.With(rest.CrawlData.Handler).Get("/docs", docs.ServeDocs)
+
+	// Make sure every route is documented, and every documented route exists.
+	for _, problem := range docs.Check(router) {
+		logg.Warn().Msgf("OpenAPI: %s", problem)
+	}
+
+	// Stream new changelog entries as the database notifies about them.
+	go postgres.Listen(ctx, db, core.ChangelogChannel, changelogHandler.Stream.Notify)
 
 	// Print the registered routes for debugging purposes.
-	rest.PrintRoutes(router)
+	rest.PrintRoutes(router, logg)
+
+	// Start the API server on the configured addresses, until it is stopped.
+	listenAddrs := cfg.APIListenAddrs
+	if len(listenAddrs) == 0 {
+		listenAddrs = []string{fmt.Sprintf("[::1]:%v", cfg.APIPort)}
+	}
+	server := rest.NewServer(router, rest.ServerConfig{
+		Addrs:           listenAddrs,
+		ReadTimeout:     cfg.APIReadTimeout,
+		WriteTimeout:    cfg.APIWriteTimeout,
+		IdleTimeout:     cfg.APIIdleTimeout,
+		ShutdownTimeout: cfg.APIShutdownTimeout,
+		TLSCert:         cfg.APITLSCert,
+		TLSKey:          cfg.APITLSKey,
+	}, logg)
+	// The changelog stream never ends on its own, its clients reconnect to the next server.
+	server.RegisterOnShutdown(changelogHandler.Stream.Close)
+	if err := server.Run(ctx); err != nil {
+		logg.Fatal().Err(err).Msg("Server stopped")
+	}
+	logg.Info().Msg("Server stopped")
+}
+
+// logWriter writes the lines of the standard logger to a zerolog logger.
+type logWriter struct {
+	logger zerolog.Logger
+}
 
-	// Start the API server with the configured listening address.
-	listenAddr := fmt.Sprintf("[::1]:%v", cfg.APIPort)
-	log.Printf("Starting server on %s", listenAddr)
-	log.Fatal(http.ListenAndServe(listenAddr, router))
+func (l logWriter) Write(p []byte) (int, error) {
+	l.logger.Warn().Msg(strings.TrimSpace(string(p)))
+	return len(p), nil
 }
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/alexeyco/simpletable"
+	"github.com/jackc/pgx/v4"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiKeyName      string // Name of the API key to create
+	apiKeyRateLimit int32  // Rate limit of the API key to create, in requests per minute
+	apiKeyAdmin     bool   // The API key to create can use the admin API
+)
+
+// apiKeyCmd represents the apikey command
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys",
+	Long: `Manage the API keys of the API consumers. Requests with a key have a higher rate limit
+than anonymous requests, which are limited by IP address.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			if err := cmd.Help(); err != nil {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	},
+}
+
+// apiKeyCreateCmd represents the apikey create command
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates an API key",
+	Long: `Creates an API key and prints it. The key is only stored as a hash, so it can not be
+shown again. A rate limit of 0 uses the RATE_LIMIT_KEY limit of the API. Admin keys can
+also use the /admin API, and every change made with them is written to the audit log.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if apiKeyName == "" {
+			logg.Error().Msg("An API key needs a name, use --name")
+			os.Exit(1)
+		}
+		if apiKeyRateLimit < 0 {
+			logg.Error().Msg("The rate limit can not be negative")
+			os.Exit(1)
+		}
+		apiKey, key, err := core.NewAPIKeyService(db).CreateAPIKey(
+			context.Background(), apiKeyName, apiKeyRateLimit, apiKeyAdmin,
+		)
+		if err != nil {
+			logg.Error().Err(err).Msg("Could not create API key")
+			os.Exit(1)
+		}
+		fmt.Printf("Created API key %d for %s, store it now as it can not be shown again:\n\n", apiKey.ID, apiKey.Name)
+		fmt.Println(key)
+	},
+}
+
+// apiKeyRevokeCmd represents the apikey revoke command
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revokes an API key",
+	Long:  "Revokes an API key by its id. The API stops accepting it within a minute.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			logg.Error().Msgf("Invalid API key id %q", args[0])
+			os.Exit(1)
+		}
+		apiKey, err := core.NewAPIKeyService(db).RevokeAPIKey(context.Background(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			logg.Error().Msgf("No API key %d, or it is already revoked", id)
+			os.Exit(1)
+		}
+		if err != nil {
+			logg.Error().Err(err).Msg("Could not revoke API key")
+			os.Exit(1)
+		}
+		logg.Info().Msgf("Revoked API key %d for %s", apiKey.ID, apiKey.Name)
+	},
+}
+
+// apiKeyListCmd represents the apikey list command
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists all API keys",
+	Long:  "Lists all API keys with their rate limit and usage, including the revoked ones.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listAPIKeys(); err != nil {
+			logg.Error().Err(err).Msg("Could not list API keys")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apiKeyCmd)
+	apiKeyCmd.AddCommand(apiKeyCreateCmd, apiKeyRevokeCmd, apiKeyListCmd)
+	apiKeyCreateCmd.Flags().StringVarP(&apiKeyName, "name", "n", "", "name of the consumer of the key")
+	apiKeyCreateCmd.Flags().Int32Var(&apiKeyRateLimit, "rate-limit", 0, "requests per minute, 0 for the default key limit")
+	apiKeyCreateCmd.Flags().BoolVar(&apiKeyAdmin, "admin", false, "allow the key to use the admin API")
+}
+
+// listAPIKeys displays a table of the API keys.
+func listAPIKeys() error {
+	apiKeys, err := core.NewAPIKeyService(db).ListAPIKeys(context.Background())
+	if err != nil {
+		return err
+	}
+
+	table := simpletable.New()
+	table.Header = &simpletable.Header{
+		Cells: []*simpletable.Cell{
+			{Align: simpletable.AlignCenter, Text: "ID"},
+			{Align: simpletable.AlignCenter, Text: "Name"},
+			{Align: simpletable.AlignCenter, Text: "Prefix"},
+			{Align: simpletable.AlignCenter, Text: "Rate Limit"},
+			{Align: simpletable.AlignCenter, Text: "Admin"},
+			{Align: simpletable.AlignCenter, Text: "Requests"},
+			{Align: simpletable.AlignCenter, Text: "Last Used"},
+			{Align: simpletable.AlignCenter, Text: "Created"},
+			{Align: simpletable.AlignCenter, Text: "Revoked"},
+		},
+	}
+	for _, apiKey := range apiKeys {
+		rateLimit := "default"
+		if apiKey.RateLimit > 0 {
+			rateLimit = fmt.Sprintf("%d/min", apiKey.RateLimit)
+		}
+		table.Body.Cells = append(table.Body.Cells, []*simpletable.Cell{
+			{Align: simpletable.AlignRight, Text: strconv.FormatInt(apiKey.ID, 10)},
+			{Text: apiKey.Name},
+			{Text: apiKey.Prefix + "…"},
+			{Align: simpletable.AlignRight, Text: rateLimit},
+			{Align: simpletable.AlignCenter, Text: formatAPIKeyAdmin(apiKey.Admin)},
+			{Align: simpletable.AlignRight, Text: strconv.FormatInt(apiKey.Requests, 10)},
+			{Text: formatAPIKeyTime(apiKey.LastUsed)},
+			{Text: formatAPIKeyTime(apiKey.CreatedAt)},
+			{Text: formatAPIKeyTime(apiKey.RevokedAt)},
+		})
+	}
+	table.SetStyle(simpletable.StyleDefault)
+	fmt.Println(table.String())
+	return nil
+}
+
+// formatAPIKeyTime formats a time of an API key, or a dash if it is not set.
+func formatAPIKeyTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// formatAPIKeyAdmin formats whether an API key can use the admin API.
+func formatAPIKeyAdmin(admin bool) string {
+	if admin {
+		return "yes"
+	}
+	return "-"
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// batchResult is the outcome of a batch of jobs.
+type batchResult struct {
+	Successful int  // Jobs that finished without errors
+	Failed     int  // Jobs that failed, timed out or were skipped
+	TimedOut   bool // The batch hit the job timeout before all jobs were done
+}
+
+// runBatch runs process for every item using the given number of workers, and waits
+// until every item has been accounted for. Each item gets its own deadline of checkTimeout,
+// and the whole batch is cancelled after jobTimeout. Items that have not been started when
+// the batch times out are counted as failed. A failed item never stops a worker.
+//
+// Every item produces exactly one result, and all workers have exited when runBatch
+// returns, so nothing from this batch can be counted in the next one.
+func runBatch[T any](
+	ctx context.Context,
+	items []T,
+	numWorkers int,
+	jobTimeout, checkTimeout time.Duration,
+	process func(ctx context.Context, item T) bool,
+) batchResult {
+	batchCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	jobs := make(chan T, len(items))    // Channel for jobs
+	done := make(chan bool, len(items)) // Channel for signaling completion of jobs
+
+	// Start workers for this batch of jobs
+	var wg sync.WaitGroup
+	for w := 1; w <= numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				// Skip the rest of the jobs once the batch has timed out.
+				if batchCtx.Err() != nil {
+					done <- false
+					continue
+				}
+
+				checkCtx, cancelCheck := context.WithTimeout(batchCtx, checkTimeout)
+				done <- process(checkCtx, item)
+				cancelCheck()
+			}
+		}()
+	}
+
+	// Send jobs to the workers
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+
+	// Collect exactly one result per job.
+	var result batchResult
+	for range items {
+		if <-done {
+			result.Successful++
+		} else {
+			result.Failed++
+		}
+	}
+	wg.Wait()
+
+	result.TimedOut = errors.Is(batchCtx.Err(), context.DeadlineExceeded)
+	return result
+}
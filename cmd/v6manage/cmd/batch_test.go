@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		items          int
+		workers        int
+		jobTimeout     time.Duration
+		checkTimeout   time.Duration
+		process        func(ctx context.Context, item int) bool
+		wantSuccessful int
+		wantFailed     int
+		wantTimedOut   bool
+	}{
+		{
+			name: "all successful", items: 10, workers: 3,
+			jobTimeout: time.Second, checkTimeout: time.Second,
+			process:        func(ctx context.Context, item int) bool { return true },
+			wantSuccessful: 10,
+		},
+		{
+			name: "failures do not stop the workers", items: 10, workers: 2,
+			jobTimeout: time.Second, checkTimeout: time.Second,
+			process:        func(ctx context.Context, item int) bool { return item%2 == 0 },
+			wantSuccessful: 5, wantFailed: 5,
+		},
+		{
+			name: "more workers than items", items: 2, workers: 10,
+			jobTimeout: time.Second, checkTimeout: time.Second,
+			process:        func(ctx context.Context, item int) bool { return true },
+			wantSuccessful: 2,
+		},
+		{
+			name: "no items", items: 0, workers: 4,
+			jobTimeout: time.Second, checkTimeout: time.Second,
+			process: func(ctx context.Context, item int) bool { return true },
+		},
+		{
+			name: "a slow item hits its own deadline", items: 4, workers: 4,
+			jobTimeout: time.Second, checkTimeout: 20 * time.Millisecond,
+			process: func(ctx context.Context, item int) bool {
+				if item == 0 {
+					<-ctx.Done()
+					return false
+				}
+				return true
+			},
+			wantSuccessful: 3, wantFailed: 1,
+		},
+		{
+			name: "items not started before the batch timeout fail", items: 5, workers: 1,
+			jobTimeout: 30 * time.Millisecond, checkTimeout: time.Second,
+			process: func(ctx context.Context, item int) bool {
+				<-ctx.Done()
+				return true
+			},
+			wantSuccessful: 1, wantFailed: 4, wantTimedOut: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]int, tt.items)
+			for i := range items {
+				items[i] = i
+			}
+			got := runBatch(context.Background(), items, tt.workers, tt.jobTimeout, tt.checkTimeout, tt.process)
+			want := batchResult{Successful: tt.wantSuccessful, Failed: tt.wantFailed, TimedOut: tt.wantTimedOut}
+			if got != want {
+				t.Errorf("runBatch() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunBatchWorkers(t *testing.T) {
+	// No more items than workers run at the same time, and every item runs once.
+	const workers = 3
+	var running, maxRunning atomic.Int32
+	var mu sync.Mutex
+	seen := make(map[int]int)
+
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+	runBatch(context.Background(), items, workers, time.Second, time.Second, func(ctx context.Context, item int) bool {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+
+		mu.Lock()
+		seen[item]++
+		mu.Unlock()
+		return true
+	})
+
+	if got := maxRunning.Load(); got > workers {
+		t.Errorf("%d items ran at the same time, want at most %d", got, workers)
+	}
+	for _, item := range items {
+		if seen[item] != 1 {
+			t.Errorf("item %d ran %d times, want once", item, seen[item])
+		}
+	}
+}
+
+func TestRunBatchCheckDeadline(t *testing.T) {
+	// Every item gets its own deadline, under the deadline of the batch.
+	var deadlines []time.Time
+	var mu sync.Mutex
+	start := time.Now()
+	runBatch(context.Background(), []int{1, 2}, 1, time.Minute, time.Second, func(ctx context.Context, item int) bool {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Error("item has no deadline")
+		}
+		mu.Lock()
+		deadlines = append(deadlines, deadline)
+		mu.Unlock()
+		return true
+	})
+	for _, deadline := range deadlines {
+		if d := deadline.Sub(start); d > 2*time.Second {
+			t.Errorf("item deadline %v after the start, want the check timeout", d)
+		}
+	}
+}
@@ -186,7 +186,7 @@ func syncDomainsWithDatabase(ctx context.Context, yamlData *CampaignYAML) error
 	for _, domain := range yamlData.DomainNames {
 		// Validate domain
 		// Ignore rcode here. Manually disable/remove domains from campaigns if they are not valid.
-		_, err := resolver.ValidateDomain(domain)
+		_, err := resolver.ValidateDomain(ctx, domain)
 		if err != nil {
 			log.Printf("error validating domain %s: %v", domain, err.Error())
 			continue
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/geoip"
+	"whynoipv6/internal/resolver"
+
+	"github.com/alexeyco/simpletable"
+	"github.com/jackc/pgx/v4"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkJSON bool // Print the report as JSON
+	checkSave bool // Store the result in the database
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <domain> [domain...]",
+	Short: "Runs all checks against one or more domains",
+	Long: `Runs all checks against one or more domains and prints a detailed report.
+Use --save to store the result and changelog in the database, like the crawler does.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		domainService = *core.NewDomainService(db)
+		countryService = *core.NewCountryService(db)
+		asnService = *core.NewASNService(db)
+		checkDomains(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "print the report as JSON")
+	checkCmd.Flags().BoolVar(&checkSave, "save", false, "store the result and changelog in the database")
+}
+
+// CheckReport is the result of an on-demand check of a single domain.
+type CheckReport struct {
+	resolver.DomainReport
+	IP       string `json:"ip,omitempty"`
+	ASN      string `json:"asn,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Duration string `json:"duration"`
+	Saved    bool   `json:"saved"`
+	Error    string `json:"error,omitempty"`
+}
+
+// checkDomains runs all checks against the given domains and prints the report.
+func checkDomains(domains []string) {
+	ctx := context.Background()
+	failed := false
+
+	// The geoip database is only needed for ASN and country information.
+	geoipErr := geoip.Initialize(cfg.GeoIPPath)
+	if geoipErr != nil {
+		logg.Warn().Err(geoipErr).Msg("Could not initialize geoip database, skipping ASN and country")
+	}
+	if checkSave && geoipErr != nil {
+		logg.Error().Msg("The geoip database is required for --save")
+		os.Exit(1)
+	}
+
+	var reports []CheckReport
+	for _, domain := range domains {
+		report := checkOneDomain(ctx, strings.ToLower(strings.TrimSpace(domain)), geoipErr == nil)
+		if report.Error != "" {
+			failed = true
+		}
+		reports = append(reports, report)
+	}
+
+	if checkJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			logg.Error().Err(err).Msg("Could not encode report")
+			os.Exit(1)
+		}
+	} else {
+		for _, report := range reports {
+			printCheckReport(report)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkOneDomain runs the checks for a single domain, and saves the result if requested.
+func checkOneDomain(ctx context.Context, domain string, useGeoIP bool) CheckReport {
+	report := CheckReport{}
+	report.Domain = domain
+
+	// Validate domain, same as the crawler does before checking it.
+	if _, err := resolver.ValidateDomain(ctx, domain); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	// Run all the checks on the domain.
+	diagnostics, err := resolver.DomainDiagnostics(ctx, domain)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.DomainReport = diagnostics
+	report.Duration = prettyDuration(diagnostics.Duration)
+
+	// Look up the network and country for the domain.
+	if useGeoIP {
+		report.IP, _ = resolver.IPLookup(ctx, domain)
+		if asn, err := geoip.AsnLookup(report.IP); err == nil {
+			report.ASN = fmt.Sprintf("AS%d %s", asn.Number, asn.Name)
+		}
+		if country, err := geoip.CountryLookup(report.IP); err == nil {
+			report.Country = country
+		}
+	}
+
+	if !checkSave {
+		return report
+	}
+
+	// Store the result through the same update path as the crawler.
+	if _, err := domainService.GetDomain(ctx, domain); err == pgx.ErrNoRows {
+		report.Error = "domain is not in the database, not saved"
+		return report
+	} else if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	result := newCheckResult(ctx, domain, diagnostics.Result)
+	if err := applyCheckResult(ctx, result); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Saved = true
+
+	return report
+}
+
+// printCheckReport prints a check report as a table.
+func printCheckReport(report CheckReport) {
+	fmt.Printf("Domain: %s\n", report.Domain)
+	if report.Error != "" && len(report.Records) == 0 {
+		fmt.Printf("Error: %s\n\n", report.Error)
+		return
+	}
+
+	// Status for each check.
+	summary := simpletable.New()
+	summary.Header = &simpletable.Header{
+		Cells: []*simpletable.Cell{
+			{Align: simpletable.AlignCenter, Text: "Base Domain"},
+			{Align: simpletable.AlignCenter, Text: "WWW Domain"},
+			{Align: simpletable.AlignCenter, Text: "Nameserver"},
+			{Align: simpletable.AlignCenter, Text: "MX Record"},
+			{Align: simpletable.AlignCenter, Text: "ASN"},
+			{Align: simpletable.AlignCenter, Text: "Country"},
+		},
+	}
+	summary.Body.Cells = append(summary.Body.Cells, []*simpletable.Cell{
+		{Text: report.Result.BaseDomain},
+		{Text: report.Result.WwwDomain},
+		{Text: report.Result.Nameserver},
+		{Text: report.Result.MXRecord},
+		{Text: report.ASN},
+		{Text: report.Country},
+	})
+	summary.SetStyle(simpletable.StyleDefault)
+	fmt.Println(summary.String())
+
+	// Every record that was looked up.
+	records := simpletable.New()
+	records.Header = &simpletable.Header{
+		Cells: []*simpletable.Cell{
+			{Align: simpletable.AlignCenter, Text: "Check"},
+			{Align: simpletable.AlignCenter, Text: "Host"},
+			{Align: simpletable.AlignCenter, Text: "Type"},
+			{Align: simpletable.AlignCenter, Text: "Result"},
+		},
+	}
+	for _, record := range report.Records {
+		result := strings.Join(record.Addresses, ", ")
+		if len(record.CNAMEs) > 0 {
+			result = "CNAME " + strings.Join(record.CNAMEs, " -> ") + " " + result
+		}
+		if record.Error != "" {
+			result = "error: " + record.Error
+		}
+		if result == "" {
+			result = "-"
+		}
+		records.Body.Cells = append(records.Body.Cells, []*simpletable.Cell{
+			{Text: record.Check},
+			{Text: record.Host},
+			{Text: record.Type},
+			{Text: strings.TrimSpace(result)},
+		})
+	}
+	records.SetStyle(simpletable.StyleDefault)
+	fmt.Println(records.String())
+
+	fmt.Printf("Duration: %s", report.Duration)
+	if report.Saved {
+		fmt.Print(", saved to database")
+	}
+	if report.Error != "" {
+		fmt.Printf(", error: %s", report.Error)
+	}
+	fmt.Print("\n\n")
+}
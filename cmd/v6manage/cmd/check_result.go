@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/postgres"
+	"whynoipv6/internal/resolver"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// recentCheckResult returns the result of a site if it was checked within the reuse window.
+func recentCheckResult(
+	ctx context.Context,
+	site string,
+	reuseWindow time.Duration,
+) (core.CheckResultModel, bool) {
+	if reuseWindow <= 0 {
+		return core.CheckResultModel{}, false
+	}
+	result, err := checkResultService.GetCheckResult(ctx, site, time.Now().Add(-reuseWindow))
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			logg.Error().Err(err).Msgf("[%s] Could not get check result", site)
+		}
+		return core.CheckResultModel{}, false
+	}
+	logg.Debug().Msgf("[%s] Reusing check result from %s", site, result.TsCheck.Format(time.RFC3339))
+	return result, true
+}
+
+// checkLog is the log of a check, stored for every domain and campaign domain of the site.
+type checkLog struct {
+	BaseDomain string `json:"base_domain"`
+	WwwDomain  string `json:"www_domain"`
+	Nameserver string `json:"nameserver"`
+	MxRecord   string `json:"mx_record"`
+}
+
+// applyCheckResult stores the new result of a site, and writes the changes to
+// its previous result to the changelog of the domain and of every campaign
+// domain of the site. It runs in a transaction that locks the result, so a
+// change found by two crawlers at the same time is only written once.
+func applyCheckResult(ctx context.Context, result core.CheckResultModel) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // No-op after a commit
+	traced := postgres.Traced(tx)
+	checkResults := core.NewCheckResultService(traced)
+	changelogs := core.NewChangelogService(traced)
+	domains := core.NewDomainService(traced)
+	campaigns := core.NewCampaignService(traced)
+
+	current, err := checkResults.LockCheckResult(ctx, result.Site)
+	if err != nil {
+		return fmt.Errorf("could not get the result of [%s]: %w", result.Site, err)
+	}
+	domainIDs, err := checkResults.ListDomainIDs(ctx, result.Site)
+	if err != nil {
+		return fmt.Errorf("could not get the domains of [%s]: %w", result.Site, err)
+	}
+	campaignDomains, err := checkResults.ListCampaignDomains(ctx, result.Site)
+	if err != nil {
+		return fmt.Errorf("could not get the campaign domains of [%s]: %w", result.Site, err)
+	}
+
+	// Helper function to create a changelog entry for every domain of the site.
+	createChangelog := func(message string, status string) error {
+		for _, id := range domainIDs {
+			_, err := changelogs.Create(ctx, core.ChangelogModel{
+				DomainID:   id,
+				Message:    message,
+				IPv6Status: status,
+			})
+			if err != nil {
+				return fmt.Errorf("could not write changelog: %w", err)
+			}
+		}
+		for _, domain := range campaignDomains {
+			_, err := changelogs.CampaignCreate(ctx, core.ChangelogModel{
+				DomainID:   domain.ID,
+				CampaignID: domain.CampaignID,
+				Message:    message,
+				IPv6Status: status,
+			})
+			if err != nil {
+				return fmt.Errorf("could not write campaign changelog: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// Check if there is any changes to the result.
+	now := time.Now()
+	if current.BaseDomain != result.BaseDomain {
+		changelog, err := generateChangelog(current, result)
+		if err != nil {
+			return err
+		}
+		if err := createChangelog(changelog, result.BaseDomain); err != nil {
+			return err
+		}
+		current.BaseDomain = result.BaseDomain
+		current.TsBaseDomain = now
+		current.TsUpdated = now
+	}
+
+	if current.WwwDomain != result.WwwDomain {
+		changelog, err := generateChangelog(current, result)
+		if err != nil {
+			return err
+		}
+		if err := createChangelog(changelog, result.WwwDomain); err != nil {
+			return err
+		}
+		current.WwwDomain = result.WwwDomain
+		current.TsWwwDomain = now
+		current.TsUpdated = now
+	}
+
+	if current.Nameserver != result.Nameserver {
+		changelog, err := generateChangelog(current, result)
+		if err != nil {
+			return err
+		}
+		if err := createChangelog(changelog, result.Nameserver); err != nil {
+			return err
+		}
+		current.Nameserver = result.Nameserver
+		current.TsNameserver = now
+		current.TsUpdated = now
+	}
+
+	if current.MXRecord != result.MXRecord {
+		changelog, err := generateChangelog(current, result)
+		if err != nil {
+			return err
+		}
+		if err := createChangelog(changelog, result.MXRecord); err != nil {
+			return err
+		}
+		current.MXRecord = result.MXRecord
+		current.TsMXRecord = now
+		current.TsUpdated = now
+	}
+
+	// Update ASN ID, Country ID and the check timestamp.
+	current.AsnID = result.AsnID
+	current.CountryID = result.CountryID
+	current.TsCheck = now
+
+	if err := checkResults.StoreCheckResult(ctx, current); err != nil {
+		return fmt.Errorf("could not store the result of [%s]: %w", result.Site, err)
+	}
+
+	// Write a log of the check.
+	log := checkLog{
+		BaseDomain: result.BaseDomain,
+		WwwDomain:  result.WwwDomain,
+		Nameserver: result.Nameserver,
+		MxRecord:   result.MXRecord,
+	}
+	for _, id := range domainIDs {
+		if err := domains.StoreDomainLog(ctx, id, log); err != nil {
+			return fmt.Errorf("could not store domain log: %w", err)
+		}
+	}
+	for _, domain := range campaignDomains {
+		if err := campaigns.StoreCampaignDomainLog(ctx, domain.ID, log); err != nil {
+			return fmt.Errorf("could not store campaign domain log: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// newCheckResult maps a resolver result to a shared check result and looks up the ASN and country.
+func newCheckResult(
+	ctx context.Context,
+	site string,
+	domainResult resolver.DomainResult,
+) core.CheckResultModel {
+	logg := logg.With().Str("service", "newCheckResult").Logger()
+
+	// Map the result to the check result model.
+	checkResult := core.CheckResultModel{
+		Site:       site,
+		BaseDomain: domainResult.BaseDomain,
+		WwwDomain:  domainResult.WwwDomain,
+		Nameserver: domainResult.Nameserver,
+		MXRecord:   domainResult.MXRecord,
+		TsCheck:    time.Now(),
+	}
+
+	// Check if the results are empty and set them to "no_record" if they are.
+	if checkResult.BaseDomain == "" {
+		logg.Error().Msgf("[%s] Empty BaseDomain", site)
+		checkResult.BaseDomain = "no_record"
+	}
+	if checkResult.WwwDomain == "" {
+		logg.Error().Msgf("[%s] Empty WwwDomain", site)
+		checkResult.WwwDomain = "no_record"
+	}
+	if checkResult.Nameserver == "" {
+		logg.Error().Msgf("[%s] Empty Nameserver", site)
+		checkResult.Nameserver = "no_record"
+	}
+	if checkResult.MXRecord == "" {
+		logg.Error().Msgf("[%s] Empty MXRecord", site)
+		checkResult.MXRecord = "no_record"
+	}
+
+	// Retrieve ASN information for the domain if it has basic dns records.
+	// If the domain has no records, set the ASN ID to 1 (Unknown).
+	if checkResult.BaseDomain != "no_record" || checkResult.WwwDomain != "no_record" {
+		asnID, err := getNetworkProvider(ctx, site)
+		if err != nil {
+			logg.Error().Err(err).Msg("Could not get ASN info")
+		}
+		// Check if the ASN is empty
+		if asnID == 0 {
+			logg.Error().Msgf("[%s] Empty ASN", site)
+			checkResult.AsnID = 1
+		} else {
+			checkResult.AsnID = asnID
+		}
+	} else {
+		checkResult.AsnID = 1
+	}
+
+	// Map country code to country table
+	// If the domain has no records, set the Country ID to 251 (Unknown).
+	if checkResult.BaseDomain != "no_record" || checkResult.WwwDomain != "no_record" {
+		countryID, err := getCountryID(ctx, site)
+		if err != nil {
+			logg.Error().Err(err).Msg("Could not get country info")
+		}
+		// Check if the country is empty
+		if countryID == 0 {
+			logg.Error().Msgf("[%s] Empty country", site)
+			checkResult.CountryID = 251
+		} else {
+			checkResult.CountryID = countryID
+		}
+	} else {
+		checkResult.CountryID = 251
+	}
+
+	return checkResult
+}
+
+// generateChangelog checks the result of the change and generates a changelog entry.
+func generateChangelog(current, result core.CheckResultModel) (string, error) {
+	// Base Domain
+	if current.BaseDomain != result.BaseDomain {
+		if current.BaseDomain == "unsupported" && result.BaseDomain == "supported" {
+			return fmt.Sprintf("IPv6 enabled for %s", current.Site), nil
+		}
+		if current.BaseDomain == "supported" && result.BaseDomain == "unsupported" {
+			return fmt.Sprintf("IPv6 lost for %s", current.Site), nil
+		}
+		if current.BaseDomain == "no_record" && result.BaseDomain == "supported" {
+			return fmt.Sprintf("IPv6 enabled for %s", current.Site), nil
+		}
+		if current.BaseDomain == "no_record" && result.BaseDomain == "unsupported" {
+			return fmt.Sprintf("IPv4-only for %s", current.Site), nil
+		}
+		if result.BaseDomain == "no_record" {
+			return fmt.Sprintf("No DNS records found for %s", current.Site), nil
+		}
+	}
+	// WWW Domain
+	if current.WwwDomain != result.WwwDomain {
+		if current.WwwDomain == "unsupported" && result.WwwDomain == "supported" {
+			return fmt.Sprintf("IPv6 enabled for www.%s", current.Site), nil
+		}
+		if current.WwwDomain == "supported" && result.WwwDomain == "unsupported" {
+			return fmt.Sprintf("IPv6 lost for www.%s", current.Site), nil
+		}
+		if current.WwwDomain == "no_record" && result.WwwDomain == "supported" {
+			return fmt.Sprintf("IPv6 enabled for www.%s", current.Site), nil
+		}
+		if current.WwwDomain == "no_record" && result.WwwDomain == "unsupported" {
+			return fmt.Sprintf("IPv4-only for www.%s", current.Site), nil
+		}
+		if result.WwwDomain == "no_record" {
+			return fmt.Sprintf("No DNS records found for www.%s", current.Site), nil
+		}
+	}
+
+	// Nameserver
+	if current.Nameserver != result.Nameserver {
+		if current.Nameserver == "unsupported" && result.Nameserver == "supported" {
+			return fmt.Sprintf("IPv6 enabled nameserver for %s", current.Site), nil
+		}
+		if current.Nameserver == "supported" && result.Nameserver == "unsupported" {
+			return fmt.Sprintf("Nameservers degraded to IPv4-only for %s", current.Site), nil
+		}
+		if current.Nameserver == "no_record" && result.Nameserver == "supported" {
+			return fmt.Sprintf("IPv6 enabled nameserver for %s", current.Site), nil
+		}
+		if current.Nameserver == "no_record" && result.Nameserver == "unsupported" {
+			return fmt.Sprintf("IPv4-only nameservers for %s", current.Site), nil
+		}
+		if result.Nameserver == "no_record" {
+			return fmt.Sprintf("No NS records found for %s", current.Site), nil
+		}
+	}
+
+	// MX Record
+	if current.MXRecord != result.MXRecord {
+		if current.MXRecord == "unsupported" && result.MXRecord == "supported" {
+			return fmt.Sprintf("IPv6 enabled MX records for %s", current.Site), nil
+		}
+		if current.MXRecord == "supported" && result.MXRecord == "unsupported" {
+			return fmt.Sprintf("MX records degraded to IPv4-only for %s", current.Site), nil
+		}
+		if current.MXRecord == "no_record" && result.MXRecord == "supported" {
+			return fmt.Sprintf("IPv6 enabled MX records for %s", current.Site), nil
+		}
+		if current.MXRecord == "no_record" && result.MXRecord == "unsupported" {
+			return fmt.Sprintf("IPv4-only MX records for %s", current.Site), nil
+		}
+		if result.MXRecord == "no_record" {
+			return fmt.Sprintf("No Mail records found for %s", current.Site), nil
+		}
+	}
+
+	return "", errors.New(
+		"Unknown change for " + current.Site + ": BaseDomain: [" + current.BaseDomain + " - " + result.BaseDomain + "] WwwDomain: [" + current.WwwDomain + " - " + result.WwwDomain + "] Nameserver: [" + current.Nameserver + " - " + result.Nameserver + "] MXRecord: [" + current.MXRecord + " - " + result.MXRecord + "]",
+	)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"whynoipv6/internal/core"
+)
+
+func TestGenerateChangelog(t *testing.T) {
+	unchanged := core.CheckResultModel{
+		Site:       "example.com",
+		BaseDomain: "supported",
+		WwwDomain:  "supported",
+		Nameserver: "supported",
+		MXRecord:   "supported",
+	}
+	with := func(fn func(r *core.CheckResultModel)) core.CheckResultModel {
+		r := unchanged
+		fn(&r)
+		return r
+	}
+	tests := []struct {
+		name    string
+		current core.CheckResultModel
+		result  core.CheckResultModel
+		want    string
+		wantErr bool
+	}{
+		{
+			"base domain gains IPv6",
+			with(func(r *core.CheckResultModel) { r.BaseDomain = "unsupported" }), unchanged,
+			"IPv6 enabled for example.com", false,
+		},
+		{
+			"www loses IPv6",
+			unchanged, with(func(r *core.CheckResultModel) { r.WwwDomain = "unsupported" }),
+			"IPv6 lost for www.example.com", false,
+		},
+		{
+			"nameservers without records",
+			unchanged, with(func(r *core.CheckResultModel) { r.Nameserver = "no_record" }),
+			"No NS records found for example.com", false,
+		},
+		{
+			"mail gets records without IPv6",
+			with(func(r *core.CheckResultModel) { r.MXRecord = "no_record" }),
+			with(func(r *core.CheckResultModel) { r.MXRecord = "unsupported" }),
+			"IPv4-only MX records for example.com", false,
+		},
+		{
+			"the first change is described",
+			unchanged,
+			with(func(r *core.CheckResultModel) { r.BaseDomain, r.MXRecord = "unsupported", "unsupported" }),
+			"IPv6 lost for example.com", false,
+		},
+		{"no change", unchanged, unchanged, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generateChangelog(tt.current, tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateChangelog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("generateChangelog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
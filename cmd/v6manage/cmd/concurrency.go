@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"whynoipv6/internal/resolver"
+)
+
+const (
+	maxHealthyErrorRate = 0.05            // Back off when more than 5% of the queries fail
+	maxHealthyLatency   = 1 * time.Second // Back off when the average round trip is slower than this
+	minBatchQueries     = 20              // Do not adjust on batches with fewer queries than this
+)
+
+// concurrencyController picks the number of workers for each batch, based on how the
+// resolvers handled the previous one. It raises the number of workers by one step while
+// the error rate and latency stay healthy, and halves it when they do not.
+type concurrencyController struct {
+	mu      sync.Mutex
+	min     int // Lower bound for the number of workers
+	max     int // Upper bound for the number of workers
+	current int // Number of workers for the next batch
+	step    int // Number of workers added after a healthy batch
+}
+
+// newConcurrencyController creates a controller that starts at the given number of workers.
+func newConcurrencyController(start, min, max int) *concurrencyController {
+	step := max / 10
+	if step < 1 {
+		step = 1
+	}
+	c := &concurrencyController{min: min, max: max, step: step}
+	c.current = c.clamp(start)
+	return c
+}
+
+// Workers returns the number of workers to use for the next batch.
+func (c *concurrencyController) Workers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// Adjust updates the number of workers from the resolver stats of the last batch,
+// and returns the new number of workers.
+func (c *concurrencyController) Adjust(stats resolver.QueryStats) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Not enough data to say anything about the resolvers.
+	if stats.Queries < minBatchQueries {
+		return c.current
+	}
+
+	if stats.ErrorRate() > maxHealthyErrorRate || stats.AvgLatency() > maxHealthyLatency {
+		c.current = c.clamp(c.current / 2)
+	} else {
+		c.current = c.clamp(c.current + c.step)
+	}
+	return c.current
+}
+
+// clamp keeps the number of workers within the bounds.
+func (c *concurrencyController) clamp(n int) int {
+	if n < c.min {
+		return c.min
+	}
+	if n > c.max {
+		return c.max
+	}
+	return n
+}
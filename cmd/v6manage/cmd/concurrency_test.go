@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"whynoipv6/internal/resolver"
+)
+
+func TestConcurrencyControllerAdjust(t *testing.T) {
+	healthy := resolver.QueryStats{Queries: 100, Latency: 100 * 50 * time.Millisecond}
+	tests := []struct {
+		name       string
+		start      int
+		min, max   int
+		stats      resolver.QueryStats
+		want       int
+		wantCreate int
+	}{
+		{"healthy batch adds a step", 10, 2, 50, healthy, 15, 10},
+		{"step is at least one", 3, 1, 5, healthy, 4, 3},
+		{"growth stops at the maximum", 48, 2, 50, healthy, 50, 48},
+		{"start is clamped to the maximum", 80, 2, 50, healthy, 50, 50},
+		{"start is clamped to the minimum", 0, 2, 50, healthy, 7, 2},
+		{"small batches are ignored", 10, 2, 50, resolver.QueryStats{Queries: 19, Errors: 19}, 10, 10},
+		{"errors halve the workers", 10, 2, 50, resolver.QueryStats{Queries: 100, Errors: 10}, 5, 10},
+		{"servfails count as errors", 10, 2, 50, resolver.QueryStats{Queries: 100, ServFail: 6}, 5, 10},
+		{"error rate at the limit is healthy", 10, 2, 50, resolver.QueryStats{Queries: 100, Errors: 5}, 15, 10},
+		{"slow answers halve the workers", 10, 2, 50, resolver.QueryStats{Queries: 100, Latency: 100 * 2 * time.Second}, 5, 10},
+		{"backoff stops at the minimum", 3, 2, 50, resolver.QueryStats{Queries: 100, Errors: 50}, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConcurrencyController(tt.start, tt.min, tt.max)
+			if got := c.Workers(); got != tt.wantCreate {
+				t.Fatalf("Workers() after create = %d, want %d", got, tt.wantCreate)
+			}
+			if got := c.Adjust(tt.stats); got != tt.want {
+				t.Errorf("Adjust() = %d, want %d", got, tt.want)
+			}
+			if got := c.Workers(); got != tt.want {
+				t.Errorf("Workers() after Adjust = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
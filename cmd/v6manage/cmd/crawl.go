@@ -2,40 +2,73 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
 	"whynoipv6/internal/core"
 	"whynoipv6/internal/geoip"
+	"whynoipv6/internal/metrics"
+	"whynoipv6/internal/postgres"
 	"whynoipv6/internal/resolver"
 	"whynoipv6/internal/toolbox"
+	"whynoipv6/internal/tracing"
 
 	"github.com/miekg/dns"
 	"github.com/spf13/cobra"
 )
 
+// crawlOptions holds the settings for the domain crawler.
+var crawlOptions crawlerOptions
+
 // crawlCmd represents the crawl command
 var crawlCmd = &cobra.Command{
 	Use:   "crawl",
 	Short: "Crawls the campaign sites in the database",
-	Long:  "Crawls the campagin sites in the database",
+	Long: `Crawls the campagin sites in the database.
+By default the crawler runs forever, use --once for a single pass or --cron to run on a schedule.
+Rechecks requested through the API are processed while the crawler runs, ahead of the scheduled passes.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		changelogService = *core.NewChangelogService(db)
-		domainService = *core.NewDomainService(db)
-		countryService = *core.NewCountryService(db)
-		asnService = *core.NewASNService(db)
-		metricService = *core.NewMetricService(db)
-		domainCrawl()
+		crawlOptions.applyConfig(cmd, crawlerOptions{
+			Workers:      cfg.CrawlerWorkers,
+			MinWorkers:   cfg.CrawlerMinWorkers,
+			MaxWorkers:   cfg.CrawlerMaxWorkers,
+			BatchSize:    cfg.CrawlerBatchSize,
+			JobTimeout:   cfg.CrawlerJobTimeout,
+			CheckTimeout: cfg.CrawlerCheckTimeout,
+			ReuseWindow:  cfg.CrawlerReuseWindow,
+			Interval:     cfg.CrawlerInterval,
+			Schedule:     cfg.CrawlerSchedule,
+		})
+		if err := crawlOptions.validate(); err != nil {
+			logg.Fatal().Err(err).Msg("Invalid crawler options")
+		}
+		traced := postgres.Traced(db)
+		domainService = *core.NewDomainService(traced)
+		countryService = *core.NewCountryService(traced)
+		asnService = *core.NewASNService(traced)
+		metricService = *core.NewMetricService(traced)
+		checkResultService = *core.NewCheckResultService(traced)
+		recheckJobService = *core.NewRecheckJobService(traced)
+		domainCrawl(crawlOptions)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(crawlCmd)
+	addCrawlerFlags(crawlCmd, &crawlOptions, crawlerOptions{
+		Workers:      10,
+		MinWorkers:   2,
+		MaxWorkers:   50,
+		BatchSize:    200,
+		JobTimeout:   2 * time.Minute,
+		CheckTimeout: 30 * time.Second,
+		ReuseWindow:  time.Hour,
+		Interval:     10 * time.Minute,
+	})
 }
 
 // domainCrawl crawls the domains in the database
-func domainCrawl() {
+func domainCrawl(opts crawlerOptions) {
 	ctx := context.Background()
 	logg := logg.With().Str("service", "domainCrawl").Logger()
 
@@ -46,450 +79,226 @@ func domainCrawl() {
 		return
 	}
 
-	const (
-		numWorkers = 10              // Number of workers
-		jobTimeout = 2 * time.Minute // Timeout for each batch of jobs
-	)
-
-	// Run the crawler indefinitely.
-	for {
-		t := time.Now()
-		logg.Info().Msg("Starting crawl at " + t.Format("2006-01-02 15:04:05"))
-
-		var lastProcessedID int64 = 0 // Offset for the database query
-		const limit int64 = 200       // Limit for the database query
-		var totalDomains int          // Total number of domains checked in this crawl
-		var totalSuccessfulJobs int   // Total number of successful jobs
-		var totalFailedJobs int       // Total number of failed jobs
-
-		// Inner loop
-		for {
-			var domainJobs int                         // Number of domains updated in this batch
-			successfulJobs := 0                        // Successful jobs in this batch
-			failedJobs := 0                            // Failed jobs in this batch
-			loopTime := time.Now()                     // Start time for this batch
-			jobs := make(chan core.DomainModel, limit) // Channel for jobs
-			done := make(chan bool, limit)             // Channel for signaling completion of jobs
-
-			// Start workers for this batch of jobs
-			for w := 1; w <= numWorkers; w++ {
-				go processDomain(ctx, jobs, done)
-			}
+	// Export the traces of the checks, if a collector is configured.
+	shutdownTracing := setupTracing(ctx, "whynoipv6-crawler")
+	defer shutdownTracing()
 
-			// Get domains to check
-			domains, err := domainService.CrawlDomain(ctx, lastProcessedID, limit)
-			if err != nil {
-				// Ping the sql server to see if it's up
-				if err = db.Ping(ctx); err != nil {
-					toolbox.HealthCheckUpdate(cfg.HealthcheckCrawler, toolbox.HealthFail)
-					logg.Fatal().Err(err).Msg("Database is down!")
-					return
-				}
-				logg.Error().Err(err).Msg("Could not get domains to check")
-				return
-			}
+	// The number of workers is adjusted after every batch, and carries over between passes.
+	workers := newConcurrencyController(opts.Workers, opts.MinWorkers, opts.MaxWorkers)
 
-			// Break out of loop if there are no domains left
-			if len(domains) == 0 {
-				break
-			}
+	// Process the requested rechecks next to the scheduled passes.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go runRecheckJobs(ctx, opts)
+	serveMetrics(ctx, cfg.CrawlerMetricsAddr)
 
-			// Initialize the variable to track the highest ID in this batch
-			var highestIDInBatch int64 = 0
+	err = runScheduled(ctx, logg, opts, func(ctx context.Context) error {
+		return domainCrawlPass(ctx, opts, workers)
+	})
+	if err != nil {
+		logg.Fatal().Err(err).Msg("Crawler stopped")
+	}
+}
 
-			// Send jobs to the workers
-			for _, domain := range domains {
-				jobs <- domain // Send the job to the jobs channel
-				domainJobs++   // Increment the number of domains updated in this batch
-				totalDomains++ // Increment the total number of domains checked in this crawl
+// domainCrawlPass runs a single pass over all the domains in the database.
+func domainCrawlPass(
+	ctx context.Context,
+	opts crawlerOptions,
+	workers *concurrencyController,
+) error {
+	logg := logg.With().Str("service", "domainCrawl").Logger()
+	limit := opts.BatchSize // Limit for the database query
 
-				// Update highestIDInBatch if the current domain's ID is higher
-				if domain.ID > highestIDInBatch {
-					highestIDInBatch = domain.ID
-				}
-			}
+	t := time.Now()
+	logg.Info().Msg("Starting crawl at " + t.Format("2006-01-02 15:04:05"))
 
-			close(
-				jobs,
-			) // Close the jobs channel and wait for this batch of workers to finish
-			timeout := time.After(jobTimeout) // Timeout for this batch of jobs
-
-			// This loop monitors the completion of domain processing jobs. It iterates up to 'domainJobs' times,
-			// checking for job completion or timeout. Each iteration either increments 'successfulJobs' or 'failedJobs'
-			// based on the job's success status reported via the 'done' channel. If a 'jobTimeout' occurs,
-			// indicated by the 'timeout' channel, it logs a warning and exits the loop early using 'goto BatchTimeout',
-			// thus handling potential delays in job processing.
-			for a := 1; a <= domainJobs; a++ {
-				select {
-				case success := <-done:
-					if success {
-						successfulJobs++
-					} else {
-						failedJobs++
-					}
-				case <-timeout:
-					logg.Warn().Msgf("Batch timeout after %v", jobTimeout)
-					goto BatchTimeout // Break out of the inner loop
-				}
-			}
+	var lastProcessedID int64 = 0 // Offset for the database query
+	var totalDomains int          // Total number of domains checked in this crawl
+	var totalSuccessfulJobs int   // Total number of successful jobs
+	var totalFailedJobs int       // Total number of failed jobs
 
-			// Batch finished
-		BatchTimeout:
-			// offset += limit // Update the offset for the next batch
-			lastProcessedID = highestIDInBatch // Update lastProcessedID for the next batch
-			totalSuccessfulJobs += successfulJobs // Update the total count of successful jobs
-			totalFailedJobs += failedJobs         // Update the total count of failed jobs
-			logg.Info().
-				Msgf("Checked %v domains, Successful: %v, Failed: %v Total: %v Duration: %s", domainJobs, successfulJobs, failedJobs, totalDomains, prettyDuration(time.Since(loopTime)))
-		}
-
-		// Outer loop finished
-		logg.Info().
-			Msgf("Total Domains: %v domains, Successful Jobs: %v, Failed Jobs: %v Duration: %s", totalDomains, totalSuccessfulJobs, totalFailedJobs, prettyDuration(time.Since(t)))
-
-		// Store crawler metrics in the database.
-		crawlData := map[string]any{
-			"duration": time.Since(t).Seconds(),
-			"total":    totalDomains,
-			"success":  totalSuccessfulJobs,
-			"failed":   totalFailedJobs,
-		}
-		if err := metricService.StoreMetric(ctx, "crawler", crawlData); err != nil {
-			logg.Err(err).Msg("Error storing metric")
-		}
+	// Inner loop
+	for {
+		numWorkers := workers.Workers() // Number of workers for this batch
+		loopTime := time.Now()          // Start time for this batch
 
-		// Collect and store domain statistics.
-		stats, err := domainService.CrawlerStats(ctx)
+		// Get domains to check
+		domains, err := domainService.CrawlDomain(ctx, lastProcessedID, limit)
 		if err != nil {
-			logg.Err(err).Msg("Error getting stats")
-		}
-		if err := metricService.StoreMetric(ctx, "domains", stats); err != nil {
-			logg.Err(err).Msg("Error storing metric")
+			// Ping the sql server to see if it's up
+			if err = db.Ping(ctx); err != nil {
+				toolbox.HealthCheckUpdate(cfg.HealthcheckCrawler, toolbox.HealthFail)
+				return fmt.Errorf("database is down: %w", err)
+			}
+			return fmt.Errorf("could not get domains to check: %w", err)
 		}
 
-		// Calculate country stats.
-		err = countryService.CalculateCountryStats(ctx)
-		if err != nil {
-			logg.Err(err).Msg("Error calculating country stats")
-		}
-		// Calculate ASN stats.
-		err = asnService.CalculateASNStats(ctx)
-		if err != nil {
-			logg.Err(err).Msg("Error calculating ASN stats")
+		// Break out of loop if there are no domains left
+		if len(domains) == 0 {
+			break
 		}
 
-		// Healthcheck reporting
-		toolbox.HealthCheckUpdate(cfg.HealthcheckCrawler, toolbox.HealthOK)
-		// Notify partyvan
-		if totalDomains > 0 {
-			toolbox.NotifyIrc(
-				fmt.Sprintf(
-					"[WhyNoIPv6] Total Domains: %v, Successful: %v, Failed: %v Duration: %s",
-					totalDomains,
-					totalSuccessfulJobs,
-					totalFailedJobs,
-					prettyDuration(time.Since(t)),
-				),
-			)
+		// Update lastProcessedID to the highest ID in this batch
+		for _, domain := range domains {
+			if domain.ID > lastProcessedID {
+				lastProcessedID = domain.ID
+			}
 		}
 
-		// Sleep for 2 hours
-		logg.Info().Msg("Time until next check: 10 minutes")
-		time.Sleep(10 * time.Minute)
-	}
-}
-
-// processDomain processes a domain and updates the database
-// Returns true if the job was successful, false if it failed
-func processDomain(ctx context.Context, jobs <-chan core.DomainModel, done chan<- bool) {
-	logg := logg.With().Str("service", "processDomain").Logger()
-	for job := range jobs {
-		// Process the job
-		checkResult, err := checkDomain(ctx, job)
-		if err != nil {
-			logg.Error().Err(err).Msgf("[%s] Could not check domain", job.Site)
-			done <- false // Signal completion indicating failure
-			return        // Stop processing this job
-		}
+		// Check the domains and wait for every result, or the batch timeout.
+		metrics.SetWorkers(metrics.CrawlerDomain, numWorkers)
+		metrics.SetQueueDepth(metrics.CrawlerDomain, len(domains))
+		// Count the queries of this batch alone, without those of the rechecks.
+		stats := &resolver.StatsSink{}
+		result := runBatch(resolver.WithStats(ctx, stats), domains, numWorkers, opts.JobTimeout, opts.CheckTimeout,
+			func(ctx context.Context, domain core.DomainModel) bool {
+				defer metrics.AddQueueDepth(metrics.CrawlerDomain, -1)
+				return processDomain(ctx, domain, opts.ReuseWindow)
+			})
+		metrics.SetQueueDepth(metrics.CrawlerDomain, 0)
+		if result.TimedOut {
+			logg.Warn().Msgf("Batch timeout after %v", opts.JobTimeout)
+		}
+
+		// Batch finished
+		totalDomains += len(domains)             // Update the total number of domains checked in this crawl
+		totalSuccessfulJobs += result.Successful // Update the total count of successful jobs
+		totalFailedJobs += result.Failed         // Update the total count of failed jobs
+		logg.Info().
+			Msgf("Checked %v domains, Successful: %v, Failed: %v Total: %v Duration: %s", len(domains), result.Successful, result.Failed, totalDomains, prettyDuration(time.Since(loopTime)))
 
-		// Update domain
-		err = updateDomain(ctx, job, checkResult)
-		if err != nil {
-			logg.Error().Err(err).Msg("Could not update domain")
-			done <- false // Signal completion indicating failure
-			return        // Stop processing this job
+		// Adjust the number of workers for the next batch based on how the resolvers kept up.
+		batchStats := stats.Stats()
+		if next := workers.Adjust(batchStats); next != numWorkers {
+			logg.Info().
+				Msgf("Workers: %v -> %v, Queries: %v, Error rate: %.1f%%, Avg latency: %s", numWorkers, next, batchStats.Queries, batchStats.ErrorRate()*100, batchStats.AvgLatency())
 		}
-
-		done <- true // Signal completion, indicating if it was successful
 	}
-}
 
-// checkDomain runns all the checks on a domain
-func checkDomain(ctx context.Context, domain core.DomainModel) (core.DomainModel, error) {
-	checkResult := core.DomainModel{}
-	logg := logg.With().Str("service", "checkDomain").Logger()
+	// Outer loop finished
+	logg.Info().
+		Msgf("Total Domains: %v domains, Successful Jobs: %v, Failed Jobs: %v Duration: %s", totalDomains, totalSuccessfulJobs, totalFailedJobs, prettyDuration(time.Since(t)))
 
-	// Validate domain
-	rcode, err := resolver.ValidateDomain(domain.Site)
-	// The return code 1 is a custom code for IDNA error
-	if rcode == dns.RcodeNameError || rcode == dns.RcodeServerFailure || rcode == 1 {
-		logg.Error().Err(err).Msgf("[%s] Disabling domain", domain.Site)
-		// Disable domain
-		if disableErr := domainService.DisableDomain(ctx, domain.Site); disableErr != nil {
-			logg.Error().Err(disableErr).Msg("Could not disable domain")
-		}
-		return domain, err
-	}
-	if err != nil {
-		// logg.Error().Err(err).Msg("Invalid domain")
-		return domain, err
-	}
-
-	// Run all the checks on the domain.
-	domainResult, err := resolver.DomainStatus(domain.Site)
-	if err != nil {
-		logg.Error().Err(err).Msgf("[%s] Could not check domain", domain.Site)
-		return domain, err
-	}
+	metrics.PassFinished(metrics.CrawlerDomain, time.Since(t))
 
-	// Map the result to the domain model.
-	checkResult.ID = domain.ID
-	checkResult.Site = domain.Site
-	checkResult.BaseDomain = domainResult.BaseDomain
-	checkResult.WwwDomain = domainResult.WwwDomain
-	checkResult.Nameserver = domainResult.Nameserver
-	checkResult.MXRecord = domainResult.MXRecord
-
-	// Check if the results are empty and set them to "no_record" if they are.
-	if checkResult.BaseDomain == "" {
-		logg.Error().Msgf("[%s] Empty BaseDomain", domain.Site)
-		checkResult.BaseDomain = "no_record"
+	// Store crawler metrics in the database.
+	crawlData := map[string]any{
+		"duration": time.Since(t).Seconds(),
+		"total":    totalDomains,
+		"success":  totalSuccessfulJobs,
+		"failed":   totalFailedJobs,
 	}
-	if checkResult.WwwDomain == "" {
-		logg.Error().Msgf("[%s] Empty WwwDomain", domain.Site)
-		checkResult.WwwDomain = "no_record"
-	}
-	if checkResult.Nameserver == "" {
-		logg.Error().Msgf("[%s] Empty Nameserver", domain.Site)
-		checkResult.Nameserver = "no_record"
-	}
-	if checkResult.MXRecord == "" {
-		logg.Error().Msgf("[%s] Empty MXRecord", domain.Site)
-		checkResult.MXRecord = "no_record"
+	if err := metricService.StoreMetric(ctx, "crawler", crawlData); err != nil {
+		logg.Err(err).Msg("Error storing metric")
 	}
 
-	// Retrieve ASN information for the domain if it has basic dns records.
-	// If the domain has no records, set the ASN ID to 1 (Unknown).
-	if checkResult.BaseDomain != "no_record" || checkResult.WwwDomain != "no_record" {
-		asnID, err := getNetworkProvider(ctx, domain.Site)
-		if err != nil {
-			logg.Error().Err(err).Msg("Could not get ASN info")
-		}
-		// Check if the ASN is empty
-		if asnID == 0 {
-			logg.Error().Msgf("[%s] Empty ASN", domain.Site)
-			checkResult.AsnID = 1
-		}
-		checkResult.AsnID = asnID
-	} else {
-		checkResult.AsnID = 1
+	// Collect and store domain statistics.
+	stats, err := domainService.CrawlerStats(ctx)
+	if err != nil {
+		logg.Err(err).Msg("Error getting stats")
 	}
-
-	// Map country code to country table
-	// If the domain has no records, set the Country ID to 251 (Unknown).
-	if checkResult.BaseDomain != "no_record" || checkResult.WwwDomain != "no_record" {
-		countryID, err := getCountryID(ctx, domain.Site)
-		if err != nil {
-			logg.Error().Err(err).Msg("Could not get country info")
-		}
-		// Check if the country is empty
-		if countryID == 0 {
-			logg.Error().Msgf("[%s] Empty country", domain.Site)
-			checkResult.CountryID = 251
-		}
-		checkResult.CountryID = countryID
-	} else {
-		checkResult.CountryID = 251
+	if err := metricService.StoreMetric(ctx, "domains", stats); err != nil {
+		logg.Err(err).Msg("Error storing metric")
 	}
 
-	// Update the domain with the check result.
-	return checkResult, nil
-}
-
-func updateDomain(ctx context.Context, currentDomain, newDomain core.DomainModel) error {
-	// Helper function to log and create a changelog entry.
-	createChangelog := func(domain core.DomainModel, message string, status string) {
-		_, err := changelogService.Create(ctx, core.ChangelogModel{
-			DomainID:   domain.ID,
-			Message:    message,
-			IPv6Status: status,
-		})
-		if err != nil {
-			logg.Error().Err(err).Msg("Could not write changelog")
-		}
+	// Calculate country stats, and keep a daily snapshot of them.
+	err = countryService.CalculateCountryStats(ctx)
+	if err != nil {
+		logg.Err(err).Msg("Error calculating country stats")
+	} else if err := countryService.SnapshotCountryHistory(ctx); err != nil {
+		logg.Err(err).Msg("Error storing country history")
 	}
-
-	// Check if there is any changes to the domain.
-	if currentDomain.BaseDomain != newDomain.BaseDomain {
-		changelog, err := generateChangelog(currentDomain, newDomain)
-		if err != nil {
-			logg.Error().Err(err).Msgf("[%s] Could not generate changelog", currentDomain.Site)
-			return err
-		}
-		createChangelog(currentDomain, changelog, newDomain.BaseDomain)
-		currentDomain.BaseDomain = newDomain.BaseDomain
-		currentDomain.TsBaseDomain = time.Now()
-		currentDomain.TsUpdated = time.Now()
+	// Calculate ASN stats, and keep a daily snapshot of them.
+	err = asnService.CalculateASNStats(ctx)
+	if err != nil {
+		logg.Err(err).Msg("Error calculating ASN stats")
+	} else if err := asnService.SnapshotASNHistory(ctx); err != nil {
+		logg.Err(err).Msg("Error storing ASN history")
 	}
 
-	if currentDomain.WwwDomain != newDomain.WwwDomain {
-		changelog, err := generateChangelog(currentDomain, newDomain)
-		if err != nil {
-			logg.Error().Err(err).Msgf("[%s] Could not generate changelog", currentDomain.Site)
-			return err
-		}
-		createChangelog(currentDomain, changelog, newDomain.WwwDomain)
-		currentDomain.WwwDomain = newDomain.WwwDomain
-		currentDomain.TsWwwDomain = time.Now()
-		currentDomain.TsUpdated = time.Now()
+	// Healthcheck reporting
+	toolbox.HealthCheckUpdate(cfg.HealthcheckCrawler, toolbox.HealthOK)
+	// Notify partyvan
+	if totalDomains > 0 {
+		toolbox.NotifyIrc(
+			fmt.Sprintf(
+				"[WhyNoIPv6] Total Domains: %v, Successful: %v, Failed: %v Duration: %s",
+				totalDomains,
+				totalSuccessfulJobs,
+				totalFailedJobs,
+				prettyDuration(time.Since(t)),
+			),
+		)
 	}
 
-	if currentDomain.Nameserver != newDomain.Nameserver {
-		changelog, err := generateChangelog(currentDomain, newDomain)
-		if err != nil {
-			logg.Error().Err(err).Msgf("[%s] Could not generate changelog", currentDomain.Site)
-			return err
-		}
-		createChangelog(currentDomain, changelog, newDomain.Nameserver)
-		currentDomain.Nameserver = newDomain.Nameserver
-		currentDomain.TsNameserver = time.Now()
-		currentDomain.TsUpdated = time.Now()
-	}
+	return nil
+}
 
-	if currentDomain.MXRecord != newDomain.MXRecord {
-		changelog, err := generateChangelog(currentDomain, newDomain)
-		if err != nil {
-			logg.Error().Err(err).Msgf("[%s] Could not generate changelog", currentDomain.Site)
-			return err
-		}
-		createChangelog(currentDomain, changelog, newDomain.MXRecord)
-		currentDomain.MXRecord = newDomain.MXRecord
-		currentDomain.TsMXRecord = time.Now()
-		currentDomain.TsUpdated = time.Now()
+// processDomain checks a domain and stores the result in the database.
+// Returns true if the job was successful, false if it failed.
+func processDomain(ctx context.Context, job core.DomainModel, reuseWindow time.Duration) (success bool) {
+	defer recordCheck(metrics.CrawlerDomain, time.Now(), &success)
+	ctx, span := startCheck(ctx, metrics.CrawlerDomain, job.Site)
+	defer endCheck(span, &success)
+	logg := tracing.Logger(ctx, logg.With().Str("service", "processDomain").Logger())
+
+	// The result is shared with the campaigns, a site one of them checked recently is not checked again.
+	if result, ok := recentCheckResult(ctx, job.Site, reuseWindow); ok {
+		recordOutcomes(metrics.CrawlerDomain, result.BaseDomain, result.WwwDomain, result.Nameserver, result.MXRecord)
+		return true
 	}
 
-	// Update ASN ID and Country ID.
-	currentDomain.AsnID = newDomain.AsnID
-	currentDomain.CountryID = newDomain.CountryID
-
-	// Update the check timestamp.
-	currentDomain.TsCheck = time.Now()
-
-	// Update the domain in the database.
-	err := domainService.UpdateDomain(ctx, currentDomain)
+	// Process the job
+	checkResult, err := checkDomain(ctx, job.Site)
 	if err != nil {
-		logg.Error().Err(err).Msgf("[%s] Could not update domain", currentDomain.Site)
-		return err
-	}
-	// CrawlerStat represents a domain statistic.
-	type DomainLog struct {
-		BaseDomain string `json:"base_domain"`
-		WwwDomain  string `json:"www_domain"`
-		Nameserver string `json:"nameserver"`
-		MxRecord   string `json:"mx_record"`
+		logg.Error().Err(err).Msgf("[%s] Could not check domain", job.Site)
+		return false
 	}
 
-	// Write a log of the check.
-	if err := domainService.StoreDomainLog(ctx, currentDomain.ID, DomainLog{
-		BaseDomain: newDomain.BaseDomain,
-		WwwDomain:  newDomain.WwwDomain,
-		Nameserver: newDomain.Nameserver,
-		MxRecord:   newDomain.MXRecord,
-	}); err != nil {
-		logg.Error().Err(err).Msgf("[%s] Could not store domain log", currentDomain.Site)
+	// Store the result, even if the deadline for the check passed while it was being written.
+	err = applyCheckResult(context.WithoutCancel(ctx), checkResult)
+	if err != nil {
+		logg.Error().Err(err).Msgf("[%s] Could not store check result", job.Site)
+		return false
 	}
 
-	return nil
+	recordOutcomes(metrics.CrawlerDomain,
+		checkResult.BaseDomain, checkResult.WwwDomain, checkResult.Nameserver, checkResult.MXRecord)
+	return true
 }
 
-// generateChangelog checks the result of the change and generates a changelog entry.
-func generateChangelog(currentDomain, newDomain core.DomainModel) (string, error) {
-	// Base Domain
-	if currentDomain.BaseDomain != newDomain.BaseDomain {
-		if currentDomain.BaseDomain == "unsupported" && newDomain.BaseDomain == "supported" {
-			return fmt.Sprintf("IPv6 enabled for %s", currentDomain.Site), nil
-		}
-		if currentDomain.BaseDomain == "supported" && newDomain.BaseDomain == "unsupported" {
-			return fmt.Sprintf("IPv6 lost for %s", currentDomain.Site), nil
-		}
-		if currentDomain.BaseDomain == "no_record" && newDomain.BaseDomain == "supported" {
-			return fmt.Sprintf("IPv6 enabled for %s", currentDomain.Site), nil
-		}
-		if currentDomain.BaseDomain == "no_record" && newDomain.BaseDomain == "unsupported" {
-			return fmt.Sprintf("IPv4-only for %s", currentDomain.Site), nil
-		}
-		if newDomain.BaseDomain == "no_record" {
-			return fmt.Sprintf("No DNS records found for %s", currentDomain.Site), nil
+// checkDomain runns all the checks on a domain
+func checkDomain(ctx context.Context, site string) (core.CheckResultModel, error) {
+	logg := tracing.Logger(ctx, logg.With().Str("service", "checkDomain").Logger())
+
+	// Validate domain
+	rcode, err := resolver.ValidateDomain(ctx, site)
+	// The return code 1 is a custom code for IDNA error
+	if rcode == dns.RcodeNameError || rcode == dns.RcodeServerFailure || rcode == 1 {
+		logg.Error().Err(err).Msgf("[%s] Disabling domain", site)
+		// Disable domain
+		if disableErr := domainService.DisableDomain(ctx, site); disableErr != nil {
+			logg.Error().Err(disableErr).Msg("Could not disable domain")
 		}
+		return core.CheckResultModel{}, err
 	}
-	// WWW Domain
-	if currentDomain.WwwDomain != newDomain.WwwDomain {
-		if currentDomain.WwwDomain == "unsupported" && newDomain.WwwDomain == "supported" {
-			return fmt.Sprintf("IPv6 enabled for www.%s", currentDomain.Site), nil
-		}
-		if currentDomain.WwwDomain == "supported" && newDomain.WwwDomain == "unsupported" {
-			return fmt.Sprintf("IPv6 lost for www.%s", currentDomain.Site), nil
-		}
-		if currentDomain.WwwDomain == "no_record" && newDomain.WwwDomain == "supported" {
-			return fmt.Sprintf("IPv6 enabled for www.%s", currentDomain.Site), nil
-		}
-		if currentDomain.WwwDomain == "no_record" && newDomain.WwwDomain == "unsupported" {
-			return fmt.Sprintf("IPv4-only for www.%s", currentDomain.Site), nil
-		}
-		if newDomain.WwwDomain == "no_record" {
-			return fmt.Sprintf("No DNS records found for www.%s", currentDomain.Site), nil
-		}
+	if err != nil {
+		// logg.Error().Err(err).Msg("Invalid domain")
+		return core.CheckResultModel{}, err
 	}
 
-	// Nameserver
-	if currentDomain.Nameserver != newDomain.Nameserver {
-		if currentDomain.Nameserver == "unsupported" && newDomain.Nameserver == "supported" {
-			return fmt.Sprintf("IPv6 enabled nameserver for %s", currentDomain.Site), nil
-		}
-		if currentDomain.Nameserver == "supported" && newDomain.Nameserver == "unsupported" {
-			return fmt.Sprintf("Nameservers degraded to IPv4-only for %s", currentDomain.Site), nil
-		}
-		if currentDomain.Nameserver == "no_record" && newDomain.Nameserver == "supported" {
-			return fmt.Sprintf("IPv6 enabled nameserver for %s", currentDomain.Site), nil
-		}
-		if currentDomain.Nameserver == "no_record" && newDomain.Nameserver == "unsupported" {
-			return fmt.Sprintf("IPv4-only nameservers for %s", currentDomain.Site), nil
-		}
-		if newDomain.Nameserver == "no_record" {
-			return fmt.Sprintf("No NS records found for %s", currentDomain.Site), nil
-		}
+	// Run all the checks on the domain.
+	domainResult, err := resolver.DomainStatus(ctx, site)
+	if err != nil {
+		logg.Error().Err(err).Msgf("[%s] Could not check domain", site)
+		return core.CheckResultModel{}, err
 	}
 
-	// MX Record
-	if currentDomain.MXRecord != newDomain.MXRecord {
-		if currentDomain.MXRecord == "unsupported" && newDomain.MXRecord == "supported" {
-			return fmt.Sprintf("IPv6 enabled MX records for %s", currentDomain.Site), nil
-		}
-		if currentDomain.MXRecord == "supported" && newDomain.MXRecord == "unsupported" {
-			return fmt.Sprintf("MX records degraded to IPv4-only for %s", currentDomain.Site), nil
-		}
-		if currentDomain.MXRecord == "no_record" && newDomain.MXRecord == "supported" {
-			return fmt.Sprintf("IPv6 enabled MX records for %s", currentDomain.Site), nil
-		}
-		if currentDomain.MXRecord == "no_record" && newDomain.MXRecord == "unsupported" {
-			return fmt.Sprintf("IPv4-only MX records for %s", currentDomain.Site), nil
-		}
-		if newDomain.MXRecord == "no_record" {
-			return fmt.Sprintf("No Mail records found for %s", currentDomain.Site), nil
-		}
+	// The ASN and country lookups do not fail when the deadline passes, so a
+	// late result is not kept.
+	result := newCheckResult(ctx, site, domainResult)
+	if err := ctx.Err(); err != nil {
+		return core.CheckResultModel{}, fmt.Errorf("check of [%s] cancelled: %w", site, err)
 	}
-
-	return "", errors.New(
-		"Unknown change for " + currentDomain.Site + ": BaseDomain: [" + currentDomain.BaseDomain + " - " + newDomain.BaseDomain + "] WwwDomain: [" + currentDomain.WwwDomain + " - " + newDomain.WwwDomain + "] Nameserver: [" + currentDomain.Nameserver + " - " + newDomain.Nameserver + "] MXRecord: [" + currentDomain.MXRecord + " - " + newDomain.MXRecord + "]",
-	)
+	return result, nil
 }
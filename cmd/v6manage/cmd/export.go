@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/export"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat   string                  // File format, from the output file extension if empty
+	exportOutput   string                  // Output file, stdout if empty
+	exportFilter   core.DomainFilterParams // Filters of the exported domains
+	exportCampaign string                  // Campaign UUID
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:     "export",
+	Aliases: []string{"dump"},
+	Short:   "Exports the domain table as CSV, NDJSON or Parquet",
+	Long: `Exports every domain, or the domains matching the filters, as CSV, NDJSON or Parquet.
+The format is taken from the extension of --output unless --format is set.
+The columns are the same as the /export API endpoints, see /export/schema.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		domainService = *core.NewDomainService(db)
+		if err := exportDomains(); err != nil {
+			logg.Error().Err(err).Msg("Export failed")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	flags := exportCmd.Flags()
+	flags.StringVarP(&exportFormat, "format", "f", "", "file format: "+strings.Join(export.Formats, ", "))
+	flags.StringVarP(&exportOutput, "output", "o", "", "output file, stdout if not set")
+	flags.StringVar(&exportFilter.BaseDomain, "base", "", "status of the AAAA check, e.g. supported")
+	flags.StringVar(&exportFilter.WwwDomain, "www", "", "status of the www AAAA check")
+	flags.StringVar(&exportFilter.Nameserver, "ns", "", "status of the nameserver check")
+	flags.StringVar(&exportFilter.MXRecord, "mx", "", "status of the MX check")
+	flags.StringVar(&exportFilter.V6Only, "v6-only", "", "status of the IPv6-only check")
+	flags.StringVar(&exportFilter.Country, "country", "", "two-letter country code")
+	flags.StringVar(&exportFilter.Continent, "continent", "", "continent name, e.g. Europe")
+	flags.Int32Var(&exportFilter.ASN, "asn", 0, "AS number")
+	flags.Int64Var(&exportFilter.RankMin, "rank-min", 0, "lowest rank, inclusive")
+	flags.Int64Var(&exportFilter.RankMax, "rank-max", 0, "highest rank, inclusive")
+	flags.StringVar(&exportCampaign, "campaign", "", "only domains that are part of this campaign UUID")
+	flags.StringVar(&exportFilter.UpdatedAfter, "updated-after", "", "last changed at or after, a date or RFC 3339 timestamp")
+	flags.StringVar(&exportFilter.UpdatedBefore, "updated-before", "", "last changed before, a date or RFC 3339 timestamp")
+}
+
+// exportDomains writes the domains matching the filters to the output file.
+func exportDomains() error {
+	ctx := context.Background()
+
+	format, err := exportFileFormat()
+	if err != nil {
+		return err
+	}
+	// The filters are validated as the /export endpoints do, the errors name
+	// them with _ where the flags have -, e.g. rank_min for --rank-min.
+	filter, err := core.ParseDomainFilter(exportFilter)
+	if err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	if exportCampaign != "" {
+		if filter.CampaignID, err = uuid.Parse(exportCampaign); err != nil {
+			return fmt.Errorf("invalid campaign uuid: %w", err)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if exportOutput != "" {
+		file, err := os.Create(exportOutput)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	t := time.Now()
+	count := 0
+	writer := export.NewWriter(buffered, format)
+	err = domainService.ExportDomains(ctx, filter, func(d core.DomainExportModel) error {
+		count++
+		return writer.Write(d)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		if exportOutput != "" {
+			_ = os.Remove(exportOutput)
+		}
+		return err
+	}
+	logg.Info().Msgf("Exported %d domains as %s in %s", count, format, prettyDuration(time.Since(t)))
+	return nil
+}
+
+// exportFileFormat returns the format of the export, from --format or the
+// extension of --output, CSV if neither is set.
+func exportFileFormat() (export.Format, error) {
+	name := exportFormat
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(exportOutput), ".")
+	}
+	if name == "" {
+		return export.FormatCSV, nil
+	}
+	format, ok := export.ParseFormat(strings.ToLower(name))
+	if !ok {
+		return "", fmt.Errorf("format must be one of %s", strings.Join(export.Formats, ", "))
+	}
+	return format, nil
+}
@@ -15,7 +15,7 @@ import (
 func getNetworkProvider(ctx context.Context, domain string) (int64, error) {
 	logg := logg.With().Str("service", "getNetworkProvider").Logger()
 	// Get the domain's IP addresses.
-	ip, err := resolver.IPLookup(domain)
+	ip, err := resolver.IPLookup(ctx, domain)
 	if err != nil {
 		logg.Debug().Msgf("[%s] GeoLookup Error: %s", domain, err)
 	}
@@ -89,7 +89,7 @@ func getCountryID(ctx context.Context, domain string) (int64, error) {
 	// If no TLD mapping is found, check the Geo Database for the country code.
 
 	// Get the domains IP.
-	ip, err := resolver.IPLookup(domain)
+	ip, err := resolver.IPLookup(ctx, domain)
 	if err != nil {
 		logg.Debug().Msgf("[%s] IPLookup Error: %s", domain, err)
 	}
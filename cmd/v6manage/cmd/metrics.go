@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"whynoipv6/internal/metrics"
+)
+
+// serveMetrics serves the Prometheus metrics of the crawler on addr, until
+// the context is cancelled. Nothing is served if addr is empty.
+func serveMetrics(ctx context.Context, addr string) {
+	if addr == "" {
+		return
+	}
+	metrics.RegisterPool(db)
+	go func() {
+		logg.Info().Msgf("Serving metrics on http://%s/metrics", addr)
+		if err := metrics.Serve(ctx, addr); err != nil {
+			logg.Error().Err(err).Msg("Metrics listener stopped")
+		}
+	}()
+}
+
+// recordCheck records a finished check of a crawler in the metrics. It is
+// deferred at the start of the check, with the named result of the check.
+func recordCheck(crawler string, start time.Time, success *bool) {
+	metrics.Check(crawler, *success, time.Since(start))
+}
+
+// recordOutcomes records the outcome of every check of a domain in the metrics.
+func recordOutcomes(crawler, baseDomain, wwwDomain, nameserver, mxRecord string) {
+	metrics.CheckOutcome(crawler, "base_domain", baseDomain)
+	metrics.CheckOutcome(crawler, "www_domain", wwwDomain)
+	metrics.CheckOutcome(crawler, "nameserver", nameserver)
+	metrics.CheckOutcome(crawler, "mx_record", mxRecord)
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/export"
+	"whynoipv6/internal/snapshot"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/spf13/cobra"
+)
+
+// snapshotChangelogBatch is the number of changelog entries read at a time.
+const snapshotChangelogBatch = 5000
+
+var (
+	snapshotDir    string // Snapshot directory, SNAPSHOT_PATH if empty
+	snapshotFormat string // Format of the domains file
+	snapshotForce  bool   // Replace today's snapshot
+)
+
+// publishSnapshotCmd represents the publish-snapshot command
+var publishSnapshotCmd = &cobra.Command{
+	Use:   "publish-snapshot",
+	Short: "Publishes a dated, checksummed archive of the dataset",
+	Long: `Publishes a dated archive of the dataset to the snapshot directory: the status of every
+domain, the changelog entries since the previous snapshot, and the country and ASN statistics.
+The archive is listed in the manifest.json of the directory, and its checksum is written next to it.
+Run it after a crawl, e.g. once a week from cron.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := publishSnapshot(); err != nil {
+			logg.Error().Err(err).Msg("Could not publish snapshot")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishSnapshotCmd)
+	flags := publishSnapshotCmd.Flags()
+	flags.StringVar(&snapshotDir, "dir", "", "snapshot directory, SNAPSHOT_PATH if not set")
+	flags.StringVar(&snapshotFormat, "format", string(export.FormatCSV),
+		"format of the domains file: "+strings.Join(export.Formats, ", "))
+	flags.BoolVar(&snapshotForce, "force", false, "replace the snapshot if one was already published today")
+}
+
+// publishSnapshot writes the snapshot of today to the snapshot directory, and
+// adds it to the manifest.
+func publishSnapshot() error {
+	ctx := context.Background()
+
+	dir := snapshotDir
+	if dir == "" {
+		dir = cfg.SnapshotPath
+	}
+	if dir == "" {
+		return errors.New("no snapshot directory, set SNAPSHOT_PATH or --dir")
+	}
+	format, ok := export.ParseFormat(snapshotFormat)
+	if !ok {
+		return fmt.Errorf("format must be one of %s", strings.Join(export.Formats, ", "))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	manifest, err := snapshot.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+	snap := snapshot.Snapshot{
+		Name:    snapshot.Name(created),
+		Date:    created.Format(time.DateOnly),
+		Created: created,
+	}
+	for _, s := range manifest.Snapshots {
+		if s.Name == snap.Name && !snapshotForce {
+			return fmt.Errorf("snapshot %s has already been published, use --force to replace it", snap.Name)
+		}
+	}
+	if previous, ok := manifest.Previous(snap.Name); ok {
+		snap.Changelog.AfterID = previous.Changelog.LastID
+	}
+
+	staging, err := os.MkdirTemp(dir, ".staging-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	// Every file is read in the same transaction, so they are consistent with
+	// each other even while a crawler is writing.
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // The transaction only reads
+
+	t := time.Now()
+	domainsFile := "domains." + string(format)
+	files := []string{domainsFile, "changelog.csv", "countries.csv", "asns.csv"}
+	err = writeSnapshotFile(filepath.Join(staging, domainsFile), func(w io.Writer) error {
+		writer := export.NewWriter(w, format)
+		err := core.NewDomainService(tx).ExportDomains(ctx, core.DomainFilter{}, func(d core.DomainExportModel) error {
+			snap.Domains++
+			return writer.Write(d)
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("could not write domains: %w", err)
+	}
+	if err := writeSnapshotFile(filepath.Join(staging, "changelog.csv"), func(w io.Writer) error {
+		return writeSnapshotChangelog(ctx, core.NewChangelogService(tx), w, &snap.Changelog)
+	}); err != nil {
+		return fmt.Errorf("could not write changelog: %w", err)
+	}
+	if err := writeSnapshotFile(filepath.Join(staging, "countries.csv"), func(w io.Writer) error {
+		snap.Countries, err = writeSnapshotCountries(ctx, core.NewCountryService(tx), w)
+		return err
+	}); err != nil {
+		return fmt.Errorf("could not write countries: %w", err)
+	}
+	if err := writeSnapshotFile(filepath.Join(staging, "asns.csv"), func(w io.Writer) error {
+		snap.ASNs, err = writeSnapshotASNs(ctx, core.NewASNService(tx), w)
+		return err
+	}); err != nil {
+		return fmt.Errorf("could not write ASNs: %w", err)
+	}
+	_ = tx.Rollback(ctx)
+
+	// The manifest in the archive lists the checksum of every file in it.
+	archiveManifest := snapshot.ArchiveManifest{
+		Snapshot:      snap,
+		DomainColumns: export.Columns,
+		Previous:      []snapshot.Snapshot{},
+	}
+	for _, name := range files {
+		file, err := snapshot.HashFile(filepath.Join(staging, name))
+		if err != nil {
+			return err
+		}
+		archiveManifest.Files = append(archiveManifest.Files, file)
+	}
+	for _, s := range manifest.Snapshots {
+		if s.Name != snap.Name {
+			archiveManifest.Previous = append(archiveManifest.Previous, s)
+		}
+	}
+	if err := snapshot.WriteJSON(filepath.Join(staging, snapshot.ManifestFile), archiveManifest); err != nil {
+		return err
+	}
+
+	snap.Archive = snap.Name + ".tar.gz"
+	files = append([]string{snapshot.ManifestFile}, files...)
+	snap.SHA256, snap.Size, err = snapshot.WriteArchive(filepath.Join(dir, snap.Archive), staging, files, created)
+	if err != nil {
+		return fmt.Errorf("could not write archive: %w", err)
+	}
+	manifest.Add(snap)
+	if err := manifest.Write(dir); err != nil {
+		return err
+	}
+
+	logg.Info().Msgf(
+		"Published snapshot %s with %d domains and %d changelog entries in %s, sha256 %s",
+		snap.Archive, snap.Domains, snap.Changelog.Entries, prettyDuration(time.Since(t)), snap.SHA256,
+	)
+	return nil
+}
+
+// writeSnapshotFile creates a file in the staging directory and writes it with fn.
+func writeSnapshotFile(path string, fn func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := fn(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeSnapshotChangelog writes the changelog entries after r.AfterID as CSV,
+// and completes the range.
+func writeSnapshotChangelog(
+	ctx context.Context,
+	changelogs *core.ChangelogService,
+	w io.Writer,
+	r *snapshot.Changelog,
+) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"id", "ts", "site", "country_code", "ipv6_status", "message"}); err != nil {
+		return err
+	}
+	lastID, _, err := changelogs.LatestChangelogIDs(ctx)
+	if err != nil {
+		return err
+	}
+	r.LastID = max(lastID, r.AfterID)
+	for afterID := r.AfterID; afterID < r.LastID; {
+		entries, err := changelogs.ListChangelogSince(ctx, afterID, nil, snapshotChangelogBatch)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			err := out.Write([]string{
+				strconv.FormatInt(entry.ID, 10),
+				entry.Ts.UTC().Format(time.RFC3339),
+				entry.Site,
+				entry.CountryCode,
+				entry.IPv6Status,
+				entry.Message,
+			})
+			if err != nil {
+				return err
+			}
+			r.Entries++
+		}
+		if len(entries) < snapshotChangelogBatch {
+			break
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeSnapshotCountries writes the statistics of every country as CSV, and
+// returns the number of countries.
+func writeSnapshotCountries(ctx context.Context, countries *core.CountryService, w io.Writer) (int, error) {
+	list, err := countries.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"country_code", "country", "country_tld", "sites", "v6sites", "percent"}); err != nil {
+		return 0, err
+	}
+	for _, country := range list {
+		var percent float64
+		if err := country.Percent.AssignTo(&percent); err != nil {
+			return 0, err
+		}
+		err := out.Write([]string{
+			country.CountryCode,
+			country.Country,
+			country.CountryTld,
+			strconv.Itoa(int(country.Sites)),
+			strconv.Itoa(int(country.V6sites)),
+			strconv.FormatFloat(percent, 'f', 1, 64),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	out.Flush()
+	return len(list), out.Error()
+}
+
+// writeSnapshotASNs writes the statistics of every ASN with domains as CSV,
+// and returns the number of ASNs.
+func writeSnapshotASNs(ctx context.Context, asns *core.ASNService, w io.Writer) (int, error) {
+	list, err := asns.ListASNStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"asn", "name", "count_v4", "count_v6", "percent_v4", "percent_v6"}); err != nil {
+		return 0, err
+	}
+	for _, asn := range list {
+		err := out.Write([]string{
+			strconv.Itoa(int(asn.Number)),
+			asn.Name,
+			strconv.Itoa(int(asn.CountV4)),
+			strconv.Itoa(int(asn.CountV6)),
+			strconv.FormatFloat(asn.PercentV4, 'f', -1, 64),
+			strconv.FormatFloat(asn.PercentV6, 'f', -1, 64),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	out.Flush()
+	return len(list), out.Error()
+}
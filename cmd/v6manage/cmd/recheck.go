@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/metrics"
+	"whynoipv6/internal/tracing"
+
+	"github.com/rs/zerolog"
+)
+
+// recheckPollInterval is how often the domain crawler looks for queued recheck jobs.
+const recheckPollInterval = 5 * time.Second
+
+// runRecheckJobs processes the rechecks requested through the API until the
+// context is cancelled. It runs next to the scheduled passes, so a requested
+// recheck does not wait for the current pass to finish.
+func runRecheckJobs(ctx context.Context, opts crawlerOptions) {
+	ticker := time.NewTicker(recheckPollInterval)
+	defer ticker.Stop()
+
+	for {
+		processRecheckJobs(ctx, opts)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processRecheckJobs claims and processes queued recheck jobs until the queue is empty.
+func processRecheckJobs(ctx context.Context, opts crawlerOptions) {
+	logg := logg.With().Str("service", "recheckJobs").Logger()
+
+	for {
+		if queued, err := recheckJobService.QueuedJobs(ctx); err == nil {
+			metrics.SetQueueDepth(metrics.CrawlerRecheck, int(queued))
+		}
+
+		// A job that has been running for longer than a batch can take was claimed
+		// by a crawler that stopped, so it is claimed again.
+		staleBefore := time.Now().Add(-2 * opts.JobTimeout)
+		jobs, err := recheckJobService.ClaimJobs(ctx, opts.BatchSize, staleBefore)
+		if err != nil {
+			logg.Error().Err(err).Msg("Could not claim recheck jobs")
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		// Rechecks use the lower bound of workers, to go easy on the resolvers
+		// while a pass is running.
+		loopTime := time.Now()
+		result := runBatch(ctx, jobs, opts.MinWorkers, opts.JobTimeout, opts.CheckTimeout, processRecheckJob)
+		logg.Info().
+			Msgf("Rechecked %v domains, Successful: %v, Failed: %v Duration: %s", len(jobs), result.Successful, result.Failed, prettyDuration(time.Since(loopTime)))
+	}
+}
+
+// processRecheckJob checks the domain of a job, stores the result in the database and
+// stores the result in the job. Returns true if the job was successful.
+func processRecheckJob(ctx context.Context, job core.RecheckJobModel) (success bool) {
+	defer recordCheck(metrics.CrawlerRecheck, time.Now(), &success)
+	ctx, span := startCheck(ctx, metrics.CrawlerRecheck, job.Site)
+	defer endCheck(span, &success)
+	logg := tracing.Logger(ctx, logg.With().Str("service", "processRecheckJob").Logger())
+
+	// The result is written even if the deadline passed while checking.
+	writeCtx := context.WithoutCancel(ctx)
+	fail := func(err error) bool {
+		logg.Error().Err(err).Msgf("[%s] Recheck failed", job.Site)
+		if err := recheckJobService.FailJob(writeCtx, job, err); err != nil {
+			logFinishError(logg, job, err)
+		}
+		return false
+	}
+
+	// The client asked for a fresh result, so a recent result is not reused.
+	result, err := checkDomain(ctx, job.Site)
+	if err != nil {
+		return fail(err)
+	}
+	if err := applyCheckResult(writeCtx, result); err != nil {
+		return fail(err)
+	}
+
+	if err := recheckJobService.CompleteJob(writeCtx, job, result); err != nil {
+		logFinishError(logg, job, err)
+		return false
+	}
+	recordOutcomes(metrics.CrawlerRecheck, result.BaseDomain, result.WwwDomain, result.Nameserver, result.MXRecord)
+	return true
+}
+
+// logFinishError logs why the outcome of a job could not be stored. A job
+// that was claimed again by another crawler is left to that crawler.
+func logFinishError(logg zerolog.Logger, job core.RecheckJobModel, err error) {
+	if errors.Is(err, core.ErrRecheckJobLost) {
+		logg.Warn().Msgf("[%s] Recheck job %s was claimed by another crawler, leaving it", job.Site, job.ID)
+		return
+	}
+	logg.Error().Err(err).Msgf("[%s] Could not store recheck job", job.Site)
+}
@@ -26,6 +26,11 @@ var rootCmd = &cobra.Command{
 	Use:   "v6manage",
 	Short: "IPv6 Magic!",
 	Long:  `Does all the magic behind https://whynoipv6.com`,
+	// Read the config and connect to the database before any command runs, and
+	// not when the package is loaded, so the package can be tested without them.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		connect()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -53,7 +58,10 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+}
 
+// connect reads the config and connects to the database.
+func connect() {
 	// Read config
 	cfg, err = config.Read()
 	if err != nil {
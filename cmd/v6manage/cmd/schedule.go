@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+// crawlerOptions holds the settings for a crawler run.
+// Values come from flags, then the config file, then the defaults for each crawler.
+type crawlerOptions struct {
+	Workers      int           // Number of workers for the first batch
+	MinWorkers   int           // Lower bound for the number of workers
+	MaxWorkers   int           // Upper bound for the number of workers
+	BatchSize    int64         // Number of domains fetched from the database per batch
+	JobTimeout   time.Duration // Timeout for each batch of jobs
+	CheckTimeout time.Duration // Timeout for checking a single domain
+	ReuseWindow  time.Duration // Skip sites checked more recently than this
+	Once         bool          // Run a single pass and exit
+	Interval     time.Duration // Time to wait between passes
+	Schedule     string        // Cron expression for when to start a pass
+}
+
+// addCrawlerFlags registers the crawler flags on a command, using the given defaults.
+func addCrawlerFlags(cmd *cobra.Command, opts *crawlerOptions, defaults crawlerOptions) {
+	cmd.Flags().IntVar(&opts.Workers, "workers", defaults.Workers, "number of workers for the first batch")
+	cmd.Flags().
+		IntVar(&opts.MinWorkers, "min-workers", defaults.MinWorkers, "lower bound for the number of workers")
+	cmd.Flags().
+		IntVar(&opts.MaxWorkers, "max-workers", defaults.MaxWorkers, "upper bound for the number of workers")
+	cmd.Flags().
+		Int64Var(&opts.BatchSize, "batch-size", defaults.BatchSize, "number of domains per batch")
+	cmd.Flags().
+		DurationVar(&opts.JobTimeout, "job-timeout", defaults.JobTimeout, "timeout for each batch of jobs")
+	cmd.Flags().
+		DurationVar(&opts.CheckTimeout, "check-timeout", defaults.CheckTimeout, "timeout for checking a single domain")
+	cmd.Flags().
+		DurationVar(&opts.ReuseWindow, "reuse-window", defaults.ReuseWindow, "do not check sites again that were checked more recently than this, 0 to always check")
+	cmd.Flags().BoolVar(&opts.Once, "once", false, "run a single pass and exit")
+	cmd.Flags().
+		DurationVar(&opts.Interval, "interval", defaults.Interval, "time to wait between passes")
+	cmd.Flags().
+		StringVar(&opts.Schedule, "cron", "", "cron expression for when to start a pass, e.g. \"0 */2 * * *\"")
+}
+
+// applyConfig fills in the options that were not set on the command line
+// with the values from the config file, if any.
+func (o *crawlerOptions) applyConfig(cmd *cobra.Command, c crawlerOptions) {
+	flags := cmd.Flags()
+	if !flags.Changed("workers") && c.Workers > 0 {
+		o.Workers = c.Workers
+	}
+	if !flags.Changed("min-workers") && c.MinWorkers > 0 {
+		o.MinWorkers = c.MinWorkers
+	}
+	if !flags.Changed("max-workers") && c.MaxWorkers > 0 {
+		o.MaxWorkers = c.MaxWorkers
+	}
+	if !flags.Changed("batch-size") && c.BatchSize > 0 {
+		o.BatchSize = c.BatchSize
+	}
+	if !flags.Changed("job-timeout") && c.JobTimeout > 0 {
+		o.JobTimeout = c.JobTimeout
+	}
+	if !flags.Changed("check-timeout") && c.CheckTimeout > 0 {
+		o.CheckTimeout = c.CheckTimeout
+	}
+	if !flags.Changed("reuse-window") && c.ReuseWindow > 0 {
+		o.ReuseWindow = c.ReuseWindow
+	}
+	if !flags.Changed("interval") && c.Interval > 0 {
+		o.Interval = c.Interval
+	}
+	if !flags.Changed("cron") && c.Schedule != "" {
+		o.Schedule = c.Schedule
+	}
+}
+
+// validate checks that the options can be used to run a crawler.
+func (o crawlerOptions) validate() error {
+	if o.Workers < 1 {
+		return errors.New("workers must be at least 1")
+	}
+	if o.MinWorkers < 1 {
+		return errors.New("min workers must be at least 1")
+	}
+	if o.MaxWorkers < o.MinWorkers {
+		return errors.New("max workers must be at least min workers")
+	}
+	if o.BatchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+	if o.JobTimeout <= 0 {
+		return errors.New("job timeout must be positive")
+	}
+	if o.CheckTimeout <= 0 {
+		return errors.New("check timeout must be positive")
+	}
+	if o.ReuseWindow < 0 {
+		return errors.New("reuse window can not be negative")
+	}
+	if !o.Once && o.Schedule == "" && o.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if o.Schedule != "" {
+		if _, err := cron.ParseStandard(o.Schedule); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", o.Schedule, err)
+		}
+	}
+	return nil
+}
+
+// runScheduled runs the crawl function according to the schedule in the options.
+// With --once it runs a single pass right away, with --cron it waits for the next
+// matching time before every pass, otherwise it sleeps for the interval between passes.
+// It returns when a pass fails or the context is cancelled.
+func runScheduled(
+	ctx context.Context,
+	logg zerolog.Logger,
+	opts crawlerOptions,
+	crawl func(ctx context.Context) error,
+) error {
+	var schedule cron.Schedule
+	if opts.Schedule != "" && !opts.Once {
+		var err error
+		schedule, err = cron.ParseStandard(opts.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", opts.Schedule, err)
+		}
+		// Wait for the first scheduled time.
+		if err := sleepUntil(ctx, logg, schedule.Next(time.Now())); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := crawl(ctx); err != nil {
+			return err
+		}
+		if opts.Once {
+			return nil
+		}
+
+		next := time.Now().Add(opts.Interval)
+		if schedule != nil {
+			next = schedule.Next(time.Now())
+		}
+		if err := sleepUntil(ctx, logg, next); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepUntil blocks until the given time, or until the context is cancelled.
+func sleepUntil(ctx context.Context, logg zerolog.Logger, next time.Time) error {
+	wait := time.Until(next)
+	logg.Info().Msgf("Time until next check: %s (%s)", prettyDuration(wait), next.Format("2006-01-02 15:04:05"))
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -10,13 +10,15 @@ import (
 
 var (
 	// Global services
-	campaignService  core.CampaignService
-	changelogService core.ChangelogService
-	domainService    core.DomainService
-	countryService   core.CountryService
-	asnService       core.ASNService
-	metricService    core.MetricService
-	logg             = logger.GetLogger() // Global logger
+	campaignService    core.CampaignService
+	changelogService   core.ChangelogService
+	domainService      core.DomainService
+	countryService     core.CountryService
+	asnService         core.ASNService
+	metricService      core.MetricService
+	checkResultService core.CheckResultService
+	recheckJobService  core.RecheckJobService
+	logg               = logger.GetLogger() // Global logger
 	// toolboxService   toolbox.Service
 	// statService      core.StatService
 	// resolver         *toolbox.Resolver
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+
+	"whynoipv6/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("whynoipv6/cmd/v6manage")
+
+// setupTracing exports the traces of a crawler, named service, if a collector
+// is configured. The returned function exports the remaining spans.
+func setupTracing(ctx context.Context, service string) func() {
+	shutdown, err := tracing.Setup(ctx, service, tracing.Config{
+		Endpoint:    cfg.TracingEndpoint,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logg.Error().Err(err).Msg("Could not set up tracing")
+		return func() {}
+	}
+	return shutdown
+}
+
+// startCheck starts the span of a domain check by a crawler. Every check is
+// its own trace.
+func startCheck(ctx context.Context, crawler, site string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "crawler.check", trace.WithAttributes(
+		attribute.String("crawler", crawler),
+		attribute.String("domain", site),
+	))
+}
+
+// endCheck ends the span of a domain check, failed if the check failed. It is
+// deferred at the start of the check, with the named result of the check.
+func endCheck(span trace.Span, success *bool) {
+	if !*success {
+		span.SetStatus(codes.Error, "check failed")
+	}
+	span.End()
+}
@@ -0,0 +1,21 @@
+-- Shared check results, one row per hostname.
+-- Both the domain and campaign crawlers read from and write to this table,
+-- so a site that is in several lists is only resolved once.
+CREATE TABLE "check_result" (
+  "site" TEXT PRIMARY KEY,
+  "base_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record
+  "www_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record for WWW
+  "nameserver" TEXT NOT NULL DEFAULT 'unsupported', -- Check NS Record
+  "mx_record" TEXT NOT NULL DEFAULT 'unsupported', -- Check MX Record
+  "asn_id" BIGINT REFERENCES asn(id) ON DELETE SET NULL,
+  "country_id" BIGINT REFERENCES country(id) ON DELETE SET NULL,
+  "ts_check" TIMESTAMPTZ NOT NULL DEFAULT NOW() -- timestamp of the check
+);
+CREATE INDEX idx_check_result_ts_check ON check_result(ts_check);
+
+-- Seed the store with the latest results from the domain table.
+INSERT INTO check_result (site, base_domain, www_domain, nameserver, mx_record, asn_id, country_id, ts_check)
+SELECT site, base_domain, www_domain, nameserver, mx_record, asn_id, country_id, ts_check
+FROM domain
+WHERE ts_check IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_changelog_ts_id;
+DROP INDEX IF EXISTS idx_campaign_changelog_ts_id;
@@ -0,0 +1,3 @@
+-- Indexes for the keyset (cursor) pagination of the changelog listings, newest first.
+CREATE INDEX idx_changelog_ts_id ON changelog(ts DESC, id DESC);
+CREATE INDEX idx_campaign_changelog_ts_id ON campaign_changelog(ts DESC, id DESC);
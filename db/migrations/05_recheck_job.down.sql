@@ -0,0 +1 @@
+DROP TABLE "recheck_job" CASCADE;
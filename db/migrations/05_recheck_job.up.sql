@@ -0,0 +1,21 @@
+-- On-demand rechecks requested through the API. The domain crawler claims
+-- queued jobs ahead of its scheduled work and stores the result in the job.
+CREATE TABLE "recheck_job" (
+  "id" UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+  "domain_id" BIGINT NOT NULL REFERENCES domain(id) ON DELETE CASCADE,
+  "site" TEXT NOT NULL,
+  "status" TEXT NOT NULL DEFAULT 'queued', -- queued, running, done or failed
+  "priority" INT NOT NULL DEFAULT 0, -- Higher priority jobs are claimed first
+  "client" TEXT NOT NULL, -- Client that requested the job, for rate limiting
+  "base_domain" TEXT, -- Result of the AAAA check
+  "www_domain" TEXT, -- Result of the AAAA check for WWW
+  "nameserver" TEXT, -- Result of the NS check
+  "mx_record" TEXT, -- Result of the MX check
+  "error" TEXT, -- Why the job failed
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "started_at" TIMESTAMPTZ,
+  "finished_at" TIMESTAMPTZ
+);
+CREATE INDEX idx_recheck_job_queue ON recheck_job(priority DESC, created_at) WHERE status IN ('queued', 'running');
+CREATE INDEX idx_recheck_job_domain ON recheck_job(domain_id, created_at DESC);
+CREATE INDEX idx_recheck_job_client ON recheck_job(client, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS changelog_notify ON changelog;
+DROP TRIGGER IF EXISTS campaign_changelog_notify ON campaign_changelog;
+DROP FUNCTION IF EXISTS notify_changelog();
@@ -0,0 +1,14 @@
+-- Notify listeners on the changelog channel when changelog entries are written,
+-- so the API can stream them without polling. The payload is the table name,
+-- listeners read the new rows themselves.
+CREATE OR REPLACE FUNCTION notify_changelog() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('changelog', TG_TABLE_NAME);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER changelog_notify AFTER INSERT ON changelog
+  FOR EACH STATEMENT EXECUTE FUNCTION notify_changelog();
+CREATE TRIGGER campaign_changelog_notify AFTER INSERT ON campaign_changelog
+  FOR EACH STATEMENT EXECUTE FUNCTION notify_changelog();
@@ -0,0 +1,2 @@
+DROP TABLE "asn_history" CASCADE;
+DROP TABLE "country_history" CASCADE;
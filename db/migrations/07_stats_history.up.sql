@@ -0,0 +1,20 @@
+-- Daily snapshots of the country and ASN statistics, which the crawler
+-- overwrites in place after every run. A run replaces the snapshot of its day.
+CREATE TABLE "country_history" (
+  "country_id" BIGINT NOT NULL REFERENCES country(id) ON DELETE CASCADE,
+  "day" DATE NOT NULL,
+  "sites" INT NOT NULL, -- number of sites in this country
+  "v6sites" INT NOT NULL, -- number of sites in this country with v6
+  "percent" FLOAT NOT NULL, -- percent of sites in this country with v6
+  PRIMARY KEY ("country_id", "day")
+);
+
+CREATE TABLE "asn_history" (
+  "asn_id" BIGINT NOT NULL REFERENCES asn(id) ON DELETE CASCADE,
+  "day" DATE NOT NULL,
+  "count_v4" INT NOT NULL, -- number of sites with v4-only in this ASN
+  "count_v6" INT NOT NULL, -- number of sites with v6 support in this ASN
+  "percent_v4" FLOAT NOT NULL, -- percent of sites with v4-only in this ASN
+  "percent_v6" FLOAT NOT NULL, -- percent of sites with v6 support in this ASN
+  PRIMARY KEY ("asn_id", "day")
+);
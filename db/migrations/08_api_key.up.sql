@@ -0,0 +1,13 @@
+-- API keys of the heavy automated consumers of the API, who get a higher rate
+-- limit than anonymous clients. Only a hash of each key is stored.
+CREATE TABLE "api_key" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "name" TEXT NOT NULL, -- Who the key was issued to
+  "prefix" TEXT NOT NULL, -- Start of the key, to recognise it in listings
+  "key_hash" TEXT NOT NULL UNIQUE, -- SHA-256 of the key, hex encoded
+  "rate_limit" INT, -- Requests per minute, the default key limit if NULL
+  "requests" BIGINT NOT NULL DEFAULT 0, -- Number of requests made with the key
+  "last_used" TIMESTAMPTZ,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "revoked_at" TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+DROP TABLE "audit_log" CASCADE;
+ALTER TABLE "domain" DROP COLUMN "disabled_reason";
+ALTER TABLE "api_key" DROP COLUMN "admin";
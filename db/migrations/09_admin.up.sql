@@ -0,0 +1,17 @@
+-- API keys that can use the /admin API.
+ALTER TABLE "api_key" ADD COLUMN "admin" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Why a domain was disabled through the /admin API.
+ALTER TABLE "domain" ADD COLUMN "disabled_reason" TEXT;
+
+-- Every change made through the /admin API.
+CREATE TABLE "audit_log" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "ts" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  "api_key_id" BIGINT REFERENCES api_key(id) ON DELETE SET NULL, -- Key the change was made with
+  "actor" TEXT NOT NULL, -- Name of the key, kept if the key is deleted
+  "action" TEXT NOT NULL, -- What was done, e.g. domain.disable
+  "target" TEXT NOT NULL, -- Domain or campaign the change was made to
+  "details" JSONB NOT NULL DEFAULT '{}' -- Parameters of the change, e.g. the reason
+);
+CREATE INDEX idx_audit_log_target ON audit_log(target, id DESC);
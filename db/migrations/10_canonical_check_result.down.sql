@@ -0,0 +1,254 @@
+DROP VIEW domain_shame_view;
+DROP VIEW domain_crawl_list;
+DROP VIEW domain_view_list;
+DROP VIEW campaign_domain_check_view;
+DROP VIEW domain_check_view;
+
+ALTER TABLE "domain"
+  ADD COLUMN "base_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record
+  ADD COLUMN "www_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record for WWW
+  ADD COLUMN "nameserver" TEXT NOT NULL DEFAULT 'unsupported', -- Check NS Record
+  ADD COLUMN "mx_record" TEXT NOT NULL DEFAULT 'unsupported', -- Check MX Record
+  ADD COLUMN "v6_only" TEXT NOT NULL DEFAULT 'unsupported', -- Check Curl
+  ADD COLUMN "asn_id" BIGINT REFERENCES asn(id), -- map to asn table
+  ADD COLUMN "country_id" BIGINT REFERENCES country(id), -- map to country table
+  ADD COLUMN "ts_base_domain" TIMESTAMPTZ, -- timestamp of last AAAA check
+  ADD COLUMN "ts_www_domain" TIMESTAMPTZ, -- timestamp of last AAAA WWW check
+  ADD COLUMN "ts_nameserver" TIMESTAMPTZ, -- timestamp of last NS check
+  ADD COLUMN "ts_mx_record" TIMESTAMPTZ, -- timestamp of last MX check
+  ADD COLUMN "ts_v6_only" TIMESTAMPTZ, -- timestamp of last curl check
+  ADD COLUMN "ts_check" TIMESTAMPTZ, -- timestamp of last check
+  ADD COLUMN "ts_updated" TIMESTAMPTZ; --  timestamp of last update
+ALTER TABLE "campaign_domain"
+  ADD COLUMN "base_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record
+  ADD COLUMN "www_domain" TEXT NOT NULL DEFAULT 'unsupported', -- Check AAAA Record for WWW
+  ADD COLUMN "nameserver" TEXT NOT NULL DEFAULT 'unsupported', -- Check NS Record
+  ADD COLUMN "mx_record" TEXT NOT NULL DEFAULT 'unsupported', -- Check MX Record
+  ADD COLUMN "v6_only" TEXT NOT NULL DEFAULT 'unsupported', -- Check Curl
+  ADD COLUMN "asn_id" BIGINT REFERENCES asn(id) ON DELETE SET NULL,
+  ADD COLUMN "country_id" BIGINT, -- map to country table
+  ADD COLUMN "ts_base_domain" TIMESTAMPTZ, -- timestamp of last AAAA check
+  ADD COLUMN "ts_www_domain" TIMESTAMPTZ, -- timestamp of last AAAA WWW check
+  ADD COLUMN "ts_nameserver" TIMESTAMPTZ, -- timestamp of last NS check
+  ADD COLUMN "ts_mx_record" TIMESTAMPTZ, -- timestamp of last MX check
+  ADD COLUMN "ts_v6_only" TIMESTAMPTZ, -- timestamp of last curl check
+  ADD COLUMN "ts_check" TIMESTAMPTZ, -- timestamp of last check
+  ADD COLUMN "ts_updated" TIMESTAMPTZ; --  timestamp of last update
+
+-- Copy the results back to every domain and campaign domain of the site.
+UPDATE domain
+SET base_domain    = r.base_domain,
+    www_domain     = r.www_domain,
+    nameserver     = r.nameserver,
+    mx_record      = r.mx_record,
+    v6_only        = r.v6_only,
+    asn_id         = r.asn_id,
+    country_id     = r.country_id,
+    ts_base_domain = r.ts_base_domain,
+    ts_www_domain  = r.ts_www_domain,
+    ts_nameserver  = r.ts_nameserver,
+    ts_mx_record   = r.ts_mx_record,
+    ts_v6_only     = r.ts_v6_only,
+    ts_check       = r.ts_check,
+    ts_updated     = r.ts_updated
+FROM check_result r
+WHERE domain.site = r.site;
+UPDATE campaign_domain
+SET base_domain    = r.base_domain,
+    www_domain     = r.www_domain,
+    nameserver     = r.nameserver,
+    mx_record      = r.mx_record,
+    v6_only        = r.v6_only,
+    asn_id         = r.asn_id,
+    country_id     = r.country_id,
+    ts_base_domain = r.ts_base_domain,
+    ts_www_domain  = r.ts_www_domain,
+    ts_nameserver  = r.ts_nameserver,
+    ts_mx_record   = r.ts_mx_record,
+    ts_v6_only     = r.ts_v6_only,
+    ts_check       = r.ts_check,
+    ts_updated     = r.ts_updated
+FROM check_result r
+WHERE campaign_domain.site = r.site;
+
+CREATE INDEX idx_domain_base_domain ON domain(base_domain);
+CREATE INDEX idx_domain_www_domain ON domain(www_domain);
+CREATE INDEX idx_domain_nameserver ON domain(nameserver);
+CREATE INDEX idx_domain_mx_record ON domain(mx_record);
+CREATE INDEX idx_domain_v6_only ON domain(v6_only);
+CREATE INDEX idx_domain_base_domain_www ON domain(base_domain, www_domain);
+CREATE INDEX idx_domain_base_domain_www_ns ON domain(base_domain, www_domain, nameserver);
+CREATE INDEX idx_domain_country_base_domain_www_ns ON domain(country_id, base_domain, www_domain, nameserver);
+CREATE INDEX idx_domain_country_base_domain_www ON domain(country_id, base_domain, www_domain);
+CREATE INDEX idx_domain_asn_id ON domain(asn_id);
+CREATE INDEX idx_domain_country_id ON domain(country_id);
+CREATE INDEX idx_domain_ts_check ON domain(ts_check);
+CREATE INDEX idx_campaign_domain_base_domain ON campaign_domain(base_domain);
+CREATE INDEX idx_campaign_domain_www_domain ON campaign_domain(www_domain);
+CREATE INDEX idx_campaign_domain_nameserver ON campaign_domain(nameserver);
+CREATE INDEX idx_campaign_domain_mx_record ON campaign_domain(mx_record);
+CREATE INDEX idx_campaign_domain_v6_only ON campaign_domain(v6_only);
+CREATE INDEX idx_campaign_domain_base_domain_www ON campaign_domain(base_domain, www_domain);
+CREATE INDEX idx_campaign_domain_base_domain_www_ns ON campaign_domain(base_domain, www_domain, nameserver);
+CREATE INDEX idx_campaign_domain_asn_id ON campaign_domain(asn_id);
+CREATE INDEX idx_campaign_domain_country_id ON campaign_domain(country_id);
+CREATE INDEX idx_campaign_domain_ts_check ON campaign_domain(ts_check);
+
+ALTER TABLE "domain" DROP CONSTRAINT "domain_site_fkey";
+ALTER TABLE "campaign_domain" DROP CONSTRAINT "campaign_domain_site_fkey";
+DROP TRIGGER domain_check_result ON domain;
+DROP TRIGGER campaign_domain_check_result ON campaign_domain;
+DROP FUNCTION add_check_result();
+
+-- The store only kept the results of sites that were checked.
+DELETE FROM check_result WHERE ts_check IS NULL;
+DROP INDEX idx_check_result_base_domain_www;
+DROP INDEX idx_check_result_base_domain_www_ns;
+DROP INDEX idx_check_result_asn_id;
+DROP INDEX idx_check_result_country_id;
+ALTER TABLE "check_result"
+  DROP COLUMN "v6_only",
+  DROP COLUMN "ts_base_domain",
+  DROP COLUMN "ts_www_domain",
+  DROP COLUMN "ts_nameserver",
+  DROP COLUMN "ts_mx_record",
+  DROP COLUMN "ts_v6_only",
+  DROP COLUMN "ts_updated",
+  ALTER COLUMN "ts_check" SET DEFAULT NOW(),
+  ALTER COLUMN "ts_check" SET NOT NULL;
+
+CREATE or REPLACE VIEW domain_view_list AS
+SELECT domain.*,
+       sites.rank,
+       asn.name as asname,
+       country.country_name
+FROM domain
+         RIGHT JOIN sites ON domain.site = sites.site
+         LEFT JOIN asn ON domain.asn_id = asn.id
+         LEFT JOIN country ON domain.country_id = country.id
+WHERE domain.disabled = FALSE;
+-- ORDER BY sites.rank;
+
+-- This view is used by the crawler to get a list of domains to crawl.
+CREATE or REPLACE VIEW domain_crawl_list AS
+SELECT *
+FROM domain
+WHERE (disabled is FALSE)
+  AND ((ts_check < now() - '1 days' :: interval) OR (ts_check IS NULL));
+-- ORDER BY id;
+
+CREATE OR REPLACE VIEW "domain_shame_view" AS
+SELECT domain.id      AS "id",
+       domain.site    AS "site",
+       domain.base_domain,
+       domain.www_domain,
+       domain.nameserver,
+       domain.mx_record,
+       domain.v6_only,
+       domain.asn_id,
+       domain.country_id,
+       domain.disabled,
+       domain.ts_base_domain,
+       domain.ts_www_domain,
+       domain.ts_nameserver,
+       domain.ts_mx_record,
+       domain.ts_v6_only,
+       domain.ts_check,
+       domain.ts_updated,
+       top_shame.id   AS "shame_id",
+       top_shame.site AS "shame_site"
+FROM domain
+         JOIN
+     top_shame
+     ON
+         domain.site = top_shame.site
+WHERE domain.base_domain = 'unsupported'
+ORDER BY domain.id;
+
+CREATE OR REPLACE FUNCTION update_country_metrics() RETURNS VOID AS $$
+BEGIN
+  WITH v6_country_count AS (
+    SELECT
+      country_id AS country,
+      COUNT(country_id) AS v6sites
+    FROM
+      domain
+    WHERE
+      country_id IS NOT NULL
+      AND base_domain = 'supported'
+    GROUP BY
+      country_id
+  )
+  UPDATE
+    country
+  SET
+    v6sites = COALESCE(v6_country_count.v6sites, 0)
+  FROM
+    v6_country_count
+  WHERE
+    country.id = v6_country_count.country;
+  
+  WITH country_count AS (
+    SELECT
+      country_id AS country,
+      COUNT(country_id) AS sites
+    FROM
+      domain
+    WHERE
+      country_id IS NOT NULL
+    GROUP BY
+      country_id
+  )
+  UPDATE
+    country
+  SET
+    sites = country_count.sites,
+    percent = ROUND((COALESCE(country.v6sites, 0)::numeric / NULLIF(country_count.sites, 0)::numeric) * 100, 1)
+  FROM
+    country_count
+  WHERE
+    country.id = country_count.country;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_asn_metrics() RETURNS VOID AS $$
+BEGIN
+  WITH v4_count AS (
+    SELECT
+      asn_id,
+      COUNT(asn_id) AS count_v4
+    FROM
+      domain
+    WHERE
+      asn_id IS NOT NULL
+    GROUP BY
+      asn_id
+  ),
+  v6_count AS (
+    SELECT
+      asn_id,
+      COUNT(asn_id) AS count_v6
+    FROM
+      domain
+    WHERE
+      asn_id IS NOT NULL AND base_domain = 'supported' AND www_domain = 'supported' AND nameserver = 'supported'
+    GROUP BY
+      asn_id
+  )
+  UPDATE
+    asn
+  SET
+    count_v4 = COALESCE(v4_count.count_v4, 0),
+    count_v6 = COALESCE(v6_count.count_v6, 0),
+    percent_v4 = ROUND((COALESCE(v4_count.count_v4, 0)::numeric / NULLIF(COALESCE(v4_count.count_v4, 0) + COALESCE(v6_count.count_v6, 0), 0)::numeric) * 100, 1),
+    percent_v6 = ROUND((COALESCE(v6_count.count_v6, 0)::numeric / NULLIF(COALESCE(v4_count.count_v4, 0) + COALESCE(v6_count.count_v6, 0), 0)::numeric) * 100, 1)
+  FROM
+    v4_count
+  FULL OUTER JOIN
+    v6_count
+  ON
+    v4_count.asn_id = v6_count.asn_id
+  WHERE
+    asn.id = COALESCE(v4_count.asn_id, v6_count.asn_id);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,277 @@
+-- check_result becomes the only store of check results. The domain and
+-- campaign_domain tables list the sites that are tracked, and read the result
+-- of their site from check_result, so a site that is in several lists always
+-- shows the same result.
+ALTER TABLE "check_result"
+  ADD COLUMN "v6_only" TEXT NOT NULL DEFAULT 'unsupported', -- Check Curl
+  ADD COLUMN "ts_base_domain" TIMESTAMPTZ, -- timestamp of last AAAA check
+  ADD COLUMN "ts_www_domain" TIMESTAMPTZ, -- timestamp of last AAAA WWW check
+  ADD COLUMN "ts_nameserver" TIMESTAMPTZ, -- timestamp of last NS check
+  ADD COLUMN "ts_mx_record" TIMESTAMPTZ, -- timestamp of last MX check
+  ADD COLUMN "ts_v6_only" TIMESTAMPTZ, -- timestamp of last curl check
+  ADD COLUMN "ts_updated" TIMESTAMPTZ, -- timestamp of last update
+  ALTER COLUMN "ts_check" DROP NOT NULL, -- NULL until the site is checked
+  ALTER COLUMN "ts_check" DROP DEFAULT;
+CREATE INDEX idx_check_result_base_domain_www ON check_result(base_domain, www_domain);
+CREATE INDEX idx_check_result_base_domain_www_ns ON check_result(base_domain, www_domain, nameserver);
+CREATE INDEX idx_check_result_asn_id ON check_result(asn_id);
+CREATE INDEX idx_check_result_country_id ON check_result(country_id);
+
+-- Move the newest result of every site out of the domain and campaign_domain tables.
+INSERT INTO check_result (site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+                          ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated)
+SELECT DISTINCT ON (site) site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+       ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
+FROM (SELECT site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+             ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
+      FROM domain
+      UNION ALL
+      SELECT site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+             ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
+      FROM campaign_domain) AS results
+ORDER BY site, ts_check DESC NULLS LAST
+ON CONFLICT (site) DO UPDATE
+    SET base_domain    = EXCLUDED.base_domain,
+        www_domain     = EXCLUDED.www_domain,
+        nameserver     = EXCLUDED.nameserver,
+        mx_record      = EXCLUDED.mx_record,
+        v6_only        = EXCLUDED.v6_only,
+        asn_id         = EXCLUDED.asn_id,
+        country_id     = EXCLUDED.country_id,
+        ts_base_domain = EXCLUDED.ts_base_domain,
+        ts_www_domain  = EXCLUDED.ts_www_domain,
+        ts_nameserver  = EXCLUDED.ts_nameserver,
+        ts_mx_record   = EXCLUDED.ts_mx_record,
+        ts_v6_only     = EXCLUDED.ts_v6_only,
+        ts_check       = EXCLUDED.ts_check,
+        ts_updated     = EXCLUDED.ts_updated;
+
+-- Every tracked site has a result, an empty one until it is checked.
+CREATE OR REPLACE FUNCTION add_check_result() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO check_result (site) VALUES (NEW.site) ON CONFLICT DO NOTHING;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER domain_check_result BEFORE INSERT OR UPDATE OF site ON domain
+  FOR EACH ROW EXECUTE FUNCTION add_check_result();
+CREATE TRIGGER campaign_domain_check_result BEFORE INSERT OR UPDATE OF site ON campaign_domain
+  FOR EACH ROW EXECUTE FUNCTION add_check_result();
+ALTER TABLE "domain" ADD FOREIGN KEY ("site") REFERENCES check_result(site);
+ALTER TABLE "campaign_domain" ADD FOREIGN KEY ("site") REFERENCES check_result(site);
+
+DROP VIEW domain_view_list;
+DROP VIEW domain_crawl_list;
+DROP VIEW domain_shame_view;
+
+ALTER TABLE "domain"
+  DROP COLUMN "base_domain",
+  DROP COLUMN "www_domain",
+  DROP COLUMN "nameserver",
+  DROP COLUMN "mx_record",
+  DROP COLUMN "v6_only",
+  DROP COLUMN "asn_id",
+  DROP COLUMN "country_id",
+  DROP COLUMN "ts_base_domain",
+  DROP COLUMN "ts_www_domain",
+  DROP COLUMN "ts_nameserver",
+  DROP COLUMN "ts_mx_record",
+  DROP COLUMN "ts_v6_only",
+  DROP COLUMN "ts_check",
+  DROP COLUMN "ts_updated";
+ALTER TABLE "campaign_domain"
+  DROP COLUMN "base_domain",
+  DROP COLUMN "www_domain",
+  DROP COLUMN "nameserver",
+  DROP COLUMN "mx_record",
+  DROP COLUMN "v6_only",
+  DROP COLUMN "asn_id",
+  DROP COLUMN "country_id",
+  DROP COLUMN "ts_base_domain",
+  DROP COLUMN "ts_www_domain",
+  DROP COLUMN "ts_nameserver",
+  DROP COLUMN "ts_mx_record",
+  DROP COLUMN "ts_v6_only",
+  DROP COLUMN "ts_check",
+  DROP COLUMN "ts_updated";
+
+-- Every domain with the result of its site, in the column order of the old domain table.
+CREATE VIEW domain_check_view AS
+SELECT domain.id,
+       domain.site,
+       check_result.base_domain,
+       check_result.www_domain,
+       check_result.nameserver,
+       check_result.mx_record,
+       check_result.v6_only,
+       check_result.asn_id,
+       check_result.country_id,
+       domain.disabled,
+       check_result.ts_base_domain,
+       check_result.ts_www_domain,
+       check_result.ts_nameserver,
+       check_result.ts_mx_record,
+       check_result.ts_v6_only,
+       check_result.ts_check,
+       check_result.ts_updated,
+       domain.disabled_reason
+FROM domain
+         JOIN check_result ON domain.site = check_result.site;
+
+-- Every campaign domain with the result of its site, in the column order of the old campaign_domain table.
+CREATE VIEW campaign_domain_check_view AS
+SELECT campaign_domain.id,
+       campaign_domain.campaign_id,
+       campaign_domain.site,
+       check_result.base_domain,
+       check_result.www_domain,
+       check_result.nameserver,
+       check_result.mx_record,
+       check_result.v6_only,
+       check_result.asn_id,
+       check_result.country_id,
+       campaign_domain.disabled,
+       check_result.ts_base_domain,
+       check_result.ts_www_domain,
+       check_result.ts_nameserver,
+       check_result.ts_mx_record,
+       check_result.ts_v6_only,
+       check_result.ts_check,
+       check_result.ts_updated
+FROM campaign_domain
+         JOIN check_result ON campaign_domain.site = check_result.site;
+
+CREATE VIEW domain_view_list AS
+SELECT domain_check_view.*,
+       sites.rank,
+       asn.name as asname,
+       country.country_name
+FROM domain_check_view
+         RIGHT JOIN sites ON domain_check_view.site = sites.site
+         LEFT JOIN asn ON domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON domain_check_view.country_id = country.id
+WHERE domain_check_view.disabled = FALSE;
+
+-- This view is used by the crawler to get a list of domains to crawl.
+CREATE VIEW domain_crawl_list AS
+SELECT *
+FROM domain_check_view
+WHERE (disabled is FALSE)
+  AND ((ts_check < now() - '1 days' :: interval) OR (ts_check IS NULL));
+
+CREATE VIEW "domain_shame_view" AS
+SELECT domain_check_view.id   AS "id",
+       domain_check_view.site AS "site",
+       domain_check_view.base_domain,
+       domain_check_view.www_domain,
+       domain_check_view.nameserver,
+       domain_check_view.mx_record,
+       domain_check_view.v6_only,
+       domain_check_view.asn_id,
+       domain_check_view.country_id,
+       domain_check_view.disabled,
+       domain_check_view.ts_base_domain,
+       domain_check_view.ts_www_domain,
+       domain_check_view.ts_nameserver,
+       domain_check_view.ts_mx_record,
+       domain_check_view.ts_v6_only,
+       domain_check_view.ts_check,
+       domain_check_view.ts_updated,
+       top_shame.id   AS "shame_id",
+       top_shame.site AS "shame_site"
+FROM domain_check_view
+         JOIN
+     top_shame
+     ON
+         domain_check_view.site = top_shame.site
+WHERE domain_check_view.base_domain = 'unsupported'
+ORDER BY domain_check_view.id;
+
+-- The country and ASN stats count the domains, not the campaign domains.
+CREATE OR REPLACE FUNCTION update_country_metrics() RETURNS VOID AS $$
+BEGIN
+  WITH v6_country_count AS (
+    SELECT
+      country_id AS country,
+      COUNT(country_id) AS v6sites
+    FROM
+      domain_check_view
+    WHERE
+      country_id IS NOT NULL
+      AND base_domain = 'supported'
+    GROUP BY
+      country_id
+  )
+  UPDATE
+    country
+  SET
+    v6sites = COALESCE(v6_country_count.v6sites, 0)
+  FROM
+    v6_country_count
+  WHERE
+    country.id = v6_country_count.country;
+
+  WITH country_count AS (
+    SELECT
+      country_id AS country,
+      COUNT(country_id) AS sites
+    FROM
+      domain_check_view
+    WHERE
+      country_id IS NOT NULL
+    GROUP BY
+      country_id
+  )
+  UPDATE
+    country
+  SET
+    sites = country_count.sites,
+    percent = ROUND((COALESCE(country.v6sites, 0)::numeric / NULLIF(country_count.sites, 0)::numeric) * 100, 1)
+  FROM
+    country_count
+  WHERE
+    country.id = country_count.country;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_asn_metrics() RETURNS VOID AS $$
+BEGIN
+  WITH v4_count AS (
+    SELECT
+      asn_id,
+      COUNT(asn_id) AS count_v4
+    FROM
+      domain_check_view
+    WHERE
+      asn_id IS NOT NULL
+    GROUP BY
+      asn_id
+  ),
+  v6_count AS (
+    SELECT
+      asn_id,
+      COUNT(asn_id) AS count_v6
+    FROM
+      domain_check_view
+    WHERE
+      asn_id IS NOT NULL AND base_domain = 'supported' AND www_domain = 'supported' AND nameserver = 'supported'
+    GROUP BY
+      asn_id
+  )
+  UPDATE
+    asn
+  SET
+    count_v4 = COALESCE(v4_count.count_v4, 0),
+    count_v6 = COALESCE(v6_count.count_v6, 0),
+    percent_v4 = ROUND((COALESCE(v4_count.count_v4, 0)::numeric / NULLIF(COALESCE(v4_count.count_v4, 0) + COALESCE(v6_count.count_v6, 0), 0)::numeric) * 100, 1),
+    percent_v6 = ROUND((COALESCE(v6_count.count_v6, 0)::numeric / NULLIF(COALESCE(v4_count.count_v4, 0) + COALESCE(v6_count.count_v6, 0), 0)::numeric) * 100, 1)
+  FROM
+    v4_count
+  FULL OUTER JOIN
+    v6_count
+  ON
+    v4_count.asn_id = v6_count.asn_id
+  WHERE
+    asn.id = COALESCE(v4_count.asn_id, v6_count.asn_id);
+END;
+$$ LANGUAGE plpgsql;
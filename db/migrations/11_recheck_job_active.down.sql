@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_recheck_job_active;
@@ -0,0 +1,12 @@
+-- A domain has at most one queued or running recheck. Requests that race each
+-- other insert with ON CONFLICT DO NOTHING, and all get the job of the winner.
+UPDATE recheck_job
+SET status      = 'failed',
+    error       = 'superseded by another recheck of the domain',
+    finished_at = NOW()
+WHERE status IN ('queued', 'running')
+  AND id NOT IN (SELECT DISTINCT ON (domain_id) id
+                 FROM recheck_job
+                 WHERE status IN ('queued', 'running')
+                 ORDER BY domain_id, created_at);
+CREATE UNIQUE INDEX idx_recheck_job_active ON recheck_job(domain_id) WHERE status IN ('queued', 'running');
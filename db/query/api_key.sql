@@ -0,0 +1,37 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_key(name, prefix, key_hash, rate_limit, admin)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+-- Returns the key with the given hash, unless it has been revoked.
+SELECT *
+FROM api_key
+WHERE key_hash = $1
+  AND revoked_at IS NULL;
+
+-- name: GetAPIKey :one
+-- Returns the key with the given id, unless it has been revoked.
+SELECT *
+FROM api_key
+WHERE id = $1
+  AND revoked_at IS NULL;
+
+-- name: ListAPIKeys :many
+SELECT *
+FROM api_key
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_key
+SET revoked_at = NOW()
+WHERE id = $1
+  AND revoked_at IS NULL
+RETURNING *;
+
+-- name: AddAPIKeyUsage :exec
+-- Adds requests to the usage counter of a key.
+UPDATE api_key
+SET requests  = requests + sqlc.arg(requests),
+    last_used = GREATEST(last_used, sqlc.arg(last_used)::timestamptz)
+WHERE id = sqlc.arg(id);
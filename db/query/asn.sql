@@ -11,6 +11,12 @@ FROM asn
 WHERE number = $1
 LIMIT 1;
 
+-- name: ListASNsByID :many
+-- Lists the ASNs with the given IDs, in no particular order.
+SELECT *
+FROM asn
+WHERE id = ANY(@ids::BIGINT[]);
+
 -- name: AsnByIPv4 :many
 SELECT * 
 FROM asn
@@ -25,6 +31,13 @@ WHERE count_v4 IS NOT NULL AND id != 1
 ORDER BY count_v6 DESC
 LIMIT $1 OFFSET $2;
 
+-- name: ListASNStats :many
+-- Lists the statistics of every ASN with domains, ordered by AS number.
+SELECT *
+FROM asn
+WHERE count_v4 IS NOT NULL AND id != 1
+ORDER BY number;
+
 -- name: SearchAsNumber :many
 SELECT *
 FROM asn
@@ -38,3 +51,29 @@ FROM asn
 WHERE name ILIKE '%' || $1 || '%'
 ORDER BY count_v4 DESC
 LIMIT 100;
+
+-- name: SnapshotASNHistory :exec
+-- Stores today's statistics of every ASN with domains, replacing an earlier snapshot of the same day.
+INSERT INTO asn_history (asn_id, day, count_v4, count_v6, percent_v4, percent_v6)
+SELECT id, CURRENT_DATE, count_v4, COALESCE(count_v6, 0), COALESCE(percent_v4, 0), COALESCE(percent_v6, 0)
+FROM asn
+WHERE count_v4 IS NOT NULL
+ON CONFLICT (asn_id, day) DO UPDATE
+SET count_v4   = EXCLUDED.count_v4,
+    count_v6   = EXCLUDED.count_v6,
+    percent_v4 = EXCLUDED.percent_v4,
+    percent_v6 = EXCLUDED.percent_v6;
+
+-- name: ListASNHistory :many
+-- Returns the last snapshot of each period, e.g. each month, between the start and end day.
+SELECT DISTINCT ON (period) date_trunc(sqlc.arg(step)::TEXT, day::TIMESTAMP)::DATE AS period,
+       day,
+       count_v4,
+       count_v6,
+       percent_v4,
+       percent_v6
+FROM asn_history
+WHERE asn_id = sqlc.arg(asn_id)
+  AND day >= sqlc.arg(start_day)
+  AND day <= sqlc.arg(end_day)
+ORDER BY period, day DESC;
@@ -0,0 +1,12 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_log(api_key_id, actor, action, target, details)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListAuditLog :many
+-- Lists the newest entries first, optionally only the ones for a target.
+SELECT *
+FROM audit_log
+WHERE (sqlc.arg(target)::text = '' OR target = sqlc.arg(target)::text)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
@@ -6,53 +6,64 @@ ON CONFLICT DO NOTHING;
 
 -- name: ListCampaignDomain :many
 -- Description: Retrieves a list of campaign domains with additional information from 'asn' and 'country' tables.
-SELECT campaign_domain.*,
+SELECT campaign_domain_check_view.*,
        asn.name as asname,
        country.country_name
-FROM campaign_domain
-         LEFT JOIN asn ON campaign_domain.asn_id = asn.id
-         LEFT JOIN country ON campaign_domain.country_id = country.id
-WHERE campaign_domain.campaign_id = $1
-ORDER BY campaign_domain.id
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+ORDER BY campaign_domain_check_view.id
 LIMIT $2 OFFSET $3;
 
+-- name: CountCampaignDomain :one
+SELECT count(*)
+FROM campaign_domain
+WHERE campaign_id = $1;
+
+-- name: ListCampaignDomainAfter :many
+-- Keyset pagination on id, returns the campaign domains after the given id.
+SELECT campaign_domain_check_view.*,
+       asn.name as asname,
+       country.country_name
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+  AND campaign_domain_check_view.id > $2
+ORDER BY campaign_domain_check_view.id
+LIMIT $3;
+
+-- name: ListCampaignDomainBefore :many
+-- Keyset pagination on id, returns the campaign domains before the given id, nearest first.
+SELECT campaign_domain_check_view.*,
+       asn.name as asname,
+       country.country_name
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+  AND campaign_domain_check_view.id < $2
+ORDER BY campaign_domain_check_view.id DESC
+LIMIT $3;
+
 -- name: ViewCampaignDomain :one
-SELECT campaign_domain.*,
+SELECT campaign_domain_check_view.*,
        asn.name as asname,
        country.country_name
-FROM campaign_domain
-         LEFT JOIN asn ON campaign_domain.asn_id = asn.id
-         LEFT JOIN country ON campaign_domain.country_id = country.id
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
 WHERE site = $1
   AND campaign_id = $2
 LIMIT 1;
 
 -- name: CrawlCampaignDomain :many
 SELECT *
-FROM campaign_domain
+FROM campaign_domain_check_view
 ORDER BY id
 LIMIT $1 OFFSET $2;
 
--- name: UpdateCampaignDomain :exec
-UPDATE
-    campaign_domain
-SET base_domain    = $3,
-    www_domain     = $4,
-    nameserver     = $5,
-    mx_record      = $6,
-    v6_only        = $7,
-    ts_base_domain = $8,
-    ts_www_domain  = $9,
-    ts_nameserver  = $10,
-    ts_mx_record   = $11,
-    ts_v6_only     = $12,
-    ts_check       = $13,
-    ts_updated     = $14,
-    asn_id         = $15,
-    country_id     = $16
-WHERE site = $1
-  AND campaign_id = $2;
-
 -- name: DisableCampaignDomain :exec
 UPDATE
     campaign_domain
@@ -73,7 +84,7 @@ SELECT campaign.*,
                    END
            )                     AS v6_ready_count
 FROM campaign
-         LEFT JOIN campaign_domain ON campaign.uuid = campaign_domain.campaign_id AND campaign.disabled = FALSE
+         LEFT JOIN campaign_domain_check_view campaign_domain ON campaign.uuid = campaign_domain.campaign_id AND campaign.disabled = FALSE
 GROUP BY campaign.id
 ORDER BY campaign.id;
 
@@ -90,7 +101,7 @@ SELECT campaign.*,
                    END
            )                     AS v6_ready_count
 FROM campaign
-         LEFT JOIN campaign_domain ON campaign.uuid = campaign_domain.campaign_id
+         LEFT JOIN campaign_domain_check_view campaign_domain ON campaign.uuid = campaign_domain.campaign_id
 WHERE campaign.uuid = $1
 GROUP BY campaign.id
 LIMIT 1;
@@ -108,6 +119,13 @@ ON CONFLICT (uuid) DO UPDATE
         description = EXCLUDED.description
 RETURNING *;
 
+-- name: UpdateCampaign :one
+UPDATE campaign
+SET name        = $2,
+    description = $3
+WHERE uuid = $1
+RETURNING *;
+
 -- name: DeleteCampaignDomain :exec
 DELETE
 FROM campaign_domain
@@ -117,10 +135,15 @@ WHERE campaign_id = $1
 -- name: GetCampaignDomainsByName :many
 -- Used for searching campaign domains by site name.
 SELECT *
-FROM campaign_domain
+FROM campaign_domain_check_view
 WHERE site LIKE '%' || $1 || '%'
 LIMIT $2 OFFSET $3;
 
+-- name: CountCampaignDomainsByName :one
+SELECT count(*)
+FROM campaign_domain
+WHERE site LIKE '%' || $1 || '%';
+
 -- name: StoreCampaignDomainLog :exec
 INSERT INTO campaign_domain_log(domain_id, data)
 VALUES ($1, $2)
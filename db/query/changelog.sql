@@ -3,23 +3,128 @@ SELECT *
 FROM changelog_view
 LIMIT $1 OFFSET $2;
 
+-- name: CountChangelog :one
+SELECT count(*)
+FROM changelog_view;
+
+-- name: ListChangelogAfter :many
+-- Keyset pagination on (ts, id), newest first, returns the entries after the given position.
+SELECT *
+FROM changelog_view
+WHERE ts < $1 OR (ts = $1 AND id < $2)
+ORDER BY ts DESC, id DESC
+LIMIT $3;
+
+-- name: ListChangelogBefore :many
+-- Keyset pagination on (ts, id), oldest first, returns the entries before the given position.
+SELECT *
+FROM changelog_view
+WHERE ts > $1 OR (ts = $1 AND id > $2)
+ORDER BY ts, id
+LIMIT $3;
+
 -- name: ListCampaignChangelog :many
 SELECT *
 FROM changelog_campaign_view
 LIMIT $1 OFFSET $2;
 
+-- name: CountCampaignChangelog :one
+SELECT count(*)
+FROM changelog_campaign_view;
+
+-- name: ListCampaignChangelogAfter :many
+-- Keyset pagination on (ts, id), newest first, returns the entries after the given position.
+SELECT *
+FROM changelog_campaign_view
+WHERE ts < $1 OR (ts = $1 AND id < $2)
+ORDER BY ts DESC, id DESC
+LIMIT $3;
+
+-- name: ListCampaignChangelogBefore :many
+-- Keyset pagination on (ts, id), oldest first, returns the entries before the given position.
+SELECT *
+FROM changelog_campaign_view
+WHERE ts > $1 OR (ts = $1 AND id > $2)
+ORDER BY ts, id
+LIMIT $3;
+
 -- name: GetChangelogByDomain :many
 SELECT *
 FROM changelog_view
 WHERE site = $1
 LIMIT $2 OFFSET $3;
 
+-- name: ListChangelogByDomainIDs :many
+-- Lists the newest entries of each of the domains, at most max_entries per domain.
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM (
+    SELECT changelog_view.*,
+           row_number() OVER (PARTITION BY changelog_view.domain_id ORDER BY changelog_view.id DESC) AS position
+    FROM changelog_view
+    WHERE changelog_view.domain_id = ANY(@domain_ids::BIGINT[])
+) AS entries
+WHERE position <= @max_entries::BIGINT
+ORDER BY domain_id, id DESC;
+
+-- name: CountChangelogByDomain :one
+SELECT count(*)
+FROM changelog_view
+WHERE site = $1;
+
+-- name: GetChangelogByDomainAfter :many
+SELECT *
+FROM changelog_view
+WHERE site = $1
+  AND (ts < $2 OR (ts = $2 AND id < $3))
+ORDER BY ts DESC, id DESC
+LIMIT $4;
+
+-- name: GetChangelogByDomainBefore :many
+SELECT *
+FROM changelog_view
+WHERE site = $1
+  AND (ts > $2 OR (ts = $2 AND id > $3))
+ORDER BY ts, id
+LIMIT $4;
+
+-- name: GetChangelogByCountry :many
+-- Newest first, returns the changelog entries of the domains in a country.
+SELECT changelog.*,
+       domain.site
+FROM changelog
+         JOIN domain ON changelog.domain_id = domain.id
+         JOIN check_result ON domain.site = check_result.site
+WHERE check_result.country_id = $1
+ORDER BY changelog.id DESC
+LIMIT $2;
+
 -- name: GetChangelogByCampaign :many
 SELECT *
 FROM changelog_campaign_view
 WHERE campaign_id = $1
 LIMIT $2 OFFSET $3;
 
+-- name: CountChangelogByCampaign :one
+SELECT count(*)
+FROM changelog_campaign_view
+WHERE campaign_id = $1;
+
+-- name: GetChangelogByCampaignAfter :many
+SELECT *
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND (ts < $2 OR (ts = $2 AND id < $3))
+ORDER BY ts DESC, id DESC
+LIMIT $4;
+
+-- name: GetChangelogByCampaignBefore :many
+SELECT *
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND (ts > $2 OR (ts = $2 AND id > $3))
+ORDER BY ts, id
+LIMIT $4;
+
 -- name: GetChangelogByCampaignDomain :many
 SELECT *
 FROM changelog_campaign_view
@@ -27,6 +132,30 @@ WHERE campaign_id = $1
   AND site = $2
 LIMIT $3 OFFSET $4;
 
+-- name: CountChangelogByCampaignDomain :one
+SELECT count(*)
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2;
+
+-- name: GetChangelogByCampaignDomainAfter :many
+SELECT *
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2
+  AND (ts < $3 OR (ts = $3 AND id < $4))
+ORDER BY ts DESC, id DESC
+LIMIT $5;
+
+-- name: GetChangelogByCampaignDomainBefore :many
+SELECT *
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2
+  AND (ts > $3 OR (ts = $3 AND id > $4))
+ORDER BY ts, id
+LIMIT $5;
+
 -- name: CreateChangelog :one
 INSERT INTO changelog (domain_id, message, ipv6_status)
 VALUES ($1, $2, $3)
@@ -36,3 +165,35 @@ RETURNING *;
 INSERT INTO campaign_changelog (domain_id, campaign_id, message, ipv6_status)
 VALUES ($1, $2, $3, $4)
 RETURNING *;
+
+-- name: ListChangelogSince :many
+-- Oldest first, returns the entries written after the given id, except the skipped ids, with the country of the domain.
+SELECT changelog.*,
+       domain.site,
+       country.country_code
+FROM changelog
+         JOIN domain ON changelog.domain_id = domain.id
+         JOIN check_result ON domain.site = check_result.site
+         LEFT JOIN country ON check_result.country_id = country.id
+WHERE changelog.id > $1
+  AND changelog.id <> ALL (sqlc.arg(skip)::BIGINT[])
+ORDER BY changelog.id
+LIMIT $2;
+
+-- name: ListCampaignChangelogSince :many
+-- Oldest first, returns the entries written after the given id, except the skipped ids, with the country of the domain.
+SELECT campaign_changelog.*,
+       campaign_domain.site,
+       country.country_code
+FROM campaign_changelog
+         JOIN campaign_domain ON campaign_changelog.domain_id = campaign_domain.id
+         JOIN check_result ON campaign_domain.site = check_result.site
+         LEFT JOIN country ON check_result.country_id = country.id
+WHERE campaign_changelog.id > $1
+  AND campaign_changelog.id <> ALL (sqlc.arg(skip)::BIGINT[])
+ORDER BY campaign_changelog.id
+LIMIT $2;
+
+-- name: LatestChangelogIDs :one
+SELECT (SELECT COALESCE(max(id), 0) FROM changelog)::BIGINT          AS changelog_id,
+       (SELECT COALESCE(max(id), 0) FROM campaign_changelog)::BIGINT AS campaign_changelog_id;
@@ -0,0 +1,48 @@
+-- name: GetCheckResult :one
+SELECT *
+FROM check_result
+WHERE site = $1
+  AND ts_check >= $2
+LIMIT 1;
+
+-- name: LockCheckResult :one
+-- Returns the result of a site and locks it until the end of the transaction,
+-- so the changes to it are only logged once when two crawlers check the site.
+SELECT *
+FROM check_result
+WHERE site = $1
+FOR UPDATE;
+
+-- name: StoreCheckResult :exec
+INSERT INTO check_result(site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+                         ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (site) DO UPDATE
+    SET base_domain    = EXCLUDED.base_domain,
+        www_domain     = EXCLUDED.www_domain,
+        nameserver     = EXCLUDED.nameserver,
+        mx_record      = EXCLUDED.mx_record,
+        v6_only        = EXCLUDED.v6_only,
+        asn_id         = EXCLUDED.asn_id,
+        country_id     = EXCLUDED.country_id,
+        ts_base_domain = EXCLUDED.ts_base_domain,
+        ts_www_domain  = EXCLUDED.ts_www_domain,
+        ts_nameserver  = EXCLUDED.ts_nameserver,
+        ts_mx_record   = EXCLUDED.ts_mx_record,
+        ts_v6_only     = EXCLUDED.ts_v6_only,
+        ts_check       = EXCLUDED.ts_check,
+        ts_updated     = EXCLUDED.ts_updated;
+
+-- name: ListCheckResultDomains :many
+-- Returns the ids of the domains that show the result of a site.
+SELECT id
+FROM domain
+WHERE site = $1
+  AND disabled = FALSE;
+
+-- name: ListCheckResultCampaignDomains :many
+-- Returns the campaign domains that show the result of a site.
+SELECT id, campaign_id
+FROM campaign_domain
+WHERE site = $1
+  AND disabled = FALSE;
@@ -4,6 +4,12 @@ FROM country
 WHERE country_code = $1
 LIMIT 1;
 
+-- name: ListCountriesByID :many
+-- Lists the countries with the given IDs, in no particular order.
+SELECT *
+FROM country
+WHERE id = ANY(@ids::BIGINT[]);
+
 -- name: GetCountryTld :one
 SELECT *
 FROM country
@@ -21,6 +27,41 @@ WHERE domain_view_list.country_id = $1
 ORDER BY domain_view_list.id
 LIMIT $2 OFFSET $3;
 
+-- name: CountDomainsByCountry :one
+SELECT count(*)
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    );
+
+-- name: ListDomainsByCountryAfter :many
+-- Keyset pagination on id, returns the domains after the given id.
+SELECT *
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    )
+  AND domain_view_list.id > $2
+ORDER BY domain_view_list.id
+LIMIT $3;
+
+-- name: ListDomainsByCountryBefore :many
+-- Keyset pagination on id, returns the domains before the given id, nearest first.
+SELECT *
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    )
+  AND domain_view_list.id < $2
+ORDER BY domain_view_list.id DESC
+LIMIT $3;
+
 -- name: ListDomainHeroesByCountry :many
 SELECT *
 FROM domain_view_list
@@ -32,6 +73,41 @@ WHERE country_id = $1
 ORDER BY rank
 LIMIT $2 OFFSET $3;
 
+-- name: CountDomainHeroesByCountry :one
+SELECT count(*)
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported';
+
+-- name: ListDomainHeroesByCountryAfter :many
+-- Keyset pagination on (rank, id), returns the domains after the given position.
+SELECT *
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank > $2 OR (rank = $2 AND id > $3))
+ORDER BY rank, id
+LIMIT $4;
+
+-- name: ListDomainHeroesByCountryBefore :many
+-- Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+SELECT *
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank < $2 OR (rank = $2 AND id < $3))
+ORDER BY rank DESC, id DESC
+LIMIT $4;
+
 -- name: AllDomainsByCountry :many
 SELECT *
 FROM domain_view_list
@@ -43,3 +119,26 @@ LIMIT $2 OFFSET $3;
 SELECT *
 FROM country
 ORDER BY sites DESC;
+
+-- name: SnapshotCountryHistory :exec
+-- Stores today's statistics of every country, replacing an earlier snapshot of the same day.
+INSERT INTO country_history (country_id, day, sites, v6sites, percent)
+SELECT id, CURRENT_DATE, sites, v6sites, percent::FLOAT
+FROM country
+ON CONFLICT (country_id, day) DO UPDATE
+SET sites   = EXCLUDED.sites,
+    v6sites = EXCLUDED.v6sites,
+    percent = EXCLUDED.percent;
+
+-- name: ListCountryHistory :many
+-- Returns the last snapshot of each period, e.g. each month, between the start and end day.
+SELECT DISTINCT ON (period) date_trunc(sqlc.arg(step)::TEXT, day::TIMESTAMP)::DATE AS period,
+       day,
+       sites,
+       v6sites,
+       percent
+FROM country_history
+WHERE country_id = sqlc.arg(country_id)
+  AND day >= sqlc.arg(start_day)
+  AND day <= sqlc.arg(end_day)
+ORDER BY period, day DESC;
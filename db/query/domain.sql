@@ -11,6 +11,32 @@ WHERE base_domain = 'unsupported'
 ORDER BY rank
 LIMIT $1 OFFSET $2;
 
+-- name: CountDomain :one
+SELECT count(*)
+FROM domain_view_list
+WHERE base_domain = 'unsupported'
+   OR www_domain = 'unsupported';
+
+-- name: ListDomainAfter :many
+-- Keyset pagination on (rank, id), returns the domains after the given position.
+SELECT *
+FROM domain_view_list
+WHERE (base_domain = 'unsupported'
+   OR www_domain = 'unsupported')
+  AND (rank > $1 OR (rank = $1 AND id > $2))
+ORDER BY rank, id
+LIMIT $3;
+
+-- name: ListDomainBefore :many
+-- Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+SELECT *
+FROM domain_view_list
+WHERE (base_domain = 'unsupported'
+   OR www_domain = 'unsupported')
+  AND (rank < $1 OR (rank = $1 AND id < $2))
+ORDER BY rank DESC, id DESC
+LIMIT $3;
+
 -- name: ListDomainHeroes :many
 SELECT *
 FROM domain_view_list
@@ -21,6 +47,38 @@ WHERE base_domain = 'supported'
 ORDER BY rank
 LIMIT $1 OFFSET $2;
 
+-- name: CountDomainHeroes :one
+SELECT count(*)
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported';
+
+-- name: ListDomainHeroesAfter :many
+-- Keyset pagination on (rank, id), returns the domains after the given position.
+SELECT *
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank > $1 OR (rank = $1 AND id > $2))
+ORDER BY rank, id
+LIMIT $3;
+
+-- name: ListDomainHeroesBefore :many
+-- Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+SELECT *
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank < $1 OR (rank = $1 AND id < $2))
+ORDER BY rank DESC, id DESC
+LIMIT $3;
+
 -- name: CrawlDomain :many
 SELECT *
 FROM domain_crawl_list
@@ -34,24 +92,11 @@ FROM domain_view_list
 WHERE site = $1
 LIMIT 1;
 
--- name: UpdateDomain :exec
-UPDATE
-    domain
-SET base_domain    = $2,
-    www_domain     = $3,
-    nameserver     = $4,
-    mx_record      = $5,
-    v6_only        = $6,
-    ts_base_domain = $7,
-    ts_www_domain  = $8,
-    ts_nameserver  = $9,
-    ts_mx_record   = $10,
-    ts_v6_only     = $11,
-    ts_check       = $12,
-    ts_updated     = $13,
-    asn_id         = $14,
-    country_id     = $15
-WHERE site = $1;
+-- name: GetDomain :one
+SELECT *
+FROM domain_check_view
+WHERE site = $1
+LIMIT 1;
 
 -- name: DisableDomain :exec
 UPDATE
@@ -59,6 +104,33 @@ UPDATE
 SET disabled = TRUE
 WHERE site = $1;
 
+-- name: CreateDomain :one
+-- Returns no rows if the domain already exists.
+INSERT INTO domain(site)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: SetDomainDisabled :one
+UPDATE
+    domain
+SET disabled        = sqlc.arg(disabled),
+    disabled_reason = sqlc.narg(reason)
+WHERE site = sqlc.arg(site)
+RETURNING *;
+
+-- name: DeleteDomainChangelog :exec
+DELETE
+FROM changelog
+WHERE domain_id = $1;
+
+-- name: DeleteDomain :exec
+-- The logs and recheck jobs of the domain are deleted with it, but not its
+-- changelog, which must be deleted first.
+DELETE
+FROM domain
+WHERE id = $1;
+
 -- name: GetDomainsByName :many
 SELECT *
 FROM domain_view_list
@@ -66,25 +138,31 @@ WHERE site LIKE '%' || $1 || '%'
 ORDER BY rank
 LIMIT $2 OFFSET $3;
 
+-- name: CountDomainsByName :one
+SELECT count(*)
+FROM domain_view_list
+WHERE site LIKE '%' || $1 || '%';
+
 -- name: ListDomainShamers :many
 SELECT *
 FROM domain_shame_view;
 
 -- name: InitSpaceTimestamps :exec
+-- Spreads the checks of the domains over a day, the campaign domains are not moved.
 WITH DomainCount AS (SELECT count(*)::DECIMAL AS total_records
                      FROM domain),
      IntervalCalculation AS (SELECT (NOW() - '1 days'::INTERVAL)         AS calculatedStartTime,
                                     ('1 days'::INTERVAL) / total_records AS calculatedIntervalStep
                              FROM DomainCount),
-     SpacedTimestampUpdates AS (SELECT d.id,
+     SpacedTimestampUpdates AS (SELECT d.site,
                                        ic.calculatedStartTime + ic.calculatedIntervalStep * 
                                        ROW_NUMBER() OVER (ORDER BY d.id) AS newSpacedTimestamp
                                 FROM domain d,
                                      IntervalCalculation ic)
-UPDATE domain
+UPDATE check_result
 SET ts_check = stu.newSpacedTimestamp
 FROM SpacedTimestampUpdates stu
-WHERE domain.id = stu.id;
+WHERE check_result.site = stu.site;
 
 -- name: StoreDomainLog :exec
 INSERT INTO domain_log(domain_id, data)
@@ -99,3 +177,124 @@ FROM domain_log
 WHERE domain_id = $1
 ORDER BY time DESC
 LIMIT 90;
+
+-- name: ListDomainLogRange :many
+-- Oldest first, returns a page of the log entries of a domain in a time range,
+-- the entries after the given time and id. The first page starts after
+-- the start of the range and id 0.
+SELECT id,
+       time,
+       data
+FROM domain_log
+WHERE domain_id = sqlc.arg(domain_id)
+  AND (time, id) > (sqlc.arg(after_time)::timestamptz, sqlc.arg(after_id)::BIGINT)
+  AND time < sqlc.arg(end_time)
+ORDER BY time, id
+LIMIT sqlc.arg(page_size);
+
+-- name: GetDomainLogBefore :one
+-- Returns the newest log entry of a domain before the given time.
+SELECT id,
+       time,
+       data
+FROM domain_log
+WHERE domain_id = $1
+  AND time < $2
+ORDER BY time DESC
+LIMIT 1;
+
+-- name: FilterDomains :many
+-- Lists the domains matching the optional filters, a NULL filter matches every domain.
+-- The sort order is one of rank, site or ts_updated, prefixed with - for descending.
+SELECT *
+FROM domain_view_list
+WHERE (sqlc.narg(base_domain)::text IS NULL OR base_domain = sqlc.narg(base_domain))
+  AND (sqlc.narg(www_domain)::text IS NULL OR www_domain = sqlc.narg(www_domain))
+  AND (sqlc.narg(nameserver)::text IS NULL OR nameserver = sqlc.narg(nameserver))
+  AND (sqlc.narg(mx_record)::text IS NULL OR mx_record = sqlc.narg(mx_record))
+  AND (sqlc.narg(v6_only)::text IS NULL OR v6_only = sqlc.narg(v6_only))
+  AND (sqlc.narg(country_code)::text IS NULL
+    OR country_id = (SELECT id FROM country WHERE country_code = sqlc.narg(country_code)))
+  AND (sqlc.narg(continent)::text IS NULL
+    OR country_id IN (SELECT id FROM country WHERE continent::text = sqlc.narg(continent)))
+  AND (sqlc.narg(asn)::int IS NULL
+    OR asn_id IN (SELECT id FROM asn WHERE number = sqlc.narg(asn)))
+  AND (sqlc.narg(rank_min)::bigint IS NULL OR rank >= sqlc.narg(rank_min))
+  AND (sqlc.narg(rank_max)::bigint IS NULL OR rank <= sqlc.narg(rank_max))
+  AND (sqlc.narg(updated_after)::timestamptz IS NULL OR ts_updated >= sqlc.narg(updated_after))
+  AND (sqlc.narg(updated_before)::timestamptz IS NULL OR ts_updated < sqlc.narg(updated_before))
+  AND (sqlc.narg(campaign_id)::uuid IS NULL
+    OR site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = sqlc.narg(campaign_id)))
+ORDER BY CASE WHEN sqlc.arg(sort)::text = 'rank' THEN rank END,
+         CASE WHEN sqlc.arg(sort)::text = '-rank' THEN rank END DESC,
+         CASE WHEN sqlc.arg(sort)::text = 'site' THEN site END,
+         CASE WHEN sqlc.arg(sort)::text = '-site' THEN site END DESC,
+         CASE WHEN sqlc.arg(sort)::text = 'ts_updated' THEN ts_updated END NULLS LAST,
+         CASE WHEN sqlc.arg(sort)::text = '-ts_updated' THEN ts_updated END DESC NULLS LAST,
+         rank, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountFilteredDomains :one
+-- Counts the domains matching the optional filters of FilterDomains.
+SELECT count(*)
+FROM domain_view_list
+WHERE (sqlc.narg(base_domain)::text IS NULL OR base_domain = sqlc.narg(base_domain))
+  AND (sqlc.narg(www_domain)::text IS NULL OR www_domain = sqlc.narg(www_domain))
+  AND (sqlc.narg(nameserver)::text IS NULL OR nameserver = sqlc.narg(nameserver))
+  AND (sqlc.narg(mx_record)::text IS NULL OR mx_record = sqlc.narg(mx_record))
+  AND (sqlc.narg(v6_only)::text IS NULL OR v6_only = sqlc.narg(v6_only))
+  AND (sqlc.narg(country_code)::text IS NULL
+    OR country_id = (SELECT id FROM country WHERE country_code = sqlc.narg(country_code)))
+  AND (sqlc.narg(continent)::text IS NULL
+    OR country_id IN (SELECT id FROM country WHERE continent::text = sqlc.narg(continent)))
+  AND (sqlc.narg(asn)::int IS NULL
+    OR asn_id IN (SELECT id FROM asn WHERE number = sqlc.narg(asn)))
+  AND (sqlc.narg(rank_min)::bigint IS NULL OR rank >= sqlc.narg(rank_min))
+  AND (sqlc.narg(rank_max)::bigint IS NULL OR rank <= sqlc.narg(rank_max))
+  AND (sqlc.narg(updated_after)::timestamptz IS NULL OR ts_updated >= sqlc.narg(updated_after))
+  AND (sqlc.narg(updated_before)::timestamptz IS NULL OR ts_updated < sqlc.narg(updated_before))
+  AND (sqlc.narg(campaign_id)::uuid IS NULL
+    OR site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = sqlc.narg(campaign_id)));
+
+-- name: ExportDomains :many
+-- Lists the domains matching the optional filters of FilterDomains with an id
+-- after the given one, ordered by id, with the country code and AS number.
+SELECT v.id,
+       v.site,
+       v.rank,
+       v.base_domain,
+       v.www_domain,
+       v.nameserver,
+       v.mx_record,
+       v.v6_only,
+       asn.number AS asn,
+       v.asname,
+       country.country_code,
+       v.country_name,
+       v.ts_base_domain,
+       v.ts_www_domain,
+       v.ts_nameserver,
+       v.ts_mx_record,
+       v.ts_v6_only,
+       v.ts_check,
+       v.ts_updated
+FROM domain_view_list v
+         LEFT JOIN asn ON v.asn_id = asn.id
+         LEFT JOIN country ON v.country_id = country.id
+WHERE v.id > sqlc.arg(after_id)::bigint
+  AND (sqlc.narg(base_domain)::text IS NULL OR v.base_domain = sqlc.narg(base_domain))
+  AND (sqlc.narg(www_domain)::text IS NULL OR v.www_domain = sqlc.narg(www_domain))
+  AND (sqlc.narg(nameserver)::text IS NULL OR v.nameserver = sqlc.narg(nameserver))
+  AND (sqlc.narg(mx_record)::text IS NULL OR v.mx_record = sqlc.narg(mx_record))
+  AND (sqlc.narg(v6_only)::text IS NULL OR v.v6_only = sqlc.narg(v6_only))
+  AND (sqlc.narg(country_code)::text IS NULL OR country.country_code = sqlc.narg(country_code))
+  AND (sqlc.narg(continent)::text IS NULL OR country.continent::text = sqlc.narg(continent))
+  AND (sqlc.narg(asn)::int IS NULL OR asn.number = sqlc.narg(asn))
+  AND (sqlc.narg(rank_min)::bigint IS NULL OR v.rank >= sqlc.narg(rank_min))
+  AND (sqlc.narg(rank_max)::bigint IS NULL OR v.rank <= sqlc.narg(rank_max))
+  AND (sqlc.narg(updated_after)::timestamptz IS NULL OR v.ts_updated >= sqlc.narg(updated_after))
+  AND (sqlc.narg(updated_before)::timestamptz IS NULL OR v.ts_updated < sqlc.narg(updated_before))
+  AND (sqlc.narg(campaign_id)::uuid IS NULL
+    OR v.site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = sqlc.narg(campaign_id)))
+ORDER BY v.id
+LIMIT sqlc.arg('limit');
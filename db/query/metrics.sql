@@ -10,6 +10,13 @@ FROM metrics
 WHERE measurement = $1
 ORDER BY time DESC;
 
+-- name: LatestMetricTime :one
+SELECT time
+FROM metrics
+WHERE measurement = $1
+ORDER BY time DESC
+LIMIT 1;
+
 -- name: DomainStats :many
 SELECT time,
        data
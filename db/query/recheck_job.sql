@@ -0,0 +1,79 @@
+-- name: CreateRecheckJob :one
+-- Returns no row if the domain already has a queued or running job.
+INSERT INTO recheck_job(domain_id, site, priority, client)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (domain_id) WHERE status IN ('queued', 'running') DO NOTHING
+RETURNING *;
+
+-- name: GetActiveRecheckJob :one
+-- Returns the queued or running job of a domain.
+SELECT *
+FROM recheck_job
+WHERE domain_id = $1
+  AND status IN ('queued', 'running')
+LIMIT 1;
+
+-- name: LockRecheckClient :exec
+-- Serializes the requests of a client until the transaction ends, so its jobs
+-- are counted and created one request at a time.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg(client)::text, 0));
+
+-- name: GetRecheckJob :one
+SELECT *
+FROM recheck_job
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetLatestRecheckJob :one
+-- Returns the newest job for a domain.
+SELECT *
+FROM recheck_job
+WHERE domain_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ClientRecheckJobStats :one
+-- Counts the jobs a client requested since the given time, and returns when the oldest of them was requested.
+SELECT count(*)                                    AS count,
+       COALESCE(min(created_at), NOW())::timestamptz AS oldest
+FROM recheck_job
+WHERE client = $1
+  AND created_at >= $2;
+
+-- name: ClaimRecheckJobs :many
+-- Marks the next queued jobs as running and returns them, highest priority first.
+-- Jobs that have been running since before the given time are claimed again,
+-- the crawler that claimed them has stopped. SKIP LOCKED lets several crawlers
+-- claim jobs at the same time without getting the same job.
+UPDATE recheck_job
+SET status     = 'running',
+    started_at = NOW()
+WHERE id IN (SELECT claim.id
+             FROM recheck_job claim
+             WHERE claim.status = 'queued'
+                OR (claim.status = 'running' AND claim.started_at < $2)
+             ORDER BY claim.priority DESC, claim.created_at
+             LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: CountQueuedRecheckJobs :one
+-- Counts the jobs that wait to be claimed, the depth of the queue.
+SELECT count(*)
+FROM recheck_job
+WHERE status = 'queued';
+
+-- name: FinishRecheckJob :execrows
+-- Stores the result of a job, status is done or failed. Only the crawler that
+-- holds the claim of the job finishes it, a job that was claimed again has
+-- another started_at.
+UPDATE recheck_job
+SET status      = $2,
+    base_domain = $3,
+    www_domain  = $4,
+    nameserver  = $5,
+    mx_record   = $6,
+    error       = $7,
+    finished_at = NOW()
+WHERE id = $1
+  AND status = 'running'
+  AND started_at = $8;
@@ -0,0 +1,152 @@
+package badge
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"text/template"
+)
+
+// Style is the visual style of a badge, named like the shields.io styles.
+type Style string
+
+// Supported badge styles.
+const (
+	StyleFlat        Style = "flat"          // 20px high, rounded corners and a subtle gradient
+	StyleForTheBadge Style = "for-the-badge" // 28px high, square corners and uppercase text
+)
+
+// ParseStyle returns the style with the given name. An empty name is the flat style.
+func ParseStyle(name string) (Style, bool) {
+	switch Style(name) {
+	case "", StyleFlat:
+		return StyleFlat, true
+	case StyleForTheBadge:
+		return StyleForTheBadge, true
+	}
+	return "", false
+}
+
+// Named colors, the same names shields.io uses.
+const (
+	ColorBrightGreen = "brightgreen"
+	ColorGreen       = "green"
+	ColorYellow      = "yellow"
+	ColorOrange      = "orange"
+	ColorRed         = "red"
+	ColorLightGrey   = "lightgrey"
+)
+
+// colors maps the named colors to their hex values.
+var colors = map[string]string{
+	ColorBrightGreen: "#4c1",
+	ColorGreen:       "#97ca00",
+	ColorYellow:      "#dfb317",
+	ColorOrange:      "#fe7d37",
+	ColorRed:         "#e05d44",
+	ColorLightGrey:   "#9f9f9f",
+}
+
+// Badge is a status badge with a label on the left and a colored message on the right.
+type Badge struct {
+	Label   string // Text on the grey left side, e.g. IPv6
+	Message string // Text on the colored right side, e.g. supported
+	Color   string // Named color of the right side, e.g. brightgreen
+}
+
+// svgBadge is the data of the SVG templates.
+type svgBadge struct {
+	Title                           string // Accessible text, in the original case
+	Label, Message                  string // Displayed text
+	Color                           string // Hex color of the message side
+	Width, LabelWidth, MessageWidth int
+	LabelX, MessageX                float64 // Center of the texts
+}
+
+// The templates follow the layout of the shields.io badges, so the badges look
+// at home next to them in a README.
+var (
+	flatTemplate = template.Must(template.New("flat").Parse(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{html .Title}}">` +
+			`<title>{{html .Title}}</title>` +
+			`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+			`<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>` +
+			`<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>` +
+			`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="11">` +
+			`<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Label}}</text><text x="{{.LabelX}}" y="14">{{html .Label}}</text>` +
+			`<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Message}}</text><text x="{{.MessageX}}" y="14">{{html .Message}}</text>` +
+			`</g></svg>`,
+	))
+	forTheBadgeTemplate = template.Must(template.New("for-the-badge").Parse(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="28" role="img" aria-label="{{html .Title}}">` +
+			`<title>{{html .Title}}</title>` +
+			`<g shape-rendering="crispEdges"><rect width="{{.LabelWidth}}" height="28" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="28" fill="{{.Color}}"/></g>` +
+			`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="10" letter-spacing="1.25">` +
+			`<text x="{{.LabelX}}" y="17.5">{{html .Label}}</text><text x="{{.MessageX}}" y="17.5" font-weight="bold">{{html .Message}}</text>` +
+			`</g></svg>`,
+	))
+)
+
+// SVG renders the badge in the given style.
+func (b Badge) SVG(style Style) []byte {
+	color, ok := colors[b.Color]
+	if !ok {
+		color = colors[ColorLightGrey]
+	}
+	data := svgBadge{
+		Title:   b.Label + ": " + b.Message,
+		Label:   b.Label,
+		Message: b.Message,
+		Color:   color,
+	}
+
+	tmpl := flatTemplate
+	padding := 10
+	spacing := 0.0
+	if style == StyleForTheBadge {
+		tmpl = forTheBadgeTemplate
+		padding = 24
+		spacing = 1.25
+		data.Label = strings.ToUpper(b.Label)
+		data.Message = strings.ToUpper(b.Message)
+	}
+
+	data.LabelWidth = textWidth(data.Label, spacing) + padding
+	data.MessageWidth = textWidth(data.Message, spacing) + padding
+	data.Width = data.LabelWidth + data.MessageWidth
+	data.LabelX = float64(data.LabelWidth) / 2
+	data.MessageX = float64(data.LabelWidth) + float64(data.MessageWidth)/2
+
+	var buf bytes.Buffer
+	// The templates only fail on a write error, and a bytes.Buffer does not return any.
+	_ = tmpl.Execute(&buf, data)
+	return buf.Bytes()
+}
+
+// charWidths are the approximate widths in pixels of the characters in 11px
+// Verdana, the font of the badges. Characters that are not listed use defaultWidth.
+var charWidths = map[rune]float64{
+	' ': 3.9, '.': 3.9, ',': 3.9, ':': 4.9, '-': 4.9, '/': 4.9, '%': 11.8, '(': 4.9, ')': 4.9,
+	'a': 6.8, 'b': 7.0, 'c': 5.9, 'd': 7.0, 'e': 6.8, 'f': 3.9, 'g': 7.0, 'h': 7.0, 'i': 3.0,
+	'j': 3.8, 'k': 6.5, 'l': 3.0, 'm': 10.7, 'n': 7.0, 'o': 6.7, 'p': 7.0, 'q': 7.0, 'r': 4.7,
+	's': 5.8, 't': 4.3, 'u': 7.0, 'v': 6.5, 'w': 9.0, 'x': 6.5, 'y': 6.5, 'z': 5.8,
+	'A': 7.5, 'B': 7.5, 'C': 7.7, 'D': 8.5, 'E': 6.9, 'F': 6.3, 'G': 8.5, 'H': 8.3, 'I': 4.6,
+	'J': 5.0, 'K': 7.6, 'L': 6.1, 'M': 9.3, 'N': 8.2, 'O': 8.7, 'P': 6.6, 'Q': 8.7, 'R': 7.6,
+	'S': 7.5, 'T': 6.8, 'U': 8.1, 'V': 7.5, 'W': 10.9, 'X': 7.5, 'Y': 6.8, 'Z': 7.5,
+}
+
+// defaultWidth is the width of digits and characters that are not in charWidths.
+const defaultWidth = 7.0
+
+// textWidth estimates the width in pixels of a text, with the given spacing between letters.
+func textWidth(text string, spacing float64) int {
+	width := 0.0
+	for _, r := range text {
+		w, ok := charWidths[r]
+		if !ok {
+			w = defaultWidth
+		}
+		width += w + spacing
+	}
+	return int(math.Ceil(width))
+}
@@ -0,0 +1,52 @@
+// Package buildinfo describes the build of the running binary.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Version is the version of the build, set with
+// -ldflags "-X whynoipv6/internal/buildinfo.Version=v1.2.3".
+var Version = "dev"
+
+// Info is the build of the running binary.
+type Info struct {
+	Version   string     `json:"version"`
+	Commit    string     `json:"commit,omitempty"`    // VCS revision, if built from a checkout
+	CommitAt  *time.Time `json:"commit_at,omitempty"` // Time of the commit
+	Modified  bool       `json:"modified"`            // Built with uncommitted changes
+	GoVersion string     `json:"go_version"`          // Go release the binary was built with
+	StartedAt time.Time  `json:"started_at"`          // When the process started
+}
+
+// startedAt is when the process started, more or less.
+var startedAt = time.Now()
+
+// Read returns the build of the running binary. The commit is read from the
+// VCS information Go stamps into binaries built from a checkout.
+func Read() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		StartedAt: startedAt,
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				info.CommitAt = &t
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
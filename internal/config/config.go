@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -17,6 +18,70 @@ type Config struct {
 	Nameserver          string `mapstructure:"NAMESERVER"`
 	HealthcheckCrawler  string `mapstructure:"HEALTHCHECK_CRAWLER"`
 	HealthcheckCampaign string `mapstructure:"HEALTHCHECK_CAMPAIGN"`
+	SiteURL             string `mapstructure:"SITE_URL"`
+	SnapshotPath        string `mapstructure:"SNAPSHOT_PATH"`
+
+	// Crawler settings, the defaults are used if these are not set.
+	CrawlerWorkers              int           `mapstructure:"CRAWLER_WORKERS"`
+	CrawlerMinWorkers           int           `mapstructure:"CRAWLER_MIN_WORKERS"`
+	CrawlerMaxWorkers           int           `mapstructure:"CRAWLER_MAX_WORKERS"`
+	CrawlerBatchSize            int64         `mapstructure:"CRAWLER_BATCH_SIZE"`
+	CrawlerJobTimeout           time.Duration `mapstructure:"CRAWLER_JOB_TIMEOUT"`
+	CrawlerCheckTimeout         time.Duration `mapstructure:"CRAWLER_CHECK_TIMEOUT"`
+	CrawlerReuseWindow          time.Duration `mapstructure:"CRAWLER_REUSE_WINDOW"`
+	CrawlerInterval             time.Duration `mapstructure:"CRAWLER_INTERVAL"`
+	CrawlerSchedule             string        `mapstructure:"CRAWLER_SCHEDULE"`
+	CampaignCrawlerWorkers      int           `mapstructure:"CAMPAIGN_CRAWLER_WORKERS"`
+	CampaignCrawlerMinWorkers   int           `mapstructure:"CAMPAIGN_CRAWLER_MIN_WORKERS"`
+	CampaignCrawlerMaxWorkers   int           `mapstructure:"CAMPAIGN_CRAWLER_MAX_WORKERS"`
+	CampaignCrawlerBatchSize    int64         `mapstructure:"CAMPAIGN_CRAWLER_BATCH_SIZE"`
+	CampaignCrawlerJobTimeout   time.Duration `mapstructure:"CAMPAIGN_CRAWLER_JOB_TIMEOUT"`
+	CampaignCrawlerCheckTimeout time.Duration `mapstructure:"CAMPAIGN_CRAWLER_CHECK_TIMEOUT"`
+	CampaignCrawlerReuseWindow  time.Duration `mapstructure:"CAMPAIGN_CRAWLER_REUSE_WINDOW"`
+	CampaignCrawlerInterval     time.Duration `mapstructure:"CAMPAIGN_CRAWLER_INTERVAL"`
+	CampaignCrawlerSchedule     string        `mapstructure:"CAMPAIGN_CRAWLER_SCHEDULE"`
+
+	// Addresses the crawlers serve their Prometheus metrics on, not served if these are not set.
+	CrawlerMetricsAddr         string `mapstructure:"CRAWLER_METRICS_ADDR"`
+	CampaignCrawlerMetricsAddr string `mapstructure:"CAMPAIGN_CRAWLER_METRICS_ADDR"`
+
+	// Rate limits of the recheck endpoint, the defaults are used if these are not set.
+	RecheckClientLimit    int           `mapstructure:"RECHECK_CLIENT_LIMIT"`
+	RecheckClientWindow   time.Duration `mapstructure:"RECHECK_CLIENT_WINDOW"`
+	RecheckDomainInterval time.Duration `mapstructure:"RECHECK_DOMAIN_INTERVAL"`
+
+	// Rate limits of the API in requests per minute, the defaults are used if these are not set.
+	RateLimitAnonymous int `mapstructure:"RATE_LIMIT_ANONYMOUS"`
+	RateLimitKey       int `mapstructure:"RATE_LIMIT_KEY"`
+
+	// Comma separated addresses and CIDR prefixes of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers are used, loopback if not set.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// How long the country and metric responses are kept in memory, zero or unset disables it.
+	ResponseCacheTTL time.Duration `mapstructure:"RESPONSE_CACHE_TTL"`
+
+	// The API is not ready if the last crawl finished longer ago, zero or unset disables the check.
+	ReadyMaxDataAge time.Duration `mapstructure:"READY_MAX_DATA_AGE"`
+
+	// OTLP/HTTP collector the traces are exported to, tracing is disabled if it is not set.
+	TracingEndpoint    string  `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+
+	// Comma separated addresses the API listens on, [::1]:API_PORT if not set.
+	APIListenAddrs []string `mapstructure:"API_LISTEN_ADDRS"`
+	// Timeouts of the API server, the defaults are used if these are not set.
+	APIReadTimeout     time.Duration `mapstructure:"API_READ_TIMEOUT"`
+	APIWriteTimeout    time.Duration `mapstructure:"API_WRITE_TIMEOUT"`
+	APIIdleTimeout     time.Duration `mapstructure:"API_IDLE_TIMEOUT"`
+	APIShutdownTimeout time.Duration `mapstructure:"API_SHUTDOWN_TIMEOUT"`
+	// Certificate and key the API serves TLS with, plain HTTP if not set.
+	APITLSCert string `mapstructure:"API_TLS_CERT"`
+	APITLSKey  string `mapstructure:"API_TLS_KEY"`
+
+	// Limits of the GraphQL queries, the defaults are used if these are not set.
+	GraphQLMaxDepth      int `mapstructure:"GRAPHQL_MAX_DEPTH"`
+	GraphQLMaxComplexity int `mapstructure:"GRAPHQL_MAX_COMPLEXITY"`
 }
 
 // Read reads the configuration from the app.env file.
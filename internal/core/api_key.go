@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+)
+
+// APIKeyService is a service for the API keys of the API consumers.
+type APIKeyService struct {
+	q *db.Queries
+}
+
+// NewAPIKeyService creates a new APIKeyService instance.
+func NewAPIKeyService(d db.DBTX) *APIKeyService {
+	return &APIKeyService{
+		q: db.New(d),
+	}
+}
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to search for.
+const APIKeyPrefix = "wn6_"
+
+// APIKeyModel represents an API key. The key itself is only known when it is created.
+type APIKeyModel struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	RateLimit int32     `json:"rate_limit"` // Requests per minute, zero for the default key limit
+	Admin     bool      `json:"admin"`      // Can use the admin API
+	Requests  int64     `json:"requests"`
+	LastUsed  time.Time `json:"last_used"`
+	CreatedAt time.Time `json:"created_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// HashAPIKey returns the hash of an API key, the value stored in the database.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateAPIKey creates a random API key with a rate limit in requests per
+// minute, zero for the default key limit. Admin keys can use the admin API.
+// It returns the key, which is not stored.
+func (s *APIKeyService) CreateAPIKey(
+	ctx context.Context,
+	name string,
+	rateLimit int32,
+	admin bool,
+) (APIKeyModel, string, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKeyModel{}, "", err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(secret)
+	apiKey, err := s.q.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+8],
+		KeyHash:   HashAPIKey(key),
+		RateLimit: optionalInt32(rateLimit),
+		Admin:     admin,
+	})
+	if err != nil {
+		return APIKeyModel{}, "", err
+	}
+	return newAPIKeyModel(apiKey), key, nil
+}
+
+// GetAPIKey returns the API key, unless it has been revoked.
+// Returns pgx.ErrNoRows if the key is unknown or revoked.
+func (s *APIKeyService) GetAPIKey(ctx context.Context, key string) (APIKeyModel, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.GetAPIKey")
+	defer span.End()
+
+	apiKey, err := s.q.GetAPIKeyByHash(ctx, HashAPIKey(key))
+	if err != nil {
+		return APIKeyModel{}, err
+	}
+	return newAPIKeyModel(apiKey), nil
+}
+
+// GetAPIKeyByID returns the API key with the given id, unless it has been revoked.
+// Returns pgx.ErrNoRows if the key is unknown or revoked.
+func (s *APIKeyService) GetAPIKeyByID(ctx context.Context, id int64) (APIKeyModel, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.GetAPIKeyByID")
+	defer span.End()
+
+	apiKey, err := s.q.GetAPIKey(ctx, id)
+	if err != nil {
+		return APIKeyModel{}, err
+	}
+	return newAPIKeyModel(apiKey), nil
+}
+
+// ListAPIKeys lists every API key, including the revoked ones.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]APIKeyModel, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer span.End()
+
+	apiKeys, err := s.q.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var models []APIKeyModel
+	for _, apiKey := range apiKeys {
+		models = append(models, newAPIKeyModel(apiKey))
+	}
+	return models, nil
+}
+
+// RevokeAPIKey revokes an API key.
+// Returns pgx.ErrNoRows if the key does not exist or is already revoked.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int64) (APIKeyModel, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	apiKey, err := s.q.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return APIKeyModel{}, err
+	}
+	return newAPIKeyModel(apiKey), nil
+}
+
+// AddAPIKeyUsage adds requests to the usage counter of an API key.
+func (s *APIKeyService) AddAPIKeyUsage(ctx context.Context, id, requests int64, lastUsed time.Time) error {
+	ctx, span := tracer.Start(ctx, "APIKeyService.AddAPIKeyUsage")
+	defer span.End()
+
+	return s.q.AddAPIKeyUsage(ctx, db.AddAPIKeyUsageParams{
+		ID:       id,
+		Requests: requests,
+		LastUsed: lastUsed,
+	})
+}
+
+// newAPIKeyModel maps an API key row to its model.
+func newAPIKeyModel(apiKey db.ApiKey) APIKeyModel {
+	return APIKeyModel{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		RateLimit: apiKey.RateLimit.Int32,
+		Admin:     apiKey.Admin,
+		Requests:  apiKey.Requests,
+		LastUsed:  TimeNull(apiKey.LastUsed),
+		CreatedAt: apiKey.CreatedAt,
+		RevokedAt: TimeNull(apiKey.RevokedAt),
+	}
+}
@@ -33,6 +33,9 @@ type ASNModel struct {
 
 // CreateAsn creates a new BGP ASN record with the specified number and name.
 func (s *ASNService) CreateAsn(ctx context.Context, number int32, name string) (ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "ASNService.CreateAsn")
+	defer span.End()
+
 	asn, err := s.q.CreateASN(ctx, db.CreateASNParams{
 		Number: number,
 		Name:   name,
@@ -49,6 +52,9 @@ func (s *ASNService) CreateAsn(ctx context.Context, number int32, name string) (
 
 // GetASByNumber retrieves the BGP ASN record with the specified AS number.
 func (s *ASNService) GetASByNumber(ctx context.Context, number int32) (ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "ASNService.GetASByNumber")
+	defer span.End()
+
 	asnRecord, err := s.q.GetASByNumber(ctx, number)
 	if err == pgx.ErrNoRows {
 		return ASNModel{}, pgx.ErrNoRows
@@ -87,5 +93,58 @@ func (s *ASNService) GetASByNumber(ctx context.Context, number int32) (ASNModel,
 
 // CalculateASNStats calculates the statistics for an ASN.
 func (s *ASNService) CalculateASNStats(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ASNService.CalculateASNStats")
+	defer span.End()
+
 	return s.q.CalculateASNStats(ctx)
 }
+
+// GetASNsByID retrieves the ASNs with the given IDs, in no particular order.
+func (s *ASNService) GetASNsByID(ctx context.Context, ids []int64) ([]ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "ASNService.GetASNsByID")
+	defer span.End()
+
+	asns, err := s.q.ListASNsByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var models []ASNModel
+	for _, asn := range asns {
+		models = append(models, ASNModel{
+			ID:        asn.ID,
+			Number:    asn.Number,
+			Name:      asn.Name,
+			CountV4:   asn.CountV4.Int32,
+			CountV6:   asn.CountV6.Int32,
+			PercentV4: asn.PercentV4.Float64,
+			PercentV6: asn.PercentV6.Float64,
+		})
+	}
+	return models, nil
+}
+
+// ListASNStats lists the statistics of every ASN with domains, ordered by AS
+// number. Unlike AsnList, CountV4 is the number of domains with IPv4, including
+// the ones that also have IPv6.
+func (s *ASNService) ListASNStats(ctx context.Context) ([]ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "ASNService.ListASNStats")
+	defer span.End()
+
+	asns, err := s.q.ListASNStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var models []ASNModel
+	for _, asn := range asns {
+		models = append(models, ASNModel{
+			ID:        asn.ID,
+			Number:    asn.Number,
+			Name:      asn.Name,
+			CountV4:   asn.CountV4.Int32,
+			CountV6:   asn.CountV6.Int32,
+			PercentV4: asn.PercentV4.Float64,
+			PercentV6: asn.PercentV6.Float64,
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/jackc/pgtype"
+)
+
+// AuditService is a service for the audit log of the changes made through the admin API.
+type AuditService struct {
+	q *db.Queries
+}
+
+// NewAuditService creates a new AuditService instance.
+func NewAuditService(d db.DBTX) *AuditService {
+	return &AuditService{
+		q: db.New(d),
+	}
+}
+
+// Actions in the audit log.
+const (
+	AuditDomainAdd            = "domain.add"
+	AuditDomainRemove         = "domain.remove"
+	AuditDomainDisable        = "domain.disable"
+	AuditDomainEnable         = "domain.enable"
+	AuditDomainRecheck        = "domain.recheck"
+	AuditCampaignCreate       = "campaign.create"
+	AuditCampaignUpdate       = "campaign.update"
+	AuditCampaignDomainAdd    = "campaign.domain.add"
+	AuditCampaignDomainRemove = "campaign.domain.remove"
+)
+
+// AuditLogModel represents a change made through the admin API.
+type AuditLogModel struct {
+	ID       int64        `json:"id"`
+	Ts       time.Time    `json:"ts"`
+	APIKeyID int64        `json:"api_key_id"`
+	Actor    string       `json:"actor"`  // Name of the API key
+	Action   string       `json:"action"` // e.g. domain.disable
+	Target   string       `json:"target"` // Domain or campaign
+	Details  pgtype.JSONB `json:"details"`
+}
+
+// CreateAuditLog writes a change made with an API key to the audit log. The
+// details are encoded as JSON.
+func (s *AuditService) CreateAuditLog(
+	ctx context.Context,
+	apiKey APIKeyModel,
+	action, target string,
+	details any,
+) (AuditLogModel, error) {
+	ctx, span := tracer.Start(ctx, "AuditService.CreateAuditLog")
+	defer span.End()
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return AuditLogModel{}, err
+	}
+	jsonb := pgtype.JSONB{}
+	if err := jsonb.Set(data); err != nil {
+		return AuditLogModel{}, err
+	}
+	entry, err := s.q.CreateAuditLog(ctx, db.CreateAuditLogParams{
+		ApiKeyID: NullInt(apiKey.ID),
+		Actor:    apiKey.Name,
+		Action:   action,
+		Target:   target,
+		Details:  jsonb,
+	})
+	if err != nil {
+		return AuditLogModel{}, err
+	}
+	return newAuditLogModel(entry), nil
+}
+
+// ListAuditLog lists the audit log, newest first. An empty target lists the
+// changes to every target.
+func (s *AuditService) ListAuditLog(ctx context.Context, target string, offset, limit int64) ([]AuditLogModel, error) {
+	ctx, span := tracer.Start(ctx, "AuditService.ListAuditLog")
+	defer span.End()
+
+	entries, err := s.q.ListAuditLog(ctx, db.ListAuditLogParams{
+		Target: target,
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := []AuditLogModel{}
+	for _, entry := range entries {
+		list = append(list, newAuditLogModel(entry))
+	}
+	return list, nil
+}
+
+// newAuditLogModel maps an audit log row to its model.
+func newAuditLogModel(entry db.AuditLog) AuditLogModel {
+	return AuditLogModel{
+		ID:       entry.ID,
+		Ts:       entry.Ts,
+		APIKeyID: IntNull(entry.ApiKeyID),
+		Actor:    entry.Actor,
+		Action:   entry.Action,
+		Target:   entry.Target,
+		Details:  entry.Details,
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"time"
 
 	"whynoipv6/internal/postgres/db"
@@ -63,6 +64,9 @@ func (s *CampaignService) InsertCampaignDomain(
 	campaignID uuid.UUID,
 	domain string,
 ) error {
+	ctx, span := tracer.Start(ctx, "CampaignService.InsertCampaignDomain")
+	defer span.End()
+
 	err := s.q.InsertCampaignDomain(ctx, db.InsertCampaignDomainParams{
 		CampaignID: campaignID,
 		Site:       domain,
@@ -78,6 +82,9 @@ func (s *CampaignService) CrawlCampaignDomain(
 	ctx context.Context,
 	offset, limit int64,
 ) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.CrawlCampaignDomain")
+	defer span.End()
+
 	domains, err := s.q.CrawlCampaignDomain(ctx, db.CrawlCampaignDomainParams{
 		Offset: offset,
 		Limit:  limit,
@@ -108,41 +115,15 @@ func (s *CampaignService) CrawlCampaignDomain(
 	return list, nil
 }
 
-// UpdateCampaignDomain updates a domain.
-func (s *CampaignService) UpdateCampaignDomain(
-	ctx context.Context,
-	domain CampaignDomainModel,
-) error {
-	err := s.q.UpdateCampaignDomain(ctx, db.UpdateCampaignDomainParams{
-		Site:         domain.Site,
-		CampaignID:   domain.CampaignID,
-		BaseDomain:   domain.BaseDomain,
-		WwwDomain:    domain.WwwDomain,
-		Nameserver:   domain.Nameserver,
-		MxRecord:     domain.MXRecord,
-		V6Only:       domain.V6Only,
-		AsnID:        NullInt(domain.AsnID),
-		CountryID:    NullInt(domain.CountryID),
-		TsBaseDomain: NullTime(domain.TsBaseDomain),
-		TsWwwDomain:  NullTime(domain.TsWwwDomain),
-		TsNameserver: NullTime(domain.TsNameserver),
-		TsMxRecord:   NullTime(domain.TsMXRecord),
-		TsV6Only:     NullTime(domain.TsV6Only),
-		TsCheck:      NullTime(domain.TsCheck),
-		TsUpdated:    NullTime(domain.TsUpdated),
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 // ViewCampaignDomain list a domain.
 func (s *CampaignService) ViewCampaignDomain(
 	ctx context.Context,
 	uuid uuid.UUID,
 	domain string,
 ) (CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.ViewCampaignDomain")
+	defer span.End()
+
 	d, err := s.q.ViewCampaignDomain(ctx, db.ViewCampaignDomainParams{
 		Site:       domain,
 		CampaignID: uuid,
@@ -172,6 +153,9 @@ func (s *CampaignService) ViewCampaignDomain(
 
 // DisableCampaignDomain disables a domain.
 func (s *CampaignService) DisableCampaignDomain(ctx context.Context, domain string) error {
+	ctx, span := tracer.Start(ctx, "CampaignService.DisableCampaignDomain")
+	defer span.End()
+
 	err := s.q.DisableCampaignDomain(ctx, domain)
 	if err != nil {
 		return err
@@ -181,6 +165,9 @@ func (s *CampaignService) DisableCampaignDomain(ctx context.Context, domain stri
 
 // ListCampaign list all campaigns.
 func (s *CampaignService) ListCampaign(ctx context.Context) ([]CampaignModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.ListCampaign")
+	defer span.End()
+
 	campaigns, err := s.q.ListCampaign(ctx)
 	if err != nil {
 		return nil, err
@@ -202,6 +189,9 @@ func (s *CampaignService) ListCampaign(ctx context.Context) ([]CampaignModel, er
 
 // GetCampaign returns a campaign.
 func (s *CampaignService) GetCampaign(ctx context.Context, id uuid.UUID) (CampaignModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.GetCampaign")
+	defer span.End()
+
 	c, err := s.q.GetCampaignByUUID(ctx, id)
 	if err != nil {
 		return CampaignModel{}, err
@@ -222,6 +212,9 @@ func (s *CampaignService) CreateCampaign(
 	ctx context.Context,
 	name, description string,
 ) (CampaignModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.CreateCampaign")
+	defer span.End()
+
 	c, err := s.q.CreateCampaign(ctx, db.CreateCampaignParams{
 		Name:        name,
 		Description: description,
@@ -242,6 +235,9 @@ func (s *CampaignService) CreateOrUpdateCampaign(
 	ctx context.Context,
 	campaign CampaignModel,
 ) (CampaignModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.CreateOrUpdateCampaign")
+	defer span.End()
+
 	c, err := s.q.CreateOrUpdateCampaign(ctx, db.CreateOrUpdateCampaignParams{
 		Uuid:        campaign.UUID,
 		Name:        campaign.Name,
@@ -264,6 +260,9 @@ func (s *CampaignService) ListCampaignDomain(
 	campaignID uuid.UUID,
 	offset, limit int64,
 ) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.ListCampaignDomain")
+	defer span.End()
+
 	domains, err := s.q.ListCampaignDomain(ctx, db.ListCampaignDomainParams{
 		CampaignID: campaignID,
 		Offset:     offset,
@@ -297,12 +296,136 @@ func (s *CampaignService) ListCampaignDomain(
 	return list, nil
 }
 
+// ListCampaignDomainAfter lists the domains for a campaign after the given domain ID, ordered by ID.
+func (s *CampaignService) ListCampaignDomainAfter(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	afterID, limit int64,
+) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.ListCampaignDomainAfter")
+	defer span.End()
+
+	domains, err := s.q.ListCampaignDomainAfter(ctx, db.ListCampaignDomainAfterParams{
+		CampaignID: campaignID,
+		ID:         afterID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []CampaignDomainModel
+	for _, d := range domains {
+		list = append(list, CampaignDomainModel{
+			ID:           d.ID,
+			Site:         d.Site,
+			CampaignID:   d.CampaignID,
+			BaseDomain:   d.BaseDomain,
+			WwwDomain:    d.WwwDomain,
+			Nameserver:   d.Nameserver,
+			MXRecord:     d.MxRecord,
+			V6Only:       d.V6Only,
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+		})
+	}
+	return list, nil
+}
+
+// ListCampaignDomainBefore lists the domains for a campaign before the given domain ID, ordered by ID.
+func (s *CampaignService) ListCampaignDomainBefore(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	beforeID, limit int64,
+) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.ListCampaignDomainBefore")
+	defer span.End()
+
+	domains, err := s.q.ListCampaignDomainBefore(ctx, db.ListCampaignDomainBeforeParams{
+		CampaignID: campaignID,
+		ID:         beforeID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []CampaignDomainModel
+	for _, d := range domains {
+		list = append(list, CampaignDomainModel{
+			ID:           d.ID,
+			Site:         d.Site,
+			CampaignID:   d.CampaignID,
+			BaseDomain:   d.BaseDomain,
+			WwwDomain:    d.WwwDomain,
+			Nameserver:   d.Nameserver,
+			MXRecord:     d.MxRecord,
+			V6Only:       d.V6Only,
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(list)
+	return list, nil
+}
+
+// CountCampaignDomain returns the number of domains in a campaign.
+// UpdateCampaign updates the name and description of a campaign.
+// Returns pgx.ErrNoRows if the campaign does not exist.
+func (s *CampaignService) UpdateCampaign(
+	ctx context.Context,
+	id uuid.UUID,
+	name, description string,
+) (CampaignModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.UpdateCampaign")
+	defer span.End()
+
+	c, err := s.q.UpdateCampaign(ctx, db.UpdateCampaignParams{
+		Uuid:        id,
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return CampaignModel{}, err
+	}
+	return CampaignModel{
+		ID:          c.ID,
+		CreatedAt:   c.CreatedAt,
+		UUID:        c.Uuid,
+		Name:        c.Name,
+		Description: c.Description,
+	}, nil
+}
+
+func (s *CampaignService) CountCampaignDomain(ctx context.Context, campaignID uuid.UUID) (int64, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.CountCampaignDomain")
+	defer span.End()
+
+	return s.q.CountCampaignDomain(ctx, campaignID)
+}
+
 // DeleteCampaignDomain deletes a domain from a campaign.
 func (s *CampaignService) DeleteCampaignDomain(
 	ctx context.Context,
 	campaignID uuid.UUID,
 	domain string,
 ) error {
+	ctx, span := tracer.Start(ctx, "CampaignService.DeleteCampaignDomain")
+	defer span.End()
+
 	err := s.q.DeleteCampaignDomain(ctx, db.DeleteCampaignDomainParams{
 		CampaignID: campaignID,
 		Site:       domain,
@@ -319,6 +442,9 @@ func (s *CampaignService) GetCampaignDomainsByName(
 	searchString string,
 	offset, limit int64,
 ) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.GetCampaignDomainsByName")
+	defer span.End()
+
 	domains, err := s.q.GetCampaignDomainsByName(ctx, db.GetCampaignDomainsByNameParams{
 		Column1: NullString(searchString),
 		Offset:  offset,
@@ -344,6 +470,14 @@ func (s *CampaignService) GetCampaignDomainsByName(
 	return list, nil
 }
 
+// CountCampaignDomainsByName returns the number of campaign domains matching a search string.
+func (s *CampaignService) CountCampaignDomainsByName(ctx context.Context, searchString string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.CountCampaignDomainsByName")
+	defer span.End()
+
+	return s.q.CountCampaignDomainsByName(ctx, NullString(searchString))
+}
+
 // CampaignDomainLog represents a crawler data point.
 type CampaignDomainLog struct {
 	ID   int64
@@ -357,6 +491,9 @@ func (s *CampaignService) StoreCampaignDomainLog(
 	domain int64,
 	data any,
 ) error {
+	ctx, span := tracer.Start(ctx, "CampaignService.StoreCampaignDomainLog")
+	defer span.End()
+
 	// Encode the data to a []byte
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
@@ -383,6 +520,9 @@ func (s *CampaignService) GetCampaignDomainLog(
 	uuid uuid.UUID,
 	domain string,
 ) ([]CampaignDomainLog, error) {
+	ctx, span := tracer.Start(ctx, "CampaignService.GetCampaignDomainLog")
+	defer span.End()
+
 	// Get the domain ID from the database
 	d, err := s.q.ViewCampaignDomain(ctx, db.ViewCampaignDomainParams{
 		CampaignID: uuid,
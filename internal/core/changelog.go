@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"whynoipv6/internal/postgres/db"
@@ -23,6 +24,10 @@ func NewChangelogService(d db.DBTX) *ChangelogService {
 	}
 }
 
+// ChangelogChannel is the PostgreSQL notification channel the database notifies
+// on when changelog or campaign changelog entries are written.
+const ChangelogChannel = "changelog"
+
 // ChangelogModel represents a changelog entry.
 type ChangelogModel struct {
 	ID         int64     `json:"id"`
@@ -32,6 +37,8 @@ type ChangelogModel struct {
 	Site       string    `json:"site"`
 	Message    string    `json:"message"`
 	IPv6Status string    `json:"ipv6_status"`
+	// CountryCode is the country of the domain, only set by the Since lists.
+	CountryCode string `json:"country_code,omitempty"`
 }
 
 // Create creates a new changelog entry.
@@ -39,6 +46,9 @@ func (s *ChangelogService) Create(
 	ctx context.Context,
 	params ChangelogModel,
 ) (ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.Create")
+	defer span.End()
+
 	changelog, err := s.q.CreateChangelog(ctx, db.CreateChangelogParams{
 		DomainID:   params.DomainID,
 		Message:    params.Message,
@@ -62,6 +72,9 @@ func (s *ChangelogService) CampaignCreate(
 	ctx context.Context,
 	params ChangelogModel,
 ) (ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CampaignCreate")
+	defer span.End()
+
 	changelog, err := s.q.CreateCampaignChangelog(ctx, db.CreateCampaignChangelogParams{
 		DomainID:   params.DomainID,
 		CampaignID: params.CampaignID,
@@ -87,6 +100,9 @@ func (s *ChangelogService) List(
 	ctx context.Context,
 	offset, limit int64,
 ) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.List")
+	defer span.End()
+
 	changelogs, err := s.q.ListChangelog(ctx, db.ListChangelogParams{
 		Offset: offset,
 		Limit:  limit,
@@ -107,11 +123,87 @@ func (s *ChangelogService) List(
 	return models, nil
 }
 
+// ListChangelogAfter lists the changelog entries after the cursor, newest first.
+func (s *ChangelogService) ListChangelogAfter(
+	ctx context.Context,
+	after TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListChangelogAfter")
+	defer span.End()
+
+	after = after.start()
+	changelogs, err := s.q.ListChangelogAfter(ctx, db.ListChangelogAfterParams{
+		Ts:    after.Ts,
+		ID:    after.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// ListChangelogBefore lists the changelog entries before the cursor, newest first.
+func (s *ChangelogService) ListChangelogBefore(
+	ctx context.Context,
+	before TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListChangelogBefore")
+	defer span.End()
+
+	changelogs, err := s.q.ListChangelogBefore(ctx, db.ListChangelogBeforeParams{
+		Ts:    before.Ts,
+		ID:    before.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(models)
+	return models, nil
+}
+
+// Count returns the number of changelog entries.
+func (s *ChangelogService) Count(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.Count")
+	defer span.End()
+
+	return s.q.CountChangelog(ctx)
+}
+
 // CampaignList lists all changelog entries for campaign table.
 func (s *ChangelogService) CampaignList(
 	ctx context.Context,
 	offset, limit int64,
 ) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CampaignList")
+	defer span.End()
+
 	changelogs, err := s.q.ListCampaignChangelog(ctx, db.ListCampaignChangelogParams{
 		Offset: offset,
 		Limit:  limit,
@@ -133,12 +225,90 @@ func (s *ChangelogService) CampaignList(
 	return models, nil
 }
 
+// ListCampaignChangelogAfter lists the campaign changelog entries after the cursor, newest first.
+func (s *ChangelogService) ListCampaignChangelogAfter(
+	ctx context.Context,
+	after TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListCampaignChangelogAfter")
+	defer span.End()
+
+	after = after.start()
+	changelogs, err := s.q.ListCampaignChangelogAfter(ctx, db.ListCampaignChangelogAfterParams{
+		Ts:    after.Ts,
+		ID:    after.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// ListCampaignChangelogBefore lists the campaign changelog entries before the cursor, newest first.
+func (s *ChangelogService) ListCampaignChangelogBefore(
+	ctx context.Context,
+	before TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListCampaignChangelogBefore")
+	defer span.End()
+
+	changelogs, err := s.q.ListCampaignChangelogBefore(ctx, db.ListCampaignChangelogBeforeParams{
+		Ts:    before.Ts,
+		ID:    before.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(models)
+	return models, nil
+}
+
+// CampaignCount returns the number of changelog entries for campaigns.
+func (s *ChangelogService) CampaignCount(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CampaignCount")
+	defer span.End()
+
+	return s.q.CountCampaignChangelog(ctx)
+}
+
 // GetChangelogByDomain gets all changelog entries for a domain name.
 func (s *ChangelogService) GetChangelogByDomain(
 	ctx context.Context,
 	site string,
 	offset, limit int64,
 ) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByDomain")
+	defer span.End()
+
 	// Get all changelog entries for site id
 	changelogs, err := s.q.GetChangelogByDomain(ctx, db.GetChangelogByDomainParams{
 		Site:   site,
@@ -165,12 +335,153 @@ func (s *ChangelogService) GetChangelogByDomain(
 	return models, nil
 }
 
+// GetChangelogByDomainIDs gets the newest changelog entries of each of the
+// domains, at most limit per domain, grouped by domain and newest first.
+func (s *ChangelogService) GetChangelogByDomainIDs(
+	ctx context.Context,
+	domainIDs []int64,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByDomainIDs")
+	defer span.End()
+
+	changelogs, err := s.q.ListChangelogByDomainIDs(ctx, db.ListChangelogByDomainIDsParams{
+		DomainIds:  domainIDs,
+		MaxEntries: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// GetChangelogByDomainAfter gets the changelog entries for a domain name after the cursor, newest first.
+func (s *ChangelogService) GetChangelogByDomainAfter(
+	ctx context.Context,
+	site string,
+	after TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByDomainAfter")
+	defer span.End()
+
+	after = after.start()
+	changelogs, err := s.q.GetChangelogByDomainAfter(ctx, db.GetChangelogByDomainAfterParams{
+		Site:  site,
+		Ts:    after.Ts,
+		ID:    after.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// GetChangelogByDomainBefore gets the changelog entries for a domain name before the cursor, newest first.
+func (s *ChangelogService) GetChangelogByDomainBefore(
+	ctx context.Context,
+	site string,
+	before TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByDomainBefore")
+	defer span.End()
+
+	changelogs, err := s.q.GetChangelogByDomainBefore(ctx, db.GetChangelogByDomainBeforeParams{
+		Site:  site,
+		Ts:    before.Ts,
+		ID:    before.ID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(models)
+	return models, nil
+}
+
+// CountChangelogByDomain returns the number of changelog entries for a domain name.
+func (s *ChangelogService) CountChangelogByDomain(ctx context.Context, site string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CountChangelogByDomain")
+	defer span.End()
+
+	return s.q.CountChangelogByDomain(ctx, site)
+}
+
+// GetChangelogByCountry gets the newest changelog entries for the domains in a country.
+func (s *ChangelogService) GetChangelogByCountry(
+	ctx context.Context,
+	countryID int64,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCountry")
+	defer span.End()
+
+	changelogs, err := s.q.GetChangelogByCountry(ctx, db.GetChangelogByCountryParams{
+		CountryID: NullInt(countryID),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
 // GetChangelogByCampaign gets all changelog entries for a campaign.
 func (s *ChangelogService) GetChangelogByCampaign(
 	ctx context.Context,
 	campaignID uuid.UUID,
 	offset, limit int64,
 ) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaign")
+	defer span.End()
+
 	// Get all changelog entries for site id
 	changelogs, err := s.q.GetChangelogByCampaign(ctx, db.GetChangelogByCampaignParams{
 		CampaignID: campaignID,
@@ -198,6 +509,88 @@ func (s *ChangelogService) GetChangelogByCampaign(
 	return models, nil
 }
 
+// GetChangelogByCampaignAfter gets the changelog entries for a campaign after the cursor, newest first.
+func (s *ChangelogService) GetChangelogByCampaignAfter(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	after TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaignAfter")
+	defer span.End()
+
+	after = after.start()
+	changelogs, err := s.q.GetChangelogByCampaignAfter(ctx, db.GetChangelogByCampaignAfterParams{
+		CampaignID: campaignID,
+		Ts:         after.Ts,
+		ID:         after.ID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// GetChangelogByCampaignBefore gets the changelog entries for a campaign before the cursor, newest first.
+func (s *ChangelogService) GetChangelogByCampaignBefore(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	before TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaignBefore")
+	defer span.End()
+
+	changelogs, err := s.q.GetChangelogByCampaignBefore(ctx, db.GetChangelogByCampaignBeforeParams{
+		CampaignID: campaignID,
+		Ts:         before.Ts,
+		ID:         before.ID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(models)
+	return models, nil
+}
+
+// CountChangelogByCampaign returns the number of changelog entries for a campaign.
+func (s *ChangelogService) CountChangelogByCampaign(
+	ctx context.Context,
+	campaignID uuid.UUID,
+) (int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CountChangelogByCampaign")
+	defer span.End()
+
+	return s.q.CountChangelogByCampaign(ctx, campaignID)
+}
+
 // GetChangelogByCampaignDomain gets all changelog entries for a campaign and domain.
 func (s *ChangelogService) GetChangelogByCampaignDomain(
 	ctx context.Context,
@@ -205,6 +598,9 @@ func (s *ChangelogService) GetChangelogByCampaignDomain(
 	site string,
 	offset, limit int64,
 ) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaignDomain")
+	defer span.End()
+
 	// Get all changelog entries for site id
 	changelogs, err := s.q.GetChangelogByCampaignDomain(ctx, db.GetChangelogByCampaignDomainParams{
 		CampaignID: campaignID,
@@ -232,3 +628,181 @@ func (s *ChangelogService) GetChangelogByCampaignDomain(
 	}
 	return models, nil
 }
+
+// GetChangelogByCampaignDomainAfter gets the changelog entries for a campaign and domain after the cursor, newest first.
+func (s *ChangelogService) GetChangelogByCampaignDomainAfter(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	site string,
+	after TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaignDomainAfter")
+	defer span.End()
+
+	after = after.start()
+	changelogs, err := s.q.GetChangelogByCampaignDomainAfter(ctx, db.GetChangelogByCampaignDomainAfterParams{
+		CampaignID: campaignID,
+		Site:       site,
+		Ts:         after.Ts,
+		ID:         after.ID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	return models, nil
+}
+
+// GetChangelogByCampaignDomainBefore gets the changelog entries for a campaign and domain before the cursor, newest first.
+func (s *ChangelogService) GetChangelogByCampaignDomainBefore(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	site string,
+	before TimeCursor,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.GetChangelogByCampaignDomainBefore")
+	defer span.End()
+
+	changelogs, err := s.q.GetChangelogByCampaignDomainBefore(ctx, db.GetChangelogByCampaignDomainBeforeParams{
+		CampaignID: campaignID,
+		Site:       site,
+		Ts:         before.Ts,
+		ID:         before.ID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:         changelog.ID,
+			Ts:         changelog.Ts,
+			DomainID:   changelog.DomainID,
+			CampaignID: changelog.CampaignID,
+			Site:       changelog.Site,
+			Message:    changelog.Message,
+			IPv6Status: changelog.Ipv6Status,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(models)
+	return models, nil
+}
+
+// CountChangelogByCampaignDomain returns the number of changelog entries for a campaign and domain.
+func (s *ChangelogService) CountChangelogByCampaignDomain(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	site string,
+) (int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.CountChangelogByCampaignDomain")
+	defer span.End()
+
+	return s.q.CountChangelogByCampaignDomain(ctx, db.CountChangelogByCampaignDomainParams{
+		CampaignID: campaignID,
+		Site:       site,
+	})
+}
+
+// ListChangelogSince lists the changelog entries written after the given id,
+// except the skipped ids, oldest first, with the country of each domain.
+func (s *ChangelogService) ListChangelogSince(
+	ctx context.Context,
+	afterID int64,
+	skip []int64,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListChangelogSince")
+	defer span.End()
+
+	if skip == nil {
+		skip = []int64{} // NULL would skip every entry
+	}
+	changelogs, err := s.q.ListChangelogSince(ctx, db.ListChangelogSinceParams{
+		ID:    afterID,
+		Skip:  skip,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:          changelog.ID,
+			Ts:          changelog.Ts,
+			DomainID:    changelog.DomainID,
+			Site:        changelog.Site,
+			Message:     changelog.Message,
+			IPv6Status:  changelog.Ipv6Status,
+			CountryCode: StringNull(changelog.CountryCode),
+		})
+	}
+	return models, nil
+}
+
+// ListCampaignChangelogSince lists the campaign changelog entries written after
+// the given id, except the skipped ids, oldest first, with the country of each domain.
+func (s *ChangelogService) ListCampaignChangelogSince(
+	ctx context.Context,
+	afterID int64,
+	skip []int64,
+	limit int64,
+) ([]ChangelogModel, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.ListCampaignChangelogSince")
+	defer span.End()
+
+	if skip == nil {
+		skip = []int64{} // NULL would skip every entry
+	}
+	changelogs, err := s.q.ListCampaignChangelogSince(ctx, db.ListCampaignChangelogSinceParams{
+		ID:    afterID,
+		Skip:  skip,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var models []ChangelogModel
+	for _, changelog := range changelogs {
+		models = append(models, ChangelogModel{
+			ID:          changelog.ID,
+			Ts:          changelog.Ts,
+			DomainID:    changelog.DomainID,
+			CampaignID:  changelog.CampaignID,
+			Site:        changelog.Site,
+			Message:     changelog.Message,
+			IPv6Status:  changelog.Ipv6Status,
+			CountryCode: StringNull(changelog.CountryCode),
+		})
+	}
+	return models, nil
+}
+
+// LatestChangelogIDs returns the ids of the newest changelog and campaign
+// changelog entries, or zero if a changelog is empty.
+func (s *ChangelogService) LatestChangelogIDs(ctx context.Context) (int64, int64, error) {
+	ctx, span := tracer.Start(ctx, "ChangelogService.LatestChangelogIDs")
+	defer span.End()
+
+	ids, err := s.q.LatestChangelogIDs(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ids.ChangelogID, ids.CampaignChangelogID, nil
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+)
+
+// CheckResultService is a service for the check results, the domains and
+// campaign domains of a site all show the result of the site.
+type CheckResultService struct {
+	q *db.Queries
+}
+
+// NewCheckResultService creates a new CheckResultService instance.
+func NewCheckResultService(d db.DBTX) *CheckResultService {
+	return &CheckResultService{
+		q: db.New(d),
+	}
+}
+
+// CheckResultModel is the result of checking a single hostname, shared by the
+// domain and campaign crawlers.
+type CheckResultModel struct {
+	Site         string
+	BaseDomain   string
+	WwwDomain    string
+	Nameserver   string
+	MXRecord     string
+	V6Only       string
+	AsnID        int64
+	CountryID    int64
+	TsBaseDomain time.Time
+	TsWwwDomain  time.Time
+	TsNameserver time.Time
+	TsMXRecord   time.Time
+	TsV6Only     time.Time
+	TsCheck      time.Time
+	TsUpdated    time.Time
+}
+
+// GetCheckResult retrieves the result for a site if it was checked after the given time.
+// Returns pgx.ErrNoRows if there is no result, or it is older.
+func (s *CheckResultService) GetCheckResult(
+	ctx context.Context,
+	site string,
+	since time.Time,
+) (CheckResultModel, error) {
+	ctx, span := tracer.Start(ctx, "CheckResultService.GetCheckResult")
+	defer span.End()
+
+	r, err := s.q.GetCheckResult(ctx, db.GetCheckResultParams{
+		Site:    site,
+		TsCheck: NullTime(since),
+	})
+	if err != nil {
+		return CheckResultModel{}, err
+	}
+	return newCheckResultModel(r), nil
+}
+
+// LockCheckResult retrieves the result for a site and locks it until the
+// transaction ends. Returns pgx.ErrNoRows if the site is not tracked.
+func (s *CheckResultService) LockCheckResult(ctx context.Context, site string) (CheckResultModel, error) {
+	ctx, span := tracer.Start(ctx, "CheckResultService.LockCheckResult")
+	defer span.End()
+
+	r, err := s.q.LockCheckResult(ctx, site)
+	if err != nil {
+		return CheckResultModel{}, err
+	}
+	return newCheckResultModel(r), nil
+}
+
+// StoreCheckResult stores the result for a site, replacing any previous result.
+func (s *CheckResultService) StoreCheckResult(ctx context.Context, result CheckResultModel) error {
+	ctx, span := tracer.Start(ctx, "CheckResultService.StoreCheckResult")
+	defer span.End()
+
+	return s.q.StoreCheckResult(ctx, db.StoreCheckResultParams{
+		Site:         result.Site,
+		BaseDomain:   result.BaseDomain,
+		WwwDomain:    result.WwwDomain,
+		Nameserver:   result.Nameserver,
+		MxRecord:     result.MXRecord,
+		V6Only:       result.V6Only,
+		AsnID:        optionalInt(result.AsnID),
+		CountryID:    optionalInt(result.CountryID),
+		TsBaseDomain: NullTime(result.TsBaseDomain),
+		TsWwwDomain:  NullTime(result.TsWwwDomain),
+		TsNameserver: NullTime(result.TsNameserver),
+		TsMxRecord:   NullTime(result.TsMXRecord),
+		TsV6Only:     NullTime(result.TsV6Only),
+		TsCheck:      NullTime(result.TsCheck),
+		TsUpdated:    NullTime(result.TsUpdated),
+	})
+}
+
+// ListDomainIDs lists the ids of the enabled domains of a site.
+func (s *CheckResultService) ListDomainIDs(ctx context.Context, site string) ([]int64, error) {
+	ctx, span := tracer.Start(ctx, "CheckResultService.ListDomainIDs")
+	defer span.End()
+
+	return s.q.ListCheckResultDomains(ctx, site)
+}
+
+// ListCampaignDomains lists the enabled campaign domains of a site, with only
+// their id and campaign set.
+func (s *CheckResultService) ListCampaignDomains(ctx context.Context, site string) ([]CampaignDomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CheckResultService.ListCampaignDomains")
+	defer span.End()
+
+	domains, err := s.q.ListCheckResultCampaignDomains(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+	var list []CampaignDomainModel
+	for _, d := range domains {
+		list = append(list, CampaignDomainModel{
+			ID:         d.ID,
+			Site:       site,
+			CampaignID: d.CampaignID,
+		})
+	}
+	return list, nil
+}
+
+// newCheckResultModel maps a check result row to a CheckResultModel.
+func newCheckResultModel(r db.CheckResult) CheckResultModel {
+	return CheckResultModel{
+		Site:         r.Site,
+		BaseDomain:   r.BaseDomain,
+		WwwDomain:    r.WwwDomain,
+		Nameserver:   r.Nameserver,
+		MXRecord:     r.MxRecord,
+		V6Only:       r.V6Only,
+		AsnID:        IntNull(r.AsnID),
+		CountryID:    IntNull(r.CountryID),
+		TsBaseDomain: TimeNull(r.TsBaseDomain),
+		TsWwwDomain:  TimeNull(r.TsWwwDomain),
+		TsNameserver: TimeNull(r.TsNameserver),
+		TsMXRecord:   TimeNull(r.TsMxRecord),
+		TsV6Only:     TimeNull(r.TsV6Only),
+		TsCheck:      TimeNull(r.TsCheck),
+		TsUpdated:    TimeNull(r.TsUpdated),
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"slices"
 	"strings"
 
 	"whynoipv6/internal/postgres/db"
@@ -34,6 +35,9 @@ type CountryModel struct {
 
 // GetCountryCode gets a country by CountryCode.
 func (s *CountryService) GetCountryCode(ctx context.Context, code string) (CountryModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.GetCountryCode")
+	defer span.End()
+
 	country, err := s.q.GetCountry(ctx, code)
 	if err != nil {
 		return CountryModel{}, err
@@ -49,8 +53,35 @@ func (s *CountryService) GetCountryCode(ctx context.Context, code string) (Count
 	}, nil
 }
 
+// GetCountriesByID gets the countries with the given IDs, in no particular order.
+func (s *CountryService) GetCountriesByID(ctx context.Context, ids []int64) ([]CountryModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.GetCountriesByID")
+	defer span.End()
+
+	countries, err := s.q.ListCountriesByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var models []CountryModel
+	for _, country := range countries {
+		models = append(models, CountryModel{
+			ID:          country.ID,
+			Country:     country.CountryName,
+			CountryCode: country.CountryCode,
+			CountryTld:  country.CountryTld,
+			Sites:       country.Sites,
+			V6sites:     country.V6sites,
+			Percent:     country.Percent,
+		})
+	}
+	return models, nil
+}
+
 // GetCountryTld gets a country by CountryTLD.
 func (s *CountryService) GetCountryTld(ctx context.Context, tld string) (CountryModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.GetCountryTld")
+	defer span.End()
+
 	country, err := s.q.GetCountryTld(ctx, strings.ToUpper(tld))
 	if err != nil {
 		return CountryModel{}, err
@@ -68,6 +99,9 @@ func (s *CountryService) GetCountryTld(ctx context.Context, tld string) (Country
 
 // List all countries.
 func (s *CountryService) List(ctx context.Context) ([]CountryModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.List")
+	defer span.End()
+
 	countries, err := s.q.ListCountry(ctx)
 	if err != nil {
 		return nil, err
@@ -93,6 +127,9 @@ func (s *CountryService) ListDomainsByCountry(
 	countryID int64,
 	offset, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainsByCountry")
+	defer span.End()
+
 	domains, err := s.q.ListDomainsByCountry(ctx, db.ListDomainsByCountryParams{
 		CountryID: NullInt(countryID),
 		Offset:    offset,
@@ -126,12 +163,109 @@ func (s *CountryService) ListDomainsByCountry(
 	return list, nil
 }
 
+// ListDomainsByCountryAfter lists the domains without IPv6 support in a country after the given domain ID, ordered by ID.
+func (s *CountryService) ListDomainsByCountryAfter(
+	ctx context.Context,
+	countryID int64,
+	afterID, limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainsByCountryAfter")
+	defer span.End()
+
+	domains, err := s.q.ListDomainsByCountryAfter(ctx, db.ListDomainsByCountryAfterParams{
+		CountryID: NullInt(countryID),
+		ID:        NullInt(afterID),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	return list, nil
+}
+
+// ListDomainsByCountryBefore lists the domains without IPv6 support in a country before the given domain ID, ordered by ID.
+func (s *CountryService) ListDomainsByCountryBefore(
+	ctx context.Context,
+	countryID int64,
+	beforeID, limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainsByCountryBefore")
+	defer span.End()
+
+	domains, err := s.q.ListDomainsByCountryBefore(ctx, db.ListDomainsByCountryBeforeParams{
+		CountryID: NullInt(countryID),
+		ID:        NullInt(beforeID),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(list)
+	return list, nil
+}
+
+// CountDomainsByCountry returns the number of domains without IPv6 support in a country.
+func (s *CountryService) CountDomainsByCountry(ctx context.Context, countryID int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.CountDomainsByCountry")
+	defer span.End()
+
+	return s.q.CountDomainsByCountry(ctx, NullInt(countryID))
+}
+
 // ListDomainHeroesByCountry gets a list of all country TLDs.
 func (s *CountryService) ListDomainHeroesByCountry(
 	ctx context.Context,
 	countryID int64,
 	offset, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainHeroesByCountry")
+	defer span.End()
+
 	domains, err := s.q.ListDomainHeroesByCountry(ctx, db.ListDomainHeroesByCountryParams{
 		CountryID: NullInt(countryID),
 		Offset:    offset,
@@ -165,7 +299,108 @@ func (s *CountryService) ListDomainHeroesByCountry(
 	return list, nil
 }
 
+// ListDomainHeroesByCountryAfter lists the domains with IPv6 support in a country after the cursor, ordered by rank.
+func (s *CountryService) ListDomainHeroesByCountryAfter(
+	ctx context.Context,
+	countryID int64,
+	after RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainHeroesByCountryAfter")
+	defer span.End()
+
+	domains, err := s.q.ListDomainHeroesByCountryAfter(ctx, db.ListDomainHeroesByCountryAfterParams{
+		CountryID: NullInt(countryID),
+		Rank:      after.Rank,
+		ID:        NullInt(after.ID),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	return list, nil
+}
+
+// ListDomainHeroesByCountryBefore lists the domains with IPv6 support in a country before the cursor, ordered by rank.
+func (s *CountryService) ListDomainHeroesByCountryBefore(
+	ctx context.Context,
+	countryID int64,
+	before RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.ListDomainHeroesByCountryBefore")
+	defer span.End()
+
+	domains, err := s.q.ListDomainHeroesByCountryBefore(ctx, db.ListDomainHeroesByCountryBeforeParams{
+		CountryID: NullInt(countryID),
+		Rank:      before.Rank,
+		ID:        NullInt(before.ID),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(list)
+	return list, nil
+}
+
+// CountDomainHeroesByCountry returns the number of domains with IPv6 support in a country.
+func (s *CountryService) CountDomainHeroesByCountry(ctx context.Context, countryID int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.CountDomainHeroesByCountry")
+	defer span.End()
+
+	return s.q.CountDomainHeroesByCountry(ctx, NullInt(countryID))
+}
+
 // CalculateCountryStats calculates the statistics for a country.
 func (s *CountryService) CalculateCountryStats(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "CountryService.CalculateCountryStats")
+	defer span.End()
+
 	return s.q.CalculateCountryStats(ctx)
 }
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// RankCursor is a position in a domain list ordered by rank. The domain ID
+// breaks ties between domains with the same rank. The zero value is the start
+// of the list.
+type RankCursor struct {
+	Rank int64
+	ID   int64
+}
+
+// TimeCursor is a position in a changelog ordered by time, newest first. The
+// entry ID breaks ties between entries with the same timestamp. The zero value
+// is the start of the list.
+type TimeCursor struct {
+	Ts time.Time
+	ID int64
+}
+
+// start returns the position before the newest entry if the cursor is the zero value.
+func (c TimeCursor) start() TimeCursor {
+	if c.Ts.IsZero() {
+		return TimeCursor{Ts: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), ID: math.MaxInt64}
+	}
+	return c
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimeCursorStart(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name   string
+		cursor TimeCursor
+		want   TimeCursor
+	}{
+		{"zero is before the newest entry", TimeCursor{}, TimeCursor{Ts: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), ID: math.MaxInt64}},
+		{"a cursor is kept", TimeCursor{Ts: ts, ID: 3}, TimeCursor{Ts: ts, ID: 3}},
+		{"an ID without a time is the start", TimeCursor{ID: 3}, TimeCursor{Ts: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), ID: math.MaxInt64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cursor.start(); !got.Ts.Equal(tt.want.Ts) || got.ID != tt.want.ID {
+				t.Errorf("start() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"time"
 
 	"whynoipv6/internal/postgres/db"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
 )
 
@@ -59,6 +61,9 @@ type InsertScanModel struct {
 
 // InsertDomain creates a new scan.
 func (s *DomainService) InsertDomain(ctx context.Context, site string) error {
+	ctx, span := tracer.Start(ctx, "DomainService.InsertDomain")
+	defer span.End()
+
 	err := s.q.InsertDomain(ctx, site)
 	if err != nil {
 		return err
@@ -71,6 +76,9 @@ func (s *DomainService) ListDomain(
 	ctx context.Context,
 	offset, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomain")
+	defer span.End()
+
 	domains, err := s.q.ListDomain(ctx, db.ListDomainParams{
 		Offset: offset,
 		Limit:  limit,
@@ -103,11 +111,108 @@ func (s *DomainService) ListDomain(
 	return list, nil
 }
 
+// ListDomainAfter lists the domains without IPv6 support after the cursor, ordered by rank.
+func (s *DomainService) ListDomainAfter(
+	ctx context.Context,
+	after RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainAfter")
+	defer span.End()
+
+	domains, err := s.q.ListDomainAfter(ctx, db.ListDomainAfterParams{
+		Rank:  after.Rank,
+		ID:    NullInt(after.ID),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	return list, nil
+}
+
+// ListDomainBefore lists the domains without IPv6 support before the cursor, ordered by rank.
+func (s *DomainService) ListDomainBefore(
+	ctx context.Context,
+	before RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainBefore")
+	defer span.End()
+
+	domains, err := s.q.ListDomainBefore(ctx, db.ListDomainBeforeParams{
+		Rank:  before.Rank,
+		ID:    NullInt(before.ID),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(list)
+	return list, nil
+}
+
+// CountDomain returns the number of domains without IPv6 support.
+func (s *DomainService) CountDomain(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CountDomain")
+	defer span.End()
+
+	return s.q.CountDomain(ctx)
+}
+
 // ListDomainHeroes lists all domains.
 func (s *DomainService) ListDomainHeroes(
 	ctx context.Context,
 	offset, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainHeroes")
+	defer span.End()
+
 	domains, err := s.q.ListDomainHeroes(ctx, db.ListDomainHeroesParams{
 		Offset: offset,
 		Limit:  limit,
@@ -140,11 +245,108 @@ func (s *DomainService) ListDomainHeroes(
 	return list, nil
 }
 
+// ListDomainHeroesAfter lists the domains with IPv6 support after the cursor, ordered by rank.
+func (s *DomainService) ListDomainHeroesAfter(
+	ctx context.Context,
+	after RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainHeroesAfter")
+	defer span.End()
+
+	domains, err := s.q.ListDomainHeroesAfter(ctx, db.ListDomainHeroesAfterParams{
+		Rank:  after.Rank,
+		ID:    NullInt(after.ID),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	return list, nil
+}
+
+// ListDomainHeroesBefore lists the domains with IPv6 support before the cursor, ordered by rank.
+func (s *DomainService) ListDomainHeroesBefore(
+	ctx context.Context,
+	before RankCursor,
+	limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainHeroesBefore")
+	defer span.End()
+
+	domains, err := s.q.ListDomainHeroesBefore(ctx, db.ListDomainHeroesBeforeParams{
+		Rank:  before.Rank,
+		ID:    NullInt(before.ID),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	// The rows are read nearest first, return them in the order of the list.
+	slices.Reverse(list)
+	return list, nil
+}
+
+// CountDomainHeroes returns the number of domains with IPv6 support.
+func (s *DomainService) CountDomainHeroes(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CountDomainHeroes")
+	defer span.End()
+
+	return s.q.CountDomainHeroes(ctx)
+}
+
 // CrawlDomain lists all domains available for crawling
 func (s *DomainService) CrawlDomain(
 	ctx context.Context,
 	lastProcessedID, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CrawlDomain")
+	defer span.End()
+
 	domains, err := s.q.CrawlDomain(ctx, db.CrawlDomainParams{
 		ID:    lastProcessedID,
 		Limit: limit,
@@ -174,33 +376,40 @@ func (s *DomainService) CrawlDomain(
 	return list, nil
 }
 
-// UpdateDomain updates a domain.
-func (s *DomainService) UpdateDomain(ctx context.Context, domain DomainModel) error {
-	err := s.q.UpdateDomain(ctx, db.UpdateDomainParams{
-		Site:         domain.Site,
-		BaseDomain:   domain.BaseDomain,
-		WwwDomain:    domain.WwwDomain,
-		Nameserver:   domain.Nameserver,
-		MxRecord:     domain.MXRecord,
-		V6Only:       domain.V6Only,
-		AsnID:        NullInt(domain.AsnID),
-		CountryID:    NullInt(domain.CountryID),
-		TsBaseDomain: NullTime(domain.TsBaseDomain),
-		TsWwwDomain:  NullTime(domain.TsWwwDomain),
-		TsNameserver: NullTime(domain.TsNameserver),
-		TsMxRecord:   NullTime(domain.TsMXRecord),
-		TsV6Only:     NullTime(domain.TsV6Only),
-		TsCheck:      NullTime(domain.TsCheck),
-		TsUpdated:    NullTime(domain.TsUpdated),
-	})
+// GetDomain retrieves a domain with the result of its site by its name, including disabled domains.
+func (s *DomainService) GetDomain(ctx context.Context, site string) (DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.GetDomain")
+	defer span.End()
+
+	d, err := s.q.GetDomain(ctx, site)
 	if err != nil {
-		return err
+		return DomainModel{}, err
 	}
-	return nil
+	return DomainModel{
+		ID:           d.ID,
+		Site:         d.Site,
+		BaseDomain:   d.BaseDomain,
+		WwwDomain:    d.WwwDomain,
+		Nameserver:   d.Nameserver,
+		MXRecord:     d.MxRecord,
+		V6Only:       d.V6Only,
+		AsnID:        IntNull(d.AsnID),
+		CountryID:    IntNull(d.CountryID),
+		TsBaseDomain: TimeNull(d.TsBaseDomain),
+		TsWwwDomain:  TimeNull(d.TsWwwDomain),
+		TsNameserver: TimeNull(d.TsNameserver),
+		TsMXRecord:   TimeNull(d.TsMxRecord),
+		TsV6Only:     TimeNull(d.TsV6Only),
+		TsCheck:      TimeNull(d.TsCheck),
+		TsUpdated:    TimeNull(d.TsUpdated),
+	}, nil
 }
 
 // ViewDomain list a domain.
 func (s *DomainService) ViewDomain(ctx context.Context, domain string) (DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ViewDomain")
+	defer span.End()
+
 	d, err := s.q.ViewDomain(ctx, NullString(domain))
 	if err != nil {
 		return DomainModel{}, err
@@ -228,6 +437,9 @@ func (s *DomainService) ViewDomain(ctx context.Context, domain string) (DomainMo
 
 // DisableDomain disables a domain.
 func (s *DomainService) DisableDomain(ctx context.Context, domain string) error {
+	ctx, span := tracer.Start(ctx, "DomainService.DisableDomain")
+	defer span.End()
+
 	err := s.q.DisableDomain(ctx, domain)
 	if err != nil {
 		return err
@@ -235,12 +447,57 @@ func (s *DomainService) DisableDomain(ctx context.Context, domain string) error
 	return nil
 }
 
+// CreateDomain adds a domain, which is checked by the next crawl.
+// Returns pgx.ErrNoRows if the domain already exists.
+func (s *DomainService) CreateDomain(ctx context.Context, site string) (DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CreateDomain")
+	defer span.End()
+
+	d, err := s.q.CreateDomain(ctx, site)
+	if err != nil {
+		return DomainModel{}, err
+	}
+	return DomainModel{
+		ID:   d.ID,
+		Site: d.Site,
+	}, nil
+}
+
+// SetDomainDisabled disables a domain with the reason, or enables it again.
+// Returns pgx.ErrNoRows if the domain does not exist.
+func (s *DomainService) SetDomainDisabled(ctx context.Context, site string, disabled bool, reason string) error {
+	ctx, span := tracer.Start(ctx, "DomainService.SetDomainDisabled")
+	defer span.End()
+
+	_, err := s.q.SetDomainDisabled(ctx, db.SetDomainDisabledParams{
+		Site:     site,
+		Disabled: disabled,
+		Reason:   optionalString(reason),
+	})
+	return err
+}
+
+// DeleteDomain deletes a domain with its changelog, logs and recheck jobs.
+// Run it in a transaction, so the domain is not left without its changelog.
+func (s *DomainService) DeleteDomain(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "DomainService.DeleteDomain")
+	defer span.End()
+
+	if err := s.q.DeleteDomainChangelog(ctx, id); err != nil {
+		return err
+	}
+	return s.q.DeleteDomain(ctx, id)
+}
+
 // GetDomainsByName returns a list of domains by name.
 func (s *DomainService) GetDomainsByName(
 	ctx context.Context,
 	searchString string,
 	offset, limit int64,
 ) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.GetDomainsByName")
+	defer span.End()
+
 	domains, err := s.q.GetDomainsByName(ctx, db.GetDomainsByNameParams{
 		Column1: NullString(searchString),
 		Offset:  offset,
@@ -275,6 +532,14 @@ func (s *DomainService) GetDomainsByName(
 	return list, nil
 }
 
+// CountDomainsByName returns the number of domains matching a search string.
+func (s *DomainService) CountDomainsByName(ctx context.Context, searchString string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CountDomainsByName")
+	defer span.End()
+
+	return s.q.CountDomainsByName(ctx, NullString(searchString))
+}
+
 // GetCampaignDomainsByName returns a list of domains from a campaign by name.
 // func (s *DomainService) GetCampaignDomainsByName(ctx context.Context, searchString string, offset, limit int32) ([]CampaignDomainModel, error) {
 // 	domains, err := s.q.GetCampaignDomainsByName(ctx, db.GetCampaignDomainsByNameParams{
@@ -303,6 +568,9 @@ func (s *DomainService) GetDomainsByName(
 
 // ListDomainShamers lists 10-ish domains without IPv6 support.
 func (s *DomainService) ListDomainShamers(ctx context.Context) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainShamers")
+	defer span.End()
+
 	domains, err := s.q.ListDomainShamers(ctx)
 	if err != nil {
 		return nil, err
@@ -330,6 +598,9 @@ func (s *DomainService) ListDomainShamers(ctx context.Context) ([]DomainModel, e
 // InitSpaceTimestamps spaces out the timestamps for all domains.
 // Is used to prevent all domains from being crawled at the same time.
 func (s *DomainService) InitSpaceTimestamps(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "DomainService.InitSpaceTimestamps")
+	defer span.End()
+
 	err := s.q.InitSpaceTimestamps(ctx)
 	if err != nil {
 		return err
@@ -351,6 +622,9 @@ type CrawlerStat struct {
 
 // CrawlerStats retrieves the statistics for all crawled domains.
 func (s *DomainService) CrawlerStats(ctx context.Context) (CrawlerStat, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CrawlerStats")
+	defer span.End()
+
 	stats, err := s.q.CrawlerStats(ctx)
 	if err != nil {
 		return CrawlerStat{}, err
@@ -376,6 +650,9 @@ type DomainLog struct {
 
 // StoreDomainLog saves a crawl log for a domain.
 func (s *DomainService) StoreDomainLog(ctx context.Context, domain int64, data any) error {
+	ctx, span := tracer.Start(ctx, "DomainService.StoreDomainLog")
+	defer span.End()
+
 	// Encode the data to a []byte
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
@@ -398,6 +675,9 @@ func (s *DomainService) StoreDomainLog(ctx context.Context, domain int64, data a
 
 // GetDomainLog retrieves all the logs for a specified domain.
 func (s *DomainService) GetDomainLog(ctx context.Context, domain string) ([]DomainLog, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.GetDomainLog")
+	defer span.End()
+
 	// Get the domain ID from the database
 	d, err := s.q.ViewDomain(ctx, NullString(domain))
 	if err != nil {
@@ -419,3 +699,107 @@ func (s *DomainService) GetDomainLog(ctx context.Context, domain string) ([]Doma
 	}
 	return logList, nil
 }
+
+// DomainFilter selects the domains of a filtered domain list. Zero values
+// match every domain.
+type DomainFilter struct {
+	BaseDomain    string    // Status of the AAAA check, e.g. supported
+	WwwDomain     string    // Status of the www AAAA check
+	Nameserver    string    // Status of the NS check
+	MXRecord      string    // Status of the MX check
+	V6Only        string    // Status of the IPv6-only check
+	CountryCode   string    // ISO 3166-1 alpha-2 country code, e.g. NO
+	Continent     string    // Continent name, e.g. Europe
+	ASN           int32     // AS number
+	RankMin       int64     // Lowest rank, inclusive
+	RankMax       int64     // Highest rank, inclusive
+	UpdatedAfter  time.Time // Last changed at or after
+	UpdatedBefore time.Time // Last changed before
+	CampaignID    uuid.UUID // Only domains that are part of this campaign
+	Sort          string    // One of DomainSortOrders, rank if empty
+}
+
+// DomainSortOrders are the sort orders of a filtered domain list. A leading
+// - sorts in descending order.
+var DomainSortOrders = []string{"rank", "-rank", "site", "-site", "ts_updated", "-ts_updated"}
+
+// ListDomainFiltered lists the domains matching the filter.
+func (s *DomainService) ListDomainFiltered(
+	ctx context.Context,
+	filter DomainFilter,
+	offset, limit int64,
+) ([]DomainModel, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.ListDomainFiltered")
+	defer span.End()
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = "rank"
+	}
+	domains, err := s.q.FilterDomains(ctx, db.FilterDomainsParams{
+		BaseDomain:    optionalString(filter.BaseDomain),
+		WwwDomain:     optionalString(filter.WwwDomain),
+		Nameserver:    optionalString(filter.Nameserver),
+		MxRecord:      optionalString(filter.MXRecord),
+		V6Only:        optionalString(filter.V6Only),
+		CountryCode:   optionalString(filter.CountryCode),
+		Continent:     optionalString(filter.Continent),
+		Asn:           optionalInt32(filter.ASN),
+		RankMin:       optionalInt(filter.RankMin),
+		RankMax:       optionalInt(filter.RankMax),
+		UpdatedAfter:  NullTime(filter.UpdatedAfter),
+		UpdatedBefore: NullTime(filter.UpdatedBefore),
+		CampaignID:    optionalUUID(filter.CampaignID),
+		Sort:          sort,
+		Offset:        offset,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []DomainModel
+	for _, d := range domains {
+		list = append(list, DomainModel{
+			ID:           IntNull(d.ID),
+			Site:         StringNull(d.Site),
+			BaseDomain:   StringNull(d.BaseDomain),
+			WwwDomain:    StringNull(d.WwwDomain),
+			Nameserver:   StringNull(d.Nameserver),
+			MXRecord:     StringNull(d.MxRecord),
+			V6Only:       StringNull(d.V6Only),
+			AsName:       StringNull(d.Asname),
+			Country:      StringNull(d.CountryName),
+			TsBaseDomain: TimeNull(d.TsBaseDomain),
+			TsWwwDomain:  TimeNull(d.TsWwwDomain),
+			TsNameserver: TimeNull(d.TsNameserver),
+			TsMXRecord:   TimeNull(d.TsMxRecord),
+			TsV6Only:     TimeNull(d.TsV6Only),
+			TsCheck:      TimeNull(d.TsCheck),
+			TsUpdated:    TimeNull(d.TsUpdated),
+			Rank:         d.Rank,
+		})
+	}
+	return list, nil
+}
+
+// CountDomainFiltered returns the number of domains matching the filter.
+func (s *DomainService) CountDomainFiltered(ctx context.Context, filter DomainFilter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.CountDomainFiltered")
+	defer span.End()
+
+	return s.q.CountFilteredDomains(ctx, db.CountFilteredDomainsParams{
+		BaseDomain:    optionalString(filter.BaseDomain),
+		WwwDomain:     optionalString(filter.WwwDomain),
+		Nameserver:    optionalString(filter.Nameserver),
+		MxRecord:      optionalString(filter.MXRecord),
+		V6Only:        optionalString(filter.V6Only),
+		CountryCode:   optionalString(filter.CountryCode),
+		Continent:     optionalString(filter.Continent),
+		Asn:           optionalInt32(filter.ASN),
+		RankMin:       optionalInt(filter.RankMin),
+		RankMax:       optionalInt(filter.RankMax),
+		UpdatedAfter:  NullTime(filter.UpdatedAfter),
+		UpdatedBefore: NullTime(filter.UpdatedBefore),
+		CampaignID:    optionalUUID(filter.CampaignID),
+	})
+}
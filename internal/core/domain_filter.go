@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CheckStatuses are the statuses a check filter accepts.
+var CheckStatuses = []string{IPv6Available, IPv4Only, NoRecordsFound}
+
+// Continents are the continents a continent filter accepts.
+var Continents = []string{"Africa", "Antarctica", "Asia", "Europe", "Oceania", "North America", "South America"}
+
+// DomainFilterParams is a domain filter as given by a client, before it is
+// validated. The campaign is left out, the API and the CLI write its UUID
+// differently.
+type DomainFilterParams struct {
+	BaseDomain    string // Check status
+	WwwDomain     string // Check status
+	Nameserver    string // Check status
+	MXRecord      string // Check status
+	V6Only        string // Check status
+	Country       string // Two-letter country code, in any case
+	Continent     string // Continent name, in any case
+	ASN           int32
+	RankMin       int64
+	RankMax       int64
+	UpdatedAfter  string // Date or RFC 3339 timestamp
+	UpdatedBefore string // Date or RFC 3339 timestamp
+	Sort          string // One of DomainSortOrders
+}
+
+// ParseDomainFilter validates the parameters and converts them to a domain
+// filter. The errors name the parameters as the API does, e.g. rank_min.
+func ParseDomainFilter(params DomainFilterParams) (DomainFilter, error) {
+	filter := DomainFilter{
+		BaseDomain:  params.BaseDomain,
+		WwwDomain:   params.WwwDomain,
+		Nameserver:  params.Nameserver,
+		MXRecord:    params.MXRecord,
+		V6Only:      params.V6Only,
+		CountryCode: strings.ToUpper(params.Country),
+		ASN:         params.ASN,
+		RankMin:     params.RankMin,
+		RankMax:     params.RankMax,
+		Sort:        params.Sort,
+	}
+
+	for _, check := range []struct{ name, status string }{
+		{"base", filter.BaseDomain},
+		{"www", filter.WwwDomain},
+		{"ns", filter.Nameserver},
+		{"mx", filter.MXRecord},
+		{"v6_only", filter.V6Only},
+	} {
+		if check.status != "" && !slices.Contains(CheckStatuses, check.status) {
+			return filter, errors.New(check.name + " must be one of " + strings.Join(CheckStatuses, ", "))
+		}
+	}
+	if filter.CountryCode != "" && len(filter.CountryCode) != 2 {
+		return filter, errors.New("country must be a two-letter country code")
+	}
+	if params.Continent != "" {
+		i := slices.IndexFunc(Continents, func(c string) bool { return strings.EqualFold(c, params.Continent) })
+		if i < 0 {
+			return filter, errors.New("continent must be one of " + strings.Join(Continents, ", "))
+		}
+		filter.Continent = Continents[i]
+	}
+	if filter.ASN < 0 {
+		return filter, errors.New("asn must not be negative")
+	}
+	if filter.RankMin < 0 || filter.RankMax < 0 {
+		return filter, errors.New("rank_min and rank_max must not be negative")
+	}
+	if filter.RankMax > 0 && filter.RankMin > filter.RankMax {
+		return filter, errors.New("rank_min must not be greater than rank_max")
+	}
+
+	var err error
+	if filter.UpdatedAfter, err = ParseDate(params.UpdatedAfter); err != nil {
+		return filter, errors.New("updated_after must be a date or an RFC 3339 timestamp")
+	}
+	if filter.UpdatedBefore, err = ParseDate(params.UpdatedBefore); err != nil {
+		return filter, errors.New("updated_before must be a date or an RFC 3339 timestamp")
+	}
+	if filter.Sort != "" && !slices.Contains(DomainSortOrders, filter.Sort) {
+		return filter, errors.New("sort must be one of " + strings.Join(DomainSortOrders, ", "))
+	}
+	return filter, nil
+}
+
+// ParseDate parses a date (2006-01-02) or an RFC 3339 timestamp. An empty
+// string is the zero time.
+func ParseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDomainFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  DomainFilterParams
+		want    DomainFilter
+		wantErr string
+	}{
+		{"empty", DomainFilterParams{}, DomainFilter{}, ""},
+		{
+			"normalized",
+			DomainFilterParams{BaseDomain: IPv6Available, Country: "no", Continent: "europe", RankMax: 1000},
+			DomainFilter{BaseDomain: IPv6Available, CountryCode: "NO", Continent: "Europe", RankMax: 1000},
+			"",
+		},
+		{
+			"dates",
+			DomainFilterParams{UpdatedAfter: "2024-01-02", UpdatedBefore: "2024-02-03T04:05:06Z"},
+			DomainFilter{UpdatedAfter: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), UpdatedBefore: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)},
+			"",
+		},
+		{"misspelled status", DomainFilterParams{MXRecord: "suported"}, DomainFilter{}, "mx must be one of supported, unsupported, no_record"},
+		{"country name", DomainFilterParams{Country: "Norway"}, DomainFilter{}, "country must be a two-letter country code"},
+		{"unknown sort", DomainFilterParams{Sort: "asn"}, DomainFilter{}, "sort must be one of rank, -rank, site, -site, ts_updated, -ts_updated"},
+		{"invalid date", DomainFilterParams{UpdatedAfter: "02/01/2024"}, DomainFilter{}, "updated_after must be a date or an RFC 3339 timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDomainFilter(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseDomainFilter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDomainFilter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDomainFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
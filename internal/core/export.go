@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+)
+
+// exportBatchSize is the number of domains read from the database at a time
+// during an export.
+const exportBatchSize = 5000
+
+// DomainExportModel is a domain in a bulk export. Values that are not known,
+// e.g. the country of a domain that has not been crawled yet, are zero.
+type DomainExportModel struct {
+	ID           int64
+	Site         string
+	Rank         int64
+	BaseDomain   string
+	WwwDomain    string
+	Nameserver   string
+	MXRecord     string
+	V6Only       string
+	ASN          int32
+	ASName       string
+	CountryCode  string
+	Country      string
+	TsBaseDomain time.Time
+	TsWwwDomain  time.Time
+	TsNameserver time.Time
+	TsMXRecord   time.Time
+	TsV6Only     time.Time
+	TsCheck      time.Time
+	TsUpdated    time.Time
+}
+
+// ExportDomains calls fn with every domain matching the filter, ordered by id,
+// reading them from the database in batches. The sort order of the filter is
+// ignored. It stops at the first error, from the database or from fn.
+func (s *DomainService) ExportDomains(
+	ctx context.Context,
+	filter DomainFilter,
+	fn func(DomainExportModel) error,
+) error {
+	ctx, span := tracer.Start(ctx, "DomainService.ExportDomains")
+	defer span.End()
+
+	var afterID int64
+	for {
+		rows, err := s.q.ExportDomains(ctx, db.ExportDomainsParams{
+			AfterID:       afterID,
+			BaseDomain:    optionalString(filter.BaseDomain),
+			WwwDomain:     optionalString(filter.WwwDomain),
+			Nameserver:    optionalString(filter.Nameserver),
+			MxRecord:      optionalString(filter.MXRecord),
+			V6Only:        optionalString(filter.V6Only),
+			CountryCode:   optionalString(filter.CountryCode),
+			Continent:     optionalString(filter.Continent),
+			Asn:           optionalInt32(filter.ASN),
+			RankMin:       optionalInt(filter.RankMin),
+			RankMax:       optionalInt(filter.RankMax),
+			UpdatedAfter:  NullTime(filter.UpdatedAfter),
+			UpdatedBefore: NullTime(filter.UpdatedBefore),
+			CampaignID:    optionalUUID(filter.CampaignID),
+			Limit:         exportBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, d := range rows {
+			err := fn(DomainExportModel{
+				ID:           IntNull(d.ID),
+				Site:         StringNull(d.Site),
+				Rank:         d.Rank,
+				BaseDomain:   StringNull(d.BaseDomain),
+				WwwDomain:    StringNull(d.WwwDomain),
+				Nameserver:   StringNull(d.Nameserver),
+				MXRecord:     StringNull(d.MxRecord),
+				V6Only:       StringNull(d.V6Only),
+				ASN:          d.Asn.Int32,
+				ASName:       StringNull(d.Asname),
+				CountryCode:  StringNull(d.CountryCode),
+				Country:      StringNull(d.CountryName),
+				TsBaseDomain: TimeNull(d.TsBaseDomain),
+				TsWwwDomain:  TimeNull(d.TsWwwDomain),
+				TsNameserver: TimeNull(d.TsNameserver),
+				TsMXRecord:   TimeNull(d.TsMxRecord),
+				TsV6Only:     TimeNull(d.TsV6Only),
+				TsCheck:      TimeNull(d.TsCheck),
+				TsUpdated:    TimeNull(d.TsUpdated),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+		afterID = IntNull(rows[len(rows)-1].ID)
+	}
+}
@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"sort"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// Checks of a domain, the keys of the domain log data.
+const (
+	CheckBaseDomain = "base_domain"
+	CheckWwwDomain  = "www_domain"
+	CheckNameserver = "nameserver"
+	CheckMXRecord   = "mx_record"
+)
+
+// HistoryChecks are the checks a domain history follows, in order.
+var HistoryChecks = []string{CheckBaseDomain, CheckWwwDomain, CheckNameserver, CheckMXRecord}
+
+// Sizes of the buckets a domain history can be downsampled to.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// HistoryBuckets are the bucket sizes a domain history supports.
+var HistoryBuckets = []string{BucketDay, BucketWeek, BucketMonth}
+
+// historyPageSize is the number of log entries a history reads at a time.
+const historyPageSize = 10000
+
+// HistoryInterval is a period in which a check of a domain had the same status.
+type HistoryInterval struct {
+	Check  string    // One of HistoryChecks
+	Status string    // e.g. supported
+	From   time.Time // Inclusive
+	To     time.Time // Exclusive
+}
+
+// HistoryBucket is the uptime of a domain in a part of its history.
+type HistoryBucket struct {
+	From    time.Time
+	To      time.Time
+	Entries int                // Number of log entries in the bucket
+	Uptime  map[string]float64 // Percent of the time each check was supported
+}
+
+// DomainHistory is the IPv6 history of a domain, built from its log.
+type DomainHistory struct {
+	From      time.Time
+	To        time.Time
+	Entries   int                // Number of log entries in the period
+	Intervals []HistoryInterval  // Grouped by check, oldest first
+	Uptime    map[string]float64 // Percent of the time each check was supported
+	Buckets   []HistoryBucket    // Only set if the history is downsampled
+}
+
+// historyPoint is a log entry, the status of every check from the time it was logged.
+type historyPoint struct {
+	Time     time.Time
+	Statuses map[string]string
+}
+
+// GetDomainHistory builds the history of a domain from its log, between from
+// and to. The status at from is the status of the last entry before it. If
+// bucket is one of HistoryBuckets, the uptime is also computed per bucket.
+// A check that is missing from a log entry has no interval until it is logged again,
+// and does not count towards the uptime.
+func (s *DomainService) GetDomainHistory(
+	ctx context.Context,
+	domain string,
+	from, to time.Time,
+	bucket string,
+) (DomainHistory, error) {
+	ctx, span := tracer.Start(ctx, "DomainService.GetDomainHistory")
+	defer span.End()
+
+	d, err := s.q.ViewDomain(ctx, NullString(domain))
+	if err != nil {
+		return DomainHistory{}, err
+	}
+
+	var points []historyPoint
+	before, err := s.q.GetDomainLogBefore(ctx, db.GetDomainLogBeforeParams{
+		DomainID: IntNull(d.ID),
+		Time:     from,
+	})
+	switch {
+	case err == nil:
+		point, err := newHistoryPoint(from, before.Data)
+		if err != nil {
+			return DomainHistory{}, err
+		}
+		points = append(points, point)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return DomainHistory{}, err
+	}
+
+	// The log is read a page at a time. Only the entries that change a status
+	// are kept as points, and the time of every entry for the bucket counts.
+	var entries []time.Time
+	afterTime, afterID := from, int64(0)
+	for {
+		logs, err := s.q.ListDomainLogRange(ctx, db.ListDomainLogRangeParams{
+			DomainID:  IntNull(d.ID),
+			AfterTime: afterTime,
+			AfterID:   afterID,
+			EndTime:   to,
+			PageSize:  historyPageSize,
+		})
+		if err != nil {
+			return DomainHistory{}, err
+		}
+		for _, log := range logs {
+			point, err := newHistoryPoint(log.Time, log.Data)
+			if err != nil {
+				return DomainHistory{}, err
+			}
+			if len(points) == 0 || !maps.Equal(points[len(points)-1].Statuses, point.Statuses) {
+				points = append(points, point)
+			}
+			entries = append(entries, log.Time)
+		}
+		if len(logs) < historyPageSize {
+			break
+		}
+		afterTime, afterID = logs[len(logs)-1].Time, logs[len(logs)-1].ID
+	}
+
+	// The status after the newest entry is only known until now.
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	history := DomainHistory{
+		From:      from,
+		To:        to,
+		Entries:   len(entries),
+		Intervals: historyIntervals(points, to),
+	}
+	history.Uptime = historyUptime(history.Intervals, from, to)
+	if bucket != "" {
+		history.Buckets = historyBuckets(history.Intervals, entries, from, to, bucket)
+	}
+	return history, nil
+}
+
+// newHistoryPoint reads the check statuses of a log entry.
+func newHistoryPoint(ts time.Time, data pgtype.JSONB) (historyPoint, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(data.Bytes, &fields); err != nil {
+		return historyPoint{}, err
+	}
+	point := historyPoint{Time: ts, Statuses: make(map[string]string)}
+	for _, check := range HistoryChecks {
+		if status, ok := fields[check].(string); ok && status != "" {
+			point.Statuses[check] = status
+		}
+	}
+	return point, nil
+}
+
+// historyIntervals merges the points, oldest first, into the intervals in
+// which each check had the same status. The last interval of a check ends at end.
+func historyIntervals(points []historyPoint, end time.Time) []HistoryInterval {
+	var intervals []HistoryInterval
+	for _, check := range HistoryChecks {
+		var current *HistoryInterval
+		for _, point := range points {
+			status := point.Statuses[check]
+			if current != nil && current.Status == status {
+				continue
+			}
+			if current != nil {
+				current.To = point.Time
+				intervals = append(intervals, *current)
+				current = nil
+			}
+			if status != "" {
+				current = &HistoryInterval{Check: check, Status: status, From: point.Time}
+			}
+		}
+		if current != nil && current.From.Before(end) {
+			current.To = end
+			intervals = append(intervals, *current)
+		}
+	}
+	return intervals
+}
+
+// historyUptime returns the percent of the time between from and to each check
+// was supported, out of the time its status is known. Checks without a known
+// status are left out.
+func historyUptime(intervals []HistoryInterval, from, to time.Time) map[string]float64 {
+	var counter uptimeCounter
+	for _, interval := range intervals {
+		counter.add(interval, from, to)
+	}
+	return counter.uptime()
+}
+
+// historyBuckets splits the period between from and to into buckets, with the
+// number of entries and the uptime of each. The entries are oldest first, and
+// the intervals of each check are too, so every entry and interval is only
+// looked at for the buckets it falls in.
+func historyBuckets(intervals []HistoryInterval, entries []time.Time, from, to time.Time, bucket string) []HistoryBucket {
+	var buckets []HistoryBucket
+	for start := bucketStart(from, bucket); start.Before(to); start = nextBucket(start, bucket) {
+		buckets = append(buckets, HistoryBucket{From: start, To: nextBucket(start, bucket)})
+	}
+
+	b := 0
+	for _, entry := range entries {
+		for b < len(buckets) && !entry.Before(buckets[b].To) {
+			b++
+		}
+		if b == len(buckets) {
+			break
+		}
+		if !entry.Before(buckets[b].From) {
+			buckets[b].Entries++
+		}
+	}
+
+	counters := make([]uptimeCounter, len(buckets))
+	for _, interval := range intervals {
+		// The first bucket that ends after the interval starts.
+		first := sort.Search(len(buckets), func(i int) bool { return buckets[i].To.After(interval.From) })
+		for i := first; i < len(buckets) && buckets[i].From.Before(interval.To); i++ {
+			counters[i].add(interval, buckets[i].From, buckets[i].To)
+		}
+	}
+	for i := range buckets {
+		buckets[i].Uptime = counters[i].uptime()
+	}
+	return buckets
+}
+
+// uptimeCounter sums the time the status of each check was known, and the
+// time it was supported.
+type uptimeCounter struct {
+	known     map[string]time.Duration
+	supported map[string]time.Duration
+}
+
+// add counts the part of an interval between from and to.
+func (c *uptimeCounter) add(interval HistoryInterval, from, to time.Time) {
+	start, end := interval.From, interval.To
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return
+	}
+	if c.known == nil {
+		c.known = make(map[string]time.Duration)
+		c.supported = make(map[string]time.Duration)
+	}
+	c.known[interval.Check] += end.Sub(start)
+	if interval.Status == IPv6Available {
+		c.supported[interval.Check] += end.Sub(start)
+	}
+}
+
+// uptime returns the percent of the known time each check was supported.
+func (c *uptimeCounter) uptime() map[string]float64 {
+	uptime := make(map[string]float64)
+	for check, total := range c.known {
+		uptime[check] = math.Round(float64(c.supported[check])/float64(total)*10000) / 100
+	}
+	return uptime
+}
+
+// bucketStart returns the start of the bucket a time is in, in UTC. Weeks start on Monday.
+func bucketStart(t time.Time, bucket string) time.Time {
+	year, month, day := t.UTC().Date()
+	switch bucket {
+	case BucketWeek:
+		weekday := (int(t.UTC().Weekday()) + 6) % 7 // Days since Monday
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, time.UTC)
+	case BucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nextBucket returns the start of the bucket after the one starting at start.
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,369 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// day returns midnight UTC of a day in May 2024.
+func day(d int) time.Time {
+	return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNewHistoryPoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			"every check",
+			`{"base_domain": "supported", "www_domain": "unsupported", "nameserver": "no_record", "mx_record": "supported"}`,
+			map[string]string{
+				CheckBaseDomain: IPv6Available, CheckWwwDomain: IPv4Only,
+				CheckNameserver: NoRecordsFound, CheckMXRecord: IPv6Available,
+			},
+			false,
+		},
+		{
+			"missing, empty and other keys are left out",
+			`{"base_domain": "supported", "www_domain": "", "nameserver": 1, "v6_only": "supported"}`,
+			map[string]string{CheckBaseDomain: IPv6Available},
+			false,
+		},
+		{"empty object", `{}`, map[string]string{}, false},
+		{"invalid JSON", `{`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			point, err := newHistoryPoint(day(1), pgtype.JSONB{Bytes: []byte(tt.data), Status: pgtype.Present})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newHistoryPoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !point.Time.Equal(day(1)) || !maps.Equal(point.Statuses, tt.want) {
+				t.Errorf("newHistoryPoint() = %+v, want %v at %v", point, tt.want, day(1))
+			}
+		})
+	}
+}
+
+func TestHistoryIntervals(t *testing.T) {
+	base := func(ts time.Time, status string) historyPoint {
+		statuses := map[string]string{}
+		if status != "" {
+			statuses[CheckBaseDomain] = status
+		}
+		return historyPoint{Time: ts, Statuses: statuses}
+	}
+	tests := []struct {
+		name   string
+		points []historyPoint
+		end    time.Time
+		want   []HistoryInterval
+	}{
+		{"no points", nil, day(10), nil},
+		{
+			"one status until the end",
+			[]historyPoint{base(day(1), IPv6Available)},
+			day(10),
+			[]HistoryInterval{{CheckBaseDomain, IPv6Available, day(1), day(10)}},
+		},
+		{
+			"repeated statuses are merged",
+			[]historyPoint{base(day(1), IPv4Only), base(day(2), IPv4Only), base(day(3), IPv6Available), base(day(4), IPv6Available)},
+			day(10),
+			[]HistoryInterval{
+				{CheckBaseDomain, IPv4Only, day(1), day(3)},
+				{CheckBaseDomain, IPv6Available, day(3), day(10)},
+			},
+		},
+		{
+			"a missing status ends the interval",
+			[]historyPoint{base(day(1), IPv6Available), base(day(3), ""), base(day(5), IPv6Available)},
+			day(10),
+			[]HistoryInterval{
+				{CheckBaseDomain, IPv6Available, day(1), day(3)},
+				{CheckBaseDomain, IPv6Available, day(5), day(10)},
+			},
+		},
+		{
+			"an interval starting at the end is left out",
+			[]historyPoint{base(day(1), IPv4Only), base(day(10), IPv6Available)},
+			day(10),
+			[]HistoryInterval{{CheckBaseDomain, IPv4Only, day(1), day(10)}},
+		},
+		{
+			"grouped by check",
+			[]historyPoint{
+				{Time: day(1), Statuses: map[string]string{CheckMXRecord: IPv4Only, CheckBaseDomain: IPv6Available}},
+				{Time: day(2), Statuses: map[string]string{CheckMXRecord: IPv6Available, CheckBaseDomain: IPv6Available}},
+			},
+			day(3),
+			[]HistoryInterval{
+				{CheckBaseDomain, IPv6Available, day(1), day(3)},
+				{CheckMXRecord, IPv4Only, day(1), day(2)},
+				{CheckMXRecord, IPv6Available, day(2), day(3)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := historyIntervals(tt.points, tt.end)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("historyIntervals() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryUptime(t *testing.T) {
+	intervals := []HistoryInterval{
+		{CheckBaseDomain, IPv4Only, day(1), day(4)},
+		{CheckBaseDomain, IPv6Available, day(4), day(11)},
+		{CheckMXRecord, NoRecordsFound, day(1), day(2)},
+		{CheckMXRecord, IPv6Available, day(5), day(8)},
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     map[string]float64
+	}{
+		{"whole history", day(1), day(11), map[string]float64{CheckBaseDomain: 70, CheckMXRecord: 75}},
+		{"clipped to the period", day(3), day(5), map[string]float64{CheckBaseDomain: 50}},
+		{"only supported", day(6), day(7), map[string]float64{CheckBaseDomain: 100, CheckMXRecord: 100}},
+		{"rounded to two decimals", day(2), day(5), map[string]float64{CheckBaseDomain: 33.33}},
+		{"before the history", day(1).AddDate(0, -1, 0), day(1), map[string]float64{}},
+		{"empty period", day(5), day(5), map[string]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyUptime(intervals, tt.from, tt.to); !maps.Equal(got, tt.want) {
+				t.Errorf("historyUptime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	// 2024-05-15 is a Wednesday.
+	ts := time.Date(2024, 5, 15, 13, 14, 15, 0, time.UTC)
+	tests := []struct {
+		bucket    string
+		at        time.Time
+		wantStart time.Time
+		wantNext  time.Time
+	}{
+		{BucketDay, ts, day(15), day(16)},
+		{BucketWeek, ts, day(13), day(20)},
+		{BucketMonth, ts, day(1), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{BucketWeek, day(13), day(13), day(20)},
+		{BucketWeek, day(19).Add(23 * time.Hour), day(13), day(20)},
+		{BucketMonth, time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{BucketDay, time.Date(2024, 5, 15, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), day(14), day(15)},
+		{"", ts, day(15), day(16)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket+" "+tt.at.Format(time.RFC3339), func(t *testing.T) {
+			start := bucketStart(tt.at, tt.bucket)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("bucketStart() = %v, want %v", start, tt.wantStart)
+			}
+			if next := nextBucket(start, tt.bucket); !next.Equal(tt.wantNext) {
+				t.Errorf("nextBucket() = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestHistoryBuckets(t *testing.T) {
+	intervals := []HistoryInterval{
+		{CheckBaseDomain, IPv4Only, day(1), day(3).Add(12 * time.Hour)},
+		{CheckBaseDomain, IPv6Available, day(3).Add(12 * time.Hour), day(5)},
+		{CheckMXRecord, IPv6Available, day(2), day(3)},
+	}
+	entries := []time.Time{day(1), day(1).Add(time.Hour), day(3).Add(12 * time.Hour), day(4).Add(23 * time.Hour)}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		bucket   string
+		want     []HistoryBucket
+	}{
+		{
+			"days", day(1), day(5), BucketDay,
+			[]HistoryBucket{
+				{day(1), day(2), 2, map[string]float64{CheckBaseDomain: 0}},
+				{day(2), day(3), 0, map[string]float64{CheckBaseDomain: 0, CheckMXRecord: 100}},
+				{day(3), day(4), 1, map[string]float64{CheckBaseDomain: 50}},
+				{day(4), day(5), 1, map[string]float64{CheckBaseDomain: 100}},
+			},
+		},
+		{
+			"from in the middle of a bucket", day(2).Add(12 * time.Hour), day(4), BucketDay,
+			[]HistoryBucket{
+				{day(2), day(3), 0, map[string]float64{CheckBaseDomain: 0, CheckMXRecord: 100}},
+				{day(3), day(4), 1, map[string]float64{CheckBaseDomain: 50}},
+			},
+		},
+		{
+			"a week", day(1), day(5), BucketWeek,
+			[]HistoryBucket{
+				{day(-1), day(6), 4, map[string]float64{CheckBaseDomain: 37.5, CheckMXRecord: 100}},
+			},
+		},
+		{
+			"no entries or intervals", day(10), day(12), BucketDay,
+			[]HistoryBucket{
+				{day(10), day(11), 0, map[string]float64{}},
+				{day(11), day(12), 0, map[string]float64{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inRange []time.Time
+			for _, entry := range entries {
+				if !entry.Before(tt.from) && entry.Before(tt.to) {
+					inRange = append(inRange, entry)
+				}
+			}
+			got := historyBuckets(intervals, inRange, tt.from, tt.to, tt.bucket)
+			if len(got) != len(tt.want) {
+				t.Fatalf("historyBuckets() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].From.Equal(tt.want[i].From) || !got[i].To.Equal(tt.want[i].To) ||
+					got[i].Entries != tt.want[i].Entries || !maps.Equal(got[i].Uptime, tt.want[i].Uptime) {
+					t.Errorf("bucket %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// logDB is a database with one domain and its log, oldest first.
+type logDB struct {
+	logs  []db.ListDomainLogRangeRow
+	pages int
+}
+
+func (d *logDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *logDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if sql != db.ListDomainLogRange {
+		return nil, errors.New("not implemented")
+	}
+	d.pages++
+	afterTime, afterID, end, limit := args[1].(time.Time), args[2].(int64), args[3].(time.Time), args[4].(int32)
+	rows := &logRows{}
+	for _, log := range d.logs {
+		after := log.Time.After(afterTime) || log.Time.Equal(afterTime) && log.ID > afterID
+		if after && log.Time.Before(end) && len(rows.logs) < int(limit) {
+			rows.logs = append(rows.logs, log)
+		}
+	}
+	return rows, nil
+}
+
+func (d *logDB) QueryRow(_ context.Context, sql string, _ ...any) pgx.Row {
+	return logRow{found: sql == db.ViewDomain}
+}
+
+// logRow is the domain, or no row for the entry before the history.
+type logRow struct {
+	found bool
+}
+
+func (r logRow) Scan(dest ...any) error {
+	if !r.found {
+		return pgx.ErrNoRows
+	}
+	*dest[0].(*sql.NullInt64) = sql.NullInt64{Int64: 1, Valid: true}
+	return nil
+}
+
+// logRows is a page of log entries.
+type logRows struct {
+	pgx.Rows
+	logs []db.ListDomainLogRangeRow
+	next int
+}
+
+func (r *logRows) Close()     {}
+func (r *logRows) Err() error { return nil }
+
+func (r *logRows) Next() bool {
+	r.next++
+	return r.next <= len(r.logs)
+}
+
+func (r *logRows) Scan(dest ...any) error {
+	log := r.logs[r.next-1]
+	*dest[0].(*int64) = log.ID
+	*dest[1].(*time.Time) = log.Time
+	*dest[2].(*pgtype.JSONB) = log.Data
+	return nil
+}
+
+func TestGetDomainHistoryReadsEveryPage(t *testing.T) {
+	// More entries than fit in two pages, logged every minute, with several
+	// entries at the same time around the page boundaries. The domain gets
+	// IPv6 at the last entry.
+	d := &logDB{}
+	start := day(1)
+	entries := 2*historyPageSize + 3
+	for i := range entries {
+		status := IPv4Only
+		if i == entries-1 {
+			status = IPv6Available
+		}
+		d.logs = append(d.logs, db.ListDomainLogRangeRow{
+			ID:   int64(i + 1),
+			Time: start.Add(time.Duration(i/3) * time.Minute),
+			Data: pgtype.JSONB{Bytes: []byte(`{"base_domain":"` + status + `"}`), Status: pgtype.Present},
+		})
+	}
+	last := d.logs[len(d.logs)-1].Time
+	to := last.Add(time.Hour)
+
+	history, err := NewDomainService(d).GetDomainHistory(context.Background(), "example.com", start, to, BucketDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.pages != 3 {
+		t.Errorf("%d pages read, want 3", d.pages)
+	}
+	if history.Entries != entries {
+		t.Errorf("%d entries, want %d", history.Entries, entries)
+	}
+	want := []HistoryInterval{
+		{CheckBaseDomain, IPv4Only, start, last},
+		{CheckBaseDomain, IPv6Available, last, to},
+	}
+	if !slices.Equal(history.Intervals, want) {
+		t.Errorf("intervals = %+v, want %+v", history.Intervals, want)
+	}
+	counted := 0
+	for _, bucket := range history.Buckets {
+		counted += bucket.Entries
+	}
+	if counted != entries {
+		t.Errorf("%d entries in the buckets, want %d", counted, entries)
+	}
+}
@@ -33,6 +33,9 @@ type Metric struct {
 
 // StoreMetric stores a metric data point with the given measurement name and data.
 func (s *MetricService) StoreMetric(ctx context.Context, measurement string, data any) error {
+	ctx, span := tracer.Start(ctx, "MetricService.StoreMetric")
+	defer span.End()
+
 	// Encode the data to a []byte
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
@@ -55,6 +58,9 @@ func (s *MetricService) StoreMetric(ctx context.Context, measurement string, dat
 
 // GetMetrics retrieves all the metrics for a specified measurement.
 func (s *MetricService) GetMetrics(ctx context.Context, measurement string) ([]Metric, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.GetMetrics")
+	defer span.End()
+
 	metrics, err := s.q.GetMetric(ctx, measurement)
 	if err != nil {
 		return nil, err
@@ -70,12 +76,25 @@ func (s *MetricService) GetMetrics(ctx context.Context, measurement string) ([]M
 	return metricList, nil
 }
 
+// LatestMetricTime returns the time of the latest data point of a measurement,
+// e.g. the end of the last crawl for "crawler".
+// Returns pgx.ErrNoRows if the measurement has no data points.
+func (s *MetricService) LatestMetricTime(ctx context.Context, measurement string) (time.Time, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.LatestMetricTime")
+	defer span.End()
+
+	return s.q.LatestMetricTime(ctx, measurement)
+}
+
 // AsnList retrieves all BGP ASN records.
 func (s *MetricService) AsnList(
 	ctx context.Context,
 	offset, limit int64,
 	order string,
 ) ([]ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.AsnList")
+	defer span.End()
+
 	var asnRecords []db.Asn
 	var err error
 
@@ -106,6 +125,9 @@ func (s *MetricService) AsnList(
 
 // SearchAsn retrieves all BGP ASN records for the given ASN number.
 func (s *MetricService) SearchAsn(ctx context.Context, searchQuery string) ([]ASNModel, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.SearchAsn")
+	defer span.End()
+
 	// Normalize search query by trimming "AS" prefix if present
 	searchQuery = strings.TrimPrefix(strings.ToUpper(searchQuery), "AS")
 
@@ -163,6 +185,9 @@ type DomainStatsModel struct {
 
 // DomainStats retrieves the aggregated metrics for all crawled domains.
 func (s *MetricService) DomainStats(ctx context.Context) ([]Metric, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.DomainStats")
+	defer span.End()
+
 	metrics, err := s.q.DomainStats(ctx)
 	if err != nil {
 		return nil, err
@@ -3,6 +3,8 @@ package core
 import (
 	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // TimeNull converts a sql.NullTime value to a time.Time value.
@@ -88,3 +90,39 @@ func NullBool(b bool) sql.NullBool {
 		Valid: true,
 	}
 }
+
+// optionalString converts a string value to a sql.NullString value.
+// If the string is empty, it returns an invalid sql.NullString value.
+func optionalString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
+
+// optionalInt converts an int64 value to a sql.NullInt64 value.
+// If the value is zero, it returns an invalid sql.NullInt64 value.
+func optionalInt(i int64) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: i,
+		Valid: i != 0,
+	}
+}
+
+// optionalInt32 converts an int32 value to a sql.NullInt32 value.
+// If the value is zero, it returns an invalid sql.NullInt32 value.
+func optionalInt32(i int32) sql.NullInt32 {
+	return sql.NullInt32{
+		Int32: i,
+		Valid: i != 0,
+	}
+}
+
+// optionalUUID converts a uuid.UUID value to a uuid.NullUUID value.
+// If the UUID is the nil UUID, it returns an invalid uuid.NullUUID value.
+func optionalUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{
+		UUID:  id,
+		Valid: id != uuid.Nil,
+	}
+}
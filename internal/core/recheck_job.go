@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// RecheckJobService is a service for the on-demand recheck jobs.
+type RecheckJobService struct {
+	q *db.Queries
+}
+
+// NewRecheckJobService creates a new RecheckJobService instance.
+func NewRecheckJobService(d db.DBTX) *RecheckJobService {
+	return &RecheckJobService{
+		q: db.New(d),
+	}
+}
+
+// Status a recheck job can have.
+const (
+	JobQueued  = "queued"  // Waiting for the crawler
+	JobRunning = "running" // Claimed by the crawler
+	JobDone    = "done"    // Checked, the result is stored in the job
+	JobFailed  = "failed"  // The check failed, the error is stored in the job
+)
+
+// JobPriorityHigh is the priority of the rechecks requested through the API.
+const JobPriorityHigh = 10
+
+// RecheckJobModel represents a recheck of a single domain.
+type RecheckJobModel struct {
+	ID         uuid.UUID `json:"id"`
+	DomainID   int64     `json:"domain_id"`
+	Site       string    `json:"site"`
+	Status     string    `json:"status"`
+	Priority   int32     `json:"priority"`
+	Client     string    `json:"client"`
+	BaseDomain string    `json:"base_domain"`
+	WwwDomain  string    `json:"www_domain"`
+	Nameserver string    `json:"nameserver"`
+	MXRecord   string    `json:"mx_record"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// CreateJob queues a recheck of a domain on behalf of a client. A domain has at
+// most one queued or running job, if it already has one that job is returned
+// and created is false.
+func (s *RecheckJobService) CreateJob(
+	ctx context.Context,
+	domain DomainModel,
+	client string,
+	priority int32,
+) (job RecheckJobModel, created bool, err error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.CreateJob")
+	defer span.End()
+
+	// The job that is in the way can finish between the insert and the
+	// select, then the insert is tried again.
+	for range 3 {
+		row, err := s.q.CreateRecheckJob(ctx, db.CreateRecheckJobParams{
+			DomainID: domain.ID,
+			Site:     domain.Site,
+			Priority: priority,
+			Client:   client,
+		})
+		if err == nil {
+			return newRecheckJobModel(row), true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return RecheckJobModel{}, false, err
+		}
+		row, err = s.q.GetActiveRecheckJob(ctx, domain.ID)
+		if err == nil {
+			return newRecheckJobModel(row), false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return RecheckJobModel{}, false, err
+		}
+	}
+	return RecheckJobModel{}, false, errors.New("the recheck jobs of " + domain.Site + " keep changing")
+}
+
+// LockClient makes the other transactions that lock the same client wait
+// until this transaction ends. It must be called in a transaction.
+func (s *RecheckJobService) LockClient(ctx context.Context, client string) error {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.LockClient")
+	defer span.End()
+
+	return s.q.LockRecheckClient(ctx, client)
+}
+
+// GetJob retrieves a job by its ID.
+func (s *RecheckJobService) GetJob(ctx context.Context, id uuid.UUID) (RecheckJobModel, error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.GetJob")
+	defer span.End()
+
+	job, err := s.q.GetRecheckJob(ctx, id)
+	if err != nil {
+		return RecheckJobModel{}, err
+	}
+	return newRecheckJobModel(job), nil
+}
+
+// GetLatestJob retrieves the newest job for a domain.
+// Returns pgx.ErrNoRows if the domain has never been rechecked.
+func (s *RecheckJobService) GetLatestJob(ctx context.Context, domainID int64) (RecheckJobModel, error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.GetLatestJob")
+	defer span.End()
+
+	job, err := s.q.GetLatestRecheckJob(ctx, domainID)
+	if err != nil {
+		return RecheckJobModel{}, err
+	}
+	return newRecheckJobModel(job), nil
+}
+
+// ClientJobs returns the number of jobs a client requested since the given time,
+// and when the oldest of them was requested.
+func (s *RecheckJobService) ClientJobs(
+	ctx context.Context,
+	client string,
+	since time.Time,
+) (int64, time.Time, error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.ClientJobs")
+	defer span.End()
+
+	stats, err := s.q.ClientRecheckJobStats(ctx, db.ClientRecheckJobStatsParams{
+		Client:    client,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return stats.Count, stats.Oldest, nil
+}
+
+// ClaimJobs marks up to limit queued jobs as running and returns them, highest
+// priority first. Jobs that have been running since before staleBefore are
+// claimed again.
+func (s *RecheckJobService) ClaimJobs(
+	ctx context.Context,
+	limit int64,
+	staleBefore time.Time,
+) ([]RecheckJobModel, error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.ClaimJobs")
+	defer span.End()
+
+	jobs, err := s.q.ClaimRecheckJobs(ctx, db.ClaimRecheckJobsParams{
+		Limit:     limit,
+		StartedAt: NullTime(staleBefore),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []RecheckJobModel
+	for _, job := range jobs {
+		list = append(list, newRecheckJobModel(job))
+	}
+	return list, nil
+}
+
+// QueuedJobs counts the jobs that wait to be claimed.
+func (s *RecheckJobService) QueuedJobs(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.QueuedJobs")
+	defer span.End()
+
+	return s.q.CountQueuedRecheckJobs(ctx)
+}
+
+// ErrRecheckJobLost is returned when a job is finished by a crawler that no
+// longer holds its claim: it was claimed again by another crawler after it ran
+// for too long, or it is finished already.
+var ErrRecheckJobLost = errors.New("the recheck job was claimed by another crawler")
+
+// CompleteJob stores the result of a successful recheck of a claimed job.
+func (s *RecheckJobService) CompleteJob(ctx context.Context, job RecheckJobModel, result CheckResultModel) error {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.CompleteJob")
+	defer span.End()
+
+	return s.finishJob(ctx, db.FinishRecheckJobParams{
+		ID:         job.ID,
+		Status:     JobDone,
+		BaseDomain: NullString(result.BaseDomain),
+		WwwDomain:  NullString(result.WwwDomain),
+		Nameserver: NullString(result.Nameserver),
+		MxRecord:   NullString(result.MXRecord),
+		StartedAt:  NullTime(job.StartedAt),
+	})
+}
+
+// FailJob stores why the recheck of a claimed job failed.
+func (s *RecheckJobService) FailJob(ctx context.Context, job RecheckJobModel, reason error) error {
+	ctx, span := tracer.Start(ctx, "RecheckJobService.FailJob")
+	defer span.End()
+
+	return s.finishJob(ctx, db.FinishRecheckJobParams{
+		ID:        job.ID,
+		Status:    JobFailed,
+		Error:     NullString(reason.Error()),
+		StartedAt: NullTime(job.StartedAt),
+	})
+}
+
+// finishJob stores the outcome of a job, if the claim of the job is still held.
+func (s *RecheckJobService) finishJob(ctx context.Context, arg db.FinishRecheckJobParams) error {
+	rows, err := s.q.FinishRecheckJob(ctx, arg)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecheckJobLost
+	}
+	return nil
+}
+
+// newRecheckJobModel maps a database row to the job model.
+func newRecheckJobModel(job db.RecheckJob) RecheckJobModel {
+	return RecheckJobModel{
+		ID:         job.ID,
+		DomainID:   job.DomainID,
+		Site:       job.Site,
+		Status:     job.Status,
+		Priority:   job.Priority,
+		Client:     job.Client,
+		BaseDomain: StringNull(job.BaseDomain),
+		WwwDomain:  StringNull(job.WwwDomain),
+		Nameserver: StringNull(job.Nameserver),
+		MXRecord:   StringNull(job.MxRecord),
+		Error:      StringNull(job.Error),
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  TimeNull(job.StartedAt),
+		FinishedAt: TimeNull(job.FinishedAt),
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// finishDB is a database that updates a given number of jobs.
+type finishDB struct {
+	tag  string
+	err  error
+	args []any
+}
+
+func (d *finishDB) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	d.args = args
+	return pgconn.CommandTag(d.tag), d.err
+}
+
+func (d *finishDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *finishDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+func TestFinishJob(t *testing.T) {
+	job := RecheckJobModel{
+		ID:        uuid.New(),
+		Site:      "example.com",
+		Status:    JobRunning,
+		StartedAt: time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC),
+	}
+	dbErr := errors.New("connection refused")
+	tests := []struct {
+		name    string
+		tag     string
+		err     error
+		finish  func(s *RecheckJobService) error
+		wantErr error
+	}{
+		{
+			name:   "complete",
+			tag:    "UPDATE 1",
+			finish: func(s *RecheckJobService) error { return s.CompleteJob(context.Background(), job, CheckResultModel{}) },
+		},
+		{
+			name:   "fail",
+			tag:    "UPDATE 1",
+			finish: func(s *RecheckJobService) error { return s.FailJob(context.Background(), job, errors.New("timeout")) },
+		},
+		{
+			name:    "complete a job claimed again",
+			tag:     "UPDATE 0",
+			finish:  func(s *RecheckJobService) error { return s.CompleteJob(context.Background(), job, CheckResultModel{}) },
+			wantErr: ErrRecheckJobLost,
+		},
+		{
+			name:    "fail a job claimed again",
+			tag:     "UPDATE 0",
+			finish:  func(s *RecheckJobService) error { return s.FailJob(context.Background(), job, errors.New("timeout")) },
+			wantErr: ErrRecheckJobLost,
+		},
+		{
+			name:    "database error",
+			err:     dbErr,
+			finish:  func(s *RecheckJobService) error { return s.CompleteJob(context.Background(), job, CheckResultModel{}) },
+			wantErr: dbErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &finishDB{tag: tt.tag, err: tt.err}
+			err := tt.finish(NewRecheckJobService(d))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			// The update is limited to the claim of the job.
+			if len(d.args) != 8 || d.args[0] != job.ID || d.args[7] != NullTime(job.StartedAt) {
+				t.Errorf("arguments = %v, want the job ID and its claim time", d.args)
+			}
+		})
+	}
+}
@@ -28,6 +28,9 @@ type SiteModel struct {
 // It accepts a context, offset, and limit as parameters.
 // Returns a slice of SiteModel and an error if any.
 func (s *SiteService) ListSite(ctx context.Context, offset, limit int64) ([]SiteModel, error) {
+	ctx, span := tracer.Start(ctx, "SiteService.ListSite")
+	defer span.End()
+
 	sites, err := s.q.ListSites(ctx, db.ListSitesParams{
 		Offset: offset,
 		Limit:  limit,
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"whynoipv6/internal/postgres/db"
+)
+
+// Intervals the country and ASN history can be downsampled to. Each period is
+// represented by its last daily snapshot.
+const (
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// StatsIntervals are the intervals the country and ASN history supports.
+var StatsIntervals = []string{IntervalDay, IntervalWeek, IntervalMonth, IntervalQuarter, IntervalYear}
+
+// CountryHistoryModel is the statistics of a country on a day.
+type CountryHistoryModel struct {
+	Period  time.Time // Start of the period, e.g. the first day of the quarter
+	Day     time.Time // Day of the snapshot, the last one in the period
+	Sites   int32
+	V6sites int32
+	Percent float64
+}
+
+// ASNHistoryModel is the statistics of an ASN on a day.
+type ASNHistoryModel struct {
+	Period    time.Time // Start of the period, e.g. the first day of the quarter
+	Day       time.Time // Day of the snapshot, the last one in the period
+	CountV4   int32
+	CountV6   int32
+	PercentV4 float64
+	PercentV6 float64
+}
+
+// SnapshotCountryHistory stores today's statistics of every country. Run it
+// after CalculateCountryStats.
+func (s *CountryService) SnapshotCountryHistory(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "CountryService.SnapshotCountryHistory")
+	defer span.End()
+
+	return s.q.SnapshotCountryHistory(ctx)
+}
+
+// CountryHistory returns the statistics of a country between the start and end
+// day, oldest first, one snapshot per interval.
+func (s *CountryService) CountryHistory(
+	ctx context.Context,
+	countryID int64,
+	start, end time.Time,
+	interval string,
+) ([]CountryHistoryModel, error) {
+	ctx, span := tracer.Start(ctx, "CountryService.CountryHistory")
+	defer span.End()
+
+	rows, err := s.q.ListCountryHistory(ctx, db.ListCountryHistoryParams{
+		Step:      interval,
+		CountryID: countryID,
+		StartDay:  start,
+		EndDay:    end,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var history []CountryHistoryModel
+	for _, row := range rows {
+		history = append(history, CountryHistoryModel{
+			Period:  row.Period,
+			Day:     row.Day,
+			Sites:   row.Sites,
+			V6sites: row.V6sites,
+			Percent: row.Percent,
+		})
+	}
+	return history, nil
+}
+
+// SnapshotASNHistory stores today's statistics of every ASN with domains. Run
+// it after CalculateASNStats.
+func (s *ASNService) SnapshotASNHistory(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ASNService.SnapshotASNHistory")
+	defer span.End()
+
+	return s.q.SnapshotASNHistory(ctx)
+}
+
+// ASNHistory returns the statistics of an AS number between the start and end
+// day, oldest first, one snapshot per interval.
+// Returns pgx.ErrNoRows if the AS number is unknown.
+func (s *MetricService) ASNHistory(
+	ctx context.Context,
+	number int32,
+	start, end time.Time,
+	interval string,
+) (ASNModel, []ASNHistoryModel, error) {
+	ctx, span := tracer.Start(ctx, "MetricService.ASNHistory")
+	defer span.End()
+
+	asn, err := s.q.GetASByNumber(ctx, number)
+	if err != nil {
+		return ASNModel{}, nil, err
+	}
+	rows, err := s.q.ListASNHistory(ctx, db.ListASNHistoryParams{
+		Step:     interval,
+		AsnID:    asn.ID,
+		StartDay: start,
+		EndDay:   end,
+	})
+	if err != nil {
+		return ASNModel{}, nil, err
+	}
+	var history []ASNHistoryModel
+	for _, row := range rows {
+		history = append(history, ASNHistoryModel{
+			Period:    row.Period,
+			Day:       row.Day,
+			CountV4:   row.CountV4,
+			CountV6:   row.CountV6,
+			PercentV4: row.PercentV4,
+			PercentV6: row.PercentV6,
+		})
+	}
+	model := ASNModel{
+		ID:     asn.ID,
+		Number: asn.Number,
+		Name:   asn.Name,
+	}
+	return model, history, nil
+}
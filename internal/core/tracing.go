@@ -0,0 +1,6 @@
+package core
+
+import "whynoipv6/internal/tracing"
+
+// tracer records a span for every service call.
+var tracer = tracing.Tracer("whynoipv6/internal/core")
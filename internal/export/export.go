@@ -0,0 +1,236 @@
+// Package export writes the domain table in bulk, as CSV, NDJSON or Parquet.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"whynoipv6/internal/core"
+)
+
+// Format is the file format of an export.
+type Format string
+
+// Supported export formats.
+const (
+	FormatCSV     Format = "csv"     // RFC 4180 CSV with a header row
+	FormatNDJSON  Format = "ndjson"  // One JSON object per line
+	FormatParquet Format = "parquet" // Apache Parquet, uncompressed
+)
+
+// Formats are the supported export formats.
+var Formats = []string{string(FormatCSV), string(FormatNDJSON), string(FormatParquet)}
+
+// ParseFormat returns the format with the given name, which is also its file extension.
+func ParseFormat(name string) (Format, bool) {
+	switch Format(name) {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return Format(name), true
+	}
+	return "", false
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.apache.parquet"
+}
+
+// Type is the type of a column.
+type Type string
+
+// Column types.
+const (
+	TypeInt32     Type = "int32"
+	TypeInt64     Type = "int64"
+	TypeString    Type = "string"
+	TypeTimestamp Type = "timestamp" // UTC, RFC 3339 in CSV and NDJSON, milliseconds in Parquet
+)
+
+// Column is a column of an export.
+type Column struct {
+	Name        string `json:"name"`
+	Type        Type   `json:"type"`
+	Nullable    bool   `json:"nullable"`
+	Description string `json:"description"`
+
+	value func(core.DomainExportModel) any // nil if the value is null
+}
+
+// Columns is the schema of a domain export, in order. A nullable column is empty
+// in CSV and null in NDJSON and Parquet when the value is not known, e.g. the
+// country of a domain that has not been crawled yet.
+var Columns = []Column{
+	{"id", TypeInt64, false, "Domain id", func(d core.DomainExportModel) any { return d.ID }},
+	{"site", TypeString, false, "Domain name, e.g. example.com", func(d core.DomainExportModel) any { return d.Site }},
+	{"rank", TypeInt64, false, "Tranco rank", func(d core.DomainExportModel) any { return d.Rank }},
+	{"base_domain", TypeString, true, "Status of the AAAA check: supported, unsupported or no_record",
+		func(d core.DomainExportModel) any { return nullString(d.BaseDomain) }},
+	{"www_domain", TypeString, true, "Status of the www AAAA check",
+		func(d core.DomainExportModel) any { return nullString(d.WwwDomain) }},
+	{"nameserver", TypeString, true, "Status of the nameserver check",
+		func(d core.DomainExportModel) any { return nullString(d.Nameserver) }},
+	{"mx_record", TypeString, true, "Status of the MX check",
+		func(d core.DomainExportModel) any { return nullString(d.MXRecord) }},
+	{"v6_only", TypeString, true, "Status of the IPv6-only check",
+		func(d core.DomainExportModel) any { return nullString(d.V6Only) }},
+	{"asn", TypeInt32, true, "AS number of the site",
+		func(d core.DomainExportModel) any { return nullInt32(d.ASN) }},
+	{"as_name", TypeString, true, "AS name of the site",
+		func(d core.DomainExportModel) any { return nullString(d.ASName) }},
+	{"country_code", TypeString, true, "ISO 3166-1 alpha-2 code of the country the site is in",
+		func(d core.DomainExportModel) any { return nullString(d.CountryCode) }},
+	{"country", TypeString, true, "Name of the country the site is in",
+		func(d core.DomainExportModel) any { return nullString(d.Country) }},
+	{"ts_base_domain", TypeTimestamp, true, "Last change of the AAAA check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsBaseDomain) }},
+	{"ts_www_domain", TypeTimestamp, true, "Last change of the www AAAA check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsWwwDomain) }},
+	{"ts_nameserver", TypeTimestamp, true, "Last change of the nameserver check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsNameserver) }},
+	{"ts_mx_record", TypeTimestamp, true, "Last change of the MX check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsMXRecord) }},
+	{"ts_v6_only", TypeTimestamp, true, "Last change of the IPv6-only check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsV6Only) }},
+	{"ts_check", TypeTimestamp, true, "Last time the site was checked",
+		func(d core.DomainExportModel) any { return nullTime(d.TsCheck) }},
+	{"ts_updated", TypeTimestamp, true, "Last change of any check",
+		func(d core.DomainExportModel) any { return nullTime(d.TsUpdated) }},
+}
+
+// Writer writes the domains of an export. Close must be called after the last
+// domain to complete the file, it does not close the underlying writer.
+type Writer interface {
+	Write(d core.DomainExportModel) error
+	Close() error
+}
+
+// NewWriter returns a writer for the format, writing to w.
+func NewWriter(w io.Writer, format Format) Writer {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: w}
+	}
+	return newParquetWriter(w)
+}
+
+// csvWriter writes an export as CSV with a header row.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), record: make([]string, len(Columns))}
+}
+
+func (c *csvWriter) Write(d core.DomainExportModel) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	for i, column := range Columns {
+		switch value := column.value(d).(type) {
+		case nil:
+			c.record[i] = ""
+		case int32:
+			c.record[i] = strconv.FormatInt(int64(value), 10)
+		case int64:
+			c.record[i] = strconv.FormatInt(value, 10)
+		case string:
+			c.record[i] = value
+		case time.Time:
+			c.record[i] = value.Format(time.RFC3339)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+// writeHeader writes the header row, once. An empty export is only the header row.
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	header := make([]string, len(Columns))
+	for i, column := range Columns {
+		header[i] = column.Name
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonWriter writes an export as one JSON object per line, with the keys in
+// the order of the columns.
+type ndjsonWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (n *ndjsonWriter) Write(d core.DomainExportModel) error {
+	n.buf = append(n.buf[:0], '{')
+	for i, column := range Columns {
+		if i > 0 {
+			n.buf = append(n.buf, ',')
+		}
+		n.buf = strconv.AppendQuote(n.buf, column.Name)
+		n.buf = append(n.buf, ':')
+		value := column.value(d)
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.buf = append(n.buf, encoded...)
+	}
+	n.buf = append(n.buf, '}', '\n')
+	_, err := n.w.Write(n.buf)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// nullString returns nil for an empty string.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullInt32 returns nil for zero.
+func nullInt32(i int32) any {
+	if i == 0 {
+		return nil
+	}
+	return i
+}
+
+// nullTime returns nil for the zero time, and the time in UTC otherwise.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,279 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"whynoipv6/internal/core"
+)
+
+// The Parquet file is written without a dependency, with the parts of the
+// format an export needs: flat columns, PLAIN encoded values and RLE encoded
+// definition levels in one uncompressed data page per column chunk, see
+// https://parquet.apache.org/docs/file-format/.
+
+const (
+	// parquetMagic starts and ends a Parquet file.
+	parquetMagic = "PAR1"
+	// parquetRowGroupSize is the number of rows in a row group, the rows that
+	// are kept in memory before they are written.
+	parquetRowGroupSize = 100000
+	// parquetCreatedBy is the application that wrote the file.
+	parquetCreatedBy = "whynoipv6"
+)
+
+// Parquet enum values, from parquet.thrift.
+const (
+	parquetInt32     = 1 // Type
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0 // FieldRepetitionType
+	parquetOptional = 1
+
+	parquetUTF8            = 0 // ConvertedType
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0 // Encoding
+	parquetRLE   = 3
+
+	parquetDataPage = 0 // PageType
+)
+
+// parquetColumn is a column chunk of the current row group.
+type parquetColumn struct {
+	values []byte // PLAIN encoded values that are not null
+	levels []byte // Definition level of each row of a nullable column, 0 if null
+}
+
+// parquetChunk is the position of a column chunk that has been written.
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup is a row group that has been written.
+type parquetRowGroup struct {
+	rows   int64
+	chunks []parquetChunk
+}
+
+// parquetWriter writes an export as Parquet. The rows are buffered and written
+// one row group at a time, and the metadata is written on Close.
+type parquetWriter struct {
+	w       io.Writer
+	offset  int64 // Bytes written
+	rows    int64 // Rows in the current row group
+	total   int64
+	columns []parquetColumn
+	groups  []parquetRowGroup
+	err     error // First write error, returned by every later call
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{w: w, columns: make([]parquetColumn, len(Columns))}
+}
+
+func (p *parquetWriter) Write(d core.DomainExportModel) error {
+	if p.offset == 0 {
+		p.write([]byte(parquetMagic))
+	}
+	for i, column := range Columns {
+		c := &p.columns[i]
+		value := column.value(d)
+		if column.Nullable {
+			if value == nil {
+				c.levels = append(c.levels, 0)
+				continue
+			}
+			c.levels = append(c.levels, 1)
+		}
+		switch value := value.(type) {
+		case int32:
+			c.values = binary.LittleEndian.AppendUint32(c.values, uint32(value))
+		case int64:
+			c.values = binary.LittleEndian.AppendUint64(c.values, uint64(value))
+		case string:
+			c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(value)))
+			c.values = append(c.values, value...)
+		case time.Time:
+			c.values = binary.LittleEndian.AppendUint64(c.values, uint64(value.UnixMilli()))
+		}
+	}
+	p.rows++
+	p.total++
+	if p.rows == parquetRowGroupSize {
+		p.flush()
+	}
+	return p.err
+}
+
+func (p *parquetWriter) Close() error {
+	if p.offset == 0 {
+		p.write([]byte(parquetMagic))
+	}
+	if p.rows > 0 {
+		p.flush()
+	}
+	footer := p.footer()
+	p.write(footer)
+	p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	p.write([]byte(parquetMagic))
+	return p.err
+}
+
+// write writes to the underlying writer, unless a write has failed.
+func (p *parquetWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	p.err = err
+}
+
+// flush writes the buffered rows as a row group, with one data page per column.
+func (p *parquetWriter) flush() {
+	group := parquetRowGroup{rows: p.rows}
+	for i, column := range Columns {
+		c := &p.columns[i]
+		var page []byte
+		if column.Nullable {
+			levels := encodeLevels(c.levels)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+			page = append(page, levels...)
+		}
+		page = append(page, c.values...)
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page))) // Uncompressed size
+		header.i32(3, int32(len(page))) // Compressed size
+		header.structField(5)           // DataPageHeader
+		header.i32(1, int32(p.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE) // Definition levels
+		header.i32(4, parquetRLE) // Repetition levels
+		header.end()
+		header.end()
+
+		chunk := parquetChunk{offset: p.offset, size: int64(len(header.buf) + len(page))}
+		p.write(header.buf)
+		p.write(page)
+		group.chunks = append(group.chunks, chunk)
+
+		c.values = c.values[:0]
+		c.levels = c.levels[:0]
+	}
+	p.groups = append(p.groups, group)
+	p.rows = 0
+}
+
+// encodeLevels encodes definition levels with the RLE/bit-packing hybrid
+// encoding, as RLE runs with a bit width of 1.
+func encodeLevels(levels []byte) []byte {
+	var buf []byte
+	for start := 0; start < len(levels); {
+		end := start + 1
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		buf = binary.AppendUvarint(buf, uint64(end-start)<<1)
+		buf = append(buf, levels[start])
+		start = end
+	}
+	return buf
+}
+
+// footer returns the file metadata: the schema, and the position of every column chunk.
+func (p *parquetWriter) footer() []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1) // Version
+	t.list(2, thriftStruct, len(Columns)+1)
+	t.begin()
+	t.string(4, "schema")
+	t.i32(5, int32(len(Columns)))
+	t.end()
+	for _, column := range Columns {
+		t.begin()
+		t.i32(1, parquetType(column.Type))
+		if column.Nullable {
+			t.i32(3, parquetOptional)
+		} else {
+			t.i32(3, parquetRequired)
+		}
+		t.string(4, column.Name)
+		switch column.Type {
+		case TypeString:
+			t.i32(6, parquetUTF8)
+			t.structField(10) // LogicalType
+			t.structField(1)  // STRING
+			t.end()
+			t.end()
+		case TypeTimestamp:
+			t.i32(6, parquetTimestampMillis)
+			t.structField(10) // LogicalType
+			t.structField(8)  // TIMESTAMP
+			t.bool(1, true)   // Adjusted to UTC
+			t.structField(2)  // Unit
+			t.structField(1)  // MILLIS
+			t.end()
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.end()
+	}
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.groups))
+	for _, group := range p.groups {
+		var size int64
+		t.begin()
+		t.list(1, thriftStruct, len(Columns))
+		for i, column := range Columns {
+			chunk := group.chunks[i]
+			size += chunk.size
+			t.begin()
+			t.i64(2, chunk.offset)
+			t.structField(3) // ColumnMetaData
+			t.i32(1, parquetType(column.Type))
+			if column.Nullable {
+				t.list(2, thriftI32, 2)
+				t.i32Value(parquetPlain)
+				t.i32Value(parquetRLE)
+			} else {
+				t.list(2, thriftI32, 1)
+				t.i32Value(parquetPlain)
+			}
+			t.list(3, thriftBinary, 1)
+			t.stringValue(column.Name)
+			t.i32(4, 0) // Uncompressed
+			t.i64(5, group.rows)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset) // Data page offset
+			t.end()
+			t.end()
+		}
+		t.i64(2, size)
+		t.i64(3, group.rows)
+		t.end()
+	}
+	t.string(6, parquetCreatedBy)
+	t.end()
+	return t.buf
+}
+
+// parquetType returns the physical type of a column type.
+func parquetType(typ Type) int32 {
+	switch typ {
+	case TypeInt32:
+		return parquetInt32
+	case TypeString:
+		return parquetByteArray
+	}
+	return parquetInt64
+}
@@ -0,0 +1,304 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"whynoipv6/internal/core"
+)
+
+// parquetFile is a Parquet file decoded from its bytes, the metadata and the
+// value of every column of every row, nil if it is null.
+type parquetFile struct {
+	meta map[int16]any
+	rows [][]any
+}
+
+// decodeParquet decodes a file the way a reader does: the footer at the end,
+// then the page of every column chunk at the offset the footer gives.
+func decodeParquet(data []byte) (parquetFile, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return parquetFile{}, fmt.Errorf("missing magic")
+	}
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerSize
+	if footerStart < 4 {
+		return parquetFile{}, fmt.Errorf("footer size %d out of range", footerSize)
+	}
+	meta, size, err := decodeThrift(data[footerStart : len(data)-8])
+	if err != nil || size != footerSize {
+		return parquetFile{}, fmt.Errorf("footer: read %d of %d bytes: %v", size, footerSize, err)
+	}
+
+	file := parquetFile{meta: meta}
+	schema := meta[2].([]any)[1:]
+	for g, group := range meta[4].([]any) {
+		group := group.(map[int16]any)
+		rows := int(group[3].(int64))
+		columns := make([][]any, len(schema))
+		for c, chunk := range group[1].([]any) {
+			element := schema[c].(map[int16]any)
+			chunkMeta := chunk.(map[int16]any)[3].(map[int16]any)
+			offset := chunkMeta[9].(int64)
+			end := offset + chunkMeta[7].(int64)
+			if offset < 4 || end > int64(footerStart) {
+				return parquetFile{}, fmt.Errorf("row group %d column %d: chunk %d-%d out of range", g, c, offset, end)
+			}
+			values, err := decodeParquetPage(data[offset:end], element, rows)
+			if err != nil {
+				return parquetFile{}, fmt.Errorf("row group %d column %v: %w", g, element[4], err)
+			}
+			columns[c] = values
+		}
+		for r := range rows {
+			row := make([]any, len(schema))
+			for c := range schema {
+				row[c] = columns[c][r]
+			}
+			file.rows = append(file.rows, row)
+		}
+	}
+	return file, nil
+}
+
+// decodeParquetPage decodes a column chunk of one uncompressed data page with
+// PLAIN encoded values and RLE/bit-packed definition levels.
+func decodeParquetPage(chunk []byte, element map[int16]any, rows int) ([]any, error) {
+	header, size, err := decodeThrift(chunk)
+	if err != nil {
+		return nil, err
+	}
+	page := chunk[size:]
+	dataHeader, _ := header[5].(map[int16]any)
+	switch {
+	case header[1] != int64(parquetDataPage) || dataHeader == nil:
+		return nil, fmt.Errorf("not a data page: %v", header)
+	case header[2] != int64(len(page)) || header[3] != int64(len(page)):
+		return nil, fmt.Errorf("page sizes %v and %v, want %d", header[2], header[3], len(page))
+	case dataHeader[1] != int64(rows) || dataHeader[2] != int64(parquetPlain):
+		return nil, fmt.Errorf("data page header %v", dataHeader)
+	}
+
+	defined := make([]bool, rows)
+	for i := range defined {
+		defined[i] = true
+	}
+	if element[3] == int64(parquetOptional) {
+		n := int(binary.LittleEndian.Uint32(page))
+		levels := page[4 : 4+n]
+		page = page[4+n:]
+		for i := 0; i < rows; {
+			run, k := binary.Uvarint(levels)
+			if k <= 0 {
+				return nil, fmt.Errorf("definition levels end after %d of %d rows", i, rows)
+			}
+			levels = levels[k:]
+			if run&1 == 0 { // RLE run of one value
+				for range run >> 1 {
+					defined[i] = levels[0] == 1
+					i++
+				}
+				levels = levels[1:]
+			} else { // Bit-packed groups of 8 values, one byte per group
+				for b := range run >> 1 {
+					for bit := 0; bit < 8 && i < rows; bit++ {
+						defined[i] = levels[b]>>bit&1 == 1
+						i++
+					}
+				}
+				levels = levels[run>>1:]
+			}
+		}
+	}
+
+	values := make([]any, rows)
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch element[1] {
+		case int64(parquetInt32):
+			values[i] = int32(binary.LittleEndian.Uint32(page))
+			page = page[4:]
+		case int64(parquetInt64):
+			v := int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+			if element[6] == int64(parquetTimestampMillis) {
+				values[i] = time.UnixMilli(v).UTC()
+			} else {
+				values[i] = v
+			}
+		case int64(parquetByteArray):
+			n := int(binary.LittleEndian.Uint32(page))
+			values[i] = string(page[4 : 4+n])
+			page = page[4+n:]
+		}
+	}
+	if len(page) != 0 {
+		return nil, fmt.Errorf("%d bytes left after the values", len(page))
+	}
+	return values, nil
+}
+
+// writeParquet writes the domains as a Parquet file.
+func writeParquet(t *testing.T, domains []core.DomainExportModel) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf, FormatParquet)
+	for _, d := range domains {
+		if err := w.Write(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exportRow returns the values of the columns of a domain.
+func exportRow(d core.DomainExportModel) []any {
+	row := make([]any, len(Columns))
+	for i, column := range Columns {
+		row[i] = column.value(d)
+	}
+	return row
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC)
+	full := core.DomainExportModel{
+		ID: 1, Site: "example.com", Rank: 10,
+		BaseDomain: "supported", WwwDomain: "supported", Nameserver: "unsupported", MXRecord: "no_record", V6Only: "unsupported",
+		ASN: 3292, ASName: "TDC", CountryCode: "DK", Country: "Denmark",
+		TsBaseDomain: ts, TsWwwDomain: ts.Add(time.Hour), TsNameserver: ts.Add(2 * time.Hour), TsMXRecord: ts,
+		TsV6Only: ts, TsCheck: ts.Add(24 * time.Hour), TsUpdated: ts.Add(2 * time.Hour),
+	}
+	unchecked := core.DomainExportModel{ID: 2, Site: "bücher.example", Rank: 20}
+	partial := core.DomainExportModel{
+		ID: 1 << 40, Site: "xn--bcher-kva.example", Rank: 1 << 33, BaseDomain: "unsupported",
+		ASN: -1, TsCheck: time.Date(1969, 12, 31, 23, 59, 59, 0, time.FixedZone("CET", 3600)),
+	}
+	many := make([]core.DomainExportModel, parquetRowGroupSize+3)
+	for i := range many {
+		many[i] = unchecked
+		if i%3 == 0 {
+			many[i] = full
+		}
+		many[i].ID = int64(i)
+	}
+
+	tests := []struct {
+		name       string
+		domains    []core.DomainExportModel
+		wantGroups int
+	}{
+		{"empty", nil, 0},
+		{"one row", []core.DomainExportModel{full}, 1},
+		{"nulls", []core.DomainExportModel{full, unchecked, partial, unchecked, unchecked}, 1},
+		{"two row groups", many, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := decodeParquet(writeParquet(t, tt.domains))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if file.meta[3] != int64(len(tt.domains)) {
+				t.Errorf("num_rows = %v, want %d", file.meta[3], len(tt.domains))
+			}
+			if groups := file.meta[4].([]any); len(groups) != tt.wantGroups {
+				t.Errorf("%d row groups, want %d", len(groups), tt.wantGroups)
+			}
+			if len(file.rows) != len(tt.domains) {
+				t.Fatalf("%d rows, want %d", len(file.rows), len(tt.domains))
+			}
+			for r, d := range tt.domains {
+				want := exportRow(d)
+				for c, column := range Columns {
+					got := file.rows[r][c]
+					if wt, ok := want[c].(time.Time); ok {
+						if gt, ok := got.(time.Time); !ok || !gt.Equal(wt.Truncate(time.Millisecond)) {
+							t.Fatalf("row %d %s = %v, want %v", r, column.Name, got, wt)
+						}
+						continue
+					}
+					if got != want[c] {
+						t.Fatalf("row %d %s = %#v, want %#v", r, column.Name, got, want[c])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParquetSchema(t *testing.T) {
+	file, err := decodeParquet(writeParquet(t, []core.DomainExportModel{{ID: 1, Site: "example.com"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.meta[1] != int64(1) || file.meta[6] != parquetCreatedBy {
+		t.Errorf("version = %v, created_by = %v", file.meta[1], file.meta[6])
+	}
+
+	schema := file.meta[2].([]any)
+	root := schema[0].(map[int16]any)
+	if root[4] != "schema" || root[5] != int64(len(Columns)) {
+		t.Errorf("root schema element = %v", root)
+	}
+	chunks := file.meta[4].([]any)[0].(map[int16]any)[1].([]any)
+	for i, column := range Columns {
+		t.Run(column.Name, func(t *testing.T) {
+			element := schema[i+1].(map[int16]any)
+			wantRepetition := int64(parquetRequired)
+			if column.Nullable {
+				wantRepetition = parquetOptional
+			}
+			if element[4] != column.Name || element[1] != int64(parquetType(column.Type)) || element[3] != wantRepetition {
+				t.Errorf("schema element = %v", element)
+			}
+			switch column.Type {
+			case TypeString:
+				if element[6] != int64(parquetUTF8) || fmt.Sprint(element[10]) != "map[1:map[]]" {
+					t.Errorf("string annotations = %v, %v", element[6], element[10])
+				}
+			case TypeTimestamp:
+				if element[6] != int64(parquetTimestampMillis) || fmt.Sprint(element[10]) != "map[8:map[1:true 2:map[1:map[]]]]" {
+					t.Errorf("timestamp annotations = %v, %v", element[6], element[10])
+				}
+			}
+
+			meta := chunks[i].(map[int16]any)[3].(map[int16]any)
+			if meta[1] != element[1] || fmt.Sprint(meta[3]) != "["+column.Name+"]" || meta[4] != int64(0) || meta[5] != int64(1) {
+				t.Errorf("column chunk metadata = %v", meta)
+			}
+			if chunks[i].(map[int16]any)[2] != meta[9] || meta[6] != meta[7] {
+				t.Errorf("column chunk offsets and sizes = %v, %v", chunks[i], meta)
+			}
+		})
+	}
+}
+
+func TestEncodeLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []byte
+		want   []byte
+	}{
+		{"empty", nil, nil},
+		{"one run", []byte{1, 1, 1}, []byte{0x06, 1}},
+		{"alternating", []byte{0, 1, 0}, []byte{0x02, 0, 0x02, 1, 0x02, 0}},
+		{"long run", bytes.Repeat([]byte{1}, 100), []byte{0xc8, 0x01, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeLevels(tt.levels); !bytes.Equal(got, tt.want) {
+				t.Errorf("encodeLevels() = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package export
+
+import "encoding/binary"
+
+// Types of the Thrift compact protocol, the encoding of the Parquet metadata.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol, see
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md.
+// Fields must be written in increasing id order.
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // Id of the last field written in each open struct
+}
+
+// begin starts a struct, the top level one or an element of a list.
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+// end ends the innermost struct.
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// field writes a field header.
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+// structField starts a struct field, ended with end.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.stringValue(v)
+}
+
+// list writes the header of a list field, followed by its n elements.
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+	} else {
+		t.buf = append(t.buf, 0xf0|typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+// i32Value writes an i32 list element.
+func (t *thriftWriter) i32Value(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+// stringValue writes a string list element.
+func (t *thriftWriter) stringValue(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
@@ -0,0 +1,211 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// thriftReader decodes Thrift structs written with the compact protocol. It is
+// written from the spec rather than from thriftWriter, so the tests check the
+// writer against the format.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+var errThrift = errors.New("invalid thrift")
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.buf) {
+		panic(errThrift)
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic(errThrift)
+	}
+	r.pos += n
+	return v
+}
+
+// zigzag reads a zigzag encoded varint, the encoding of i16, i32 and i64.
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// readStruct reads a struct into a map from field id to value. Integers are
+// int64, binaries string, lists []any and structs map[int16]any.
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		typ := header & 0x0f
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		switch typ {
+		case thriftTrue:
+			fields[id] = true
+		case thriftFalse:
+			fields[id] = false
+		default:
+			fields[id] = r.value(typ)
+		}
+		last = id
+	}
+}
+
+// value reads a value of a type, as a list element or a field that is not a bool.
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue, thriftFalse: // A bool list element is a byte
+		return r.byte() == thriftTrue
+	case 3: // byte
+		return int64(int8(r.byte()))
+	case 4, thriftI32, thriftI64: // i16, i32, i64
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.uvarint())
+		if r.pos+n > len(r.buf) {
+			panic(errThrift)
+		}
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.byte()
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(errThrift)
+}
+
+// decodeThrift decodes a struct at the start of buf, and returns its size.
+func decodeThrift(buf []byte) (fields map[int16]any, size int, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errThrift
+		}
+	}()
+	r := &thriftReader{buf: buf}
+	fields = r.readStruct()
+	return fields, r.pos, nil
+}
+
+func TestThriftWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *thriftWriter)
+		want  []byte
+	}{
+		{
+			"empty struct",
+			func(t *thriftWriter) {},
+			[]byte{0x00},
+		},
+		{
+			"i32 with a short field header",
+			func(t *thriftWriter) { t.i32(1, 5) },
+			[]byte{0x15, 0x0a, 0x00},
+		},
+		{
+			"negative i32",
+			func(t *thriftWriter) { t.i32(2, -3) },
+			[]byte{0x25, 0x05, 0x00},
+		},
+		{
+			"i64 with a long field header",
+			func(t *thriftWriter) { t.i32(1, 0); t.i64(20, -1) },
+			[]byte{0x15, 0x00, 0x06, 0x28, 0x01, 0x00},
+		},
+		{
+			"large i64",
+			func(t *thriftWriter) { t.i64(1, 1<<40) },
+			[]byte{0x16, 0x80, 0x80, 0x80, 0x80, 0x80, 0x40, 0x00},
+		},
+		{
+			"bools are in the field header",
+			func(t *thriftWriter) { t.bool(1, true); t.bool(2, false) },
+			[]byte{0x11, 0x12, 0x00},
+		},
+		{
+			"string",
+			func(t *thriftWriter) { t.string(4, "ab") },
+			[]byte{0x48, 0x02, 'a', 'b', 0x00},
+		},
+		{
+			"short list",
+			func(t *thriftWriter) { t.list(2, thriftI32, 2); t.i32Value(0); t.i32Value(3) },
+			[]byte{0x29, 0x25, 0x00, 0x06, 0x00},
+		},
+		{
+			"long list",
+			func(t *thriftWriter) {
+				t.list(1, thriftBinary, 15)
+				for range 15 {
+					t.stringValue("")
+				}
+			},
+			append([]byte{0x19, 0xf8, 0x0f}, append(make([]byte, 15), 0x00)...),
+		},
+		{
+			"nested struct keeps the field ids apart",
+			func(t *thriftWriter) {
+				t.i32(3, 1)
+				t.structField(4)
+				t.i32(1, 1)
+				t.end()
+				t.i32(5, 1)
+			},
+			[]byte{0x35, 0x02, 0x1c, 0x15, 0x02, 0x00, 0x15, 0x02, 0x00},
+		},
+		{
+			"list of structs",
+			func(t *thriftWriter) {
+				t.list(1, thriftStruct, 2)
+				t.begin()
+				t.i32(2, 1)
+				t.end()
+				t.begin()
+				t.end()
+			},
+			[]byte{0x19, 0x2c, 0x25, 0x02, 0x00, 0x00, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w thriftWriter
+			w.begin()
+			tt.write(&w)
+			w.end()
+			if !bytes.Equal(w.buf, tt.want) {
+				t.Errorf("thriftWriter wrote % x, want % x", w.buf, tt.want)
+			}
+			if _, size, err := decodeThrift(w.buf); err != nil || size != len(w.buf) {
+				t.Errorf("decodeThrift() read %d of %d bytes, error = %v", size, len(w.buf), err)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sync"
+	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -40,12 +42,15 @@ func GetLogger() zerolog.Logger {
 
 			logLevel = levelFromEnv
 		}
-		// Configure an auto rotating file for storing JSON-formatted records
-		fileLogger := &lumberjack.Logger{
-			Filename:   "logs/crawler.log",
-			MaxSize:    10, // Max size in megabytes before the file is rotated
-			MaxBackups: 2,  // Max number of old log files to keep
-			MaxAge:     14, // Max number of days to retain the log files
+		// The JSON log file is logs/crawler.log unless LOG_FILE is set, and an
+		// empty LOG_FILE turns it off. Tests only log to the console, so they
+		// do not write files into the source tree.
+		logFile := "logs/crawler.log"
+		if testing.Testing() {
+			logFile = ""
+		}
+		if fileEnv, ok := os.LookupEnv("LOG_FILE"); ok {
+			logFile = fileEnv
 		}
 
 		// Configure console logging in a human-friendly and colorized format
@@ -60,7 +65,17 @@ func GetLogger() zerolog.Logger {
 		}
 
 		// Allows logging to multiple destinations at once
-		multiLevelOutput := zerolog.MultiLevelWriter(consoleLogger, fileLogger)
+		writers := []io.Writer{consoleLogger}
+		if logFile != "" {
+			// Configure an auto rotating file for storing JSON-formatted records
+			writers = append(writers, &lumberjack.Logger{
+				Filename:   logFile,
+				MaxSize:    10, // Max size in megabytes before the file is rotated
+				MaxBackups: 2,  // Max number of old log files to keep
+				MaxAge:     14, // Max number of days to retain the log files
+			})
+		}
+		multiLevelOutput := zerolog.MultiLevelWriter(writers...)
 
 		// Create a global logger instance
 		loggerInstance = zerolog.New(multiLevelOutput).
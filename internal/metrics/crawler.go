@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Crawlers, the values of the crawler label.
+const (
+	CrawlerDomain   = "domain"
+	CrawlerCampaign = "campaign"
+	CrawlerRecheck  = "recheck"
+)
+
+var (
+	checks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "checks_total",
+		Help:      "Domain checks, by crawler and result (success or failed).",
+	}, []string{"crawler", "result"})
+
+	checkDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "check_duration_seconds",
+		Help:      "Time to check a domain and store the result, by crawler.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"crawler"})
+
+	checkOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "check_outcomes_total",
+		Help:      "Outcomes of the successful checks, by crawler, check (base_domain, www_domain, nameserver or mx_record) and status.",
+	}, []string{"crawler", "check", "status"})
+
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "queue_depth",
+		Help:      "Domains waiting to be checked: the rest of the current batch, or the queued rechecks.",
+	}, []string{"crawler"})
+
+	workers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "workers",
+		Help:      "Workers checking domains concurrently, by crawler.",
+	}, []string{"crawler"})
+
+	passDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "last_pass_duration_seconds",
+		Help:      "Duration of the last finished pass over all domains, by crawler.",
+	}, []string{"crawler"})
+
+	passFinished = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "last_pass_timestamp_seconds",
+		Help:      "Unix time the last pass over all domains finished, by crawler.",
+	}, []string{"crawler"})
+)
+
+// Check records a domain check of a crawler, and the time it took.
+func Check(crawler string, success bool, duration time.Duration) {
+	result := "success"
+	if !success {
+		result = "failed"
+	}
+	checks.WithLabelValues(crawler, result).Inc()
+	checkDuration.WithLabelValues(crawler).Observe(duration.Seconds())
+}
+
+// CheckOutcome records the status of one of the checks of a domain, e.g.
+// supported for base_domain.
+func CheckOutcome(crawler, check, status string) {
+	checkOutcomes.WithLabelValues(crawler, check, status).Inc()
+}
+
+// SetQueueDepth sets the number of domains waiting to be checked by a crawler.
+func SetQueueDepth(crawler string, depth int) {
+	queueDepth.WithLabelValues(crawler).Set(float64(depth))
+}
+
+// AddQueueDepth changes the number of domains waiting to be checked by a crawler.
+func AddQueueDepth(crawler string, delta int) {
+	queueDepth.WithLabelValues(crawler).Add(float64(delta))
+}
+
+// SetWorkers sets the number of workers of a crawler.
+func SetWorkers(crawler string, n int) {
+	workers.WithLabelValues(crawler).Set(float64(n))
+}
+
+// PassFinished records a finished pass of a crawler over all its domains.
+func PassFinished(crawler string, duration time.Duration) {
+	passDuration.WithLabelValues(crawler).Set(duration.Seconds())
+	passFinished.WithLabelValues(crawler).SetToCurrentTime()
+}
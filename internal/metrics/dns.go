@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dnsQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dns",
+		Name:      "queries_total",
+		Help:      "DNS queries sent, by upstream nameserver and result (ok, servfail, timeout or error).",
+	}, []string{"nameserver", "result"})
+
+	dnsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "dns",
+		Name:      "query_duration_seconds",
+		Help:      "Round trip time of the answered DNS queries, by upstream nameserver.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"nameserver"})
+)
+
+// DNSQuery records a DNS query sent to a nameserver, with its round trip time
+// if it was answered.
+func DNSQuery(nameserver string, servFail bool, rtt time.Duration, err error) {
+	result := "ok"
+	switch {
+	case err != nil:
+		result = "error"
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			result = "timeout"
+		}
+	case servFail:
+		result = "servfail"
+	}
+	dnsQueries.WithLabelValues(nameserver, result).Inc()
+	if err == nil {
+		dnsDuration.WithLabelValues(nameserver).Observe(rtt.Seconds())
+	}
+}
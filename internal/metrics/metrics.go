@@ -0,0 +1,58 @@
+// Package metrics has the Prometheus metrics of the API server and the
+// crawlers, and serves them for scraping.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"whynoipv6/internal/buildinfo"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of all metrics.
+const namespace = "whynoipv6"
+
+// buildInfo is always 1, with the build of the binary in its labels.
+var buildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "build_info",
+	Help:      "Build of the running binary, always 1.",
+}, []string{"version", "commit", "goversion"})
+
+func init() {
+	info := buildinfo.Read()
+	buildInfo.WithLabelValues(info.Version, info.Commit, info.GoVersion).Set(1)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve serves the metrics on /metrics of addr until the context is cancelled.
+// It is used by the processes that do not serve HTTP otherwise, like the crawlers.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the statistics of a database pool when it is scraped.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+	acquireSeconds   *prometheus.Desc
+}
+
+// RegisterPool exports the statistics of the database pool of the process.
+func RegisterPool(pool *pgxpool.Pool) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	prometheus.MustRegister(&poolCollector{
+		pool:             pool,
+		acquiredConns:    desc("acquired_connections", "Connections in use."),
+		idleConns:        desc("idle_connections", "Idle connections."),
+		totalConns:       desc("connections", "Open connections, in use, idle or being opened."),
+		maxConns:         desc("max_connections", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires that were cancelled while waiting."),
+		acquireSeconds:   desc("acquire_seconds_total", "Time spent acquiring connections."),
+	})
+}
+
+// Describe implements prometheus.Collector.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.acquireSeconds, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,184 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const AddAPIKeyUsage = `-- name: AddAPIKeyUsage :exec
+UPDATE api_key
+SET requests  = requests + $1,
+    last_used = GREATEST(last_used, $2::timestamptz)
+WHERE id = $3
+`
+
+type AddAPIKeyUsageParams struct {
+	Requests int64
+	LastUsed time.Time
+	ID       int64
+}
+
+// Adds requests to the usage counter of a key.
+func (q *Queries) AddAPIKeyUsage(ctx context.Context, arg AddAPIKeyUsageParams) error {
+	_, err := q.db.Exec(ctx, AddAPIKeyUsage, arg.Requests, arg.LastUsed, arg.ID)
+	return err
+}
+
+const CreateAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_key(name, prefix, key_hash, rate_limit, admin)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, prefix, key_hash, rate_limit, requests, last_used, created_at, revoked_at, admin
+`
+
+type CreateAPIKeyParams struct {
+	Name      string
+	Prefix    string
+	KeyHash   string
+	RateLimit sql.NullInt32
+	Admin     bool
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, CreateAPIKey,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.RateLimit,
+		arg.Admin,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.RateLimit,
+		&i.Requests,
+		&i.LastUsed,
+		&i.CreatedAt,
+		&i.RevokedAt,
+		&i.Admin,
+	)
+	return i, err
+}
+
+const GetAPIKey = `-- name: GetAPIKey :one
+SELECT id, name, prefix, key_hash, rate_limit, requests, last_used, created_at, revoked_at, admin
+FROM api_key
+WHERE id = $1
+  AND revoked_at IS NULL
+`
+
+// Returns the key with the given id, unless it has been revoked.
+func (q *Queries) GetAPIKey(ctx context.Context, id int64) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, GetAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.RateLimit,
+		&i.Requests,
+		&i.LastUsed,
+		&i.CreatedAt,
+		&i.RevokedAt,
+		&i.Admin,
+	)
+	return i, err
+}
+
+const GetAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT id, name, prefix, key_hash, rate_limit, requests, last_used, created_at, revoked_at, admin
+FROM api_key
+WHERE key_hash = $1
+  AND revoked_at IS NULL
+`
+
+// Returns the key with the given hash, unless it has been revoked.
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, GetAPIKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.RateLimit,
+		&i.Requests,
+		&i.LastUsed,
+		&i.CreatedAt,
+		&i.RevokedAt,
+		&i.Admin,
+	)
+	return i, err
+}
+
+const ListAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, name, prefix, key_hash, rate_limit, requests, last_used, created_at, revoked_at, admin
+FROM api_key
+ORDER BY id
+`
+
+func (q *Queries) ListAPIKeys(ctx context.Context) ([]ApiKey, error) {
+	rows, err := q.db.Query(ctx, ListAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Prefix,
+			&i.KeyHash,
+			&i.RateLimit,
+			&i.Requests,
+			&i.LastUsed,
+			&i.CreatedAt,
+			&i.RevokedAt,
+			&i.Admin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const RevokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_key
+SET revoked_at = NOW()
+WHERE id = $1
+  AND revoked_at IS NULL
+RETURNING id, name, prefix, key_hash, rate_limit, requests, last_used, created_at, revoked_at, admin
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, id int64) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, RevokeAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.RateLimit,
+		&i.Requests,
+		&i.LastUsed,
+		&i.CreatedAt,
+		&i.RevokedAt,
+		&i.Admin,
+	)
+	return i, err
+}
@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const AsnByIPv4 = `-- name: AsnByIPv4 :many
@@ -142,6 +143,140 @@ func (q *Queries) GetASByNumber(ctx context.Context, number int32) (Asn, error)
 	return i, err
 }
 
+const ListASNHistory = `-- name: ListASNHistory :many
+SELECT DISTINCT ON (period) date_trunc($1::TEXT, day::TIMESTAMP)::DATE AS period,
+       day,
+       count_v4,
+       count_v6,
+       percent_v4,
+       percent_v6
+FROM asn_history
+WHERE asn_id = $2
+  AND day >= $3
+  AND day <= $4
+ORDER BY period, day DESC
+`
+
+type ListASNHistoryParams struct {
+	Step     string
+	AsnID    int64
+	StartDay time.Time
+	EndDay   time.Time
+}
+
+type ListASNHistoryRow struct {
+	Period    time.Time
+	Day       time.Time
+	CountV4   int32
+	CountV6   int32
+	PercentV4 float64
+	PercentV6 float64
+}
+
+// Returns the last snapshot of each period, e.g. each month, between the start and end day.
+func (q *Queries) ListASNHistory(ctx context.Context, arg ListASNHistoryParams) ([]ListASNHistoryRow, error) {
+	rows, err := q.db.Query(ctx, ListASNHistory,
+		arg.Step,
+		arg.AsnID,
+		arg.StartDay,
+		arg.EndDay,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListASNHistoryRow{}
+	for rows.Next() {
+		var i ListASNHistoryRow
+		if err := rows.Scan(
+			&i.Period,
+			&i.Day,
+			&i.CountV4,
+			&i.CountV6,
+			&i.PercentV4,
+			&i.PercentV6,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListASNStats = `-- name: ListASNStats :many
+SELECT id, number, name, count_v4, count_v6, percent_v4, percent_v6
+FROM asn
+WHERE count_v4 IS NOT NULL AND id != 1
+ORDER BY number
+`
+
+// Lists the statistics of every ASN with domains, ordered by AS number.
+func (q *Queries) ListASNStats(ctx context.Context) ([]Asn, error) {
+	rows, err := q.db.Query(ctx, ListASNStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Asn{}
+	for rows.Next() {
+		var i Asn
+		if err := rows.Scan(
+			&i.ID,
+			&i.Number,
+			&i.Name,
+			&i.CountV4,
+			&i.CountV6,
+			&i.PercentV4,
+			&i.PercentV6,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListASNsByID = `-- name: ListASNsByID :many
+SELECT id, number, name, count_v4, count_v6, percent_v4, percent_v6
+FROM asn
+WHERE id = ANY($1::BIGINT[])
+`
+
+// Lists the ASNs with the given IDs, in no particular order.
+func (q *Queries) ListASNsByID(ctx context.Context, ids []int64) ([]Asn, error) {
+	rows, err := q.db.Query(ctx, ListASNsByID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Asn{}
+	for rows.Next() {
+		var i Asn
+		if err := rows.Scan(
+			&i.ID,
+			&i.Number,
+			&i.Name,
+			&i.CountV4,
+			&i.CountV6,
+			&i.PercentV4,
+			&i.PercentV6,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const SearchAsName = `-- name: SearchAsName :many
 SELECT id, number, name, count_v4, count_v6, percent_v4, percent_v6
 FROM asn
@@ -213,3 +348,21 @@ func (q *Queries) SearchAsNumber(ctx context.Context, number int32) ([]Asn, erro
 	}
 	return items, nil
 }
+
+const SnapshotASNHistory = `-- name: SnapshotASNHistory :exec
+INSERT INTO asn_history (asn_id, day, count_v4, count_v6, percent_v4, percent_v6)
+SELECT id, CURRENT_DATE, count_v4, COALESCE(count_v6, 0), COALESCE(percent_v4, 0), COALESCE(percent_v6, 0)
+FROM asn
+WHERE count_v4 IS NOT NULL
+ON CONFLICT (asn_id, day) DO UPDATE
+SET count_v4   = EXCLUDED.count_v4,
+    count_v6   = EXCLUDED.count_v6,
+    percent_v4 = EXCLUDED.percent_v4,
+    percent_v6 = EXCLUDED.percent_v6
+`
+
+// Stores today's statistics of every ASN with domains, replacing an earlier snapshot of the same day.
+func (q *Queries) SnapshotASNHistory(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, SnapshotASNHistory)
+	return err
+}
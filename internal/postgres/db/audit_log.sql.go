@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: audit_log.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgtype"
+)
+
+const CreateAuditLog = `-- name: CreateAuditLog :one
+INSERT INTO audit_log(api_key_id, actor, action, target, details)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, ts, api_key_id, actor, action, target, details
+`
+
+type CreateAuditLogParams struct {
+	ApiKeyID sql.NullInt64
+	Actor    string
+	Action   string
+	Target   string
+	Details  pgtype.JSONB
+}
+
+func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	row := q.db.QueryRow(ctx, CreateAuditLog,
+		arg.ApiKeyID,
+		arg.Actor,
+		arg.Action,
+		arg.Target,
+		arg.Details,
+	)
+	var i AuditLog
+	err := row.Scan(
+		&i.ID,
+		&i.Ts,
+		&i.ApiKeyID,
+		&i.Actor,
+		&i.Action,
+		&i.Target,
+		&i.Details,
+	)
+	return i, err
+}
+
+const ListAuditLog = `-- name: ListAuditLog :many
+SELECT id, ts, api_key_id, actor, action, target, details
+FROM audit_log
+WHERE ($1::text = '' OR target = $1::text)
+ORDER BY id DESC
+LIMIT $3 OFFSET $2
+`
+
+type ListAuditLogParams struct {
+	Target string
+	Offset int64
+	Limit  int64
+}
+
+// Lists the newest entries first, optionally only the ones for a target.
+func (q *Queries) ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, ListAuditLog, arg.Target, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.ApiKeyID,
+			&i.Actor,
+			&i.Action,
+			&i.Target,
+			&i.Details,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -14,9 +14,35 @@ import (
 	"github.com/jackc/pgtype"
 )
 
+const CountCampaignDomain = `-- name: CountCampaignDomain :one
+SELECT count(*)
+FROM campaign_domain
+WHERE campaign_id = $1
+`
+
+func (q *Queries) CountCampaignDomain(ctx context.Context, campaignID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, CountCampaignDomain, campaignID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountCampaignDomainsByName = `-- name: CountCampaignDomainsByName :one
+SELECT count(*)
+FROM campaign_domain
+WHERE site LIKE '%' || $1 || '%'
+`
+
+func (q *Queries) CountCampaignDomainsByName(ctx context.Context, dollar_1 sql.NullString) (int64, error) {
+	row := q.db.QueryRow(ctx, CountCampaignDomainsByName, dollar_1)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const CrawlCampaignDomain = `-- name: CrawlCampaignDomain :many
 SELECT id, campaign_id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
-FROM campaign_domain
+FROM campaign_domain_check_view
 ORDER BY id
 LIMIT $1 OFFSET $2
 `
@@ -26,15 +52,15 @@ type CrawlCampaignDomainParams struct {
 	Offset int64
 }
 
-func (q *Queries) CrawlCampaignDomain(ctx context.Context, arg CrawlCampaignDomainParams) ([]CampaignDomain, error) {
+func (q *Queries) CrawlCampaignDomain(ctx context.Context, arg CrawlCampaignDomainParams) ([]CampaignDomainCheckView, error) {
 	rows, err := q.db.Query(ctx, CrawlCampaignDomain, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []CampaignDomain{}
+	items := []CampaignDomainCheckView{}
 	for rows.Next() {
-		var i CampaignDomain
+		var i CampaignDomainCheckView
 		if err := rows.Scan(
 			&i.ID,
 			&i.CampaignID,
@@ -161,7 +187,7 @@ SELECT campaign.id, campaign.created_at, campaign.uuid, campaign.name, campaign.
                    END
            )                     AS v6_ready_count
 FROM campaign
-         LEFT JOIN campaign_domain ON campaign.uuid = campaign_domain.campaign_id
+         LEFT JOIN campaign_domain_check_view campaign_domain ON campaign.uuid = campaign_domain.campaign_id
 WHERE campaign.uuid = $1
 GROUP BY campaign.id
 LIMIT 1
@@ -232,7 +258,7 @@ func (q *Queries) GetCampaignDomainLog(ctx context.Context, domainID int64) ([]G
 
 const GetCampaignDomainsByName = `-- name: GetCampaignDomainsByName :many
 SELECT id, campaign_id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
-FROM campaign_domain
+FROM campaign_domain_check_view
 WHERE site LIKE '%' || $1 || '%'
 LIMIT $2 OFFSET $3
 `
@@ -244,15 +270,15 @@ type GetCampaignDomainsByNameParams struct {
 }
 
 // Used for searching campaign domains by site name.
-func (q *Queries) GetCampaignDomainsByName(ctx context.Context, arg GetCampaignDomainsByNameParams) ([]CampaignDomain, error) {
+func (q *Queries) GetCampaignDomainsByName(ctx context.Context, arg GetCampaignDomainsByNameParams) ([]CampaignDomainCheckView, error) {
 	rows, err := q.db.Query(ctx, GetCampaignDomainsByName, arg.Column1, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []CampaignDomain{}
+	items := []CampaignDomainCheckView{}
 	for rows.Next() {
-		var i CampaignDomain
+		var i CampaignDomainCheckView
 		if err := rows.Scan(
 			&i.ID,
 			&i.CampaignID,
@@ -313,7 +339,7 @@ SELECT campaign.id, campaign.created_at, campaign.uuid, campaign.name, campaign.
                    END
            )                     AS v6_ready_count
 FROM campaign
-         LEFT JOIN campaign_domain ON campaign.uuid = campaign_domain.campaign_id AND campaign.disabled = FALSE
+         LEFT JOIN campaign_domain_check_view campaign_domain ON campaign.uuid = campaign_domain.campaign_id AND campaign.disabled = FALSE
 GROUP BY campaign.id
 ORDER BY campaign.id
 `
@@ -360,14 +386,14 @@ func (q *Queries) ListCampaign(ctx context.Context) ([]ListCampaignRow, error) {
 }
 
 const ListCampaignDomain = `-- name: ListCampaignDomain :many
-SELECT campaign_domain.id, campaign_domain.campaign_id, campaign_domain.site, campaign_domain.base_domain, campaign_domain.www_domain, campaign_domain.nameserver, campaign_domain.mx_record, campaign_domain.v6_only, campaign_domain.asn_id, campaign_domain.country_id, campaign_domain.disabled, campaign_domain.ts_base_domain, campaign_domain.ts_www_domain, campaign_domain.ts_nameserver, campaign_domain.ts_mx_record, campaign_domain.ts_v6_only, campaign_domain.ts_check, campaign_domain.ts_updated,
+SELECT campaign_domain_check_view.id, campaign_domain_check_view.campaign_id, campaign_domain_check_view.site, campaign_domain_check_view.base_domain, campaign_domain_check_view.www_domain, campaign_domain_check_view.nameserver, campaign_domain_check_view.mx_record, campaign_domain_check_view.v6_only, campaign_domain_check_view.asn_id, campaign_domain_check_view.country_id, campaign_domain_check_view.disabled, campaign_domain_check_view.ts_base_domain, campaign_domain_check_view.ts_www_domain, campaign_domain_check_view.ts_nameserver, campaign_domain_check_view.ts_mx_record, campaign_domain_check_view.ts_v6_only, campaign_domain_check_view.ts_check, campaign_domain_check_view.ts_updated,
        asn.name as asname,
        country.country_name
-FROM campaign_domain
-         LEFT JOIN asn ON campaign_domain.asn_id = asn.id
-         LEFT JOIN country ON campaign_domain.country_id = country.id
-WHERE campaign_domain.campaign_id = $1
-ORDER BY campaign_domain.id
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+ORDER BY campaign_domain_check_view.id
 LIMIT $2 OFFSET $3
 `
 
@@ -442,51 +468,121 @@ func (q *Queries) ListCampaignDomain(ctx context.Context, arg ListCampaignDomain
 	return items, nil
 }
 
-const StoreCampaignDomainLog = `-- name: StoreCampaignDomainLog :exec
-INSERT INTO campaign_domain_log(domain_id, data)
-VALUES ($1, $2)
-RETURNING id, domain_id, time, data
+const ListCampaignDomainAfter = `-- name: ListCampaignDomainAfter :many
+SELECT campaign_domain_check_view.id, campaign_domain_check_view.campaign_id, campaign_domain_check_view.site, campaign_domain_check_view.base_domain, campaign_domain_check_view.www_domain, campaign_domain_check_view.nameserver, campaign_domain_check_view.mx_record, campaign_domain_check_view.v6_only, campaign_domain_check_view.asn_id, campaign_domain_check_view.country_id, campaign_domain_check_view.disabled, campaign_domain_check_view.ts_base_domain, campaign_domain_check_view.ts_www_domain, campaign_domain_check_view.ts_nameserver, campaign_domain_check_view.ts_mx_record, campaign_domain_check_view.ts_v6_only, campaign_domain_check_view.ts_check, campaign_domain_check_view.ts_updated,
+       asn.name as asname,
+       country.country_name
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+  AND campaign_domain_check_view.id > $2
+ORDER BY campaign_domain_check_view.id
+LIMIT $3
 `
 
-type StoreCampaignDomainLogParams struct {
-	DomainID int64
-	Data     pgtype.JSONB
+type ListCampaignDomainAfterParams struct {
+	CampaignID uuid.UUID
+	ID         int64
+	Limit      int64
 }
 
-func (q *Queries) StoreCampaignDomainLog(ctx context.Context, arg StoreCampaignDomainLogParams) error {
-	_, err := q.db.Exec(ctx, StoreCampaignDomainLog, arg.DomainID, arg.Data)
-	return err
+type ListCampaignDomainAfterRow struct {
+	ID           int64
+	CampaignID   uuid.UUID
+	Site         string
+	BaseDomain   string
+	WwwDomain    string
+	Nameserver   string
+	MxRecord     string
+	V6Only       string
+	AsnID        sql.NullInt64
+	CountryID    sql.NullInt64
+	Disabled     bool
+	TsBaseDomain sql.NullTime
+	TsWwwDomain  sql.NullTime
+	TsNameserver sql.NullTime
+	TsMxRecord   sql.NullTime
+	TsV6Only     sql.NullTime
+	TsCheck      sql.NullTime
+	TsUpdated    sql.NullTime
+	Asname       sql.NullString
+	CountryName  sql.NullString
 }
 
-const UpdateCampaignDomain = `-- name: UpdateCampaignDomain :exec
-UPDATE
-    campaign_domain
-SET base_domain    = $3,
-    www_domain     = $4,
-    nameserver     = $5,
-    mx_record      = $6,
-    v6_only        = $7,
-    ts_base_domain = $8,
-    ts_www_domain  = $9,
-    ts_nameserver  = $10,
-    ts_mx_record   = $11,
-    ts_v6_only     = $12,
-    ts_check       = $13,
-    ts_updated     = $14,
-    asn_id         = $15,
-    country_id     = $16
-WHERE site = $1
-  AND campaign_id = $2
+// Keyset pagination on id, returns the campaign domains after the given id.
+func (q *Queries) ListCampaignDomainAfter(ctx context.Context, arg ListCampaignDomainAfterParams) ([]ListCampaignDomainAfterRow, error) {
+	rows, err := q.db.Query(ctx, ListCampaignDomainAfter, arg.CampaignID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCampaignDomainAfterRow{}
+	for rows.Next() {
+		var i ListCampaignDomainAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CampaignID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCampaignDomainBefore = `-- name: ListCampaignDomainBefore :many
+SELECT campaign_domain_check_view.id, campaign_domain_check_view.campaign_id, campaign_domain_check_view.site, campaign_domain_check_view.base_domain, campaign_domain_check_view.www_domain, campaign_domain_check_view.nameserver, campaign_domain_check_view.mx_record, campaign_domain_check_view.v6_only, campaign_domain_check_view.asn_id, campaign_domain_check_view.country_id, campaign_domain_check_view.disabled, campaign_domain_check_view.ts_base_domain, campaign_domain_check_view.ts_www_domain, campaign_domain_check_view.ts_nameserver, campaign_domain_check_view.ts_mx_record, campaign_domain_check_view.ts_v6_only, campaign_domain_check_view.ts_check, campaign_domain_check_view.ts_updated,
+       asn.name as asname,
+       country.country_name
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
+WHERE campaign_domain_check_view.campaign_id = $1
+  AND campaign_domain_check_view.id < $2
+ORDER BY campaign_domain_check_view.id DESC
+LIMIT $3
 `
 
-type UpdateCampaignDomainParams struct {
-	Site         string
+type ListCampaignDomainBeforeParams struct {
+	CampaignID uuid.UUID
+	ID         int64
+	Limit      int64
+}
+
+type ListCampaignDomainBeforeRow struct {
+	ID           int64
 	CampaignID   uuid.UUID
+	Site         string
 	BaseDomain   string
 	WwwDomain    string
 	Nameserver   string
 	MxRecord     string
 	V6Only       string
+	AsnID        sql.NullInt64
+	CountryID    sql.NullInt64
+	Disabled     bool
 	TsBaseDomain sql.NullTime
 	TsWwwDomain  sql.NullTime
 	TsNameserver sql.NullTime
@@ -494,39 +590,103 @@ type UpdateCampaignDomainParams struct {
 	TsV6Only     sql.NullTime
 	TsCheck      sql.NullTime
 	TsUpdated    sql.NullTime
-	AsnID        sql.NullInt64
-	CountryID    sql.NullInt64
+	Asname       sql.NullString
+	CountryName  sql.NullString
 }
 
-func (q *Queries) UpdateCampaignDomain(ctx context.Context, arg UpdateCampaignDomainParams) error {
-	_, err := q.db.Exec(ctx, UpdateCampaignDomain,
-		arg.Site,
-		arg.CampaignID,
-		arg.BaseDomain,
-		arg.WwwDomain,
-		arg.Nameserver,
-		arg.MxRecord,
-		arg.V6Only,
-		arg.TsBaseDomain,
-		arg.TsWwwDomain,
-		arg.TsNameserver,
-		arg.TsMxRecord,
-		arg.TsV6Only,
-		arg.TsCheck,
-		arg.TsUpdated,
-		arg.AsnID,
-		arg.CountryID,
-	)
+// Keyset pagination on id, returns the campaign domains before the given id, nearest first.
+func (q *Queries) ListCampaignDomainBefore(ctx context.Context, arg ListCampaignDomainBeforeParams) ([]ListCampaignDomainBeforeRow, error) {
+	rows, err := q.db.Query(ctx, ListCampaignDomainBefore, arg.CampaignID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCampaignDomainBeforeRow{}
+	for rows.Next() {
+		var i ListCampaignDomainBeforeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CampaignID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const StoreCampaignDomainLog = `-- name: StoreCampaignDomainLog :exec
+INSERT INTO campaign_domain_log(domain_id, data)
+VALUES ($1, $2)
+RETURNING id, domain_id, time, data
+`
+
+type StoreCampaignDomainLogParams struct {
+	DomainID int64
+	Data     pgtype.JSONB
+}
+
+func (q *Queries) StoreCampaignDomainLog(ctx context.Context, arg StoreCampaignDomainLogParams) error {
+	_, err := q.db.Exec(ctx, StoreCampaignDomainLog, arg.DomainID, arg.Data)
 	return err
 }
 
+const UpdateCampaign = `-- name: UpdateCampaign :one
+UPDATE campaign
+SET name        = $2,
+    description = $3
+WHERE uuid = $1
+RETURNING id, created_at, uuid, name, description, disabled
+`
+
+type UpdateCampaignParams struct {
+	Uuid        uuid.UUID
+	Name        string
+	Description string
+}
+
+func (q *Queries) UpdateCampaign(ctx context.Context, arg UpdateCampaignParams) (Campaign, error) {
+	row := q.db.QueryRow(ctx, UpdateCampaign, arg.Uuid, arg.Name, arg.Description)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Uuid,
+		&i.Name,
+		&i.Description,
+		&i.Disabled,
+	)
+	return i, err
+}
+
 const ViewCampaignDomain = `-- name: ViewCampaignDomain :one
-SELECT campaign_domain.id, campaign_domain.campaign_id, campaign_domain.site, campaign_domain.base_domain, campaign_domain.www_domain, campaign_domain.nameserver, campaign_domain.mx_record, campaign_domain.v6_only, campaign_domain.asn_id, campaign_domain.country_id, campaign_domain.disabled, campaign_domain.ts_base_domain, campaign_domain.ts_www_domain, campaign_domain.ts_nameserver, campaign_domain.ts_mx_record, campaign_domain.ts_v6_only, campaign_domain.ts_check, campaign_domain.ts_updated,
+SELECT campaign_domain_check_view.id, campaign_domain_check_view.campaign_id, campaign_domain_check_view.site, campaign_domain_check_view.base_domain, campaign_domain_check_view.www_domain, campaign_domain_check_view.nameserver, campaign_domain_check_view.mx_record, campaign_domain_check_view.v6_only, campaign_domain_check_view.asn_id, campaign_domain_check_view.country_id, campaign_domain_check_view.disabled, campaign_domain_check_view.ts_base_domain, campaign_domain_check_view.ts_www_domain, campaign_domain_check_view.ts_nameserver, campaign_domain_check_view.ts_mx_record, campaign_domain_check_view.ts_v6_only, campaign_domain_check_view.ts_check, campaign_domain_check_view.ts_updated,
        asn.name as asname,
        country.country_name
-FROM campaign_domain
-         LEFT JOIN asn ON campaign_domain.asn_id = asn.id
-         LEFT JOIN country ON campaign_domain.country_id = country.id
+FROM campaign_domain_check_view
+         LEFT JOIN asn ON campaign_domain_check_view.asn_id = asn.id
+         LEFT JOIN country ON campaign_domain_check_view.country_id = country.id
 WHERE site = $1
   AND campaign_id = $2
 LIMIT 1
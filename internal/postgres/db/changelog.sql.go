@@ -7,10 +7,81 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const CountCampaignChangelog = `-- name: CountCampaignChangelog :one
+SELECT count(*)
+FROM changelog_campaign_view
+`
+
+func (q *Queries) CountCampaignChangelog(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, CountCampaignChangelog)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountChangelog = `-- name: CountChangelog :one
+SELECT count(*)
+FROM changelog_view
+`
+
+func (q *Queries) CountChangelog(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, CountChangelog)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountChangelogByCampaign = `-- name: CountChangelogByCampaign :one
+SELECT count(*)
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+`
+
+func (q *Queries) CountChangelogByCampaign(ctx context.Context, campaignID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, CountChangelogByCampaign, campaignID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountChangelogByCampaignDomain = `-- name: CountChangelogByCampaignDomain :one
+SELECT count(*)
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2
+`
+
+type CountChangelogByCampaignDomainParams struct {
+	CampaignID uuid.UUID
+	Site       string
+}
+
+func (q *Queries) CountChangelogByCampaignDomain(ctx context.Context, arg CountChangelogByCampaignDomainParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountChangelogByCampaignDomain, arg.CampaignID, arg.Site)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountChangelogByDomain = `-- name: CountChangelogByDomain :one
+SELECT count(*)
+FROM changelog_view
+WHERE site = $1
+`
+
+func (q *Queries) CountChangelogByDomain(ctx context.Context, site string) (int64, error) {
+	row := q.db.QueryRow(ctx, CountChangelogByDomain, site)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const CreateCampaignChangelog = `-- name: CreateCampaignChangelog :one
 INSERT INTO campaign_changelog (domain_id, campaign_id, message, ipv6_status)
 VALUES ($1, $2, $3, $4)
@@ -109,6 +180,104 @@ func (q *Queries) GetChangelogByCampaign(ctx context.Context, arg GetChangelogBy
 	return items, nil
 }
 
+const GetChangelogByCampaignAfter = `-- name: GetChangelogByCampaignAfter :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND (ts < $2 OR (ts = $2 AND id < $3))
+ORDER BY ts DESC, id DESC
+LIMIT $4
+`
+
+type GetChangelogByCampaignAfterParams struct {
+	CampaignID uuid.UUID
+	Ts         time.Time
+	ID         int64
+	Limit      int64
+}
+
+func (q *Queries) GetChangelogByCampaignAfter(ctx context.Context, arg GetChangelogByCampaignAfterParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByCampaignAfter,
+		arg.CampaignID,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetChangelogByCampaignBefore = `-- name: GetChangelogByCampaignBefore :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND (ts > $2 OR (ts = $2 AND id > $3))
+ORDER BY ts, id
+LIMIT $4
+`
+
+type GetChangelogByCampaignBeforeParams struct {
+	CampaignID uuid.UUID
+	Ts         time.Time
+	ID         int64
+	Limit      int64
+}
+
+func (q *Queries) GetChangelogByCampaignBefore(ctx context.Context, arg GetChangelogByCampaignBeforeParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByCampaignBefore,
+		arg.CampaignID,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetChangelogByCampaignDomain = `-- name: GetChangelogByCampaignDomain :many
 SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
 FROM changelog_campaign_view
@@ -157,6 +326,163 @@ func (q *Queries) GetChangelogByCampaignDomain(ctx context.Context, arg GetChang
 	return items, nil
 }
 
+const GetChangelogByCampaignDomainAfter = `-- name: GetChangelogByCampaignDomainAfter :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2
+  AND (ts < $3 OR (ts = $3 AND id < $4))
+ORDER BY ts DESC, id DESC
+LIMIT $5
+`
+
+type GetChangelogByCampaignDomainAfterParams struct {
+	CampaignID uuid.UUID
+	Site       string
+	Ts         time.Time
+	ID         int64
+	Limit      int64
+}
+
+func (q *Queries) GetChangelogByCampaignDomainAfter(ctx context.Context, arg GetChangelogByCampaignDomainAfterParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByCampaignDomainAfter,
+		arg.CampaignID,
+		arg.Site,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetChangelogByCampaignDomainBefore = `-- name: GetChangelogByCampaignDomainBefore :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE campaign_id = $1
+  AND site = $2
+  AND (ts > $3 OR (ts = $3 AND id > $4))
+ORDER BY ts, id
+LIMIT $5
+`
+
+type GetChangelogByCampaignDomainBeforeParams struct {
+	CampaignID uuid.UUID
+	Site       string
+	Ts         time.Time
+	ID         int64
+	Limit      int64
+}
+
+func (q *Queries) GetChangelogByCampaignDomainBefore(ctx context.Context, arg GetChangelogByCampaignDomainBeforeParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByCampaignDomainBefore,
+		arg.CampaignID,
+		arg.Site,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetChangelogByCountry = `-- name: GetChangelogByCountry :many
+SELECT changelog.id, changelog.ts, changelog.domain_id, changelog.message, changelog.ipv6_status,
+       domain.site
+FROM changelog
+         JOIN domain ON changelog.domain_id = domain.id
+         JOIN check_result ON domain.site = check_result.site
+WHERE check_result.country_id = $1
+ORDER BY changelog.id DESC
+LIMIT $2
+`
+
+type GetChangelogByCountryParams struct {
+	CountryID sql.NullInt64
+	Limit     int64
+}
+
+type GetChangelogByCountryRow struct {
+	ID         int64
+	Ts         time.Time
+	DomainID   int64
+	Message    string
+	Ipv6Status string
+	Site       string
+}
+
+// Newest first, returns the changelog entries of the domains in a country.
+func (q *Queries) GetChangelogByCountry(ctx context.Context, arg GetChangelogByCountryParams) ([]GetChangelogByCountryRow, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByCountry, arg.CountryID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetChangelogByCountryRow{}
+	for rows.Next() {
+		var i GetChangelogByCountryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetChangelogByDomain = `-- name: GetChangelogByDomain :many
 SELECT id, ts, domain_id, message, ipv6_status, site
 FROM changelog_view
@@ -197,31 +523,40 @@ func (q *Queries) GetChangelogByDomain(ctx context.Context, arg GetChangelogByDo
 	return items, nil
 }
 
-const ListCampaignChangelog = `-- name: ListCampaignChangelog :many
-SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
-FROM changelog_campaign_view
-LIMIT $1 OFFSET $2
+const GetChangelogByDomainAfter = `-- name: GetChangelogByDomainAfter :many
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM changelog_view
+WHERE site = $1
+  AND (ts < $2 OR (ts = $2 AND id < $3))
+ORDER BY ts DESC, id DESC
+LIMIT $4
 `
 
-type ListCampaignChangelogParams struct {
-	Limit  int64
-	Offset int64
+type GetChangelogByDomainAfterParams struct {
+	Site  string
+	Ts    time.Time
+	ID    int64
+	Limit int64
 }
 
-func (q *Queries) ListCampaignChangelog(ctx context.Context, arg ListCampaignChangelogParams) ([]ChangelogCampaignView, error) {
-	rows, err := q.db.Query(ctx, ListCampaignChangelog, arg.Limit, arg.Offset)
+func (q *Queries) GetChangelogByDomainAfter(ctx context.Context, arg GetChangelogByDomainAfterParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByDomainAfter,
+		arg.Site,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ChangelogCampaignView{}
+	items := []ChangelogView{}
 	for rows.Next() {
-		var i ChangelogCampaignView
+		var i ChangelogView
 		if err := rows.Scan(
 			&i.ID,
 			&i.Ts,
 			&i.DomainID,
-			&i.CampaignID,
 			&i.Message,
 			&i.Ipv6Status,
 			&i.Site,
@@ -236,19 +571,29 @@ func (q *Queries) ListCampaignChangelog(ctx context.Context, arg ListCampaignCha
 	return items, nil
 }
 
-const ListChangelog = `-- name: ListChangelog :many
+const GetChangelogByDomainBefore = `-- name: GetChangelogByDomainBefore :many
 SELECT id, ts, domain_id, message, ipv6_status, site
 FROM changelog_view
-LIMIT $1 OFFSET $2
+WHERE site = $1
+  AND (ts > $2 OR (ts = $2 AND id > $3))
+ORDER BY ts, id
+LIMIT $4
 `
 
-type ListChangelogParams struct {
-	Limit  int64
-	Offset int64
+type GetChangelogByDomainBeforeParams struct {
+	Site  string
+	Ts    time.Time
+	ID    int64
+	Limit int64
 }
 
-func (q *Queries) ListChangelog(ctx context.Context, arg ListChangelogParams) ([]ChangelogView, error) {
-	rows, err := q.db.Query(ctx, ListChangelog, arg.Limit, arg.Offset)
+func (q *Queries) GetChangelogByDomainBefore(ctx context.Context, arg GetChangelogByDomainBeforeParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, GetChangelogByDomainBefore,
+		arg.Site,
+		arg.Ts,
+		arg.ID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -273,3 +618,432 @@ func (q *Queries) ListChangelog(ctx context.Context, arg ListChangelogParams) ([
 	}
 	return items, nil
 }
+
+const LatestChangelogIDs = `-- name: LatestChangelogIDs :one
+SELECT (SELECT COALESCE(max(id), 0) FROM changelog)::BIGINT          AS changelog_id,
+       (SELECT COALESCE(max(id), 0) FROM campaign_changelog)::BIGINT AS campaign_changelog_id
+`
+
+type LatestChangelogIDsRow struct {
+	ChangelogID         int64
+	CampaignChangelogID int64
+}
+
+func (q *Queries) LatestChangelogIDs(ctx context.Context) (LatestChangelogIDsRow, error) {
+	row := q.db.QueryRow(ctx, LatestChangelogIDs)
+	var i LatestChangelogIDsRow
+	err := row.Scan(&i.ChangelogID, &i.CampaignChangelogID)
+	return i, err
+}
+
+const ListCampaignChangelog = `-- name: ListCampaignChangelog :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+LIMIT $1 OFFSET $2
+`
+
+type ListCampaignChangelogParams struct {
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) ListCampaignChangelog(ctx context.Context, arg ListCampaignChangelogParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, ListCampaignChangelog, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCampaignChangelogAfter = `-- name: ListCampaignChangelogAfter :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE ts < $1 OR (ts = $1 AND id < $2)
+ORDER BY ts DESC, id DESC
+LIMIT $3
+`
+
+type ListCampaignChangelogAfterParams struct {
+	Ts    time.Time
+	ID    int64
+	Limit int64
+}
+
+// Keyset pagination on (ts, id), newest first, returns the entries after the given position.
+func (q *Queries) ListCampaignChangelogAfter(ctx context.Context, arg ListCampaignChangelogAfterParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, ListCampaignChangelogAfter, arg.Ts, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCampaignChangelogBefore = `-- name: ListCampaignChangelogBefore :many
+SELECT id, ts, domain_id, campaign_id, message, ipv6_status, site
+FROM changelog_campaign_view
+WHERE ts > $1 OR (ts = $1 AND id > $2)
+ORDER BY ts, id
+LIMIT $3
+`
+
+type ListCampaignChangelogBeforeParams struct {
+	Ts    time.Time
+	ID    int64
+	Limit int64
+}
+
+// Keyset pagination on (ts, id), oldest first, returns the entries before the given position.
+func (q *Queries) ListCampaignChangelogBefore(ctx context.Context, arg ListCampaignChangelogBeforeParams) ([]ChangelogCampaignView, error) {
+	rows, err := q.db.Query(ctx, ListCampaignChangelogBefore, arg.Ts, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogCampaignView{}
+	for rows.Next() {
+		var i ChangelogCampaignView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCampaignChangelogSince = `-- name: ListCampaignChangelogSince :many
+SELECT campaign_changelog.id, campaign_changelog.ts, campaign_changelog.domain_id, campaign_changelog.campaign_id, campaign_changelog.message, campaign_changelog.ipv6_status,
+       campaign_domain.site,
+       country.country_code
+FROM campaign_changelog
+         JOIN campaign_domain ON campaign_changelog.domain_id = campaign_domain.id
+         JOIN check_result ON campaign_domain.site = check_result.site
+         LEFT JOIN country ON check_result.country_id = country.id
+WHERE campaign_changelog.id > $1
+  AND campaign_changelog.id <> ALL ($3::BIGINT[])
+ORDER BY campaign_changelog.id
+LIMIT $2
+`
+
+type ListCampaignChangelogSinceParams struct {
+	ID    int64
+	Limit int64
+	Skip  []int64
+}
+
+type ListCampaignChangelogSinceRow struct {
+	ID          int64
+	Ts          time.Time
+	DomainID    int64
+	CampaignID  uuid.UUID
+	Message     string
+	Ipv6Status  string
+	Site        string
+	CountryCode sql.NullString
+}
+
+// Oldest first, returns the entries written after the given id, except the skipped ids, with the country of the domain.
+func (q *Queries) ListCampaignChangelogSince(ctx context.Context, arg ListCampaignChangelogSinceParams) ([]ListCampaignChangelogSinceRow, error) {
+	rows, err := q.db.Query(ctx, ListCampaignChangelogSince, arg.ID, arg.Limit, arg.Skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCampaignChangelogSinceRow{}
+	for rows.Next() {
+		var i ListCampaignChangelogSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.CampaignID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+			&i.CountryCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListChangelog = `-- name: ListChangelog :many
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM changelog_view
+LIMIT $1 OFFSET $2
+`
+
+type ListChangelogParams struct {
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) ListChangelog(ctx context.Context, arg ListChangelogParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, ListChangelog, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogView{}
+	for rows.Next() {
+		var i ChangelogView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListChangelogAfter = `-- name: ListChangelogAfter :many
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM changelog_view
+WHERE ts < $1 OR (ts = $1 AND id < $2)
+ORDER BY ts DESC, id DESC
+LIMIT $3
+`
+
+type ListChangelogAfterParams struct {
+	Ts    time.Time
+	ID    int64
+	Limit int64
+}
+
+// Keyset pagination on (ts, id), newest first, returns the entries after the given position.
+func (q *Queries) ListChangelogAfter(ctx context.Context, arg ListChangelogAfterParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, ListChangelogAfter, arg.Ts, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogView{}
+	for rows.Next() {
+		var i ChangelogView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListChangelogBefore = `-- name: ListChangelogBefore :many
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM changelog_view
+WHERE ts > $1 OR (ts = $1 AND id > $2)
+ORDER BY ts, id
+LIMIT $3
+`
+
+type ListChangelogBeforeParams struct {
+	Ts    time.Time
+	ID    int64
+	Limit int64
+}
+
+// Keyset pagination on (ts, id), oldest first, returns the entries before the given position.
+func (q *Queries) ListChangelogBefore(ctx context.Context, arg ListChangelogBeforeParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, ListChangelogBefore, arg.Ts, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogView{}
+	for rows.Next() {
+		var i ChangelogView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListChangelogByDomainIDs = `-- name: ListChangelogByDomainIDs :many
+SELECT id, ts, domain_id, message, ipv6_status, site
+FROM (
+    SELECT changelog_view.id, changelog_view.ts, changelog_view.domain_id, changelog_view.message, changelog_view.ipv6_status, changelog_view.site,
+           row_number() OVER (PARTITION BY changelog_view.domain_id ORDER BY changelog_view.id DESC) AS position
+    FROM changelog_view
+    WHERE changelog_view.domain_id = ANY($1::BIGINT[])
+) AS entries
+WHERE position <= $2::BIGINT
+ORDER BY domain_id, id DESC
+`
+
+type ListChangelogByDomainIDsParams struct {
+	DomainIds  []int64
+	MaxEntries int64
+}
+
+// Lists the newest entries of each of the domains, at most max_entries per domain.
+func (q *Queries) ListChangelogByDomainIDs(ctx context.Context, arg ListChangelogByDomainIDsParams) ([]ChangelogView, error) {
+	rows, err := q.db.Query(ctx, ListChangelogByDomainIDs, arg.DomainIds, arg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ChangelogView{}
+	for rows.Next() {
+		var i ChangelogView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListChangelogSince = `-- name: ListChangelogSince :many
+SELECT changelog.id, changelog.ts, changelog.domain_id, changelog.message, changelog.ipv6_status,
+       domain.site,
+       country.country_code
+FROM changelog
+         JOIN domain ON changelog.domain_id = domain.id
+         JOIN check_result ON domain.site = check_result.site
+         LEFT JOIN country ON check_result.country_id = country.id
+WHERE changelog.id > $1
+  AND changelog.id <> ALL ($3::BIGINT[])
+ORDER BY changelog.id
+LIMIT $2
+`
+
+type ListChangelogSinceParams struct {
+	ID    int64
+	Limit int64
+	Skip  []int64
+}
+
+type ListChangelogSinceRow struct {
+	ID          int64
+	Ts          time.Time
+	DomainID    int64
+	Message     string
+	Ipv6Status  string
+	Site        string
+	CountryCode sql.NullString
+}
+
+// Oldest first, returns the entries written after the given id, except the skipped ids, with the country of the domain.
+func (q *Queries) ListChangelogSince(ctx context.Context, arg ListChangelogSinceParams) ([]ListChangelogSinceRow, error) {
+	rows, err := q.db.Query(ctx, ListChangelogSince, arg.ID, arg.Limit, arg.Skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListChangelogSinceRow{}
+	for rows.Next() {
+		var i ListChangelogSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Ts,
+			&i.DomainID,
+			&i.Message,
+			&i.Ipv6Status,
+			&i.Site,
+			&i.CountryCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,202 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: check_result.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const GetCheckResult = `-- name: GetCheckResult :one
+SELECT site, base_domain, www_domain, nameserver, mx_record, asn_id, country_id, ts_check, v6_only, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_updated
+FROM check_result
+WHERE site = $1
+  AND ts_check >= $2
+LIMIT 1
+`
+
+type GetCheckResultParams struct {
+	Site    string
+	TsCheck sql.NullTime
+}
+
+func (q *Queries) GetCheckResult(ctx context.Context, arg GetCheckResultParams) (CheckResult, error) {
+	row := q.db.QueryRow(ctx, GetCheckResult, arg.Site, arg.TsCheck)
+	var i CheckResult
+	err := row.Scan(
+		&i.Site,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.AsnID,
+		&i.CountryID,
+		&i.TsCheck,
+		&i.V6Only,
+		&i.TsBaseDomain,
+		&i.TsWwwDomain,
+		&i.TsNameserver,
+		&i.TsMxRecord,
+		&i.TsV6Only,
+		&i.TsUpdated,
+	)
+	return i, err
+}
+
+const ListCheckResultCampaignDomains = `-- name: ListCheckResultCampaignDomains :many
+SELECT id, campaign_id
+FROM campaign_domain
+WHERE site = $1
+  AND disabled = FALSE
+`
+
+type ListCheckResultCampaignDomainsRow struct {
+	ID         int64
+	CampaignID uuid.UUID
+}
+
+// Returns the campaign domains that show the result of a site.
+func (q *Queries) ListCheckResultCampaignDomains(ctx context.Context, site string) ([]ListCheckResultCampaignDomainsRow, error) {
+	rows, err := q.db.Query(ctx, ListCheckResultCampaignDomains, site)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCheckResultCampaignDomainsRow{}
+	for rows.Next() {
+		var i ListCheckResultCampaignDomainsRow
+		if err := rows.Scan(&i.ID, &i.CampaignID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCheckResultDomains = `-- name: ListCheckResultDomains :many
+SELECT id
+FROM domain
+WHERE site = $1
+  AND disabled = FALSE
+`
+
+// Returns the ids of the domains that show the result of a site.
+func (q *Queries) ListCheckResultDomains(ctx context.Context, site string) ([]int64, error) {
+	rows, err := q.db.Query(ctx, ListCheckResultDomains, site)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockCheckResult = `-- name: LockCheckResult :one
+SELECT site, base_domain, www_domain, nameserver, mx_record, asn_id, country_id, ts_check, v6_only, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_updated
+FROM check_result
+WHERE site = $1
+FOR UPDATE
+`
+
+// Returns the result of a site and locks it until the end of the transaction,
+// so the changes to it are only logged once when two crawlers check the site.
+func (q *Queries) LockCheckResult(ctx context.Context, site string) (CheckResult, error) {
+	row := q.db.QueryRow(ctx, LockCheckResult, site)
+	var i CheckResult
+	err := row.Scan(
+		&i.Site,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.AsnID,
+		&i.CountryID,
+		&i.TsCheck,
+		&i.V6Only,
+		&i.TsBaseDomain,
+		&i.TsWwwDomain,
+		&i.TsNameserver,
+		&i.TsMxRecord,
+		&i.TsV6Only,
+		&i.TsUpdated,
+	)
+	return i, err
+}
+
+const StoreCheckResult = `-- name: StoreCheckResult :exec
+INSERT INTO check_result(site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id,
+                         ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (site) DO UPDATE
+    SET base_domain    = EXCLUDED.base_domain,
+        www_domain     = EXCLUDED.www_domain,
+        nameserver     = EXCLUDED.nameserver,
+        mx_record      = EXCLUDED.mx_record,
+        v6_only        = EXCLUDED.v6_only,
+        asn_id         = EXCLUDED.asn_id,
+        country_id     = EXCLUDED.country_id,
+        ts_base_domain = EXCLUDED.ts_base_domain,
+        ts_www_domain  = EXCLUDED.ts_www_domain,
+        ts_nameserver  = EXCLUDED.ts_nameserver,
+        ts_mx_record   = EXCLUDED.ts_mx_record,
+        ts_v6_only     = EXCLUDED.ts_v6_only,
+        ts_check       = EXCLUDED.ts_check,
+        ts_updated     = EXCLUDED.ts_updated
+`
+
+type StoreCheckResultParams struct {
+	Site         string
+	BaseDomain   string
+	WwwDomain    string
+	Nameserver   string
+	MxRecord     string
+	V6Only       string
+	AsnID        sql.NullInt64
+	CountryID    sql.NullInt64
+	TsBaseDomain sql.NullTime
+	TsWwwDomain  sql.NullTime
+	TsNameserver sql.NullTime
+	TsMxRecord   sql.NullTime
+	TsV6Only     sql.NullTime
+	TsCheck      sql.NullTime
+	TsUpdated    sql.NullTime
+}
+
+func (q *Queries) StoreCheckResult(ctx context.Context, arg StoreCheckResultParams) error {
+	_, err := q.db.Exec(ctx, StoreCheckResult,
+		arg.Site,
+		arg.BaseDomain,
+		arg.WwwDomain,
+		arg.Nameserver,
+		arg.MxRecord,
+		arg.V6Only,
+		arg.AsnID,
+		arg.CountryID,
+		arg.TsBaseDomain,
+		arg.TsWwwDomain,
+		arg.TsNameserver,
+		arg.TsMxRecord,
+		arg.TsV6Only,
+		arg.TsCheck,
+		arg.TsUpdated,
+	)
+	return err
+}
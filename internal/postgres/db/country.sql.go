@@ -8,10 +8,11 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const AllDomainsByCountry = `-- name: AllDomainsByCountry :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE domain_view_list.country_id = $1
 ORDER BY domain_view_list.id
@@ -51,6 +52,7 @@ func (q *Queries) AllDomainsByCountry(ctx context.Context, arg AllDomainsByCount
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -65,6 +67,40 @@ func (q *Queries) AllDomainsByCountry(ctx context.Context, arg AllDomainsByCount
 	return items, nil
 }
 
+const CountDomainHeroesByCountry = `-- name: CountDomainHeroesByCountry :one
+SELECT count(*)
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+`
+
+func (q *Queries) CountDomainHeroesByCountry(ctx context.Context, countryID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDomainHeroesByCountry, countryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountDomainsByCountry = `-- name: CountDomainsByCountry :one
+SELECT count(*)
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    )
+`
+
+func (q *Queries) CountDomainsByCountry(ctx context.Context, countryID sql.NullInt64) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDomainsByCountry, countryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const GetCountry = `-- name: GetCountry :one
 SELECT id, country_name, country_code, country_tld, continent, sites, v6sites, percent
 FROM country
@@ -111,6 +147,42 @@ func (q *Queries) GetCountryTld(ctx context.Context, countryTld string) (Country
 	return i, err
 }
 
+const ListCountriesByID = `-- name: ListCountriesByID :many
+SELECT id, country_name, country_code, country_tld, continent, sites, v6sites, percent
+FROM country
+WHERE id = ANY($1::BIGINT[])
+`
+
+// Lists the countries with the given IDs, in no particular order.
+func (q *Queries) ListCountriesByID(ctx context.Context, ids []int64) ([]Country, error) {
+	rows, err := q.db.Query(ctx, ListCountriesByID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Country{}
+	for rows.Next() {
+		var i Country
+		if err := rows.Scan(
+			&i.ID,
+			&i.CountryName,
+			&i.CountryCode,
+			&i.CountryTld,
+			&i.Continent,
+			&i.Sites,
+			&i.V6sites,
+			&i.Percent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListCountry = `-- name: ListCountry :many
 SELECT id, country_name, country_code, country_tld, continent, sites, v6sites, percent
 FROM country
@@ -146,8 +218,68 @@ func (q *Queries) ListCountry(ctx context.Context) ([]Country, error) {
 	return items, nil
 }
 
+const ListCountryHistory = `-- name: ListCountryHistory :many
+SELECT DISTINCT ON (period) date_trunc($1::TEXT, day::TIMESTAMP)::DATE AS period,
+       day,
+       sites,
+       v6sites,
+       percent
+FROM country_history
+WHERE country_id = $2
+  AND day >= $3
+  AND day <= $4
+ORDER BY period, day DESC
+`
+
+type ListCountryHistoryParams struct {
+	Step      string
+	CountryID int64
+	StartDay  time.Time
+	EndDay    time.Time
+}
+
+type ListCountryHistoryRow struct {
+	Period  time.Time
+	Day     time.Time
+	Sites   int32
+	V6sites int32
+	Percent float64
+}
+
+// Returns the last snapshot of each period, e.g. each month, between the start and end day.
+func (q *Queries) ListCountryHistory(ctx context.Context, arg ListCountryHistoryParams) ([]ListCountryHistoryRow, error) {
+	rows, err := q.db.Query(ctx, ListCountryHistory,
+		arg.Step,
+		arg.CountryID,
+		arg.StartDay,
+		arg.EndDay,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCountryHistoryRow{}
+	for rows.Next() {
+		var i ListCountryHistoryRow
+		if err := rows.Scan(
+			&i.Period,
+			&i.Day,
+			&i.Sites,
+			&i.V6sites,
+			&i.Percent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListDomainHeroesByCountry = `-- name: ListDomainHeroesByCountry :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE country_id = $1
   AND base_domain = 'supported'
@@ -191,6 +323,143 @@ func (q *Queries) ListDomainHeroesByCountry(ctx context.Context, arg ListDomainH
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainHeroesByCountryAfter = `-- name: ListDomainHeroesByCountryAfter :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank > $2 OR (rank = $2 AND id > $3))
+ORDER BY rank, id
+LIMIT $4
+`
+
+type ListDomainHeroesByCountryAfterParams struct {
+	CountryID sql.NullInt64
+	Rank      int64
+	ID        sql.NullInt64
+	Limit     int64
+}
+
+// Keyset pagination on (rank, id), returns the domains after the given position.
+func (q *Queries) ListDomainHeroesByCountryAfter(ctx context.Context, arg ListDomainHeroesByCountryAfterParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainHeroesByCountryAfter,
+		arg.CountryID,
+		arg.Rank,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainHeroesByCountryBefore = `-- name: ListDomainHeroesByCountryBefore :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE country_id = $1
+  AND base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank < $2 OR (rank = $2 AND id < $3))
+ORDER BY rank DESC, id DESC
+LIMIT $4
+`
+
+type ListDomainHeroesByCountryBeforeParams struct {
+	CountryID sql.NullInt64
+	Rank      int64
+	ID        sql.NullInt64
+	Limit     int64
+}
+
+// Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+func (q *Queries) ListDomainHeroesByCountryBefore(ctx context.Context, arg ListDomainHeroesByCountryBeforeParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainHeroesByCountryBefore,
+		arg.CountryID,
+		arg.Rank,
+		arg.ID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -206,7 +475,7 @@ func (q *Queries) ListDomainHeroesByCountry(ctx context.Context, arg ListDomainH
 }
 
 const ListDomainsByCountry = `-- name: ListDomainsByCountry :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE domain_view_list.country_id = $1
   AND (
@@ -250,6 +519,7 @@ func (q *Queries) ListDomainsByCountry(ctx context.Context, arg ListDomainsByCou
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -263,3 +533,143 @@ func (q *Queries) ListDomainsByCountry(ctx context.Context, arg ListDomainsByCou
 	}
 	return items, nil
 }
+
+const ListDomainsByCountryAfter = `-- name: ListDomainsByCountryAfter :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    )
+  AND domain_view_list.id > $2
+ORDER BY domain_view_list.id
+LIMIT $3
+`
+
+type ListDomainsByCountryAfterParams struct {
+	CountryID sql.NullInt64
+	ID        sql.NullInt64
+	Limit     int64
+}
+
+// Keyset pagination on id, returns the domains after the given id.
+func (q *Queries) ListDomainsByCountryAfter(ctx context.Context, arg ListDomainsByCountryAfterParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainsByCountryAfter, arg.CountryID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainsByCountryBefore = `-- name: ListDomainsByCountryBefore :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE domain_view_list.country_id = $1
+  AND (
+      domain_view_list.base_domain = 'unsupported'
+      OR domain_view_list.www_domain = 'unsupported'
+    )
+  AND domain_view_list.id < $2
+ORDER BY domain_view_list.id DESC
+LIMIT $3
+`
+
+type ListDomainsByCountryBeforeParams struct {
+	CountryID sql.NullInt64
+	ID        sql.NullInt64
+	Limit     int64
+}
+
+// Keyset pagination on id, returns the domains before the given id, nearest first.
+func (q *Queries) ListDomainsByCountryBefore(ctx context.Context, arg ListDomainsByCountryBeforeParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainsByCountryBefore, arg.CountryID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SnapshotCountryHistory = `-- name: SnapshotCountryHistory :exec
+INSERT INTO country_history (country_id, day, sites, v6sites, percent)
+SELECT id, CURRENT_DATE, sites, v6sites, percent::FLOAT
+FROM country
+ON CONFLICT (country_id, day) DO UPDATE
+SET sites   = EXCLUDED.sites,
+    v6sites = EXCLUDED.v6sites,
+    percent = EXCLUDED.percent
+`
+
+// Stores today's statistics of every country, replacing an earlier snapshot of the same day.
+func (q *Queries) SnapshotCountryHistory(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, SnapshotCountryHistory)
+	return err
+}
@@ -10,11 +10,115 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
 )
 
+const CountDomain = `-- name: CountDomain :one
+SELECT count(*)
+FROM domain_view_list
+WHERE base_domain = 'unsupported'
+   OR www_domain = 'unsupported'
+`
+
+func (q *Queries) CountDomain(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDomain)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountDomainHeroes = `-- name: CountDomainHeroes :one
+SELECT count(*)
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+`
+
+func (q *Queries) CountDomainHeroes(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDomainHeroes)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountDomainsByName = `-- name: CountDomainsByName :one
+SELECT count(*)
+FROM domain_view_list
+WHERE site LIKE '%' || $1 || '%'
+`
+
+func (q *Queries) CountDomainsByName(ctx context.Context, dollar_1 sql.NullString) (int64, error) {
+	row := q.db.QueryRow(ctx, CountDomainsByName, dollar_1)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountFilteredDomains = `-- name: CountFilteredDomains :one
+SELECT count(*)
+FROM domain_view_list
+WHERE ($1::text IS NULL OR base_domain = $1)
+  AND ($2::text IS NULL OR www_domain = $2)
+  AND ($3::text IS NULL OR nameserver = $3)
+  AND ($4::text IS NULL OR mx_record = $4)
+  AND ($5::text IS NULL OR v6_only = $5)
+  AND ($6::text IS NULL
+    OR country_id = (SELECT id FROM country WHERE country_code = $6))
+  AND ($7::text IS NULL
+    OR country_id IN (SELECT id FROM country WHERE continent::text = $7))
+  AND ($8::int IS NULL
+    OR asn_id IN (SELECT id FROM asn WHERE number = $8))
+  AND ($9::bigint IS NULL OR rank >= $9)
+  AND ($10::bigint IS NULL OR rank <= $10)
+  AND ($11::timestamptz IS NULL OR ts_updated >= $11)
+  AND ($12::timestamptz IS NULL OR ts_updated < $12)
+  AND ($13::uuid IS NULL
+    OR site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = $13))
+`
+
+type CountFilteredDomainsParams struct {
+	BaseDomain    sql.NullString
+	WwwDomain     sql.NullString
+	Nameserver    sql.NullString
+	MxRecord      sql.NullString
+	V6Only        sql.NullString
+	CountryCode   sql.NullString
+	Continent     sql.NullString
+	Asn           sql.NullInt32
+	RankMin       sql.NullInt64
+	RankMax       sql.NullInt64
+	UpdatedAfter  sql.NullTime
+	UpdatedBefore sql.NullTime
+	CampaignID    uuid.NullUUID
+}
+
+// Counts the domains matching the optional filters of FilterDomains.
+func (q *Queries) CountFilteredDomains(ctx context.Context, arg CountFilteredDomainsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountFilteredDomains,
+		arg.BaseDomain,
+		arg.WwwDomain,
+		arg.Nameserver,
+		arg.MxRecord,
+		arg.V6Only,
+		arg.CountryCode,
+		arg.Continent,
+		arg.Asn,
+		arg.RankMin,
+		arg.RankMax,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.CampaignID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const CrawlDomain = `-- name: CrawlDomain :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason
 FROM domain_crawl_list
 WHERE id > $1
 ORDER BY id
@@ -53,6 +157,7 @@ func (q *Queries) CrawlDomain(ctx context.Context, arg CrawlDomainParams) ([]Dom
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
 		); err != nil {
 			return nil, err
 		}
@@ -64,6 +169,50 @@ func (q *Queries) CrawlDomain(ctx context.Context, arg CrawlDomainParams) ([]Dom
 	return items, nil
 }
 
+const CreateDomain = `-- name: CreateDomain :one
+INSERT INTO domain(site)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+RETURNING id, site, disabled, disabled_reason
+`
+
+// Returns no rows if the domain already exists.
+func (q *Queries) CreateDomain(ctx context.Context, site string) (Domain, error) {
+	row := q.db.QueryRow(ctx, CreateDomain, site)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Site,
+		&i.Disabled,
+		&i.DisabledReason,
+	)
+	return i, err
+}
+
+const DeleteDomain = `-- name: DeleteDomain :exec
+DELETE
+FROM domain
+WHERE id = $1
+`
+
+// The logs and recheck jobs of the domain are deleted with it, but not its
+// changelog, which must be deleted first.
+func (q *Queries) DeleteDomain(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, DeleteDomain, id)
+	return err
+}
+
+const DeleteDomainChangelog = `-- name: DeleteDomainChangelog :exec
+DELETE
+FROM changelog
+WHERE domain_id = $1
+`
+
+func (q *Queries) DeleteDomainChangelog(ctx context.Context, domainID int64) error {
+	_, err := q.db.Exec(ctx, DeleteDomainChangelog, domainID)
+	return err
+}
+
 const DisableDomain = `-- name: DisableDomain :exec
 UPDATE
     domain
@@ -76,6 +225,289 @@ func (q *Queries) DisableDomain(ctx context.Context, site string) error {
 	return err
 }
 
+const ExportDomains = `-- name: ExportDomains :many
+SELECT v.id,
+       v.site,
+       v.rank,
+       v.base_domain,
+       v.www_domain,
+       v.nameserver,
+       v.mx_record,
+       v.v6_only,
+       asn.number AS asn,
+       v.asname,
+       country.country_code,
+       v.country_name,
+       v.ts_base_domain,
+       v.ts_www_domain,
+       v.ts_nameserver,
+       v.ts_mx_record,
+       v.ts_v6_only,
+       v.ts_check,
+       v.ts_updated
+FROM domain_view_list v
+         LEFT JOIN asn ON v.asn_id = asn.id
+         LEFT JOIN country ON v.country_id = country.id
+WHERE v.id > $1::bigint
+  AND ($2::text IS NULL OR v.base_domain = $2)
+  AND ($3::text IS NULL OR v.www_domain = $3)
+  AND ($4::text IS NULL OR v.nameserver = $4)
+  AND ($5::text IS NULL OR v.mx_record = $5)
+  AND ($6::text IS NULL OR v.v6_only = $6)
+  AND ($7::text IS NULL OR country.country_code = $7)
+  AND ($8::text IS NULL OR country.continent::text = $8)
+  AND ($9::int IS NULL OR asn.number = $9)
+  AND ($10::bigint IS NULL OR v.rank >= $10)
+  AND ($11::bigint IS NULL OR v.rank <= $11)
+  AND ($12::timestamptz IS NULL OR v.ts_updated >= $12)
+  AND ($13::timestamptz IS NULL OR v.ts_updated < $13)
+  AND ($14::uuid IS NULL
+    OR v.site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = $14))
+ORDER BY v.id
+LIMIT $15
+`
+
+type ExportDomainsParams struct {
+	AfterID       int64
+	BaseDomain    sql.NullString
+	WwwDomain     sql.NullString
+	Nameserver    sql.NullString
+	MxRecord      sql.NullString
+	V6Only        sql.NullString
+	CountryCode   sql.NullString
+	Continent     sql.NullString
+	Asn           sql.NullInt32
+	RankMin       sql.NullInt64
+	RankMax       sql.NullInt64
+	UpdatedAfter  sql.NullTime
+	UpdatedBefore sql.NullTime
+	CampaignID    uuid.NullUUID
+	Limit         int64
+}
+
+type ExportDomainsRow struct {
+	ID           sql.NullInt64
+	Site         sql.NullString
+	Rank         int64
+	BaseDomain   sql.NullString
+	WwwDomain    sql.NullString
+	Nameserver   sql.NullString
+	MxRecord     sql.NullString
+	V6Only       sql.NullString
+	Asn          sql.NullInt32
+	Asname       sql.NullString
+	CountryCode  sql.NullString
+	CountryName  sql.NullString
+	TsBaseDomain sql.NullTime
+	TsWwwDomain  sql.NullTime
+	TsNameserver sql.NullTime
+	TsMxRecord   sql.NullTime
+	TsV6Only     sql.NullTime
+	TsCheck      sql.NullTime
+	TsUpdated    sql.NullTime
+}
+
+// Lists the domains matching the optional filters of FilterDomains with an id
+// after the given one, ordered by id, with the country code and AS number.
+func (q *Queries) ExportDomains(ctx context.Context, arg ExportDomainsParams) ([]ExportDomainsRow, error) {
+	rows, err := q.db.Query(ctx, ExportDomains,
+		arg.AfterID,
+		arg.BaseDomain,
+		arg.WwwDomain,
+		arg.Nameserver,
+		arg.MxRecord,
+		arg.V6Only,
+		arg.CountryCode,
+		arg.Continent,
+		arg.Asn,
+		arg.RankMin,
+		arg.RankMax,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.CampaignID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExportDomainsRow{}
+	for rows.Next() {
+		var i ExportDomainsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.Rank,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.Asn,
+			&i.Asname,
+			&i.CountryCode,
+			&i.CountryName,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const FilterDomains = `-- name: FilterDomains :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE ($1::text IS NULL OR base_domain = $1)
+  AND ($2::text IS NULL OR www_domain = $2)
+  AND ($3::text IS NULL OR nameserver = $3)
+  AND ($4::text IS NULL OR mx_record = $4)
+  AND ($5::text IS NULL OR v6_only = $5)
+  AND ($6::text IS NULL
+    OR country_id = (SELECT id FROM country WHERE country_code = $6))
+  AND ($7::text IS NULL
+    OR country_id IN (SELECT id FROM country WHERE continent::text = $7))
+  AND ($8::int IS NULL
+    OR asn_id IN (SELECT id FROM asn WHERE number = $8))
+  AND ($9::bigint IS NULL OR rank >= $9)
+  AND ($10::bigint IS NULL OR rank <= $10)
+  AND ($11::timestamptz IS NULL OR ts_updated >= $11)
+  AND ($12::timestamptz IS NULL OR ts_updated < $12)
+  AND ($13::uuid IS NULL
+    OR site IN (SELECT campaign_domain.site FROM campaign_domain WHERE campaign_id = $13))
+ORDER BY CASE WHEN $14::text = 'rank' THEN rank END,
+         CASE WHEN $14::text = '-rank' THEN rank END DESC,
+         CASE WHEN $14::text = 'site' THEN site END,
+         CASE WHEN $14::text = '-site' THEN site END DESC,
+         CASE WHEN $14::text = 'ts_updated' THEN ts_updated END NULLS LAST,
+         CASE WHEN $14::text = '-ts_updated' THEN ts_updated END DESC NULLS LAST,
+         rank, id
+LIMIT $16 OFFSET $15
+`
+
+type FilterDomainsParams struct {
+	BaseDomain    sql.NullString
+	WwwDomain     sql.NullString
+	Nameserver    sql.NullString
+	MxRecord      sql.NullString
+	V6Only        sql.NullString
+	CountryCode   sql.NullString
+	Continent     sql.NullString
+	Asn           sql.NullInt32
+	RankMin       sql.NullInt64
+	RankMax       sql.NullInt64
+	UpdatedAfter  sql.NullTime
+	UpdatedBefore sql.NullTime
+	CampaignID    uuid.NullUUID
+	Sort          string
+	Offset        int64
+	Limit         int64
+}
+
+// Lists the domains matching the optional filters, a NULL filter matches every domain.
+// The sort order is one of rank, site or ts_updated, prefixed with - for descending.
+func (q *Queries) FilterDomains(ctx context.Context, arg FilterDomainsParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, FilterDomains,
+		arg.BaseDomain,
+		arg.WwwDomain,
+		arg.Nameserver,
+		arg.MxRecord,
+		arg.V6Only,
+		arg.CountryCode,
+		arg.Continent,
+		arg.Asn,
+		arg.RankMin,
+		arg.RankMax,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.CampaignID,
+		arg.Sort,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetDomain = `-- name: GetDomain :one
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason
+FROM domain_check_view
+WHERE site = $1
+LIMIT 1
+`
+
+func (q *Queries) GetDomain(ctx context.Context, site string) (DomainCheckView, error) {
+	row := q.db.QueryRow(ctx, GetDomain, site)
+	var i DomainCheckView
+	err := row.Scan(
+		&i.ID,
+		&i.Site,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.V6Only,
+		&i.AsnID,
+		&i.CountryID,
+		&i.Disabled,
+		&i.TsBaseDomain,
+		&i.TsWwwDomain,
+		&i.TsNameserver,
+		&i.TsMxRecord,
+		&i.TsV6Only,
+		&i.TsCheck,
+		&i.TsUpdated,
+		&i.DisabledReason,
+	)
+	return i, err
+}
+
 const GetDomainLog = `-- name: GetDomainLog :many
 SELECT id,
        time,
@@ -112,8 +544,38 @@ func (q *Queries) GetDomainLog(ctx context.Context, domainID int64) ([]GetDomain
 	return items, nil
 }
 
+const GetDomainLogBefore = `-- name: GetDomainLogBefore :one
+SELECT id,
+       time,
+       data
+FROM domain_log
+WHERE domain_id = $1
+  AND time < $2
+ORDER BY time DESC
+LIMIT 1
+`
+
+type GetDomainLogBeforeParams struct {
+	DomainID int64
+	Time     time.Time
+}
+
+type GetDomainLogBeforeRow struct {
+	ID   int64
+	Time time.Time
+	Data pgtype.JSONB
+}
+
+// Returns the newest log entry of a domain before the given time.
+func (q *Queries) GetDomainLogBefore(ctx context.Context, arg GetDomainLogBeforeParams) (GetDomainLogBeforeRow, error) {
+	row := q.db.QueryRow(ctx, GetDomainLogBefore, arg.DomainID, arg.Time)
+	var i GetDomainLogBeforeRow
+	err := row.Scan(&i.ID, &i.Time, &i.Data)
+	return i, err
+}
+
 const GetDomainsByName = `-- name: GetDomainsByName :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE site LIKE '%' || $1 || '%'
 ORDER BY rank
@@ -153,6 +615,7 @@ func (q *Queries) GetDomainsByName(ctx context.Context, arg GetDomainsByNamePara
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -173,17 +636,18 @@ WITH DomainCount AS (SELECT count(*)::DECIMAL AS total_records
      IntervalCalculation AS (SELECT (NOW() - '3 days'::INTERVAL)         AS calculatedStartTime,
                                     ('3 days'::INTERVAL) / total_records AS calculatedIntervalStep
                              FROM DomainCount),
-     SpacedTimestampUpdates AS (SELECT d.id,
+     SpacedTimestampUpdates AS (SELECT d.site,
                                        ic.calculatedStartTime + ic.calculatedIntervalStep * 
                                        ROW_NUMBER() OVER (ORDER BY d.id) AS newSpacedTimestamp
                                 FROM domain d,
                                      IntervalCalculation ic)
-UPDATE domain
+UPDATE check_result
 SET ts_check = stu.newSpacedTimestamp
 FROM SpacedTimestampUpdates stu
-WHERE domain.id = stu.id
+WHERE check_result.site = stu.site
 `
 
+// Spreads the checks of the domains over a day, the campaign domains are not moved.
 func (q *Queries) InitSpaceTimestamps(ctx context.Context) error {
 	_, err := q.db.Exec(ctx, InitSpaceTimestamps)
 	return err
@@ -201,7 +665,7 @@ func (q *Queries) InsertDomain(ctx context.Context, site string) error {
 }
 
 const ListDomain = `-- name: ListDomain :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE base_domain = 'unsupported'
    OR www_domain = 'unsupported'
@@ -241,6 +705,125 @@ func (q *Queries) ListDomain(ctx context.Context, arg ListDomainParams) ([]Domai
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainAfter = `-- name: ListDomainAfter :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE (base_domain = 'unsupported'
+   OR www_domain = 'unsupported')
+  AND (rank > $1 OR (rank = $1 AND id > $2))
+ORDER BY rank, id
+LIMIT $3
+`
+
+type ListDomainAfterParams struct {
+	Rank  int64
+	ID    sql.NullInt64
+	Limit int64
+}
+
+// Keyset pagination on (rank, id), returns the domains after the given position.
+func (q *Queries) ListDomainAfter(ctx context.Context, arg ListDomainAfterParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainAfter, arg.Rank, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainBefore = `-- name: ListDomainBefore :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE (base_domain = 'unsupported'
+   OR www_domain = 'unsupported')
+  AND (rank < $1 OR (rank = $1 AND id < $2))
+ORDER BY rank DESC, id DESC
+LIMIT $3
+`
+
+type ListDomainBeforeParams struct {
+	Rank  int64
+	ID    sql.NullInt64
+	Limit int64
+}
+
+// Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+func (q *Queries) ListDomainBefore(ctx context.Context, arg ListDomainBeforeParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainBefore, arg.Rank, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -256,7 +839,7 @@ func (q *Queries) ListDomain(ctx context.Context, arg ListDomainParams) ([]Domai
 }
 
 const ListDomainHeroes = `-- name: ListDomainHeroes :many
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE base_domain = 'supported'
   AND www_domain = 'supported'
@@ -298,6 +881,129 @@ func (q *Queries) ListDomainHeroes(ctx context.Context, arg ListDomainHeroesPara
 			&i.TsV6Only,
 			&i.TsCheck,
 			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainHeroesAfter = `-- name: ListDomainHeroesAfter :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank > $1 OR (rank = $1 AND id > $2))
+ORDER BY rank, id
+LIMIT $3
+`
+
+type ListDomainHeroesAfterParams struct {
+	Rank  int64
+	ID    sql.NullInt64
+	Limit int64
+}
+
+// Keyset pagination on (rank, id), returns the domains after the given position.
+func (q *Queries) ListDomainHeroesAfter(ctx context.Context, arg ListDomainHeroesAfterParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainHeroesAfter, arg.Rank, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
+			&i.Rank,
+			&i.Asname,
+			&i.CountryName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDomainHeroesBefore = `-- name: ListDomainHeroesBefore :many
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
+FROM domain_view_list
+WHERE base_domain = 'supported'
+  AND www_domain = 'supported'
+  AND nameserver = 'supported'
+  AND mx_record != 'unsupported'
+  AND (rank < $1 OR (rank = $1 AND id < $2))
+ORDER BY rank DESC, id DESC
+LIMIT $3
+`
+
+type ListDomainHeroesBeforeParams struct {
+	Rank  int64
+	ID    sql.NullInt64
+	Limit int64
+}
+
+// Keyset pagination on (rank, id), returns the domains before the given position, nearest first.
+func (q *Queries) ListDomainHeroesBefore(ctx context.Context, arg ListDomainHeroesBeforeParams) ([]DomainViewList, error) {
+	rows, err := q.db.Query(ctx, ListDomainHeroesBefore, arg.Rank, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DomainViewList{}
+	for rows.Next() {
+		var i DomainViewList
+		if err := rows.Scan(
+			&i.ID,
+			&i.Site,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.V6Only,
+			&i.AsnID,
+			&i.CountryID,
+			&i.Disabled,
+			&i.TsBaseDomain,
+			&i.TsWwwDomain,
+			&i.TsNameserver,
+			&i.TsMxRecord,
+			&i.TsV6Only,
+			&i.TsCheck,
+			&i.TsUpdated,
+			&i.DisabledReason,
 			&i.Rank,
 			&i.Asname,
 			&i.CountryName,
@@ -312,6 +1018,61 @@ func (q *Queries) ListDomainHeroes(ctx context.Context, arg ListDomainHeroesPara
 	return items, nil
 }
 
+const ListDomainLogRange = `-- name: ListDomainLogRange :many
+SELECT id,
+       time,
+       data
+FROM domain_log
+WHERE domain_id = $1
+  AND (time, id) > ($2::timestamptz, $3::BIGINT)
+  AND time < $4
+ORDER BY time, id
+LIMIT $5
+`
+
+type ListDomainLogRangeParams struct {
+	DomainID  int64
+	AfterTime time.Time
+	AfterID   int64
+	EndTime   time.Time
+	PageSize  int32
+}
+
+type ListDomainLogRangeRow struct {
+	ID   int64
+	Time time.Time
+	Data pgtype.JSONB
+}
+
+// Oldest first, returns a page of the log entries of a domain in a time range,
+// the entries after the given time and id. The first page starts after
+// the start of the range and id 0.
+func (q *Queries) ListDomainLogRange(ctx context.Context, arg ListDomainLogRangeParams) ([]ListDomainLogRangeRow, error) {
+	rows, err := q.db.Query(ctx, ListDomainLogRange,
+		arg.DomainID,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.EndTime,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDomainLogRangeRow{}
+	for rows.Next() {
+		var i ListDomainLogRangeRow
+		if err := rows.Scan(&i.ID, &i.Time, &i.Data); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListDomainShamers = `-- name: ListDomainShamers :many
 SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, shame_id, shame_site
 FROM domain_shame_view
@@ -357,6 +1118,33 @@ func (q *Queries) ListDomainShamers(ctx context.Context) ([]DomainShameView, err
 	return items, nil
 }
 
+const SetDomainDisabled = `-- name: SetDomainDisabled :one
+UPDATE
+    domain
+SET disabled        = $1,
+    disabled_reason = $2
+WHERE site = $3
+RETURNING id, site, disabled, disabled_reason
+`
+
+type SetDomainDisabledParams struct {
+	Disabled bool
+	Reason   sql.NullString
+	Site     string
+}
+
+func (q *Queries) SetDomainDisabled(ctx context.Context, arg SetDomainDisabledParams) (Domain, error) {
+	row := q.db.QueryRow(ctx, SetDomainDisabled, arg.Disabled, arg.Reason, arg.Site)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Site,
+		&i.Disabled,
+		&i.DisabledReason,
+	)
+	return i, err
+}
+
 const StoreDomainLog = `-- name: StoreDomainLog :exec
 INSERT INTO domain_log(domain_id, data)
 VALUES ($1, $2)
@@ -373,67 +1161,8 @@ func (q *Queries) StoreDomainLog(ctx context.Context, arg StoreDomainLogParams)
 	return err
 }
 
-const UpdateDomain = `-- name: UpdateDomain :exec
-UPDATE
-    domain
-SET base_domain    = $2,
-    www_domain     = $3,
-    nameserver     = $4,
-    mx_record      = $5,
-    v6_only        = $6,
-    ts_base_domain = $7,
-    ts_www_domain  = $8,
-    ts_nameserver  = $9,
-    ts_mx_record   = $10,
-    ts_v6_only     = $11,
-    ts_check       = $12,
-    ts_updated     = $13,
-    asn_id         = $14,
-    country_id     = $15
-WHERE site = $1
-`
-
-type UpdateDomainParams struct {
-	Site         string
-	BaseDomain   string
-	WwwDomain    string
-	Nameserver   string
-	MxRecord     string
-	V6Only       string
-	TsBaseDomain sql.NullTime
-	TsWwwDomain  sql.NullTime
-	TsNameserver sql.NullTime
-	TsMxRecord   sql.NullTime
-	TsV6Only     sql.NullTime
-	TsCheck      sql.NullTime
-	TsUpdated    sql.NullTime
-	AsnID        sql.NullInt64
-	CountryID    sql.NullInt64
-}
-
-func (q *Queries) UpdateDomain(ctx context.Context, arg UpdateDomainParams) error {
-	_, err := q.db.Exec(ctx, UpdateDomain,
-		arg.Site,
-		arg.BaseDomain,
-		arg.WwwDomain,
-		arg.Nameserver,
-		arg.MxRecord,
-		arg.V6Only,
-		arg.TsBaseDomain,
-		arg.TsWwwDomain,
-		arg.TsNameserver,
-		arg.TsMxRecord,
-		arg.TsV6Only,
-		arg.TsCheck,
-		arg.TsUpdated,
-		arg.AsnID,
-		arg.CountryID,
-	)
-	return err
-}
-
 const ViewDomain = `-- name: ViewDomain :one
-SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, rank, asname, country_name
+SELECT id, site, base_domain, www_domain, nameserver, mx_record, v6_only, asn_id, country_id, disabled, ts_base_domain, ts_www_domain, ts_nameserver, ts_mx_record, ts_v6_only, ts_check, ts_updated, disabled_reason, rank, asname, country_name
 FROM domain_view_list
 WHERE site = $1
 LIMIT 1
@@ -460,6 +1189,7 @@ func (q *Queries) ViewDomain(ctx context.Context, site sql.NullString) (DomainVi
 		&i.TsV6Only,
 		&i.TsCheck,
 		&i.TsUpdated,
+		&i.DisabledReason,
 		&i.Rank,
 		&i.Asname,
 		&i.CountryName,
@@ -79,6 +79,21 @@ func (q *Queries) GetMetric(ctx context.Context, measurement string) ([]GetMetri
 	return items, nil
 }
 
+const LatestMetricTime = `-- name: LatestMetricTime :one
+SELECT time
+FROM metrics
+WHERE measurement = $1
+ORDER BY time DESC
+LIMIT 1
+`
+
+func (q *Queries) LatestMetricTime(ctx context.Context, measurement string) (time.Time, error) {
+	row := q.db.QueryRow(ctx, LatestMetricTime, measurement)
+	var time time.Time
+	err := row.Scan(&time)
+	return time, err
+}
+
 const StoreMetric = `-- name: StoreMetric :exec
 INSERT INTO metrics(measurement, data)
 VALUES ($1, $2)
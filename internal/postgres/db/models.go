@@ -61,6 +61,19 @@ func (ns NullContinents) Value() (driver.Value, error) {
 	return string(ns.Continents), nil
 }
 
+type ApiKey struct {
+	ID        int64
+	Name      string
+	Prefix    string
+	KeyHash   string
+	RateLimit sql.NullInt32
+	Requests  int64
+	LastUsed  sql.NullTime
+	CreatedAt time.Time
+	RevokedAt sql.NullTime
+	Admin     bool
+}
+
 type Asn struct {
 	ID        int64
 	Number    int32
@@ -71,6 +84,25 @@ type Asn struct {
 	PercentV6 sql.NullFloat64
 }
 
+type AsnHistory struct {
+	AsnID     int64
+	Day       time.Time
+	CountV4   int32
+	CountV6   int32
+	PercentV4 float64
+	PercentV6 float64
+}
+
+type AuditLog struct {
+	ID       int64
+	Ts       time.Time
+	ApiKeyID sql.NullInt64
+	Actor    string
+	Action   string
+	Target   string
+	Details  pgtype.JSONB
+}
+
 type Campaign struct {
 	ID          int64
 	CreatedAt   time.Time
@@ -90,6 +122,13 @@ type CampaignChangelog struct {
 }
 
 type CampaignDomain struct {
+	ID         int64
+	CampaignID uuid.UUID
+	Site       string
+	Disabled   bool
+}
+
+type CampaignDomainCheckView struct {
 	ID           int64
 	CampaignID   uuid.UUID
 	Site         string
@@ -144,55 +183,90 @@ type ChangelogView struct {
 	Site       string
 }
 
-type Country struct {
-	ID          int64
-	CountryName string
-	CountryCode string
-	CountryTld  string
-	Continent   NullContinents
-	Sites       int32
-	V6sites     int32
-	Percent     pgtype.Numeric
-}
-
-type Domain struct {
-	ID           int64
+type CheckResult struct {
 	Site         string
 	BaseDomain   string
 	WwwDomain    string
 	Nameserver   string
 	MxRecord     string
-	V6Only       string
 	AsnID        sql.NullInt64
 	CountryID    sql.NullInt64
-	Disabled     bool
+	TsCheck      sql.NullTime
+	V6Only       string
 	TsBaseDomain sql.NullTime
 	TsWwwDomain  sql.NullTime
 	TsNameserver sql.NullTime
 	TsMxRecord   sql.NullTime
 	TsV6Only     sql.NullTime
-	TsCheck      sql.NullTime
 	TsUpdated    sql.NullTime
 }
 
+type Country struct {
+	ID          int64
+	CountryName string
+	CountryCode string
+	CountryTld  string
+	Continent   NullContinents
+	Sites       int32
+	V6sites     int32
+	Percent     pgtype.Numeric
+}
+
+type CountryHistory struct {
+	CountryID int64
+	Day       time.Time
+	Sites     int32
+	V6sites   int32
+	Percent   float64
+}
+
+type Domain struct {
+	ID             int64
+	Site           string
+	Disabled       bool
+	DisabledReason sql.NullString
+}
+
+type DomainCheckView struct {
+	ID             int64
+	Site           string
+	BaseDomain     string
+	WwwDomain      string
+	Nameserver     string
+	MxRecord       string
+	V6Only         string
+	AsnID          sql.NullInt64
+	CountryID      sql.NullInt64
+	Disabled       bool
+	TsBaseDomain   sql.NullTime
+	TsWwwDomain    sql.NullTime
+	TsNameserver   sql.NullTime
+	TsMxRecord     sql.NullTime
+	TsV6Only       sql.NullTime
+	TsCheck        sql.NullTime
+	TsUpdated      sql.NullTime
+	DisabledReason sql.NullString
+}
+
 type DomainCrawlList struct {
-	ID           int64
-	Site         string
-	BaseDomain   string
-	WwwDomain    string
-	Nameserver   string
-	MxRecord     string
-	V6Only       string
-	AsnID        sql.NullInt64
-	CountryID    sql.NullInt64
-	Disabled     bool
-	TsBaseDomain sql.NullTime
-	TsWwwDomain  sql.NullTime
-	TsNameserver sql.NullTime
-	TsMxRecord   sql.NullTime
-	TsV6Only     sql.NullTime
-	TsCheck      sql.NullTime
-	TsUpdated    sql.NullTime
+	ID             int64
+	Site           string
+	BaseDomain     string
+	WwwDomain      string
+	Nameserver     string
+	MxRecord       string
+	V6Only         string
+	AsnID          sql.NullInt64
+	CountryID      sql.NullInt64
+	Disabled       bool
+	TsBaseDomain   sql.NullTime
+	TsWwwDomain    sql.NullTime
+	TsNameserver   sql.NullTime
+	TsMxRecord     sql.NullTime
+	TsV6Only       sql.NullTime
+	TsCheck        sql.NullTime
+	TsUpdated      sql.NullTime
+	DisabledReason sql.NullString
 }
 
 type DomainLog struct {
@@ -225,26 +299,27 @@ type DomainShameView struct {
 }
 
 type DomainViewList struct {
-	ID           sql.NullInt64
-	Site         sql.NullString
-	BaseDomain   sql.NullString
-	WwwDomain    sql.NullString
-	Nameserver   sql.NullString
-	MxRecord     sql.NullString
-	V6Only       sql.NullString
-	AsnID        sql.NullInt64
-	CountryID    sql.NullInt64
-	Disabled     sql.NullBool
-	TsBaseDomain sql.NullTime
-	TsWwwDomain  sql.NullTime
-	TsNameserver sql.NullTime
-	TsMxRecord   sql.NullTime
-	TsV6Only     sql.NullTime
-	TsCheck      sql.NullTime
-	TsUpdated    sql.NullTime
-	Rank         int64
-	Asname       sql.NullString
-	CountryName  sql.NullString
+	ID             sql.NullInt64
+	Site           sql.NullString
+	BaseDomain     sql.NullString
+	WwwDomain      sql.NullString
+	Nameserver     sql.NullString
+	MxRecord       sql.NullString
+	V6Only         sql.NullString
+	AsnID          sql.NullInt64
+	CountryID      sql.NullInt64
+	Disabled       sql.NullBool
+	TsBaseDomain   sql.NullTime
+	TsWwwDomain    sql.NullTime
+	TsNameserver   sql.NullTime
+	TsMxRecord     sql.NullTime
+	TsV6Only       sql.NullTime
+	TsCheck        sql.NullTime
+	TsUpdated      sql.NullTime
+	DisabledReason sql.NullString
+	Rank           int64
+	Asname         sql.NullString
+	CountryName    sql.NullString
 }
 
 type Lists struct {
@@ -260,6 +335,23 @@ type Metrics struct {
 	Data        pgtype.JSONB
 }
 
+type RecheckJob struct {
+	ID         uuid.UUID
+	DomainID   int64
+	Site       string
+	Status     string
+	Priority   int32
+	Client     string
+	BaseDomain sql.NullString
+	WwwDomain  sql.NullString
+	Nameserver sql.NullString
+	MxRecord   sql.NullString
+	Error      sql.NullString
+	CreatedAt  time.Time
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+}
+
 type Sites struct {
 	ID     int64
 	ListID int64
@@ -0,0 +1,300 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: recheck_job.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const ClaimRecheckJobs = `-- name: ClaimRecheckJobs :many
+UPDATE recheck_job
+SET status     = 'running',
+    started_at = NOW()
+WHERE id IN (SELECT claim.id
+             FROM recheck_job claim
+             WHERE claim.status = 'queued'
+                OR (claim.status = 'running' AND claim.started_at < $2)
+             ORDER BY claim.priority DESC, claim.created_at
+             LIMIT $1 FOR UPDATE SKIP LOCKED)
+RETURNING id, domain_id, site, status, priority, client, base_domain, www_domain, nameserver, mx_record, error, created_at, started_at, finished_at
+`
+
+type ClaimRecheckJobsParams struct {
+	Limit     int64
+	StartedAt sql.NullTime
+}
+
+// Marks the next queued jobs as running and returns them, highest priority first.
+// Jobs that have been running since before the given time are claimed again,
+// the crawler that claimed them has stopped. SKIP LOCKED lets several crawlers
+// claim jobs at the same time without getting the same job.
+func (q *Queries) ClaimRecheckJobs(ctx context.Context, arg ClaimRecheckJobsParams) ([]RecheckJob, error) {
+	rows, err := q.db.Query(ctx, ClaimRecheckJobs, arg.Limit, arg.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RecheckJob{}
+	for rows.Next() {
+		var i RecheckJob
+		if err := rows.Scan(
+			&i.ID,
+			&i.DomainID,
+			&i.Site,
+			&i.Status,
+			&i.Priority,
+			&i.Client,
+			&i.BaseDomain,
+			&i.WwwDomain,
+			&i.Nameserver,
+			&i.MxRecord,
+			&i.Error,
+			&i.CreatedAt,
+			&i.StartedAt,
+			&i.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ClientRecheckJobStats = `-- name: ClientRecheckJobStats :one
+SELECT count(*)                                    AS count,
+       COALESCE(min(created_at), NOW())::timestamptz AS oldest
+FROM recheck_job
+WHERE client = $1
+  AND created_at >= $2
+`
+
+type ClientRecheckJobStatsParams struct {
+	Client    string
+	CreatedAt time.Time
+}
+
+type ClientRecheckJobStatsRow struct {
+	Count  int64
+	Oldest time.Time
+}
+
+// Counts the jobs a client requested since the given time, and returns when the oldest of them was requested.
+func (q *Queries) ClientRecheckJobStats(ctx context.Context, arg ClientRecheckJobStatsParams) (ClientRecheckJobStatsRow, error) {
+	row := q.db.QueryRow(ctx, ClientRecheckJobStats, arg.Client, arg.CreatedAt)
+	var i ClientRecheckJobStatsRow
+	err := row.Scan(&i.Count, &i.Oldest)
+	return i, err
+}
+
+const CountQueuedRecheckJobs = `-- name: CountQueuedRecheckJobs :one
+SELECT count(*)
+FROM recheck_job
+WHERE status = 'queued'
+`
+
+// Counts the jobs that wait to be claimed, the depth of the queue.
+func (q *Queries) CountQueuedRecheckJobs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, CountQueuedRecheckJobs)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateRecheckJob = `-- name: CreateRecheckJob :one
+INSERT INTO recheck_job(domain_id, site, priority, client)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (domain_id) WHERE status IN ('queued', 'running') DO NOTHING
+RETURNING id, domain_id, site, status, priority, client, base_domain, www_domain, nameserver, mx_record, error, created_at, started_at, finished_at
+`
+
+type CreateRecheckJobParams struct {
+	DomainID int64
+	Site     string
+	Priority int32
+	Client   string
+}
+
+// Returns no row if the domain already has a queued or running job.
+func (q *Queries) CreateRecheckJob(ctx context.Context, arg CreateRecheckJobParams) (RecheckJob, error) {
+	row := q.db.QueryRow(ctx, CreateRecheckJob,
+		arg.DomainID,
+		arg.Site,
+		arg.Priority,
+		arg.Client,
+	)
+	var i RecheckJob
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Site,
+		&i.Status,
+		&i.Priority,
+		&i.Client,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const FinishRecheckJob = `-- name: FinishRecheckJob :execrows
+UPDATE recheck_job
+SET status      = $2,
+    base_domain = $3,
+    www_domain  = $4,
+    nameserver  = $5,
+    mx_record   = $6,
+    error       = $7,
+    finished_at = NOW()
+WHERE id = $1
+  AND status = 'running'
+  AND started_at = $8
+`
+
+type FinishRecheckJobParams struct {
+	ID         uuid.UUID
+	Status     string
+	BaseDomain sql.NullString
+	WwwDomain  sql.NullString
+	Nameserver sql.NullString
+	MxRecord   sql.NullString
+	Error      sql.NullString
+	StartedAt  sql.NullTime
+}
+
+// Stores the result of a job, status is done or failed. Only the crawler that
+// holds the claim of the job finishes it, a job that was claimed again has
+// another started_at.
+func (q *Queries) FinishRecheckJob(ctx context.Context, arg FinishRecheckJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, FinishRecheckJob,
+		arg.ID,
+		arg.Status,
+		arg.BaseDomain,
+		arg.WwwDomain,
+		arg.Nameserver,
+		arg.MxRecord,
+		arg.Error,
+		arg.StartedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const GetActiveRecheckJob = `-- name: GetActiveRecheckJob :one
+SELECT id, domain_id, site, status, priority, client, base_domain, www_domain, nameserver, mx_record, error, created_at, started_at, finished_at
+FROM recheck_job
+WHERE domain_id = $1
+  AND status IN ('queued', 'running')
+LIMIT 1
+`
+
+// Returns the queued or running job of a domain.
+func (q *Queries) GetActiveRecheckJob(ctx context.Context, domainID int64) (RecheckJob, error) {
+	row := q.db.QueryRow(ctx, GetActiveRecheckJob, domainID)
+	var i RecheckJob
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Site,
+		&i.Status,
+		&i.Priority,
+		&i.Client,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const GetLatestRecheckJob = `-- name: GetLatestRecheckJob :one
+SELECT id, domain_id, site, status, priority, client, base_domain, www_domain, nameserver, mx_record, error, created_at, started_at, finished_at
+FROM recheck_job
+WHERE domain_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+// Returns the newest job for a domain.
+func (q *Queries) GetLatestRecheckJob(ctx context.Context, domainID int64) (RecheckJob, error) {
+	row := q.db.QueryRow(ctx, GetLatestRecheckJob, domainID)
+	var i RecheckJob
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Site,
+		&i.Status,
+		&i.Priority,
+		&i.Client,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const GetRecheckJob = `-- name: GetRecheckJob :one
+SELECT id, domain_id, site, status, priority, client, base_domain, www_domain, nameserver, mx_record, error, created_at, started_at, finished_at
+FROM recheck_job
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetRecheckJob(ctx context.Context, id uuid.UUID) (RecheckJob, error) {
+	row := q.db.QueryRow(ctx, GetRecheckJob, id)
+	var i RecheckJob
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Site,
+		&i.Status,
+		&i.Priority,
+		&i.Client,
+		&i.BaseDomain,
+		&i.WwwDomain,
+		&i.Nameserver,
+		&i.MxRecord,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const LockRecheckClient = `-- name: LockRecheckClient :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))
+`
+
+// Serializes the requests of a client until the transaction ends, so its jobs
+// are counted and created one request at a time.
+func (q *Queries) LockRecheckClient(ctx context.Context, client string) error {
+	_, err := q.db.Exec(ctx, LockRecheckClient, client)
+	return err
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// listenRetryDelay is how long Listen waits before reconnecting after the
+// connection is lost.
+const listenRetryDelay = 5 * time.Second
+
+// Listen calls notify for every notification on the channel until the context
+// is cancelled. It holds a connection of the pool for itself, and reconnects
+// when the connection is lost. Notifications sent while it was not listening are
+// lost, so notify is also called every time the LISTEN is in place, to let the
+// caller catch up on what it missed.
+func Listen(ctx context.Context, pool *pgxpool.Pool, channel string, notify func(ctx context.Context)) {
+	for {
+		err := listen(ctx, pool, channel, notify)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Lost the PostgreSQL listen connection", "channel", channel, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// listen listens on the channel on a connection of its own, calling notify once
+// the LISTEN is in place and for every notification, until the connection fails
+// or the context is cancelled.
+func listen(ctx context.Context, pool *pgxpool.Pool, channel string, notify func(ctx context.Context)) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Take the connection out of the pool, it is closed rather than handed to
+	// another query with the LISTEN still active.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		notify(ctx)
+
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"whynoipv6/internal/postgres/db"
+	"whynoipv6/internal/tracing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("whynoipv6/internal/postgres")
+
+// tracedDB records a span for every query, named by its sqlc query name.
+type tracedDB struct {
+	db db.DBTX
+}
+
+// Traced returns the connection, pool or transaction with a span for every
+// query. Pass it to the services instead of d.
+func Traced(d db.DBTX) db.DBTX {
+	return tracedDB{db: d}
+}
+
+// Exec implements db.DBTX.
+func (t tracedDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, span := startQuery(ctx, sql)
+	defer span.End()
+	tag, err := t.db.Exec(ctx, sql, args...)
+	tracing.RecordError(span, err)
+	return tag, err
+}
+
+// Query implements db.DBTX. The span ends when the rows are closed.
+func (t tracedDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, span := startQuery(ctx, sql)
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		span.End()
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// QueryRow implements db.DBTX. The span ends when the row is scanned.
+func (t tracedDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, span := startQuery(ctx, sql)
+	return tracedRow{row: t.db.QueryRow(ctx, sql, args...), span: span}
+}
+
+// tracedRows ends the span of a query when its rows are closed.
+type tracedRows struct {
+	pgx.Rows
+	span  trace.Span
+	ended bool
+}
+
+// Close implements pgx.Rows.
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	if !r.ended {
+		r.ended = true
+		tracing.RecordError(r.span, r.Rows.Err())
+		r.span.End()
+	}
+}
+
+// tracedRow ends the span of a query when its row is scanned.
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+// Scan implements pgx.Row. No rows is not an error of the query.
+func (r tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		tracing.RecordError(r.span, err)
+	}
+	r.span.End()
+	return err
+}
+
+// startQuery starts the span of a query.
+func startQuery(ctx context.Context, sql string) (context.Context, trace.Span) {
+	name := queryName(sql)
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(sql),
+		),
+	)
+}
+
+// queryName returns the name of a sqlc query, from the "-- name: GetDomain :one"
+// comment it starts with, or "query" for other queries.
+func queryName(sql string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(sql), "-- name: ")
+	if !ok {
+		return "query"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
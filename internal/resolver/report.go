@@ -0,0 +1,150 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RecordDetail describes the outcome of a single lookup made while checking a domain.
+type RecordDetail struct {
+	Check     string   `json:"check"`               // base_domain, www_domain, nameserver or mx_record
+	Host      string   `json:"host"`                // Name that was queried
+	Type      string   `json:"type"`                // AAAA or A
+	Addresses []string `json:"addresses,omitempty"` // Addresses found, after following CNAMEs
+	CNAMEs    []string `json:"cnames,omitempty"`    // CNAME chain followed to reach the addresses
+	Error     string   `json:"error,omitempty"`     // Lookup error, if any
+}
+
+// DomainReport is the full diagnostic output of all checks for a single domain.
+type DomainReport struct {
+	Domain   string         `json:"domain"`
+	ASCII    string         `json:"ascii"`
+	Result   DomainResult   `json:"result"`
+	Records  []RecordDetail `json:"records"`
+	Duration time.Duration  `json:"-"`
+}
+
+// DomainDiagnostics runs all the checks on a domain and returns the status together with
+// every record that was looked up to reach it. It is slower than DomainStatus and is
+// meant for on-demand checks, not the crawler.
+func DomainDiagnostics(ctx context.Context, domain string) (DomainReport, error) {
+	t := time.Now()
+	c := &dns.Client{Timeout: DefaultTimeout}
+
+	// Convert domain to ASCII for DNS lookup
+	asciiDomain, err := convertToASCII(domain)
+	if err != nil {
+		return DomainReport{}, fmt.Errorf("IDNA conversion error: %v", err)
+	}
+
+	// Run the same checks as the crawler to get the status.
+	result, err := DomainStatus(ctx, domain)
+	if err != nil {
+		return DomainReport{}, err
+	}
+
+	report := DomainReport{
+		Domain: domain,
+		ASCII:  asciiDomain,
+		Result: result,
+	}
+
+	// Address records for the base and www domain.
+	report.Records = append(report.Records, addressDetails(ctx, c, "base_domain", asciiDomain)...)
+	report.Records = append(report.Records, addressDetails(ctx, c, "www_domain", "www."+asciiDomain)...)
+
+	// Address records for every nameserver.
+	nsList, err := getNameservers(ctx, c, getTopLevelDomain(asciiDomain))
+	if err != nil {
+		report.Records = append(report.Records, RecordDetail{
+			Check: "nameserver",
+			Host:  getTopLevelDomain(asciiDomain),
+			Type:  "NS",
+			Error: err.Error(),
+		})
+	}
+	for _, ns := range nsList {
+		report.Records = append(report.Records, addressDetails(ctx, c, "nameserver", ns)...)
+	}
+
+	// Address records for every mail exchanger.
+	mxList, err := getMXRecords(ctx, c, asciiDomain)
+	if err != nil {
+		report.Records = append(report.Records, RecordDetail{
+			Check: "mx_record",
+			Host:  asciiDomain,
+			Type:  "MX",
+			Error: err.Error(),
+		})
+	}
+	for _, mx := range mxList {
+		report.Records = append(report.Records, addressDetails(ctx, c, "mx_record", mx)...)
+	}
+
+	report.Duration = time.Since(t)
+	return report, nil
+}
+
+// addressDetails looks up both the AAAA and A records for a host.
+func addressDetails(ctx context.Context, c *dns.Client, check, host string) []RecordDetail {
+	return []RecordDetail{
+		lookupAddresses(ctx, c, check, host, dns.TypeAAAA),
+		lookupAddresses(ctx, c, check, host, dns.TypeA),
+	}
+}
+
+// lookupAddresses queries a host for a single address type, following CNAME records.
+// Addresses that are not globally routable are listed but marked as such.
+func lookupAddresses(ctx context.Context, c *dns.Client, check, host string, qtype uint16) RecordDetail {
+	detail := RecordDetail{
+		Check: check,
+		Host:  dns.Fqdn(host),
+		Type:  dns.TypeToString[qtype],
+	}
+
+	name := host
+	for hops := 0; hops <= maxCNAMEHops; hops++ {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+		m.RecursionDesired = true
+
+		r, err := performQuery(ctx, c, m)
+		if err != nil {
+			detail.Error = err.Error()
+			return detail
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			detail.Error = dns.RcodeToString[r.Rcode]
+			return detail
+		}
+
+		var target string
+		for _, rr := range r.Answer {
+			switch rr := rr.(type) {
+			case *dns.AAAA:
+				if !isGloballyRoutableIPv6(rr.AAAA) {
+					detail.Addresses = append(detail.Addresses, rr.AAAA.String()+" (not routable)")
+					continue
+				}
+				detail.Addresses = append(detail.Addresses, rr.AAAA.String())
+			case *dns.A:
+				detail.Addresses = append(detail.Addresses, rr.A.String())
+			case *dns.CNAME:
+				target = rr.Target
+			}
+		}
+
+		// Stop when we have an answer, or there is no CNAME left to follow.
+		if len(detail.Addresses) > 0 || target == "" {
+			return detail
+		}
+		detail.CNAMEs = append(detail.CNAMEs, target)
+		name = target
+	}
+
+	detail.Error = fmt.Sprintf("exceeded CNAME hop limit for domain [%s]", host)
+	return detail
+}
@@ -1,6 +1,8 @@
 package resolver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -8,8 +10,12 @@ import (
 	"time"
 
 	"whynoipv6/internal/logger"
+	"whynoipv6/internal/tracing"
 
 	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/idna"
 )
 
@@ -24,6 +30,14 @@ const (
 
 var log = logger.GetLogger()
 
+var tracer = tracing.Tracer("whynoipv6/internal/resolver")
+
+// Span attributes of a DNS exchange.
+const (
+	qtypeKey = attribute.Key("dns.question.type")
+	rcodeKey = attribute.Key("dns.response.rcode")
+)
+
 // var nameservers = []string{"1.1.1.1:53", "8.8.8.8:53", "9.9.9.9:53"}
 var nameservers = []string{
 	"[2606:4700:4700::1111]:53",
@@ -34,15 +48,15 @@ var nameservers = []string{
 
 // DomainResult represents a scan result.
 type DomainResult struct {
-	BaseDomain string
-	WwwDomain  string
-	Nameserver string
-	MXRecord   string
+	BaseDomain string `json:"base_domain"`
+	WwwDomain  string `json:"www_domain"`
+	Nameserver string `json:"nameserver"`
+	MXRecord   string `json:"mx_record"`
 	// v6Only     string // TODO: Add v6Only check
 }
 
 // DomainStatus checks the domain's IPv6, NS, and MX records.
-func DomainStatus(domain string) (DomainResult, error) {
+func DomainStatus(ctx context.Context, domain string) (DomainResult, error) {
 	c := &dns.Client{Timeout: DefaultTimeout}
 	log := log.With().Str("service", "DomainStatus").Logger()
 
@@ -52,23 +66,28 @@ func DomainStatus(domain string) (DomainResult, error) {
 		return DomainResult{}, fmt.Errorf("IDNA conversion error: %v", err)
 	}
 
-	baseDomainStatus, err := checkDomainStatus(domain, c)
-	if err != nil {
-		log.Error().Msgf("Error checking base domain [%s]: %v", domain, err)
-		log.Debug().Err(err).Msgf("Error checking base domain [%s]", domain)
-		// return DomainResult{}, err
+	baseDomainStatus, baseErr := checkDomainStatus(ctx, domain, c)
+	if baseErr != nil {
+		log.Error().Msgf("Error checking base domain [%s]: %v", domain, baseErr)
 	}
 
-	WwwDomainStatus, err := checkDomainStatus("www."+domain, c)
-	if err != nil {
-		log.Error().Msgf("Error checking www domain [%s]: %v", domain, err)
-		// return DomainResult{}, err
+	WwwDomainStatus, wwwErr := checkDomainStatus(ctx, "www."+domain, c)
+	if wwwErr != nil {
+		log.Error().Msgf("Error checking www domain [%s]: %v", domain, wwwErr)
 	}
 
-	nsStatus, mxStatus, err := checkDNSRecords(domain, c)
-	if err != nil {
-		log.Err(err).Msgf("Error checking NS/MX records for domain [%s]: %v", domain, err)
-		// return DomainResult{}, err
+	nsStatus, mxStatus, recordsErr := checkDNSRecords(ctx, domain, c)
+	if recordsErr != nil {
+		log.Err(recordsErr).Msgf("Error checking NS/MX records for domain [%s]", domain)
+	}
+
+	// A check that failed has no status, which must not be mistaken for a
+	// domain without records.
+	if err := ctx.Err(); err != nil {
+		return DomainResult{}, fmt.Errorf("checks of [%s] cancelled: %w", domain, err)
+	}
+	if err := errors.Join(baseErr, wwwErr, recordsErr); err != nil {
+		return DomainResult{}, fmt.Errorf("checks of [%s] failed: %w", domain, err)
 	}
 
 	return DomainResult{
@@ -81,8 +100,8 @@ func DomainStatus(domain string) (DomainResult, error) {
 
 // checkDomainStatus checks the domain's IPv6 availability.
 // It returns the string value of the result, or an error if the query fails.
-func checkDomainStatus(domain string, c *dns.Client) (string, error) {
-	result, err := queryDomainStatus(c, domain, dns.TypeAAAA)
+func checkDomainStatus(ctx context.Context, domain string, c *dns.Client) (string, error) {
+	result, err := queryDomainStatus(ctx, c, domain, dns.TypeAAAA)
 	if err != nil {
 		return "", err
 	}
@@ -91,7 +110,7 @@ func checkDomainStatus(domain string, c *dns.Client) (string, error) {
 	}
 
 	// Check for IPv4 as fallback
-	result, err = queryDomainStatus(c, domain, dns.TypeA)
+	result, err = queryDomainStatus(ctx, c, domain, dns.TypeA)
 	if err != nil {
 		return "", err
 	}
@@ -103,7 +122,7 @@ func checkDomainStatus(domain string, c *dns.Client) (string, error) {
 }
 
 // checkDNSRecords checks DNS records (NS, MX) concurrently.
-func checkDNSRecords(domain string, c *dns.Client) (string, string, error) {
+func checkDNSRecords(ctx context.Context, domain string, c *dns.Client) (string, string, error) {
 	var nsStatus, mxStatus string
 	var nsErr, mxErr error
 	var wg sync.WaitGroup
@@ -111,11 +130,11 @@ func checkDNSRecords(domain string, c *dns.Client) (string, string, error) {
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		nsStatus, nsErr = checkNameserver(domain, c)
+		nsStatus, nsErr = checkNameserver(ctx, domain, c)
 	}()
 	go func() {
 		defer wg.Done()
-		mxStatus, mxErr = checkMX(domain, c)
+		mxStatus, mxErr = checkMX(ctx, domain, c)
 	}()
 	wg.Wait()
 
@@ -131,7 +150,7 @@ func checkDNSRecords(domain string, c *dns.Client) (string, string, error) {
 }
 
 // checkNameserver performs a DNS query for NS records
-func checkNameserver(domain string, c *dns.Client) (string, error) {
+func checkNameserver(ctx context.Context, domain string, c *dns.Client) (string, error) {
 	log := log.With().Str("service", "checkNameserver").Logger()
 	// log.Debug().Msgf("Checking nameservers for [%s]", domain)
 
@@ -139,7 +158,7 @@ func checkNameserver(domain string, c *dns.Client) (string, error) {
 	tld := getTopLevelDomain(domain)
 
 	// Get all nameservers for the domain
-	nsList, err := getNameservers(c, tld)
+	nsList, err := getNameservers(ctx, c, tld)
 	if err != nil {
 		log.Warn().Msgf("Error getting nameservers for domain [%s]: %v", domain, err)
 		return "", err
@@ -147,14 +166,14 @@ func checkNameserver(domain string, c *dns.Client) (string, error) {
 
 	// Check each nameserver for IPv6
 	for _, ns := range nsList {
-		if checkInetType(c, ns, dns.TypeAAAA) {
+		if checkInetType(ctx, c, ns, dns.TypeAAAA) {
 			log.Debug().Msgf("[%s] Nameserver [%s] has IPv6", domain, ns)
 			return IPv6Available, nil
 		}
 	}
 	// If no nameservers have IPv6, check for IPv4
 	for _, ns := range nsList {
-		if checkInetType(c, ns, dns.TypeA) {
+		if checkInetType(ctx, c, ns, dns.TypeA) {
 			log.Debug().Msgf("[%s] Nameserver [%s] has IPv4", domain, ns)
 			return IPv4Only, nil
 		}
@@ -166,12 +185,12 @@ func checkNameserver(domain string, c *dns.Client) (string, error) {
 }
 
 // getNameservers retrieves the nameservers for a given domain
-func getNameservers(c *dns.Client, domain string) ([]string, error) {
+func getNameservers(ctx context.Context, c *dns.Client, domain string) ([]string, error) {
 	m := new(dns.Msg)
 	m.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	m.RecursionDesired = true
 
-	r, err := performQuery(c, m)
+	r, err := performQuery(ctx, c, m)
 	if err != nil {
 		log.Err(err).Msgf("Error querying DNS for nameservers for domain [%s]", domain)
 		return nil, err
@@ -187,12 +206,12 @@ func getNameservers(c *dns.Client, domain string) ([]string, error) {
 }
 
 // checkMX performs a DNS query for MX records
-func checkMX(domain string, c *dns.Client) (string, error) {
+func checkMX(ctx context.Context, domain string, c *dns.Client) (string, error) {
 	log := log.With().Str("service", "checkMX").Logger()
 	// log.Debug().Msgf("Checking MX records for IPv6 for domain [%s]", domain)
 
 	// Get all MX records for the domain
-	mxRecords, err := getMXRecords(c, domain)
+	mxRecords, err := getMXRecords(ctx, c, domain)
 	if err != nil {
 		log.Warn().Msgf("Error getting mailservers for domain [%s]: %v", domain, err)
 		return "", err
@@ -200,7 +219,7 @@ func checkMX(domain string, c *dns.Client) (string, error) {
 
 	// Check each MX record for IPv6
 	for _, mx := range mxRecords {
-		if checkInetType(c, mx, dns.TypeAAAA) {
+		if checkInetType(ctx, c, mx, dns.TypeAAAA) {
 			log.Debug().Msgf("[%s] MX record [%s] has IPv6", domain, mx)
 			return IPv6Available, nil
 		}
@@ -208,7 +227,7 @@ func checkMX(domain string, c *dns.Client) (string, error) {
 
 	// If no MX records have IPv6, check for IPv4
 	for _, mx := range mxRecords {
-		if checkInetType(c, mx, dns.TypeA) {
+		if checkInetType(ctx, c, mx, dns.TypeA) {
 			log.Debug().Msgf("[%s] MX record [%s] has IPv4", domain, mx)
 			return IPv4Only, nil
 		}
@@ -220,12 +239,12 @@ func checkMX(domain string, c *dns.Client) (string, error) {
 }
 
 // getMXRecords retrieves the MX records for a given domain
-func getMXRecords(c *dns.Client, domain string) ([]string, error) {
+func getMXRecords(ctx context.Context, c *dns.Client, domain string) ([]string, error) {
 	m := new(dns.Msg)
 	m.SetQuestion(dns.Fqdn(domain), dns.TypeMX)
 	m.RecursionDesired = true
 
-	r, err := performQuery(c, m)
+	r, err := performQuery(ctx, c, m)
 	if err != nil {
 		log.Err(err).Msgf("Error querying DNS for MX records for domain [%s]", domain)
 		return nil, err
@@ -242,7 +261,7 @@ func getMXRecords(c *dns.Client, domain string) ([]string, error) {
 
 // checkInetType checks if a domain has a specified type of DNS record, following CNAME records if necessary.
 // It returns true if the domain has a record of the specified type, and false otherwise.
-func checkInetType(c *dns.Client, domain string, recordType uint16) bool {
+func checkInetType(ctx context.Context, c *dns.Client, domain string, recordType uint16) bool {
 	cnameHops := 0
 
 	for {
@@ -250,7 +269,7 @@ func checkInetType(c *dns.Client, domain string, recordType uint16) bool {
 		m.SetQuestion(dns.Fqdn(domain), recordType)
 		m.RecursionDesired = true
 
-		r, err := performQuery(c, m)
+		r, err := performQuery(ctx, c, m)
 		if err != nil {
 			log.Err(err).
 				Msgf("Error querying DNS for record type [%d] for domain [%s]", recordType, domain)
@@ -289,7 +308,7 @@ func checkInetType(c *dns.Client, domain string, recordType uint16) bool {
 
 // queryDomainStatus performs a DNS query for a given query name and type.
 // It returns the string value of the result, or an error if the query fails.
-func queryDomainStatus(client *dns.Client, domain string, qtype uint16) (string, error) {
+func queryDomainStatus(ctx context.Context, client *dns.Client, domain string, qtype uint16) (string, error) {
 	log := log.With().Str("service", "queryDomainStatus").Logger()
 	cnameHops := 0
 
@@ -298,7 +317,7 @@ func queryDomainStatus(client *dns.Client, domain string, qtype uint16) (string,
 		m.SetQuestion(dns.Fqdn(domain), qtype)
 		m.RecursionDesired = true
 
-		r, err := performQuery(client, m)
+		r, err := performQuery(ctx, client, m)
 		if err != nil {
 			log.Err(err).Msgf("Error querying DNS [%s]", domain)
 			return "", err
@@ -308,8 +327,7 @@ func queryDomainStatus(client *dns.Client, domain string, qtype uint16) (string,
 			if r.Rcode == dns.RcodeNameError { // NXDOMAIN
 				return NoRecordsFound, nil
 			}
-			log.Printf("[%s] DNS query unsuccessful: %s", domain, dns.RcodeToString[r.Rcode])
-			return "", err
+			return "", fmt.Errorf("[%s] DNS query unsuccessful: %s", domain, dns.RcodeToString[r.Rcode])
 		}
 
 		for _, rr := range r.Answer {
@@ -346,7 +364,7 @@ func queryDomainStatus(client *dns.Client, domain string, qtype uint16) (string,
 }
 
 // IPLookup performs a DNS lookup for a given domain and returns the first IPv6 or IPv4 address found.
-func IPLookup(domain string) (string, error) {
+func IPLookup(ctx context.Context, domain string) (string, error) {
 	c := &dns.Client{Timeout: DefaultTimeout}
 
 	// Convert domain to ASCII for DNS lookup
@@ -356,7 +374,7 @@ func IPLookup(domain string) (string, error) {
 	}
 
 	// Get the IPv6 for the domain
-	ipv6, err := queryDNSRecord(c, domain, dns.TypeAAAA)
+	ipv6, err := queryDNSRecord(ctx, c, domain, dns.TypeAAAA)
 	if err != nil {
 		return "", err
 	}
@@ -365,7 +383,7 @@ func IPLookup(domain string) (string, error) {
 	}
 
 	// Get the IPv4 for the domain
-	ip, err := queryDNSRecord(c, domain, dns.TypeA)
+	ip, err := queryDNSRecord(ctx, c, domain, dns.TypeA)
 	if err != nil {
 		return "", err
 	}
@@ -378,13 +396,13 @@ func IPLookup(domain string) (string, error) {
 
 // queryDNSRecord performs a DNS query for a given query name and type.
 // It returns the answer in a *dns.Msg (or nil in case of an error, in which case err will be set accordingly.)
-func queryDNSRecord(client *dns.Client, domain string, qtype uint16) (*dns.Msg, error) {
+func queryDNSRecord(ctx context.Context, client *dns.Client, domain string, qtype uint16) (*dns.Msg, error) {
 	log := log.With().Str("service", "queryDNSRecord").Logger()
 	m := new(dns.Msg)
 	m.SetQuestion(dns.Fqdn(domain), qtype)
 	m.RecursionDesired = true
 
-	r, err := performQuery(client, m)
+	r, err := performQuery(ctx, client, m)
 	if err != nil {
 		log.Err(err).Msgf("Error querying DNS [%s]", domain)
 		return nil, err
@@ -401,7 +419,7 @@ func queryDNSRecord(client *dns.Client, domain string, qtype uint16) (*dns.Msg,
 		case *dns.CNAME:
 			log.Debug().Msgf("[%s] Following CNAME: %s", domain, rr.Target)
 			domain = rr.Target // Set the domain to the target of the CNAME and check again
-			return queryDNSRecord(client, domain, qtype)
+			return queryDNSRecord(ctx, client, domain, qtype)
 		}
 	}
 
@@ -409,12 +427,17 @@ func queryDNSRecord(client *dns.Client, domain string, qtype uint16) (*dns.Msg,
 }
 
 // performQuery performs a DNS query using multiple nameservers.
-// returns the first successful response, or an error if all nameservers fail.
-func performQuery(c *dns.Client, m *dns.Msg) (*dns.Msg, error) {
+// returns the first successful response, or an error if all nameservers fail
+// or the context is done.
+func performQuery(ctx context.Context, c *dns.Client, m *dns.Msg) (*dns.Msg, error) {
 	var errs []string
 	for _, nameserver := range nameservers {
-		r, _, err := c.Exchange(m, nameserver)
-		log := log.With().Str("nameserver", nameserver).Logger()
+		// Stop trying when the deadline for the check has passed.
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("query cancelled: %w", err)
+		}
+		r, err := exchange(ctx, c, m, nameserver)
+		log := tracing.Logger(ctx, log.With().Str("nameserver", nameserver).Logger())
 		if err != nil {
 			// errMsg := fmt.Sprintf("Error querying DNS server [%s]: %v", nameserver, err)
 			// log.Warn().Msg(errMsg)
@@ -432,6 +455,27 @@ func performQuery(c *dns.Client, m *dns.Msg) (*dns.Msg, error) {
 	return nil, fmt.Errorf("all nameservers failed: %s", strings.Join(errs, "; "))
 }
 
+// exchange sends a query to a nameserver, and records it in the stats and in a span.
+func exchange(ctx context.Context, c *dns.Client, m *dns.Msg, nameserver string) (*dns.Msg, error) {
+	ctx, span := tracer.Start(ctx, "dns.exchange",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DNSQuestionName(m.Question[0].Name),
+			qtypeKey.String(dns.TypeToString[m.Question[0].Qtype]),
+			semconv.ServerAddress(nameserver),
+		),
+	)
+	defer span.End()
+
+	r, rtt, err := c.ExchangeContext(ctx, m, nameserver)
+	recordQuery(ctx, nameserver, r, rtt, err)
+	tracing.RecordError(span, err)
+	if r != nil {
+		span.SetAttributes(rcodeKey.String(dns.RcodeToString[r.Rcode]))
+	}
+	return r, err
+}
+
 // convertToASCII converts a domain to ASCII (Punycode) using IDNA2008 rules.
 func convertToASCII(domain string) (string, error) {
 	asciiDomain, err := idna.Lookup.ToASCII(domain)
@@ -442,7 +486,7 @@ func convertToASCII(domain string) (string, error) {
 }
 
 // ValidateDomain checks if the domain has enough DNS information to proceed with the checks.
-func ValidateDomain(domain string) (int, error) {
+func ValidateDomain(ctx context.Context, domain string) (int, error) {
 	c := &dns.Client{Timeout: DefaultTimeout}
 
 	// Convert domain to ASCII for DNS lookup
@@ -457,7 +501,7 @@ func ValidateDomain(domain string) (int, error) {
 	m.RecursionDesired = true
 
 	// Check if domain has any DNS records, else disable it before performing any checks
-	result, err := performQuery(c, m)
+	result, err := performQuery(ctx, c, m)
 	if err != nil {
 		return 0, err
 	}
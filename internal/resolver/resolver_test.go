@@ -0,0 +1,22 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDomainStatusCancelled(t *testing.T) {
+	// The checks of a domain whose deadline has passed fail, instead of
+	// returning empty statuses that look like a domain without records.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := DomainStatus(ctx, "example.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("DomainStatus() error = %v, want context.Canceled", err)
+	}
+	if result != (DomainResult{}) {
+		t.Errorf("DomainStatus() = %+v, want no result", result)
+	}
+}
@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"whynoipv6/internal/metrics"
+
+	"github.com/miekg/dns"
+)
+
+// QueryStats holds counters for the DNS queries made by the resolver.
+type QueryStats struct {
+	Queries  int64         // Number of queries sent to a nameserver
+	Errors   int64         // Queries that failed without an answer
+	Timeouts int64         // Queries that timed out, also counted in Errors
+	ServFail int64         // Answers with a SERVFAIL rcode
+	Latency  time.Duration // Total round trip time of the answered queries
+}
+
+// ErrorRate returns the share of queries that failed or got a SERVFAIL answer.
+func (s QueryStats) ErrorRate() float64 {
+	if s.Queries == 0 {
+		return 0
+	}
+	return float64(s.Errors+s.ServFail) / float64(s.Queries)
+}
+
+// AvgLatency returns the average round trip time of the answered queries.
+func (s QueryStats) AvgLatency() time.Duration {
+	answered := s.Queries - s.Errors
+	if answered <= 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(answered)
+}
+
+// StatsSink counts the queries made with a context, see WithStats. It is safe
+// for concurrent use.
+type StatsSink struct {
+	queries  atomic.Int64
+	errors   atomic.Int64
+	timeouts atomic.Int64
+	servFail atomic.Int64
+	latency  atomic.Int64
+}
+
+// Stats returns a snapshot of the counters of the sink.
+func (s *StatsSink) Stats() QueryStats {
+	return QueryStats{
+		Queries:  s.queries.Load(),
+		Errors:   s.errors.Load(),
+		Timeouts: s.timeouts.Load(),
+		ServFail: s.servFail.Load(),
+		Latency:  time.Duration(s.latency.Load()),
+	}
+}
+
+// statsKey is the context key of the stats sink.
+type statsKey struct{}
+
+// WithStats returns a context that counts the queries made with it in sink, so
+// every crawler run can see how the resolvers handled its own queries.
+func WithStats(ctx context.Context, sink *StatsSink) context.Context {
+	return context.WithValue(ctx, statsKey{}, sink)
+}
+
+// recordQuery updates the metrics of the nameserver, and the stats sink of the
+// context, with the outcome of a single query.
+func recordQuery(ctx context.Context, nameserver string, r *dns.Msg, rtt time.Duration, err error) {
+	// A query cut short by our own deadline or cancellation says nothing about
+	// the nameserver.
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	metrics.DNSQuery(nameserver, r != nil && r.Rcode == dns.RcodeServerFailure, rtt, err)
+
+	sink, ok := ctx.Value(statsKey{}).(*StatsSink)
+	if !ok {
+		return
+	}
+	sink.queries.Add(1)
+	if err != nil {
+		sink.errors.Add(1)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			sink.timeouts.Add(1)
+		}
+		return
+	}
+	sink.latency.Add(int64(rtt))
+	if r != nil && r.Rcode == dns.RcodeServerFailure {
+		sink.servFail.Add(1)
+	}
+}
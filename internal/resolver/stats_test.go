@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRecordQuery(t *testing.T) {
+	servFail := new(dns.Msg)
+	servFail.Rcode = dns.RcodeServerFailure
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		r    *dns.Msg
+		rtt  time.Duration
+		err  error
+		want QueryStats
+	}{
+		{"answer", context.Background(), new(dns.Msg), 30 * time.Millisecond, nil,
+			QueryStats{Queries: 1, Latency: 30 * time.Millisecond}},
+		{"servfail", context.Background(), servFail, 10 * time.Millisecond, nil,
+			QueryStats{Queries: 1, ServFail: 1, Latency: 10 * time.Millisecond}},
+		{"error", context.Background(), nil, 0, errors.New("connection refused"),
+			QueryStats{Queries: 1, Errors: 1}},
+		{"timeout", context.Background(), nil, 0, timeoutError{},
+			QueryStats{Queries: 1, Errors: 1, Timeouts: 1}},
+		{"our own cancellation", cancelled, nil, 0, timeoutError{},
+			QueryStats{}},
+		{"answer after cancellation", cancelled, new(dns.Msg), 5 * time.Millisecond, nil,
+			QueryStats{Queries: 1, Latency: 5 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &StatsSink{}
+			recordQuery(WithStats(tt.ctx, sink), "192.0.2.1:53", tt.r, tt.rtt, tt.err)
+			if got := sink.Stats(); got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordQueryWithoutSink(t *testing.T) {
+	// Queries made outside a crawler run are not counted anywhere, and must not panic.
+	recordQuery(context.Background(), "192.0.2.1:53", new(dns.Msg), time.Millisecond, nil)
+}
+
+func TestQueryStats(t *testing.T) {
+	s := QueryStats{Queries: 10, Errors: 2, ServFail: 1, Latency: 800 * time.Millisecond}
+	if got := s.ErrorRate(); got != 0.3 {
+		t.Errorf("ErrorRate() = %v, want 0.3", got)
+	}
+	if got := s.AvgLatency(); got != 100*time.Millisecond {
+		t.Errorf("AvgLatency() = %v, want 100ms", got)
+	}
+	if got := (QueryStats{}).ErrorRate(); got != 0 {
+		t.Errorf("ErrorRate() of no queries = %v, want 0", got)
+	}
+	if got := (QueryStats{Queries: 3, Errors: 3}).AvgLatency(); got != 0 {
+		t.Errorf("AvgLatency() of no answers = %v, want 0", got)
+	}
+}
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"whynoipv6/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// AccessLog is a middleware that logs every request when it has been served,
+// with its status, size, duration, request ID and trace ID. Server errors are
+// logged at the error level. It must come after the RequestID and Trace
+// middleware.
+func AccessLog(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger := tracing.Logger(r.Context(), logger)
+			event := logger.Info()
+			if status >= http.StatusInternalServerError {
+				event = logger.Error()
+			}
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			event.
+				Str("method", r.Method).
+				Str("path", r.URL.RequestURI()).
+				Str("route", route).
+				Int("status", status).
+				Int("bytes", ww.BytesWritten()).
+				Dur("duration", time.Since(start)).
+				Str("remote", r.RemoteAddr).
+				Str("user_agent", r.UserAgent()).
+				Str("request_id", middleware.GetReqID(r.Context())).
+				Msg("Request")
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/postgres"
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/net/idna"
+)
+
+// maxAdminBody is the largest request body the admin API accepts, in bytes.
+const maxAdminBody = 64 << 10
+
+// errExists is returned by a change if what it adds already exists.
+var errExists = errors.New("already exists")
+
+// AdminHandler is a handler for the admin API, which changes domains and
+// campaigns. Every request needs an admin API key, and every change is written
+// to the audit log in the same transaction as the change itself.
+type AdminHandler struct {
+	DB TxDB
+}
+
+// TxDB is a database that runs transactions, e.g. a *pgxpool.Pool.
+type TxDB interface {
+	db.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// AdminDomainRequest is the request body to add a domain.
+type AdminDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// AdminDisableRequest is the request body to disable a domain.
+type AdminDisableRequest struct {
+	Reason string `json:"reason"` // Why the domain is disabled, e.g. spam
+}
+
+// AdminCampaignRequest is the request body to create or edit a campaign. When
+// editing, the fields that are not set are left as they are.
+type AdminCampaignRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// AuditLogInput is the query parameters of the audit log.
+type AuditLogInput struct {
+	PaginationInput
+	Target string `in:"query=target"` // Only the changes to a domain or campaign
+}
+
+// adminDescription documents how every admin endpoint responds.
+const adminDescription = "Needs an admin API key. Responds with the audit log entry of the change."
+
+// Routes returns a router with all admin endpoints mounted.
+func (rs AdminHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(rs.requireAdmin)
+
+	// GET /admin/audit - list the changes made through the admin API
+	r.With(httpin.NewInput(AuditLogInput{})).Get("/audit", rs.AuditLog)
+	// POST /admin/domain - add a domain
+	r.Post("/domain", rs.AddDomain)
+	// DELETE /admin/domain/{domain} - remove a domain and its history
+	r.Delete("/domain/{domain}", rs.RemoveDomain)
+	// POST /admin/domain/{domain}/disable - stop crawling and listing a domain
+	r.Post("/domain/{domain}/disable", rs.DisableDomain)
+	// POST /admin/domain/{domain}/enable - crawl and list a disabled domain again
+	r.Post("/domain/{domain}/enable", rs.EnableDomain)
+	// POST /admin/domain/{domain}/recheck - queue a recheck, without the rate limits
+	r.Post("/domain/{domain}/recheck", rs.RecheckDomain)
+	// POST /admin/campaign - create a campaign
+	r.Post("/campaign", rs.CreateCampaign)
+	// PATCH /admin/campaign/{uuid} - edit the name or description of a campaign
+	r.Patch("/campaign/{uuid}", rs.UpdateCampaign)
+	// POST /admin/campaign/{uuid}/domain - add a domain to a campaign
+	r.Post("/campaign/{uuid}/domain", rs.AddCampaignDomain)
+	// DELETE /admin/campaign/{uuid}/domain/{domain} - remove a domain from a campaign
+	r.Delete("/campaign/{uuid}/domain/{domain}", rs.RemoveCampaignDomain)
+
+	return r
+}
+
+// Operations returns the documentation of all admin endpoints.
+func (rs AdminHandler) Operations() []Operation {
+	change := core.AuditLogModel{}
+	return []Operation{
+		{Method: "GET", Path: "/audit", Summary: "List the changes made through the admin API, newest first", Description: "Needs an admin API key.", Input: AuditLogInput{}, Response: []core.AuditLogModel{}, Admin: true},
+		{Method: "POST", Path: "/domain", Summary: "Add a domain, which is checked by the next crawl", Description: adminDescription, Body: AdminDomainRequest{}, Response: change, Admin: true},
+		{Method: "DELETE", Path: "/domain/{domain}", Summary: "Remove a domain with its changelog and history", Description: adminDescription + " Domains in the site list are added again by the next import, disable them instead.", Response: change, Admin: true},
+		{Method: "POST", Path: "/domain/{domain}/disable", Summary: "Disable a domain, which is no longer crawled or listed", Description: adminDescription, Body: AdminDisableRequest{}, Response: change, Admin: true},
+		{Method: "POST", Path: "/domain/{domain}/enable", Summary: "Enable a disabled domain", Description: adminDescription, Response: change, Admin: true},
+		{Method: "POST", Path: "/domain/{domain}/recheck", Summary: "Queue a recheck of a domain, without the rate limits of /domain/{domain}/recheck", Description: adminDescription + " The job is in the details, poll /jobs/{id} for the result.", Response: change, Admin: true},
+		{Method: "POST", Path: "/campaign", Summary: "Create a campaign", Description: adminDescription + " The uuid of the campaign is in the details.", Body: AdminCampaignRequest{}, Response: change, Admin: true},
+		{Method: "PATCH", Path: "/campaign/{uuid}", Summary: "Edit the name or description of a campaign", Description: adminDescription, Body: AdminCampaignRequest{}, Response: change, Admin: true},
+		{Method: "POST", Path: "/campaign/{uuid}/domain", Summary: "Add a domain to a campaign", Description: adminDescription, Body: AdminDomainRequest{}, Response: change, Admin: true},
+		{Method: "DELETE", Path: "/campaign/{uuid}/domain/{domain}", Summary: "Remove a domain from a campaign", Description: adminDescription, Response: change, Admin: true},
+	}
+}
+
+// requireAdmin is a middleware that only lets requests with an admin API key
+// through. The rate limiter caches keys for a while, so the key is read from
+// the database again: a revoked key can not make changes.
+func (rs AdminHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, ok := APIKeyFromContext(r.Context())
+		if !ok {
+			_ = render.Render(w, r, ErrUnauthorized(errors.New("the admin API needs an admin API key")))
+			return
+		}
+		apiKey, err := core.NewAPIKeyService(postgres.Traced(rs.DB)).GetAPIKeyByID(r.Context(), apiKey.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			_ = render.Render(w, r, ErrUnauthorized(errors.New("invalid or revoked API key")))
+			return
+		}
+		if err != nil {
+			renderAdminError(w, r, err)
+			return
+		}
+		if !apiKey.Admin {
+			_ = render.Render(w, r, ErrForbidden(errors.New("the API key can not use the admin API")))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey)))
+	})
+}
+
+// AuditLog lists the changes made through the admin API with pagination.
+func (rs AdminHandler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*AuditLogInput)
+
+	// Limit the maximum number of entries per page to 100
+	if input.Limit > 100 {
+		input.Limit = 100
+	}
+
+	entries, err := core.NewAuditService(postgres.Traced(rs.DB)).ListAuditLog(r.Context(), input.Target, input.Offset, input.Limit)
+	if err != nil {
+		renderAdminError(w, r, err)
+		return
+	}
+	render.JSON(w, r, entries)
+}
+
+// AddDomain adds a domain, which is checked by the next crawl.
+func (rs AdminHandler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	var body AdminDomainRequest
+	if err := decodeAdminBody(w, r, &body); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	site, err := adminSite(body.Domain)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	entry, ok := rs.change(w, r, core.AuditDomainAdd, site, func(tx db.DBTX) (any, error) {
+		domain, err := core.NewDomainService(tx).CreateDomain(r.Context(), site)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("domain %s %w", site, errExists)
+		}
+		return map[string]any{"id": domain.ID}, err
+	})
+	if ok {
+		renderChange(w, r, http.StatusCreated, entry)
+	}
+}
+
+// RemoveDomain removes a domain with its changelog and history.
+func (rs AdminHandler) RemoveDomain(w http.ResponseWriter, r *http.Request) {
+	site := strings.ToLower(chi.URLParam(r, "domain"))
+	entry, ok := rs.change(w, r, core.AuditDomainRemove, site, func(tx db.DBTX) (any, error) {
+		domains := core.NewDomainService(tx)
+		domain, err := domains.GetDomain(r.Context(), site)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"id": domain.ID}, domains.DeleteDomain(r.Context(), domain.ID)
+	})
+	if ok {
+		renderChange(w, r, http.StatusOK, entry)
+	}
+}
+
+// DisableDomain disables a domain with a reason, so it is no longer crawled or listed.
+func (rs AdminHandler) DisableDomain(w http.ResponseWriter, r *http.Request) {
+	var body AdminDisableRequest
+	if err := decodeAdminBody(w, r, &body); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("a reason is required")))
+		return
+	}
+
+	site := strings.ToLower(chi.URLParam(r, "domain"))
+	entry, ok := rs.change(w, r, core.AuditDomainDisable, site, func(tx db.DBTX) (any, error) {
+		err := core.NewDomainService(tx).SetDomainDisabled(r.Context(), site, true, body.Reason)
+		return map[string]any{"reason": body.Reason}, err
+	})
+	if ok {
+		renderChange(w, r, http.StatusOK, entry)
+	}
+}
+
+// EnableDomain enables a disabled domain, so it is crawled and listed again.
+func (rs AdminHandler) EnableDomain(w http.ResponseWriter, r *http.Request) {
+	site := strings.ToLower(chi.URLParam(r, "domain"))
+	entry, ok := rs.change(w, r, core.AuditDomainEnable, site, func(tx db.DBTX) (any, error) {
+		return map[string]any{}, core.NewDomainService(tx).SetDomainDisabled(r.Context(), site, false, "")
+	})
+	if ok {
+		renderChange(w, r, http.StatusOK, entry)
+	}
+}
+
+// RecheckDomain queues a recheck of a domain, without the rate limits of the
+// public endpoint. If a recheck of the domain is already queued or running, that job is used.
+func (rs AdminHandler) RecheckDomain(w http.ResponseWriter, r *http.Request) {
+	site := strings.ToLower(chi.URLParam(r, "domain"))
+	apiKey, _ := APIKeyFromContext(r.Context())
+	var job core.RecheckJobModel
+	entry, ok := rs.change(w, r, core.AuditDomainRecheck, site, func(tx db.DBTX) (any, error) {
+		domain, err := core.NewDomainService(tx).GetDomain(r.Context(), site)
+		if err != nil {
+			return nil, err
+		}
+		client := "apikey:" + strconv.FormatInt(apiKey.ID, 10)
+		job, _, err = core.NewRecheckJobService(tx).CreateJob(r.Context(), domain, client, core.JobPriorityHigh)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"job": encodeUUID(job.ID)}, nil
+	})
+	if ok {
+		w.Header().Set("Location", "/jobs/"+encodeUUID(job.ID))
+		renderChange(w, r, http.StatusAccepted, entry)
+	}
+}
+
+// CreateCampaign creates a campaign.
+func (rs AdminHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var body AdminCampaignRequest
+	if err := decodeAdminBody(w, r, &body); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	if body.Name == nil || strings.TrimSpace(*body.Name) == "" {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("a name is required")))
+		return
+	}
+	name, description := strings.TrimSpace(*body.Name), ""
+	if body.Description != nil {
+		description = strings.TrimSpace(*body.Description)
+	}
+
+	// The campaign is the target, so its uuid is only known after it is created.
+	entry, ok := rs.changeTarget(w, r, core.AuditCampaignCreate, func(tx db.DBTX) (string, any, error) {
+		campaign, err := core.NewCampaignService(tx).CreateCampaign(r.Context(), name, description)
+		if err != nil {
+			return "", nil, err
+		}
+		id := encodeUUID(campaign.UUID)
+		return id, map[string]any{"uuid": id, "name": name, "description": description}, nil
+	})
+	if ok {
+		w.Header().Set("Location", "/campaign/"+entry.Target)
+		renderChange(w, r, http.StatusCreated, entry)
+	}
+}
+
+// UpdateCampaign edits the name or description of a campaign.
+func (rs AdminHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := decodeUUID(chi.URLParam(r, "uuid"))
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("invalid campaign uuid")))
+		return
+	}
+	var body AdminCampaignRequest
+	if err := decodeAdminBody(w, r, &body); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	if body.Name != nil && strings.TrimSpace(*body.Name) == "" {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("the name can not be empty")))
+		return
+	}
+
+	entry, ok := rs.change(w, r, core.AuditCampaignUpdate, encodeUUID(id), func(tx db.DBTX) (any, error) {
+		campaigns := core.NewCampaignService(tx)
+		campaign, err := campaigns.GetCampaign(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]any{}
+		if body.Name != nil {
+			details["name"] = strings.TrimSpace(*body.Name)
+			campaign.Name = strings.TrimSpace(*body.Name)
+		}
+		if body.Description != nil {
+			details["description"] = strings.TrimSpace(*body.Description)
+			campaign.Description = strings.TrimSpace(*body.Description)
+		}
+		_, err = campaigns.UpdateCampaign(r.Context(), id, campaign.Name, campaign.Description)
+		return details, err
+	})
+	if ok {
+		renderChange(w, r, http.StatusOK, entry)
+	}
+}
+
+// AddCampaignDomain adds a domain to a campaign, which is checked by the next campaign crawl.
+func (rs AdminHandler) AddCampaignDomain(w http.ResponseWriter, r *http.Request) {
+	id, err := decodeUUID(chi.URLParam(r, "uuid"))
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("invalid campaign uuid")))
+		return
+	}
+	var body AdminDomainRequest
+	if err := decodeAdminBody(w, r, &body); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	site, err := adminSite(body.Domain)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	entry, ok := rs.change(w, r, core.AuditCampaignDomainAdd, encodeUUID(id), func(tx db.DBTX) (any, error) {
+		campaigns := core.NewCampaignService(tx)
+		if _, err := campaigns.GetCampaign(r.Context(), id); err != nil {
+			return nil, err
+		}
+		_, err := campaigns.ViewCampaignDomain(r.Context(), id, site)
+		if err == nil {
+			return nil, fmt.Errorf("domain %s is in the campaign, it %w", site, errExists)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return map[string]any{"domain": site}, campaigns.InsertCampaignDomain(r.Context(), id, site)
+	})
+	if ok {
+		renderChange(w, r, http.StatusCreated, entry)
+	}
+}
+
+// RemoveCampaignDomain removes a domain from a campaign, with its changelog and history.
+func (rs AdminHandler) RemoveCampaignDomain(w http.ResponseWriter, r *http.Request) {
+	id, err := decodeUUID(chi.URLParam(r, "uuid"))
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("invalid campaign uuid")))
+		return
+	}
+	site := strings.ToLower(chi.URLParam(r, "domain"))
+
+	entry, ok := rs.change(w, r, core.AuditCampaignDomainRemove, encodeUUID(id), func(tx db.DBTX) (any, error) {
+		campaigns := core.NewCampaignService(tx)
+		if _, err := campaigns.ViewCampaignDomain(r.Context(), id, site); err != nil {
+			return nil, err
+		}
+		return map[string]any{"domain": site}, campaigns.DeleteCampaignDomain(r.Context(), id, site)
+	})
+	if ok {
+		renderChange(w, r, http.StatusOK, entry)
+	}
+}
+
+// change makes a change to a target in a transaction, and writes it to the
+// audit log with the details returned by fn. It renders the error and returns
+// false if the change fails.
+func (rs AdminHandler) change(
+	w http.ResponseWriter,
+	r *http.Request,
+	action, target string,
+	fn func(tx db.DBTX) (any, error),
+) (core.AuditLogModel, bool) {
+	return rs.changeTarget(w, r, action, func(tx db.DBTX) (string, any, error) {
+		details, err := fn(tx)
+		return target, details, err
+	})
+}
+
+// changeTarget is change for a target that is only known after the change.
+func (rs AdminHandler) changeTarget(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	fn func(tx db.DBTX) (string, any, error),
+) (core.AuditLogModel, bool) {
+	apiKey, _ := APIKeyFromContext(r.Context())
+	var entry core.AuditLogModel
+	err := inTx(r.Context(), rs.DB, func(tx db.DBTX) error {
+		target, details, err := fn(tx)
+		if err != nil {
+			return err
+		}
+		entry, err = core.NewAuditService(tx).CreateAuditLog(r.Context(), apiKey, action, target, details)
+		return err
+	})
+	if err != nil {
+		renderAdminError(w, r, err)
+		return entry, false
+	}
+	log.Printf("Admin: %s %s %s by %s", entry.Action, entry.Target, entry.Details.Bytes, entry.Actor)
+	return entry, true
+}
+
+// renderChange responds with the audit log entry of a change.
+func renderChange(w http.ResponseWriter, r *http.Request, status int, entry core.AuditLogModel) {
+	render.Status(r, status)
+	render.JSON(w, r, entry)
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func inTx(ctx context.Context, pool TxDB, fn func(tx db.DBTX) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // No-op after a commit
+	if err := fn(postgres.Traced(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// renderAdminError renders the error of an admin request.
+func renderAdminError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		_ = render.Render(w, r, ErrNotFound())
+	case errors.Is(err, errExists):
+		_ = render.Render(w, r, ErrConflict(err))
+	default:
+		log.Println("Error in admin request:", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+	}
+}
+
+// decodeAdminBody decodes the JSON request body of an admin request.
+func decodeAdminBody(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+// adminSite validates a domain to add, and returns it in its lowercase ASCII form.
+func adminSite(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", errors.New("a domain is required")
+	}
+	site, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(site, ".") || strings.Contains(site, "..") {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+	return site, nil
+}
@@ -0,0 +1,297 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// adminDB is a database with at most one API key, whose transactions record
+// the statements they run and how they end.
+type adminDB struct {
+	key      *db.ApiKey // Not revoked, nil if there is none
+	keyErr   error      // Error of reading the key
+	auditErr error      // Error of writing to the audit log
+	keyReads int
+	txs      []*adminTx
+}
+
+func (d *adminDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return nil, errors.New("not in a transaction")
+}
+
+func (d *adminDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *adminDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	if sql != db.GetAPIKey {
+		return errRow{errors.New("not in a transaction")}
+	}
+	d.keyReads++
+	switch {
+	case d.keyErr != nil:
+		return errRow{d.keyErr}
+	case d.key == nil || d.key.ID != args[0]:
+		return errRow{pgx.ErrNoRows}
+	}
+	return apiKeyRow{*d.key}
+}
+
+func (d *adminDB) Begin(context.Context) (pgx.Tx, error) {
+	tx := &adminTx{db: d}
+	d.txs = append(d.txs, tx)
+	return tx, nil
+}
+
+// adminTx is a transaction of an adminDB.
+type adminTx struct {
+	pgx.Tx
+	db         *adminDB
+	statements []string
+	auditArgs  []any
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *adminTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	tx.statements = append(tx.statements, sql)
+	return pgconn.CommandTag("UPDATE 1"), nil
+}
+
+func (tx *adminTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	tx.statements = append(tx.statements, sql)
+	if sql != db.CreateAuditLog {
+		return errRow{errors.New("not implemented")}
+	}
+	tx.auditArgs = args
+	if tx.db.auditErr != nil {
+		return errRow{tx.db.auditErr}
+	}
+	return auditRow{args}
+}
+
+func (tx *adminTx) Commit(context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *adminTx) Rollback(context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+// errRow is a row that scans nothing and returns err.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// auditRow is the audit log row inserted with the arguments.
+type auditRow struct {
+	args []any
+}
+
+func (r auditRow) Scan(dest ...any) error {
+	*dest[3].(*string) = r.args[1].(string)
+	*dest[4].(*string) = r.args[2].(string)
+	*dest[5].(*string) = r.args[3].(string)
+	*dest[6].(*pgtype.JSONB) = r.args[4].(pgtype.JSONB)
+	return nil
+}
+
+// apiKeyRow is a row of the api_key table.
+type apiKeyRow struct {
+	key db.ApiKey
+}
+
+func (r apiKeyRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = r.key.ID
+	*dest[1].(*string) = r.key.Name
+	*dest[9].(*bool) = r.key.Admin
+	return nil
+}
+
+// withAPIKey returns the request as if the rate limiter found the key.
+func withAPIKey(r *http.Request, key core.APIKeyModel) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key))
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admin := &db.ApiKey{ID: 1, Name: "moderator", Admin: true}
+	tests := []struct {
+		name       string
+		key        *core.APIKeyModel // As cached by the rate limiter
+		db         *adminDB
+		wantStatus int
+		wantReads  int
+	}{
+		{"no key", nil, &adminDB{key: admin}, http.StatusUnauthorized, 0},
+		{"admin key", &core.APIKeyModel{ID: 1, Admin: true}, &adminDB{key: admin}, http.StatusOK, 1},
+		{"key that is not an admin key", &core.APIKeyModel{ID: 2}, &adminDB{key: &db.ApiKey{ID: 2}}, http.StatusForbidden, 1},
+		{"key that is no longer an admin key", &core.APIKeyModel{ID: 2, Admin: true}, &adminDB{key: &db.ApiKey{ID: 2}}, http.StatusForbidden, 1},
+		{"revoked key", &core.APIKeyModel{ID: 1, Admin: true}, &adminDB{}, http.StatusUnauthorized, 1},
+		{"database error", &core.APIKeyModel{ID: 1, Admin: true}, &adminDB{keyErr: errors.New("connection refused")}, http.StatusInternalServerError, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/domain", nil)
+			if tt.key != nil {
+				r = withAPIKey(r, *tt.key)
+			}
+			var got core.APIKeyModel
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = APIKeyFromContext(r.Context())
+			})
+			rec := httptest.NewRecorder()
+			AdminHandler{DB: tt.db}.requireAdmin(next).ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.db.keyReads != tt.wantReads {
+				t.Errorf("key read %d times, want %d", tt.db.keyReads, tt.wantReads)
+			}
+			if tt.wantStatus == http.StatusOK && got.Name != admin.Name {
+				t.Errorf("key in the context = %+v, want the key from the database", got)
+			}
+		})
+	}
+}
+
+func TestChange(t *testing.T) {
+	const update = "UPDATE domain SET disabled = true"
+	tests := []struct {
+		name           string
+		changeErr      error
+		auditErr       error
+		wantStatus     int
+		wantCommit     bool
+		wantStatements []string
+	}{
+		{"change", nil, nil, http.StatusOK, true, []string{update, db.CreateAuditLog}},
+		{"change fails", errors.New("connection refused"), nil, http.StatusInternalServerError, false, []string{update}},
+		{"target not found", fmt.Errorf("domain: %w", pgx.ErrNoRows), nil, http.StatusNotFound, false, []string{update}},
+		{"target exists", fmt.Errorf("domain example.com %w", errExists), nil, http.StatusConflict, false, []string{update}},
+		{"audit log fails", nil, errors.New("connection refused"), http.StatusInternalServerError, false, []string{update, db.CreateAuditLog}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &adminDB{auditErr: tt.auditErr}
+			rs := AdminHandler{DB: d}
+			r := withAPIKey(httptest.NewRequest(http.MethodPost, "/domain/example.com/disable", nil),
+				core.APIKeyModel{ID: 1, Name: "moderator", Admin: true})
+			rec := httptest.NewRecorder()
+
+			entry, ok := rs.change(rec, r, core.AuditDomainDisable, "example.com", func(tx db.DBTX) (any, error) {
+				if _, err := tx.Exec(r.Context(), update); err != nil {
+					return nil, err
+				}
+				return map[string]any{"reason": "spam"}, tt.changeErr
+			})
+			if ok {
+				renderChange(rec, r, http.StatusOK, entry)
+			}
+
+			if ok != (tt.wantStatus == http.StatusOK) || rec.Code != tt.wantStatus {
+				t.Errorf("change() = %v with status %d, want %d", ok, rec.Code, tt.wantStatus)
+			}
+			if len(d.txs) != 1 {
+				t.Fatalf("%d transactions, want 1", len(d.txs))
+			}
+			tx := d.txs[0]
+			if !slices.Equal(tx.statements, tt.wantStatements) {
+				t.Errorf("statements = %q, want %q", tx.statements, tt.wantStatements)
+			}
+			if tx.committed != tt.wantCommit || tx.rolledBack == tt.wantCommit {
+				t.Errorf("committed = %v, rolled back = %v, want committed %v", tx.committed, tx.rolledBack, tt.wantCommit)
+			}
+			if tt.changeErr == nil {
+				want := []any{sql.NullInt64{Int64: 1, Valid: true}, "moderator", core.AuditDomainDisable, "example.com"}
+				if len(tx.auditArgs) != 5 || !slices.Equal(tx.auditArgs[:4], want) ||
+					string(tx.auditArgs[4].(pgtype.JSONB).Bytes) != `{"reason":"spam"}` {
+					t.Errorf("audit log arguments = %v, want %v and the details", tx.auditArgs, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeAdminBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    AdminDomainRequest
+		wantErr bool
+	}{
+		{"valid", `{"domain": "example.com"}`, AdminDomainRequest{Domain: "example.com"}, false},
+		{"empty object", `{}`, AdminDomainRequest{}, false},
+		{"unknown field", `{"domain": "example.com", "rank": 1}`, AdminDomainRequest{}, true},
+		{"misspelled field", `{"domian": "example.com"}`, AdminDomainRequest{}, true},
+		{"wrong type", `{"domain": 1}`, AdminDomainRequest{}, true},
+		{"invalid JSON", `{"domain": `, AdminDomainRequest{}, true},
+		{"empty body", ``, AdminDomainRequest{}, true},
+		{"too large", `{"domain": "` + strings.Repeat("a", maxAdminBody) + `"}`, AdminDomainRequest{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/domain", strings.NewReader(tt.body))
+			var got AdminDomainRequest
+			err := decodeAdminBody(httptest.NewRecorder(), r, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeAdminBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("decodeAdminBody() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminSite(t *testing.T) {
+	tests := []struct {
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "example.com", false},
+		{" Example.COM. ", "example.com", false},
+		{"www.example.co.uk", "www.example.co.uk", false},
+		{"bücher.example", "xn--bcher-kva.example", false},
+		{"", "", true},
+		{" . ", "", true},
+		{"localhost", "", true},
+		{"exa mple.com", "", true},
+		{"-example.com", "", true},
+		{"example..com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := adminSite(tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("adminSite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("adminSite() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/badge"
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+// Badges are cached by browsers, GitHub's image proxy and shields.io. The crawler
+// checks a domain every few days, so an hour old badge is still accurate, while
+// errors are retried sooner.
+const (
+	badgeMaxAge      = time.Hour
+	badgeErrorMaxAge = 5 * time.Minute
+)
+
+// maxBadgeLabel is the maximum length of a custom badge label.
+const maxBadgeLabel = 64
+
+// BadgeHandler is a handler for the status badges of domains and campaigns.
+type BadgeHandler struct {
+	Domains   *core.DomainService
+	Campaigns *core.CampaignService
+}
+
+// ShieldsResponse is the response structure for the shields.io endpoint badge,
+// see https://shields.io/badges/endpoint-badge.
+type ShieldsResponse struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	IsError       bool   `json:"isError,omitempty"`
+	Style         string `json:"style,omitempty"`
+	CacheSeconds  int    `json:"cacheSeconds"`
+}
+
+// badgeDescription documents the formats and query parameters of a badge endpoint.
+const badgeDescription = "The badge ends in .svg for an SVG image, or in .json for the shields.io endpoint badge schema. " +
+	"The style query parameter is flat (default) or for-the-badge, and label replaces the text on the left side."
+
+// Routes returns a router with all badge endpoints mounted.
+func (rs BadgeHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Domain names contain dots, so the file extension is split off in the
+	// handler rather than in the route pattern.
+
+	// GET /badge/domain/{domain}.svg - status badge for a domain
+	r.Get("/domain/{badge}", rs.DomainBadge)
+	// GET /badge/campaign/{uuid}.svg - status badge for a campaign
+	r.Get("/campaign/{badge}", rs.CampaignBadge)
+
+	return r
+}
+
+// Operations returns the documentation of all badge endpoints.
+func (rs BadgeHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:      "GET",
+			Path:        "/domain/{badge}",
+			Summary:     "IPv6 status badge for a domain, e.g. /badge/domain/github.com.svg",
+			Description: badgeDescription,
+			Query:       badgeQuery,
+			Response:    ShieldsResponse{},
+		},
+		{
+			Method:      "GET",
+			Path:        "/campaign/{badge}",
+			Summary:     "IPv6 status badge for a campaign, e.g. /badge/campaign/{uuid}.svg",
+			Description: badgeDescription,
+			Query:       badgeQuery,
+			Response:    ShieldsResponse{},
+		},
+	}
+}
+
+// badgeQuery documents the query parameters of the badge endpoints.
+var badgeQuery = []QueryParam{
+	{Name: "style", Description: "Badge style", Enum: []string{string(badge.StyleFlat), string(badge.StyleForTheBadge)}},
+	{Name: "label", Description: "Text on the left side of the badge"},
+}
+
+// DomainBadge renders the IPv6 status badge for a domain.
+func (rs BadgeHandler) DomainBadge(w http.ResponseWriter, r *http.Request) {
+	site, format, ok := splitBadge(w, r)
+	if !ok {
+		return
+	}
+
+	b := badge.Badge{Label: "IPv6"}
+	domain, err := rs.Domains.ViewDomain(r.Context(), strings.ToLower(site))
+	if err != nil {
+		renderBadgeError(w, r, format, b, err)
+		return
+	}
+	b.Message, b.Color = domainStatus(domain)
+	renderBadge(w, r, http.StatusOK, format, b)
+}
+
+// CampaignBadge renders the IPv6 status badge for a campaign, the number of
+// domains with IPv6 out of all domains in the campaign.
+func (rs BadgeHandler) CampaignBadge(w http.ResponseWriter, r *http.Request) {
+	id, format, ok := splitBadge(w, r)
+	if !ok {
+		return
+	}
+
+	b := badge.Badge{Label: "IPv6 ready"}
+	campaignID, err := decodeUUID(id)
+	if err != nil {
+		renderBadgeError(w, r, format, b, pgx.ErrNoRows)
+		return
+	}
+	campaign, err := rs.Campaigns.GetCampaign(r.Context(), campaignID)
+	if err != nil {
+		renderBadgeError(w, r, format, b, err)
+		return
+	}
+	b.Message = fmt.Sprintf("%d/%d", campaign.V6Ready, campaign.Count)
+	b.Color = campaignColor(campaign.V6Ready, campaign.Count)
+	renderBadge(w, r, http.StatusOK, format, b)
+}
+
+// domainStatus returns the badge message and color for a domain. A domain
+// supports IPv6 if the base domain has an IPv6 address and the www domain
+// does not lack one.
+func domainStatus(domain core.DomainModel) (string, string) {
+	switch {
+	case domain.BaseDomain == core.IPv6Available && domain.WwwDomain != core.IPv4Only:
+		return "supported", badge.ColorBrightGreen
+	case domain.BaseDomain == core.IPv6Available || domain.WwwDomain == core.IPv6Available:
+		return "partial", badge.ColorYellow
+	}
+	return "missing", badge.ColorRed
+}
+
+// campaignColor returns the badge color for the share of domains with IPv6 in a campaign.
+func campaignColor(ready, count int64) string {
+	switch {
+	case count == 0:
+		return badge.ColorLightGrey
+	case ready == count:
+		return badge.ColorBrightGreen
+	case ready*2 >= count:
+		return badge.ColorYellow
+	case ready > 0:
+		return badge.ColorOrange
+	}
+	return badge.ColorRed
+}
+
+// splitBadge splits the badge path parameter into the name and the file
+// extension, svg or json. It responds with an error if the extension or the
+// query parameters are not supported.
+func splitBadge(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	file := chi.URLParam(r, "badge")
+	ext := path.Ext(file)
+	if ext != ".svg" && ext != ".json" {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "badge must end in .svg or .json"})
+		return "", "", false
+	}
+	if _, ok := badge.ParseStyle(r.URL.Query().Get("style")); !ok {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("style must be flat or for-the-badge")))
+		return "", "", false
+	}
+	if len(r.URL.Query().Get("label")) > maxBadgeLabel {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("label must be at most "+strconv.Itoa(maxBadgeLabel)+" characters")))
+		return "", "", false
+	}
+	return strings.TrimSuffix(file, ext), ext[1:], true
+}
+
+// renderBadgeError renders a grey badge for a domain or campaign that does not
+// exist, or for an unexpected error.
+func renderBadgeError(w http.ResponseWriter, r *http.Request, format string, b badge.Badge, err error) {
+	b.Color = badge.ColorLightGrey
+	if errors.Is(err, pgx.ErrNoRows) {
+		b.Message = "not found"
+		renderBadge(w, r, http.StatusNotFound, format, b)
+		return
+	}
+	log.Println("Error rendering badge:", err)
+	b.Message = "error"
+	renderBadge(w, r, http.StatusInternalServerError, format, b)
+}
+
+// renderBadge renders a badge as SVG or as shields.io endpoint JSON, with the
+// style and label of the query parameters. The status is the status of the SVG
+// response, the JSON response is always a 200.
+func renderBadge(w http.ResponseWriter, r *http.Request, status int, format string, b badge.Badge) {
+	style, _ := badge.ParseStyle(r.URL.Query().Get("style"))
+	if label := r.URL.Query().Get("label"); label != "" {
+		b.Label = label
+	}
+
+	maxAge := badgeMaxAge
+	if status != http.StatusOK {
+		maxAge = badgeErrorMaxAge
+	}
+	// Badges are meant to be cached.
+	setMaxAge(w, maxAge)
+
+	if format == "json" {
+		// shields.io shows any response that is not a 200 as an invalid
+		// response, so errors are sent as a 200 with isError set.
+		render.JSON(w, r, ShieldsResponse{
+			SchemaVersion: 1,
+			Label:         b.Label,
+			Message:       b.Message,
+			Color:         b.Color,
+			IsError:       status != http.StatusOK,
+			Style:         string(style),
+			CacheSeconds:  int(maxAge.Seconds()),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	// The SVG is rendered inline when opened directly, so it must not run scripts.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(status)
+	_, _ = w.Write(b.SVG(style))
+}
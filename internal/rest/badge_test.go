@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"whynoipv6/internal/badge"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func TestRenderBadgeError(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{"SVG not found", "svg", pgx.ErrNoRows, http.StatusNotFound, "not found"},
+		{"SVG error", "svg", errors.New("connection refused"), http.StatusInternalServerError, "error"},
+		{"shields.io not found", "json", pgx.ErrNoRows, http.StatusOK, "not found"},
+		{"shields.io error", "json", errors.New("connection refused"), http.StatusOK, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/badge/domain/example.com."+tt.format, nil)
+			rec := httptest.NewRecorder()
+			renderBadgeError(rec, r, tt.format, badge.Badge{Label: "IPv6"}, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "public, max-age=300" {
+				t.Errorf("Cache-Control = %q, want the error max age", got)
+			}
+			if tt.format != "json" {
+				return
+			}
+			var response ShieldsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if !response.IsError || response.Message != tt.wantMessage || response.Color != badge.ColorLightGrey {
+				t.Errorf("response = %+v, want an error badge with %q", response, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedBody is the largest response the cache middleware buffers to
+	// compute its ETag. Larger responses, like exports, are streamed without one.
+	maxCachedBody = 1 << 20
+	// maxCacheEntries is the number of responses kept by a ResponseCache.
+	maxCacheEntries = 1000
+)
+
+// CachePolicy is how the GET responses of a route may be cached. Every
+// response that fits in memory gets a strong ETag, the hash of its body, and
+// requests with a matching If-None-Match, or an If-Modified-Since after the
+// Last-Modified set by the handler, get a 304 Not Modified.
+type CachePolicy struct {
+	MaxAge  time.Duration  // How long clients and proxies may use a response without revalidating it
+	NoStore bool           // The responses may not be cached at all, and get no ETag
+	Cache   *ResponseCache // Keeps the responses in memory, if not nil
+}
+
+// Cache policies of the routes.
+var (
+	// NoStore is for responses that change at any moment, or are private.
+	NoStore = CachePolicy{NoStore: true}
+	// Revalidate is for responses that change often. Clients revalidate them on
+	// every use, which costs a 304 when they have not changed.
+	Revalidate = CachePolicy{}
+	// CrawlData is for responses that change at most once per crawl.
+	CrawlData = CachePolicy{MaxAge: 5 * time.Minute}
+)
+
+// WithCache returns the policy with responses kept in an in-process cache.
+func (p CachePolicy) WithCache(cache *ResponseCache) CachePolicy {
+	p.Cache = cache
+	return p
+}
+
+// cacheControl returns the Cache-Control header of the policy.
+func (p CachePolicy) cacheControl() string {
+	switch {
+	case p.NoStore:
+		return "no-store"
+	case p.MaxAge <= 0:
+		return "no-cache"
+	default:
+		return "public, max-age=" + strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+}
+
+// Handler is a middleware that applies the policy to the responses of a route.
+// Handlers can replace the Cache-Control header, e.g. with setMaxAge.
+func (p CachePolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || p.NoStore {
+			w.Header().Set("Cache-Control", "no-store")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.URL.RequestURI()
+		if cached, ok := p.Cache.get(key); ok {
+			for name, values := range cached.header {
+				w.Header()[name] = slices.Clone(values)
+			}
+			writeConditional(w, r, cached.body)
+			return
+		}
+
+		w.Header().Set("Cache-Control", p.cacheControl())
+		before := w.Header().Clone()
+		bw := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(bw, r)
+		if bw.streaming {
+			return
+		}
+		if bw.status != http.StatusOK {
+			if bw.status >= http.StatusInternalServerError {
+				w.Header().Set("Cache-Control", "no-store")
+			}
+			w.WriteHeader(bw.status)
+			_, _ = w.Write(bw.body.Bytes())
+			return
+		}
+
+		body := bw.body.Bytes()
+		hash := sha256.Sum256(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+		p.Cache.set(key, handlerHeader(before, w.Header()), body)
+		writeConditional(w, r, body)
+	})
+}
+
+// writeConditional writes a 200 response with the body, or a 304 if the client
+// has it already. The ETag and Last-Modified headers must be set.
+func writeConditional(w http.ResponseWriter, r *http.Request, body []byte) {
+	if notModified(r, w.Header()) {
+		// A 304 has the validators and caching headers of the 200 it stands
+		// for, but no content headers.
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// notModified reports whether the client has the response already. Following
+// RFC 9110, If-Modified-Since is ignored if the request has an If-None-Match.
+func notModified(r *http.Request, header http.Header) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		etag := header.Get("ETag")
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	return err == nil && !modified.After(since)
+}
+
+// setLastModified sets the Last-Modified header to the latest of the times
+// the data of a response was changed. Zero times are ignored.
+func setLastModified(w http.ResponseWriter, times ...time.Time) {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	if !latest.IsZero() {
+		w.Header().Set("Last-Modified", latest.UTC().Format(http.TimeFormat))
+	}
+}
+
+// bufferedResponseWriter buffers a response, so its ETag can be computed
+// before it is sent. A response that is flushed or grows too large is
+// streamed instead.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	streaming   bool
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	if b.streaming {
+		b.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if !b.streaming && b.body.Len()+len(p) > maxCachedBody {
+		b.stream()
+	}
+	if b.streaming {
+		return b.ResponseWriter.Write(p)
+	}
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// Flush streams the response, e.g. a stream of events.
+func (b *bufferedResponseWriter) Flush() {
+	b.stream()
+	if flusher, ok := b.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (b *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// stream sends what has been buffered, and passes the rest of the response through.
+func (b *bufferedResponseWriter) stream() {
+	if b.streaming {
+		return
+	}
+	b.streaming = true
+	b.ResponseWriter.WriteHeader(b.status)
+	_, _ = b.ResponseWriter.Write(b.body.Bytes())
+	b.body = bytes.Buffer{}
+}
+
+// ResponseCache keeps the 200 responses of the routes whose policy uses it in
+// memory, so a burst of requests for the same data does not reach the
+// database. A nil cache keeps nothing.
+type ResponseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse // By request URI
+}
+
+// cachedResponse is a response kept by a ResponseCache.
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// NewResponseCache creates a cache that keeps responses for ttl. It returns
+// nil, a cache that keeps nothing, if ttl is not positive.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ResponseCache{ttl: ttl, entries: map[string]cachedResponse{}}
+}
+
+// get returns the response for a request URI, if it has not expired.
+func (c *ResponseCache) get(key string) (cachedResponse, bool) {
+	if c == nil {
+		return cachedResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.entries[key]
+	if !ok || time.Now().After(cached.expires) {
+		return cachedResponse{}, false
+	}
+	return cached, true
+}
+
+// handlerHeader returns the headers of a response that were set by the
+// handler and the cache middleware, and not by the middlewares before them,
+// like the rate limits.
+func handlerHeader(before, after http.Header) http.Header {
+	header := http.Header{}
+	for name, values := range after {
+		if name == "Cache-Control" || !slices.Equal(before[name], values) {
+			header[name] = slices.Clone(values)
+		}
+	}
+	return header
+}
+
+// set keeps the response for a request URI, with the headers set by its handler.
+func (c *ResponseCache) set(key string, header http.Header, body []byte) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		for k, cached := range c.entries {
+			if now.After(cached.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < maxCacheEntries {
+		c.entries[key] = cachedResponse{header: header, body: body, expires: now.Add(c.ttl)}
+	}
+}
@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		ifModified   string
+		etag         string
+		lastModified string
+		want         bool
+	}{
+		{"no conditions", "", "", `"abc"`, modified.Format(http.TimeFormat), false},
+		{"matching ETag", `"abc"`, "", `"abc"`, "", true},
+		{"other ETag", `"def"`, "", `"abc"`, "", false},
+		{"one of several ETags", `"def", "abc"`, "", `"abc"`, "", true},
+		{"weak ETag", `W/"abc"`, "", `"abc"`, "", true},
+		{"any ETag", "*", "", `"abc"`, "", true},
+		{"unquoted ETag", "abc", "", `"abc"`, "", false},
+		{"If-None-Match wins over If-Modified-Since", `"def"`, modified.Format(http.TimeFormat), `"abc"`, modified.Format(http.TimeFormat), false},
+		{"not modified since", "", modified.Format(http.TimeFormat), `"abc"`, modified.Format(http.TimeFormat), true},
+		{"modified before", "", modified.Add(time.Hour).Format(http.TimeFormat), `"abc"`, modified.Format(http.TimeFormat), true},
+		{"modified since", "", modified.Add(-time.Second).Format(http.TimeFormat), `"abc"`, modified.Format(http.TimeFormat), false},
+		{"no Last-Modified", "", modified.Format(http.TimeFormat), `"abc"`, "", false},
+		{"invalid If-Modified-Since", "", "yesterday", `"abc"`, modified.Format(http.TimeFormat), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.ifModified != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModified)
+			}
+			header := http.Header{}
+			header.Set("ETag", tt.etag)
+			if tt.lastModified != "" {
+				header.Set("Last-Modified", tt.lastModified)
+			}
+			if got := notModified(r, header); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBufferedResponseWriter(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), maxCachedBody+1)
+	tests := []struct {
+		name          string
+		write         func(w *bufferedResponseWriter)
+		wantStreaming bool
+		wantStatus    int    // Of the buffered response, or the one sent if streaming
+		wantBody      string // Buffered, or sent if streaming
+	}{
+		{
+			name:       "buffers a response",
+			write:      func(w *bufferedResponseWriter) { w.Write([]byte("a")); w.Write([]byte("b")) },
+			wantStatus: http.StatusOK,
+			wantBody:   "ab",
+		},
+		{
+			name: "keeps the first status",
+			write: func(w *bufferedResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("missing"))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   "missing",
+		},
+		{
+			name:       "a write sends the header",
+			write:      func(w *bufferedResponseWriter) { w.Write([]byte("a")); w.WriteHeader(http.StatusNotFound) },
+			wantStatus: http.StatusOK,
+			wantBody:   "a",
+		},
+		{
+			name:          "streams a large response",
+			write:         func(w *bufferedResponseWriter) { w.Write([]byte("a")); w.Write(large) },
+			wantStreaming: true,
+			wantStatus:    http.StatusOK,
+			wantBody:      "a" + string(large),
+		},
+		{
+			name: "streams when flushed",
+			write: func(w *bufferedResponseWriter) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("event 1\n"))
+				w.Flush()
+				w.Write([]byte("event 2\n"))
+				w.Flush()
+			},
+			wantStreaming: true,
+			wantStatus:    http.StatusAccepted,
+			wantBody:      "event 1\nevent 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			w := &bufferedResponseWriter{ResponseWriter: rec, status: http.StatusOK}
+			tt.write(w)
+
+			if w.streaming != tt.wantStreaming {
+				t.Fatalf("streaming = %v, want %v", w.streaming, tt.wantStreaming)
+			}
+			status, body := w.status, w.body.String()
+			if tt.wantStreaming {
+				status, body = rec.Code, rec.Body.String()
+				if w.body.Len() != 0 {
+					t.Errorf("%d bytes left in the buffer", w.body.Len())
+				}
+			} else if rec.Body.Len() != 0 {
+				t.Errorf("%d bytes sent before the response was complete", rec.Body.Len())
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %.20q (%d bytes), want %.20q (%d bytes)", body, len(body), tt.wantBody, len(tt.wantBody))
+			}
+		})
+	}
+}
+
+func TestCachePolicyHandler(t *testing.T) {
+	modified := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setLastModified(w, modified)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	tests := []struct {
+		name         string
+		policy       CachePolicy
+		handler      http.Handler
+		method       string
+		header       map[string]string
+		wantStatus   int
+		wantControl  string
+		wantETag     bool
+		wantBodySize int
+	}{
+		{"200 with an ETag", CrawlData, ok, http.MethodGet, nil, http.StatusOK, "public, max-age=300", true, 11},
+		{"revalidate", Revalidate, ok, http.MethodGet, nil, http.StatusOK, "no-cache", true, 11},
+		{"no store", NoStore, ok, http.MethodGet, nil, http.StatusOK, "no-store", false, 11},
+		{"not a GET", CrawlData, ok, http.MethodPost, nil, http.StatusOK, "no-store", false, 11},
+		{
+			"not modified since", CrawlData, ok, http.MethodGet,
+			map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			http.StatusNotModified, "public, max-age=300", true, 0,
+		},
+		{
+			"error", CrawlData,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", http.StatusInternalServerError)
+			}),
+			http.MethodGet, nil, http.StatusInternalServerError, "no-store", false, 7,
+		},
+		{
+			"streamed", CrawlData,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("event"))
+				w.(http.Flusher).Flush()
+			}),
+			http.MethodGet, nil, http.StatusOK, "public, max-age=300", false, 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/country", nil)
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			tt.policy.Handler(tt.handler).ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantControl)
+			}
+			if got := rec.Header().Get("ETag"); (got != "") != tt.wantETag {
+				t.Errorf("ETag = %q, want one: %v", got, tt.wantETag)
+			}
+			if rec.Body.Len() != tt.wantBodySize {
+				t.Errorf("body is %d bytes, want %d", rec.Body.Len(), tt.wantBodySize)
+			}
+		})
+	}
+}
+
+func TestCachePolicyHandlerETag(t *testing.T) {
+	body := "first"
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(body))
+	})
+	get := func(policy CachePolicy, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/metric/overview", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		policy.Handler(handler).ServeHTTP(rec, r)
+		return rec
+	}
+
+	etag := get(CrawlData, "").Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Fatalf("ETag = %q, want a strong ETag", etag)
+	}
+	if rec := get(CrawlData, etag); rec.Code != http.StatusNotModified || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("same body: status = %d, Content-Length = %q, want a 304 without content headers",
+			rec.Code, rec.Header().Get("Content-Length"))
+	}
+	body = "second"
+	if rec := get(CrawlData, etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed body: status = %d, ETag = %q, want a 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// A cached response is served without calling the handler.
+	cached := CrawlData.WithCache(NewResponseCache(time.Minute))
+	calls = 0
+	first := get(cached, "")
+	body = "third"
+	second := get(cached, "")
+	if calls != 1 || second.Body.String() != first.Body.String() || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("%d calls, bodies %q and %q, want the first response from the cache", calls, first.Body, second.Body)
+	}
+	if rec := get(cached, first.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Errorf("cached response: status = %d, want a 304", rec.Code)
+	}
+}
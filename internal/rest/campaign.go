@@ -36,6 +36,7 @@ type CampaignResponse struct {
 	TsV6Only     time.Time `json:"ts_curl"`
 	TsCheck      time.Time `json:"ts_check"`
 	TsUpdated    time.Time `json:"ts_updated"`
+	CampaignUUID string    `json:"campaign_uuid,omitempty"`
 }
 
 // CampaignListResponse represents a campaign.
@@ -48,6 +49,12 @@ type CampaignListResponse struct {
 	V6Ready     int64  `json:"v6_ready"`
 }
 
+// CampaignDomainsResponse is the response structure for a campaign and its domains.
+type CampaignDomainsResponse struct {
+	Campaign CampaignListResponse `json:"campaign"`
+	Domains  []CampaignResponse   `json:"domains"`
+}
+
 // CampaignDomainLogResponse is the response structure for a domain log.
 type CampaignDomainLogResponse struct {
 	ID         int64     `json:"id"`
@@ -76,6 +83,17 @@ func (rs CampaignHandler) Routes() chi.Router {
 	return r
 }
 
+// Operations returns the documentation of all campaign endpoints.
+func (rs CampaignHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all campaigns", Response: []CampaignListResponse{}},
+		{Method: "GET", Path: "/{uuid}", Summary: "List all domains for a campaign", Input: PaginationInput{}, Response: CampaignDomainsResponse{}},
+		{Method: "GET", Path: "/{uuid}/{domain}", Summary: "View a single domain in a campaign", Response: CampaignResponse{}},
+		{Method: "GET", Path: "/{uuid}/{domain}/log", Summary: "Retrieve the crawler log for a domain in a campaign", Response: []CampaignDomainLogResponse{}},
+		{Method: "GET", Path: "/search/{domain}", Summary: "Search for a campaign domain by its name", Input: PaginationInput{}, Response: DomainSearchResponse{}},
+	}
+}
+
 // CampaignList retrieves and lists all campaigns.
 func (rs CampaignHandler) CampaignList(w http.ResponseWriter, r *http.Request) {
 	// Retrieve all campaigns from the repository
@@ -176,10 +194,7 @@ func (rs CampaignHandler) CampaignDomains(w http.ResponseWriter, r *http.Request
 	}
 
 	// Connect campaign details with domain list
-	campaignList := struct {
-		Campaign CampaignListResponse `json:"campaign"`
-		Domains  []CampaignResponse   `json:"domains"`
-	}{
+	campaignList := CampaignDomainsResponse{
 		Campaign: CampaignListResponse{
 			ID:          campaignDetails.ID,
 			UUID:        encodeUUID(campaignDetails.UUID),
@@ -315,8 +330,8 @@ func (rs CampaignHandler) SearchDomain(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	render.JSON(w, r, render.M{
-		"data": campaignDomainList,
+	render.JSON(w, r, DomainSearchResponse{
+		Data: campaignDomainList,
 	})
 }
 
@@ -349,20 +364,46 @@ func (rs CampaignHandler) GetCampaignDomainLog(w http.ResponseWriter, r *http.Re
 	}
 	var domainlist []CampaignDomainLogResponse
 	for _, log := range logs {
-		var data map[string]any
-		if err := log.Data.AssignTo(&data); err != nil {
+		response, err := newDomainLogResponse(log.ID, log.Time, log.Data)
+		if err != nil {
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, render.M{"error": "internal server error"})
 			return
 		}
-		domainlist = append(domainlist, CampaignDomainLogResponse{
-			ID:         log.ID,
-			Time:       log.Time,
-			BaseDomain: data["base_domain"].(string),
-			WwwDomain:  data["www_domain"].(string),
-			Nameserver: data["nameserver"].(string),
-			MXRecord:   data["mx_record"].(string),
-		})
+		domainlist = append(domainlist, CampaignDomainLogResponse(response))
 	}
 	render.JSON(w, r, domainlist)
 }
+
+// newCampaignResponse maps a campaign domain to its response structure.
+func newCampaignResponse(domain core.CampaignDomainModel) CampaignResponse {
+	return CampaignResponse{
+		Domain:       domain.Site,
+		BaseDomain:   domain.BaseDomain,
+		WwwDomain:    domain.WwwDomain,
+		Nameserver:   domain.Nameserver,
+		MXRecord:     domain.MXRecord,
+		V6Only:       domain.V6Only,
+		AsName:       domain.AsName,
+		Country:      domain.Country,
+		TsBaseDomain: domain.TsBaseDomain,
+		TsWwwDomain:  domain.TsWwwDomain,
+		TsNameserver: domain.TsNameserver,
+		TsMXRecord:   domain.TsMXRecord,
+		TsV6Only:     domain.TsV6Only,
+		TsCheck:      domain.TsCheck,
+		TsUpdated:    domain.TsUpdated,
+	}
+}
+
+// newCampaignListResponse maps a campaign to its response structure.
+func newCampaignListResponse(campaign core.CampaignModel) CampaignListResponse {
+	return CampaignListResponse{
+		ID:          campaign.ID,
+		UUID:        encodeUUID(campaign.UUID),
+		Name:        campaign.Name,
+		Description: campaign.Description,
+		Count:       campaign.Count,
+		V6Ready:     campaign.V6Ready,
+	}
+}
@@ -15,7 +15,8 @@ import (
 
 // ChangelogHandler is a handler for changelog endpoints.
 type ChangelogHandler struct {
-	Repo *core.ChangelogService
+	Repo   *core.ChangelogService
+	Stream *ChangelogStream
 }
 
 // ChangelogResponse is the response for a changelog.
@@ -36,6 +37,8 @@ func (rs ChangelogHandler) Routes() chi.Router {
 	r.With(httpin.NewInput(PaginationInput{})).Get("/", rs.ChangelogList)
 	// GET /changelog/campaign - List all campaign changelog entries
 	r.With(httpin.NewInput(PaginationInput{})).Get("/campaign", rs.CampaignChangelogList)
+	// GET /changelog/stream - Stream new changelog entries as Server-Sent Events
+	r.With(LongLived, httpin.NewInput(ChangelogStreamInput{})).Get("/stream", rs.ChangelogStream)
 	// GET /changelog/{domain} - List all changelog entries for a specific domain
 	r.With(httpin.NewInput(PaginationInput{})).Get("/{domain}", rs.ChangelogByDomain)
 	// GET /changelog/campaign/{uuid} - List all changelog entries for a specific campaign UUID
@@ -47,6 +50,18 @@ func (rs ChangelogHandler) Routes() chi.Router {
 	return r
 }
 
+// Operations returns the documentation of all changelog endpoints.
+func (rs ChangelogHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all changelog entries", Input: PaginationInput{}, Response: []ChangelogResponse{}},
+		{Method: "GET", Path: "/campaign", Summary: "List all campaign changelog entries", Input: PaginationInput{}, Response: []ChangelogResponse{}},
+		{Method: "GET", Path: "/stream", Summary: "Stream new changelog entries", Description: changelogStreamDescription, Input: ChangelogStreamInput{}, Response: ChangelogResponse{}},
+		{Method: "GET", Path: "/{domain}", Summary: "List the changelog entries for a domain", Input: PaginationInput{}, Response: []ChangelogResponse{}},
+		{Method: "GET", Path: "/campaign/{uuid}", Summary: "List the changelog entries for a campaign", Input: PaginationInput{}, Response: []ChangelogResponse{}},
+		{Method: "GET", Path: "/campaign/{uuid}/{domain}", Summary: "List the changelog entries for a domain in a campaign", Input: PaginationInput{}, Response: []ChangelogResponse{}},
+	}
+}
+
 // ChangelogList lists all changelog entries with pagination.
 func (rs ChangelogHandler) ChangelogList(w http.ResponseWriter, r *http.Request) {
 	// Retrieve pagination input from context
@@ -339,3 +354,15 @@ func (rs ChangelogHandler) ChangelogByCampaignDomain(w http.ResponseWriter, r *h
 	// Send the changelog list as JSON
 	render.JSON(w, r, changelogList)
 }
+
+// newChangelogResponse maps a changelog entry to its response structure.
+func newChangelogResponse(changelog core.ChangelogModel, domainURL string) ChangelogResponse {
+	return ChangelogResponse{
+		ID:         changelog.ID,
+		Ts:         changelog.Ts,
+		Domain:     changelog.Site,
+		DomainURL:  domainURL,
+		Message:    changelog.Message,
+		IPv6Status: changelog.IPv6Status,
+	}
+}
@@ -42,9 +42,30 @@ func (rs CountryHandler) Routes() chi.Router {
 	// GET /country/{code}/heroes - Retrieve all domains with IPv6 for a specific country by country code
 	r.With(httpin.NewInput(PaginationInput{})).Get("/{code}/heroes", rs.CountryHeroes)
 
+	// GET /country/{code}/history - Retrieve the daily statistics of a specific country over time
+	r.With(httpin.NewInput(StatsHistoryInput{})).Get("/{code}/history", rs.CountryHistory)
+
 	return r
 }
 
+// Operations returns the documentation of all country-related endpoints.
+func (rs CountryHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all countries", Response: []CountryResponse{}},
+		{Method: "GET", Path: "/{code}", Summary: "Retrieve a country by its country code", Response: CountryResponse{}},
+		{Method: "GET", Path: "/{code}/sinners", Summary: "List the domains without IPv6 in a country", Input: PaginationInput{}, Response: []DomainResponse{}},
+		{Method: "GET", Path: "/{code}/heroes", Summary: "List the domains with IPv6 in a country", Input: PaginationInput{}, Response: []DomainResponse{}},
+		{
+			Method:      "GET",
+			Path:        "/{code}/history",
+			Summary:     "Retrieve the statistics of a country over time",
+			Description: statsHistoryDescription,
+			Input:       StatsHistoryInput{},
+			Response:    CountryHistoryResponse{},
+		},
+	}
+}
+
 // CountryList retrieves and returns a list of all countries.
 func (rs CountryHandler) CountryList(w http.ResponseWriter, r *http.Request) {
 	countries, err := rs.Repo.List(r.Context())
@@ -209,3 +230,19 @@ func (rs CountryHandler) CountryHeroes(w http.ResponseWriter, r *http.Request) {
 	}
 	render.JSON(w, r, heroList)
 }
+
+// newCountryResponse maps a country to its response structure.
+func newCountryResponse(country core.CountryModel) (CountryResponse, error) {
+	// Convert pgtype.Numeric to float64
+	percent, err := strconv.ParseFloat(country.Percent.Int.String(), 64)
+	if err != nil {
+		return CountryResponse{}, err
+	}
+	return CountryResponse{
+		Country:     country.Country,
+		CountryCode: country.CountryCode,
+		Sites:       country.Sites,
+		V6sites:     country.V6sites,
+		Percent:     percent / 10,
+	}, nil
+}
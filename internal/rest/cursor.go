@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/render"
+)
+
+// Cursors are opaque to the client. They hold the sort key of a row of a
+// page, so the next page starts right after the last row and the previous page
+// ends right before the first row, even if rows are added or removed in the
+// meantime, and deep pages are as fast as the first one.
+
+// errInvalidCursor is returned if a cursor can not be decoded.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// CursorPaginationInput is the query parameters of a list endpoint that can be
+// walked with a cursor. Cursor is the position the page starts after, and
+// Before the position it ends before. Counting the rows of a large list is
+// slow, so the total is only returned for the first page and for pages by
+// offset, unless Total is set.
+type CursorPaginationInput struct {
+	Cursor string `in:"query=cursor"`
+	Before string `in:"query=before"`
+	Offset int64  `in:"query=offset;default=0"`
+	Limit  int64  `in:"query=limit;default=50"`
+	Total  bool   `in:"query=total"`
+}
+
+// cursorPager is an input struct that has CursorPaginationInput, itself or embedded.
+type cursorPager interface {
+	cursorPage() *CursorPaginationInput
+}
+
+// cursorPage returns the pagination input.
+func (p *CursorPaginationInput) cursorPage() *CursorPaginationInput {
+	return p
+}
+
+// cursorList fetches the pages of a list endpoint that can be walked with a cursor.
+// byCursorBefore returns the rows before the cursor in the order of the list.
+type cursorList[M any] struct {
+	count          func(ctx context.Context) (int64, error)
+	byOffset       func(ctx context.Context, offset, limit int64) ([]M, error)
+	byCursor       func(ctx context.Context, cursor string, limit int64) ([]M, error)
+	byCursorBefore func(ctx context.Context, cursor string, limit int64) ([]M, error)
+	cursor         func(item M) string
+}
+
+// renderCursorList renders a page of a /v2 list endpoint that can be walked with
+// a cursor. The page is fetched by cursor if the request has one or starts at the
+// beginning of the list, and by offset otherwise.
+func renderCursorList[M, R any](w http.ResponseWriter, r *http.Request, list cursorList[M], response func(M) R) {
+	page := r.Context().Value(httpin.Input).(cursorPager).cursorPage()
+	if err := validatePagination(page.Offset, page.Limit); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	if page.Cursor != "" && page.Before != "" {
+		_ = render.Render(w, r, ErrInvalidRequestV2("cursor and before can not be used together"))
+		return
+	}
+	byCursor := page.Cursor != "" || page.Before != ""
+
+	var total *int64
+	if page.Total || !byCursor {
+		count, err := list.count(r.Context())
+		if err != nil {
+			_ = render.Render(w, r, ErrInternalV2(err))
+			return
+		}
+		total = &count
+	}
+
+	if !byCursor && page.Offset > 0 {
+		items, err := list.byOffset(r.Context(), page.Offset, page.Limit)
+		if err != nil {
+			_ = render.Render(w, r, ErrInternalV2(err))
+			return
+		}
+		renderPage(w, r, mapItems(items, response), *total, &PaginationInput{Offset: page.Offset, Limit: page.Limit})
+		return
+	}
+
+	// Fetch one extra row to find out if there is a page after, or before, this one.
+	var items []M
+	var err error
+	if page.Before != "" {
+		items, err = list.byCursorBefore(r.Context(), page.Before, page.Limit+1)
+	} else {
+		items, err = list.byCursor(r.Context(), page.Cursor, page.Limit+1)
+	}
+	if errors.Is(err, errInvalidCursor) {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	var next, prev string
+	links := &Links{}
+	if page.Before != "" {
+		// The row the page ends before comes after it.
+		if int64(len(items)) > page.Limit {
+			items = items[int64(len(items))-page.Limit:]
+			prev = list.cursor(items[0])
+		}
+		if len(items) > 0 {
+			next = list.cursor(items[len(items)-1])
+		}
+		links.Self = cursorURL(r.URL, "before", page.Before, page.Limit)
+	} else {
+		// The row the page starts after comes before it.
+		if int64(len(items)) > page.Limit {
+			items = items[:page.Limit]
+			next = list.cursor(items[len(items)-1])
+		}
+		if page.Cursor != "" && len(items) > 0 {
+			prev = list.cursor(items[0])
+		}
+		links.Self = cursorURL(r.URL, "cursor", page.Cursor, page.Limit)
+	}
+	if next != "" {
+		links.Next = cursorURL(r.URL, "cursor", next, page.Limit)
+	}
+	if prev != "" {
+		links.Prev = cursorURL(r.URL, "before", prev, page.Limit)
+	}
+	render.JSON(w, r, Envelope[[]R]{
+		Data:  mapItems(items, response),
+		Meta:  &Meta{Total: total, Limit: page.Limit, NextCursor: next, PrevCursor: prev},
+		Links: links,
+	})
+}
+
+// mapItems maps a list of models to their response structures.
+func mapItems[M, R any](items []M, response func(M) R) []R {
+	list := make([]R, 0, len(items))
+	for _, item := range items {
+		list = append(list, response(item))
+	}
+	return list
+}
+
+// cursorURL returns the request URL with the given cursor parameter, cursor or before, and limit.
+func cursorURL(u *url.URL, param, cursor string, limit int64) string {
+	query := u.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Del("before")
+	if cursor != "" {
+		query.Set(param, cursor)
+	}
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	return u.Path + "?" + query.Encode()
+}
+
+// encodeCursor encodes the kind of cursor and the sort key of a row to an opaque cursor.
+func encodeCursor(kind string, values ...int64) string {
+	parts := []string{kind}
+	for _, v := range values {
+		parts = append(parts, strconv.FormatInt(v, 10))
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ":")))
+}
+
+// decodeCursor decodes a cursor of the given kind with n sort key values.
+func decodeCursor(cursor, kind string, n int) ([]int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != n+1 || parts[0] != kind {
+		return nil, errInvalidCursor
+	}
+	values := make([]int64, n)
+	for i, part := range parts[1:] {
+		if values[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return nil, errInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// rankCursor returns the cursor after a domain in a list ordered by rank.
+func rankCursor(domain core.DomainModel) string {
+	return encodeCursor("r", domain.Rank, domain.ID)
+}
+
+// decodeRankCursor decodes a rank cursor. An empty cursor is the start of the list.
+func decodeRankCursor(cursor string) (core.RankCursor, error) {
+	if cursor == "" {
+		return core.RankCursor{}, nil
+	}
+	values, err := decodeCursor(cursor, "r", 2)
+	if err != nil {
+		return core.RankCursor{}, err
+	}
+	return core.RankCursor{Rank: values[0], ID: values[1]}, nil
+}
+
+// idCursor returns the cursor after a row in a list ordered by ID.
+func idCursor(id int64) string {
+	return encodeCursor("i", id)
+}
+
+// decodeIDCursor decodes an ID cursor. An empty cursor is the start of the list.
+func decodeIDCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	values, err := decodeCursor(cursor, "i", 1)
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// timeCursor returns the cursor after a changelog entry in a list ordered by time.
+func timeCursor(changelog core.ChangelogModel) string {
+	return encodeCursor("t", changelog.Ts.UnixMicro(), changelog.ID)
+}
+
+// decodeTimeCursor decodes a time cursor. An empty cursor is the start of the list.
+func decodeTimeCursor(cursor string) (core.TimeCursor, error) {
+	if cursor == "" {
+		return core.TimeCursor{}, nil
+	}
+	values, err := decodeCursor(cursor, "t", 2)
+	if err != nil {
+		return core.TimeCursor{}, err
+	}
+	return core.TimeCursor{Ts: time.UnixMicro(values[0]), ID: values[1]}, nil
+}
@@ -0,0 +1,298 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name    string
+		cursor  string
+		kind    string
+		n       int
+		want    []int64
+		wantErr bool
+	}{
+		{"one value", encodeCursor("i", 42), "i", 1, []int64{42}, false},
+		{"two values", encodeCursor("r", 3, -7), "r", 2, []int64{3, -7}, false},
+		{"not base64", "not a cursor!", "i", 1, nil, true},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("i:12")), "i", 1, nil, true},
+		{"other kind", encodeCursor("r", 1, 2), "t", 2, nil, true},
+		{"too few values", encodeCursor("r", 1), "r", 2, nil, true},
+		{"too many values", encodeCursor("i", 1, 2), "i", 1, nil, true},
+		{"not a number", raw("i:one"), "i", 1, nil, true},
+		{"empty value", raw("i:"), "i", 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.cursor, tt.kind, tt.n)
+			if tt.wantErr {
+				if err != errInvalidCursor {
+					t.Fatalf("decodeCursor() error = %v, want %v", err, errInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("decodeCursor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRankCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    core.RankCursor
+		wantErr bool
+	}{
+		{"empty is the start", "", core.RankCursor{}, false},
+		{"round trip", rankCursor(core.DomainModel{Rank: 12, ID: 345}), core.RankCursor{Rank: 12, ID: 345}, false},
+		{"id cursor", idCursor(12), core.RankCursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeRankCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRankCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeRankCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIDCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    int64
+		wantErr bool
+	}{
+		{"empty is the start", "", 0, false},
+		{"round trip", idCursor(99), 99, false},
+		{"rank cursor", rankCursor(core.DomainModel{Rank: 1, ID: 2}), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeIDCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeIDCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeIDCursor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTimeCursor(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	tests := []struct {
+		name    string
+		cursor  string
+		want    core.TimeCursor
+		wantErr bool
+	}{
+		{"empty is the start", "", core.TimeCursor{}, false},
+		{"round trip", timeCursor(core.ChangelogModel{Ts: ts, ID: 7}), core.TimeCursor{Ts: ts, ID: 7}, false},
+		{"below a microsecond is dropped", timeCursor(core.ChangelogModel{Ts: ts.Add(999), ID: 7}), core.TimeCursor{Ts: ts, ID: 7}, false},
+		{"id cursor", idCursor(7), core.TimeCursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTimeCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeTimeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Ts.Equal(tt.want.Ts) || got.ID != tt.want.ID {
+				t.Errorf("decodeTimeCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorURL(t *testing.T) {
+	u, _ := url.Parse("/v2/domains?offset=50&cursor=a&before=b&limit=10&total=true")
+	tests := []struct {
+		name   string
+		param  string
+		cursor string
+		want   string
+	}{
+		{"next page", "cursor", "c", "/v2/domains?cursor=c&limit=5&total=true"},
+		{"previous page", "before", "c", "/v2/domains?before=c&limit=5&total=true"},
+		{"first page", "cursor", "", "/v2/domains?limit=5&total=true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cursorURL(u, tt.param, tt.cursor, 5); got != tt.want {
+				t.Errorf("cursorURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCursorList(t *testing.T) {
+	// A list of the numbers 1 to 7, a number is its own cursor.
+	rows := []int64{1, 2, 3, 4, 5, 6, 7}
+	var counted bool
+	list := cursorList[int64]{
+		count: func(ctx context.Context) (int64, error) {
+			counted = true
+			return int64(len(rows)), nil
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]int64, error) {
+			return rows[min(offset, int64(len(rows))):min(offset+limit, int64(len(rows)))], nil
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]int64, error) {
+			after, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			var page []int64
+			for _, n := range rows {
+				if n > after && int64(len(page)) < limit {
+					page = append(page, n)
+				}
+			}
+			return page, nil
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]int64, error) {
+			before, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			var page []int64
+			for i := len(rows) - 1; i >= 0; i-- {
+				if rows[i] < before && int64(len(page)) < limit {
+					page = append(page, rows[i])
+				}
+			}
+			slices.Reverse(page)
+			return page, nil
+		},
+		cursor: idCursor,
+	}
+
+	tests := []struct {
+		name       string
+		input      CursorPaginationInput
+		wantStatus int
+		wantData   []int64
+		wantTotal  bool
+		wantNext   string
+		wantPrev   string
+	}{
+		{
+			name:       "first page",
+			input:      CursorPaginationInput{Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{1, 2, 3}, wantTotal: true,
+			wantNext: idCursor(3),
+		},
+		{
+			name:       "page after a cursor",
+			input:      CursorPaginationInput{Cursor: idCursor(3), Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{4, 5, 6},
+			wantNext: idCursor(6), wantPrev: idCursor(4),
+		},
+		{
+			name:       "last page",
+			input:      CursorPaginationInput{Cursor: idCursor(6), Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{7},
+			wantPrev: idCursor(7),
+		},
+		{
+			name:       "page before a cursor",
+			input:      CursorPaginationInput{Before: idCursor(7), Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{4, 5, 6},
+			wantNext: idCursor(6), wantPrev: idCursor(4),
+		},
+		{
+			name:       "page before reaches the start",
+			input:      CursorPaginationInput{Before: idCursor(4), Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{1, 2, 3},
+			wantNext: idCursor(3),
+		},
+		{
+			name:       "total on request",
+			input:      CursorPaginationInput{Cursor: idCursor(3), Limit: 3, Total: true},
+			wantStatus: http.StatusOK, wantData: []int64{4, 5, 6}, wantTotal: true,
+			wantNext: idCursor(6), wantPrev: idCursor(4),
+		},
+		{
+			name:       "page by offset",
+			input:      CursorPaginationInput{Offset: 5, Limit: 3},
+			wantStatus: http.StatusOK, wantData: []int64{6, 7}, wantTotal: true,
+		},
+		{
+			name:       "cursor and before",
+			input:      CursorPaginationInput{Cursor: idCursor(1), Before: idCursor(5), Limit: 3},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cursor",
+			input:      CursorPaginationInput{Cursor: "nope", Limit: 3},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "limit too large",
+			input:      CursorPaginationInput{Limit: maxLimit + 1},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted = false
+			input := tt.input
+			r := httptest.NewRequest(http.MethodGet, "/v2/numbers", nil)
+			r = r.WithContext(context.WithValue(r.Context(), httpin.Input, &input))
+			w := httptest.NewRecorder()
+
+			renderCursorList(w, r, list, func(n int64) int64 { return n })
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var body Envelope[[]int64]
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(body.Data, tt.wantData) {
+				t.Errorf("data = %v, want %v", body.Data, tt.wantData)
+			}
+			if counted != tt.wantTotal || (body.Meta.Total != nil) != tt.wantTotal {
+				t.Errorf("counted = %v, total = %v, want total %v", counted, body.Meta.Total, tt.wantTotal)
+			}
+			if body.Meta.NextCursor != tt.wantNext {
+				t.Errorf("next cursor = %q, want %q", body.Meta.NextCursor, tt.wantNext)
+			}
+			if body.Meta.PrevCursor != tt.wantPrev {
+				t.Errorf("prev cursor = %q, want %q", body.Meta.PrevCursor, tt.wantPrev)
+			}
+			if input.Offset == 0 && (body.Links.Prev != "") != (tt.wantPrev != "") {
+				t.Errorf("prev link = %q, want cursor %q", body.Links.Prev, tt.wantPrev)
+			}
+		})
+	}
+}
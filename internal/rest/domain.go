@@ -11,11 +11,15 @@ import (
 	"github.com/ggicci/httpin"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/jackc/pgtype"
 )
 
 // DomainHandler is a handler for managing domain-related operations.
 type DomainHandler struct {
-	Repo *core.DomainService
+	Repo          *core.DomainService
+	Jobs          *core.RecheckJobService // Queue for the recheck endpoint
+	DB            TxDB                    // Transactions of the recheck endpoint
+	RecheckLimits RecheckLimits           // Rate limits of the recheck endpoint, the defaults if not set
 }
 
 // DomainResponse is the response structure for a domain.
@@ -49,12 +53,17 @@ type DomainLogResponse struct {
 	MXRecord   string    `json:"mx_record"`
 }
 
+// DomainSearchResponse is the response structure for a domain search.
+type DomainSearchResponse struct {
+	Data []DomainResponse `json:"data"`
+}
+
 // Routes returns a router with all domain-related endpoints mounted.
 func (rs DomainHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	// GET /domain - list all domains
-	r.With(httpin.NewInput(PaginationInput{})).Get("/", rs.DomainList)
+	// GET /domain - list the domains without IPv6, or the domains matching the filters
+	r.With(httpin.NewInput(DomainListInput{})).Get("/", rs.DomainList)
 	// GET /domain/heroes - list the domains with IPv6
 	r.With(httpin.NewInput(PaginationInput{})).Get("/heroes", rs.DomainHeroes)
 	// GET /domain/topsinner - list the top 10-ish domains without IPv6
@@ -63,21 +72,66 @@ func (rs DomainHandler) Routes() chi.Router {
 	r.Get("/{domain}", rs.RetrieveDomain)
 	// GET /domain/{domain}/log - retrieve a domain by its name
 	r.Get("/{domain}/log", rs.GetDomainLog)
+	// GET /domain/{domain}/history - retrieve the IPv6 history of a domain
+	r.With(httpin.NewInput(DomainHistoryInput{})).Get("/{domain}/history", rs.GetDomainHistory)
+	// POST /domain/{domain}/recheck - queue a recheck of a domain
+	r.Post("/{domain}/recheck", rs.RecheckDomain)
 	// GET /domain/search/{domain} - search for a domain by its name
 	r.With(httpin.NewInput(PaginationInput{})).Get("/search/{domain}", rs.SearchDomain)
 
 	return r
 }
 
-// DomainList returns all domains.
+// Operations returns the documentation of all domain-related endpoints.
+func (rs DomainHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:      "GET",
+			Path:        "/",
+			Summary:     "List the domains without IPv6",
+			Description: domainFilterDescription,
+			Input:       DomainListInput{},
+			Response:    []DomainResponse{},
+		},
+		{Method: "GET", Path: "/heroes", Summary: "List the domains with IPv6", Input: PaginationInput{}, Response: []DomainResponse{}},
+		{Method: "GET", Path: "/topsinner", Summary: "List the top 10-ish domains without IPv6", Response: []DomainResponse{}},
+		{Method: "GET", Path: "/{domain}", Summary: "Retrieve a domain by its name", Response: DomainResponse{}},
+		{Method: "GET", Path: "/{domain}/log", Summary: "Retrieve the crawler log for a domain", Response: []DomainLogResponse{}},
+		{
+			Method:      "GET",
+			Path:        "/{domain}/history",
+			Summary:     "Retrieve the IPv6 history of a domain",
+			Description: domainHistoryDescription,
+			Input:       DomainHistoryInput{},
+			Response:    DomainHistoryResponse{},
+		},
+		{Method: "POST", Path: "/{domain}/recheck", Summary: "Queue a recheck of a domain, poll the returned job for the result", Response: JobResponse{}},
+		{Method: "GET", Path: "/search/{domain}", Summary: "Search for a domain by its name", Input: PaginationInput{}, Response: DomainSearchResponse{}},
+	}
+}
+
+// DomainList returns all domains, or the domains matching the filters if the
+// request has any.
 func (rs DomainHandler) DomainList(w http.ResponseWriter, r *http.Request) {
 	// Handle query params
-	paginationInput := r.Context().Value(httpin.Input).(*PaginationInput)
+	input := r.Context().Value(httpin.Input).(*DomainListInput)
+	paginationInput := &input.PaginationInput
 	if paginationInput.Limit > 100 {
 		paginationInput.Limit = 100
 	}
 
-	domains, err := rs.Repo.ListDomain(r.Context(), paginationInput.Offset, paginationInput.Limit)
+	var domains []core.DomainModel
+	var err error
+	if input.DomainFilterInput.isSet() {
+		filter, ferr := input.filter()
+		if ferr != nil {
+			_ = render.Render(w, r, ErrInvalidRequest(ferr))
+			return
+		}
+		domains, err = rs.Repo.ListDomainFiltered(r.Context(), filter, paginationInput.Offset, paginationInput.Limit)
+	} else {
+		domains, err = rs.Repo.ListDomain(r.Context(), paginationInput.Offset, paginationInput.Limit)
+	}
 	if err != nil {
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, render.M{"error": "internal server error"})
@@ -158,6 +212,7 @@ func (rs DomainHandler) RetrieveDomain(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, render.M{"error": "domain not found"})
 		return
 	}
+	setLastModified(w, domain.TsCheck, domain.TsUpdated)
 	render.JSON(w, r, DomainResponse{
 		Rank:         domain.Rank,
 		Domain:       domain.Site,
@@ -228,8 +283,8 @@ func (rs DomainHandler) SearchDomain(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	render.JSON(w, r, render.M{
-		"data": domainList,
+	render.JSON(w, r, DomainSearchResponse{
+		Data: domainList,
 	})
 }
 
@@ -275,20 +330,56 @@ func (rs DomainHandler) GetDomainLog(w http.ResponseWriter, r *http.Request) {
 	}
 	var domainlist []DomainLogResponse
 	for _, log := range logs {
-		var data map[string]any
-		if err := log.Data.AssignTo(&data); err != nil {
+		response, err := newDomainLogResponse(log.ID, log.Time, log.Data)
+		if err != nil {
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, render.M{"error": "internal server error"})
 			return
 		}
-		domainlist = append(domainlist, DomainLogResponse{
-			ID:         log.ID,
-			Time:       log.Time,
-			BaseDomain: data["base_domain"].(string),
-			WwwDomain:  data["www_domain"].(string),
-			Nameserver: data["nameserver"].(string),
-			MXRecord:   data["mx_record"].(string),
-		})
+		domainlist = append(domainlist, response)
 	}
 	render.JSON(w, r, domainlist)
 }
+
+// newDomainResponse maps a domain to its response structure.
+func newDomainResponse(domain core.DomainModel) DomainResponse {
+	return DomainResponse{
+		Rank:         domain.Rank,
+		Domain:       domain.Site,
+		BaseDomain:   domain.BaseDomain,
+		WwwDomain:    domain.WwwDomain,
+		Nameserver:   domain.Nameserver,
+		MXRecord:     domain.MXRecord,
+		V6Only:       domain.V6Only,
+		AsName:       domain.AsName,
+		Country:      domain.Country,
+		TsBaseDomain: domain.TsBaseDomain,
+		TsWwwDomain:  domain.TsWwwDomain,
+		TsNameserver: domain.TsNameserver,
+		TsMXRecord:   domain.TsMXRecord,
+		TsV6Only:     domain.TsV6Only,
+		TsCheck:      domain.TsCheck,
+		TsUpdated:    domain.TsUpdated,
+	}
+}
+
+// newDomainLogResponse maps a crawler log entry to its response structure.
+// Fields missing from the log data are left empty.
+func newDomainLogResponse(id int64, ts time.Time, data pgtype.JSONB) (DomainLogResponse, error) {
+	var fields map[string]any
+	if err := data.AssignTo(&fields); err != nil {
+		return DomainLogResponse{}, err
+	}
+	field := func(name string) string {
+		value, _ := fields[name].(string)
+		return value
+	}
+	return DomainLogResponse{
+		ID:         id,
+		Time:       ts,
+		BaseDomain: field("base_domain"),
+		WwwDomain:  field("www_domain"),
+		Nameserver: field("nameserver"),
+		MXRecord:   field("mx_record"),
+	}, nil
+}
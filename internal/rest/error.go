@@ -32,6 +32,46 @@ func ErrInvalidRequest(err error) render.Renderer {
 	}
 }
 
+// ErrUnauthorized returns a structured HTTP response if a client sent an invalid API key.
+func ErrUnauthorized(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusUnauthorized,
+		StatusText:     "Unauthorized.",
+		ErrorText:      err.Error(),
+	}
+}
+
+// ErrForbidden returns a structured HTTP response if an API key may not use an endpoint.
+func ErrForbidden(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusForbidden,
+		StatusText:     "Forbidden.",
+		ErrorText:      err.Error(),
+	}
+}
+
+// ErrConflict returns a structured HTTP response if a resource already exists.
+func ErrConflict(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusConflict,
+		StatusText:     "Conflict.",
+		ErrorText:      err.Error(),
+	}
+}
+
+// ErrTooManyRequests returns a structured HTTP response if a client hit a rate limit.
+func ErrTooManyRequests(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusTooManyRequests,
+		StatusText:     "Too many requests.",
+		ErrorText:      err.Error(),
+	}
+}
+
 // ErrRender returns a structured HTTP response in case of rendering errors.
 func ErrRender(err error) render.Renderer {
 	return &ErrResponse{
@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/export"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ExportHandler is a handler for the bulk exports of the domain table.
+type ExportHandler struct {
+	Repo *core.DomainService
+}
+
+// ExportSchemaResponse is the response structure for the export schema.
+type ExportSchemaResponse struct {
+	Formats []string        `json:"formats"`
+	Columns []export.Column `json:"columns"`
+}
+
+// exportDescription documents the formats and filters of the export endpoint.
+const exportDescription = "Streams every domain matching the filters, ordered by id. The file ends in .csv " +
+	"for CSV with a header row, .ndjson for one JSON object per line, or .parquet for Apache Parquet. " +
+	"The columns are listed by /export/schema. The filters are the ones of the /domain list, " +
+	"without sort. Without filters, the export holds the full domain table."
+
+// Routes returns a router with all export endpoints mounted.
+func (rs ExportHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /export/schema - the columns of an export
+	r.Get("/schema", rs.ExportSchema)
+	// GET /export/domains.csv - export the domains matching the filters
+	r.With(LongLived, httpin.NewInput(DomainFilterInput{})).Get("/domains.{format}", rs.ExportDomains)
+
+	return r
+}
+
+// Operations returns the documentation of all export endpoints.
+func (rs ExportHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/schema", Summary: "List the formats and columns of an export", Response: ExportSchemaResponse{}},
+		{
+			Method:      "GET",
+			Path:        "/domains.{format}",
+			Summary:     "Export the domains matching the filters, e.g. /export/domains.parquet?country=NO",
+			Description: exportDescription,
+			Input:       DomainFilterInput{},
+		},
+	}
+}
+
+// ExportSchema returns the formats and columns of an export.
+func (rs ExportHandler) ExportSchema(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, ExportSchemaResponse{Formats: export.Formats, Columns: export.Columns})
+}
+
+// ExportDomains streams the domains matching the filters as a file.
+func (rs ExportHandler) ExportDomains(w http.ResponseWriter, r *http.Request) {
+	format, ok := export.ParseFormat(chi.URLParam(r, "format"))
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "export must end in .csv, .ndjson or .parquet"})
+		return
+	}
+	input := r.Context().Value(httpin.Input).(*DomainFilterInput)
+	if input.Sort != "" {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("an export is ordered by id and can not be sorted")))
+		return
+	}
+	filter, err := input.filter()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	// The headers are sent with the first domain. An error after that can only
+	// abort the response, so the client does not mistake a partial file for a
+	// complete one.
+	out := &exportResponseWriter{ResponseWriter: w, format: format}
+	writer := export.NewWriter(out, format)
+	err = rs.Repo.ExportDomains(r.Context(), filter, writer.Write)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+	if out.started {
+		log.Println("Error writing export:", err)
+		panic(http.ErrAbortHandler)
+	}
+	log.Println("Error exporting domains:", err)
+	render.Status(r, http.StatusInternalServerError)
+	render.JSON(w, r, render.M{"error": "internal server error"})
+}
+
+// exportResponseWriter sets the headers of an export before the first write.
+type exportResponseWriter struct {
+	http.ResponseWriter
+	format  export.Format
+	started bool
+}
+
+func (e *exportResponseWriter) Write(b []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		filename := "whynoipv6-domains-" + time.Now().UTC().Format(time.DateOnly) + "." + string(e.format)
+		e.Header().Set("Content-Type", e.format.ContentType())
+		e.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	return e.ResponseWriter.Write(b)
+}
@@ -0,0 +1,301 @@
+package rest
+
+import (
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/gorilla/feeds"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// feedSize is the number of entries in a feed.
+	feedSize = 50
+	// feedMaxAge is how long feed readers and proxies may cache a feed.
+	feedMaxAge = 5 * time.Minute
+	// DefaultSiteURL is the website the feeds link to.
+	DefaultSiteURL = "https://whynoipv6.com"
+	// feedTagPrefix starts the entry IDs, tag URIs (RFC 4151) that stay the same
+	// in every feed an entry is in, and do not change with the site URL.
+	feedTagPrefix = "tag:whynoipv6.com,2023:"
+)
+
+// Feed formats, the file extension of a feed.
+const (
+	feedAtom = "atom"
+	feedRSS  = "rss"
+)
+
+// FeedHandler is a handler for the Atom and RSS feeds of the changelogs.
+type FeedHandler struct {
+	Changelog *core.ChangelogService
+	Domains   *core.DomainService
+	Countries *core.CountryService
+	Campaigns *core.CampaignService
+	SiteURL   string // Website the feeds link to, DefaultSiteURL if empty
+}
+
+// feedDescription documents the formats of a feed endpoint.
+const feedDescription = "The feed ends in .atom for an Atom feed, or in .rss for an RSS 2.0 feed, " +
+	"and holds the newest 50 changelog entries."
+
+// Routes returns a router with all feed endpoints mounted.
+func (rs FeedHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// Domain names contain dots, so the file extension is split off in the
+	// handler rather than in the route pattern.
+
+	// GET /feed/changelog.atom - feed of all changelog entries
+	r.Get("/changelog.{format}", rs.ChangelogFeed)
+	// GET /feed/campaign.atom - feed of all campaign changelog entries
+	r.Get("/campaign.{format}", rs.CampaignChangelogFeed)
+	// GET /feed/domain/{domain}.atom - feed of the changelog entries for a domain
+	r.Get("/domain/{feed}", rs.DomainFeed)
+	// GET /feed/country/{code}.atom - feed of the changelog entries for a country
+	r.Get("/country/{feed}", rs.CountryFeed)
+	// GET /feed/campaign/{uuid}.atom - feed of the changelog entries for a campaign
+	r.Get("/campaign/{feed}", rs.CampaignFeed)
+
+	return r
+}
+
+// Operations returns the documentation of all feed endpoints.
+func (rs FeedHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/changelog.{format}", Summary: "Feed of all changelog entries, e.g. /feed/changelog.atom", Description: feedDescription},
+		{Method: "GET", Path: "/campaign.{format}", Summary: "Feed of all campaign changelog entries, e.g. /feed/campaign.rss", Description: feedDescription},
+		{Method: "GET", Path: "/domain/{feed}", Summary: "Feed of the changelog entries for a domain, e.g. /feed/domain/github.com.atom", Description: feedDescription},
+		{Method: "GET", Path: "/country/{feed}", Summary: "Feed of the changelog entries for a country, e.g. /feed/country/NO.atom", Description: feedDescription},
+		{Method: "GET", Path: "/campaign/{feed}", Summary: "Feed of the changelog entries for a campaign, e.g. /feed/campaign/{uuid}.atom", Description: feedDescription},
+	}
+}
+
+// ChangelogFeed renders the feed of all changelog entries.
+func (rs FeedHandler) ChangelogFeed(w http.ResponseWriter, r *http.Request) {
+	format, ok := feedFormat(w, r, chi.URLParam(r, "format"))
+	if !ok {
+		return
+	}
+	entries, err := rs.Changelog.List(r.Context(), 0, feedSize)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	feed := rs.newFeed("WhyNoIPv6 changelog", "IPv6 changes of the top domains", "/changelog")
+	rs.addDomainEntries(feed, entries)
+	renderFeed(w, r, format, feed)
+}
+
+// CampaignChangelogFeed renders the feed of all campaign changelog entries.
+func (rs FeedHandler) CampaignChangelogFeed(w http.ResponseWriter, r *http.Request) {
+	format, ok := feedFormat(w, r, chi.URLParam(r, "format"))
+	if !ok {
+		return
+	}
+	entries, err := rs.Changelog.CampaignList(r.Context(), 0, feedSize)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	feed := rs.newFeed("WhyNoIPv6 campaign changelog", "IPv6 changes of the domains in all campaigns", "/campaign")
+	rs.addCampaignEntries(feed, entries)
+	renderFeed(w, r, format, feed)
+}
+
+// DomainFeed renders the feed of the changelog entries for a domain.
+func (rs FeedHandler) DomainFeed(w http.ResponseWriter, r *http.Request) {
+	site, format, ok := splitFeed(w, r)
+	if !ok {
+		return
+	}
+	site = strings.ToLower(site)
+	if !domainRegex.MatchString(site) {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("invalid domain")))
+		return
+	}
+	// A domain without changes still has a feed, an empty one.
+	if _, err := rs.Domains.ViewDomain(r.Context(), site); err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	entries, err := rs.Changelog.GetChangelogByDomain(r.Context(), site, 0, feedSize)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	feed := rs.newFeed("WhyNoIPv6 changelog for "+site, "IPv6 changes of "+site, "/domain/"+site)
+	rs.addDomainEntries(feed, entries)
+	renderFeed(w, r, format, feed)
+}
+
+// CountryFeed renders the feed of the changelog entries for the domains in a country.
+func (rs FeedHandler) CountryFeed(w http.ResponseWriter, r *http.Request) {
+	code, format, ok := splitFeed(w, r)
+	if !ok {
+		return
+	}
+	country, err := rs.Countries.GetCountryCode(r.Context(), strings.ToUpper(code))
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	entries, err := rs.Changelog.GetChangelogByCountry(r.Context(), country.ID, feedSize)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	feed := rs.newFeed(
+		"WhyNoIPv6 changelog for "+country.Country,
+		"IPv6 changes of the top domains in "+country.Country,
+		"/country/"+strings.ToLower(country.CountryCode),
+	)
+	rs.addDomainEntries(feed, entries)
+	renderFeed(w, r, format, feed)
+}
+
+// CampaignFeed renders the feed of the changelog entries for a campaign.
+func (rs FeedHandler) CampaignFeed(w http.ResponseWriter, r *http.Request) {
+	id, format, ok := splitFeed(w, r)
+	if !ok {
+		return
+	}
+	campaignID, err := decodeUUID(id)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(errors.New("invalid campaign uuid")))
+		return
+	}
+	campaign, err := rs.Campaigns.GetCampaign(r.Context(), campaignID)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	entries, err := rs.Changelog.GetChangelogByCampaign(r.Context(), campaignID, 0, feedSize)
+	if err != nil {
+		renderFeedError(w, r, err)
+		return
+	}
+	feed := rs.newFeed(
+		"WhyNoIPv6 changelog for "+campaign.Name,
+		campaign.Description,
+		"/campaign/"+encodeUUID(campaign.UUID),
+	)
+	rs.addCampaignEntries(feed, entries)
+	renderFeed(w, r, format, feed)
+}
+
+// siteURL returns the URL of a page on the website.
+func (rs FeedHandler) siteURL(page string) string {
+	base := rs.SiteURL
+	if base == "" {
+		base = DefaultSiteURL
+	}
+	return strings.TrimSuffix(base, "/") + page
+}
+
+// newFeed creates an empty feed linking to a page on the website.
+func (rs FeedHandler) newFeed(title, description, page string) *feeds.Feed {
+	return &feeds.Feed{
+		Title:       title,
+		Description: description,
+		Link:        &feeds.Link{Href: rs.siteURL(page)},
+		Id:          rs.siteURL(page),
+	}
+}
+
+// addDomainEntries adds changelog entries to a feed, linking to the domains.
+func (rs FeedHandler) addDomainEntries(feed *feeds.Feed, entries []core.ChangelogModel) {
+	for _, entry := range entries {
+		addFeedEntry(feed, entry, "changelog/", rs.siteURL("/domain/"+entry.Site))
+	}
+}
+
+// addCampaignEntries adds campaign changelog entries to a feed, linking to the
+// domains in their campaigns.
+func (rs FeedHandler) addCampaignEntries(feed *feeds.Feed, entries []core.ChangelogModel) {
+	for _, entry := range entries {
+		addFeedEntry(feed, entry, "campaign-changelog/", rs.siteURL("/campaign/"+encodeUUID(entry.CampaignID)+"/"+entry.Site))
+	}
+}
+
+// addFeedEntry adds a changelog entry to a feed. The feed is updated when its
+// newest entry was written.
+func addFeedEntry(feed *feeds.Feed, entry core.ChangelogModel, kind, link string) {
+	feed.Add(&feeds.Item{
+		Title:       entry.Message,
+		Link:        &feeds.Link{Href: link},
+		Description: html.EscapeString(entry.Site + ": IPv6 " + entry.IPv6Status),
+		Id:          feedTagPrefix + kind + strconv.FormatInt(entry.ID, 10),
+		IsPermaLink: "false",
+		Created:     entry.Ts,
+		Updated:     entry.Ts,
+	})
+	if entry.Ts.After(feed.Updated) {
+		feed.Updated = entry.Ts
+	}
+}
+
+// feedFormat validates the format of a feed. It responds with an error if the
+// format is not supported.
+func feedFormat(w http.ResponseWriter, r *http.Request, format string) (string, bool) {
+	if format != feedAtom && format != feedRSS {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "feed must end in .atom or .rss"})
+		return "", false
+	}
+	return format, true
+}
+
+// splitFeed splits the feed path parameter into the name and the format.
+func splitFeed(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	file := chi.URLParam(r, "feed")
+	ext := path.Ext(file)
+	format, ok := feedFormat(w, r, strings.TrimPrefix(ext, "."))
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSuffix(file, ext), format, true
+}
+
+// renderFeedError renders a not found error for a missing domain, country or
+// campaign, and an internal server error otherwise.
+func renderFeedError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = render.Render(w, r, ErrNotFound())
+		return
+	}
+	log.Println("Error rendering feed:", err)
+	render.Status(r, http.StatusInternalServerError)
+	render.JSON(w, r, render.M{"error": "internal server error"})
+}
+
+// renderFeed renders a feed as Atom or RSS.
+func renderFeed(w http.ResponseWriter, r *http.Request, format string, feed *feeds.Feed) {
+	// An empty feed was last updated when it was requested.
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+
+	setMaxAge(w, feedMaxAge)
+	var err error
+	if format == feedAtom {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		err = feed.WriteAtom(w)
+	} else {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		err = feed.WriteRss(w)
+	}
+	if err != nil {
+		log.Println("Error writing feed:", err)
+	}
+}
@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"errors"
+
+	"whynoipv6/internal/core"
+)
+
+// DomainFilterInput is the query parameters that filter and sort a domain list,
+// e.g. ?rank_max=10000&ns=supported&mx=unsupported&sort=-ts_updated.
+type DomainFilterInput struct {
+	BaseDomain    string `in:"query=base"`
+	WwwDomain     string `in:"query=www"`
+	Nameserver    string `in:"query=ns"`
+	MXRecord      string `in:"query=mx"`
+	V6Only        string `in:"query=v6_only"`
+	Country       string `in:"query=country"`
+	Continent     string `in:"query=continent"`
+	ASN           int32  `in:"query=asn"`
+	RankMin       int64  `in:"query=rank_min"`
+	RankMax       int64  `in:"query=rank_max"`
+	UpdatedAfter  string `in:"query=updated_after"`
+	UpdatedBefore string `in:"query=updated_before"`
+	Campaign      string `in:"query=campaign"`
+	Sort          string `in:"query=sort"`
+}
+
+// DomainListInput is the query parameters of the /domain list.
+type DomainListInput struct {
+	PaginationInput
+	DomainFilterInput
+}
+
+// DomainListInputV2 is the query parameters of the /v2/domain list.
+type DomainListInputV2 struct {
+	CursorPaginationInput
+	DomainFilterInput
+}
+
+// domainFilterDescription documents the filters of a domain list endpoint.
+const domainFilterDescription = "Without filters, the list holds the domains without IPv6 ordered by rank. " +
+	"With any filter or sort order, it holds every domain matching all filters: " +
+	"base, www, ns, mx and v6_only take a check status (supported, unsupported or no_record), " +
+	"country a two-letter country code, continent a continent name, asn an AS number, " +
+	"rank_min and rank_max an inclusive rank range, updated_after and updated_before a date or " +
+	"RFC 3339 timestamp of the last change, and campaign a campaign UUID. " +
+	"sort is one of rank, site or ts_updated, prefixed with - for descending order."
+
+// isSet reports whether the input has any filter or sort order.
+func (in DomainFilterInput) isSet() bool {
+	return in != DomainFilterInput{}
+}
+
+// filter validates the input and converts it to a domain filter.
+func (in DomainFilterInput) filter() (core.DomainFilter, error) {
+	filter, err := core.ParseDomainFilter(core.DomainFilterParams{
+		BaseDomain:    in.BaseDomain,
+		WwwDomain:     in.WwwDomain,
+		Nameserver:    in.Nameserver,
+		MXRecord:      in.MXRecord,
+		V6Only:        in.V6Only,
+		Country:       in.Country,
+		Continent:     in.Continent,
+		ASN:           in.ASN,
+		RankMin:       in.RankMin,
+		RankMax:       in.RankMax,
+		UpdatedAfter:  in.UpdatedAfter,
+		UpdatedBefore: in.UpdatedBefore,
+		Sort:          in.Sort,
+	})
+	if err != nil {
+		return filter, err
+	}
+	if in.Campaign != "" {
+		if filter.CampaignID, err = decodeUUID(in.Campaign); err != nil {
+			return filter, errors.New("invalid campaign uuid")
+		}
+	}
+	return filter, nil
+}
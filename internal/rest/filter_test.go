@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/google/uuid"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+func TestDomainFilterInputFilter(t *testing.T) {
+	campaignID := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	tests := []struct {
+		name    string
+		input   DomainFilterInput
+		want    core.DomainFilter
+		wantErr string
+	}{
+		{
+			name:  "empty",
+			input: DomainFilterInput{},
+			want:  core.DomainFilter{},
+		},
+		{
+			name: "check statuses and sort",
+			input: DomainFilterInput{
+				BaseDomain: core.IPv6Available, Nameserver: core.IPv4Only, MXRecord: core.NoRecordsFound,
+				Sort: "-ts_updated",
+			},
+			want: core.DomainFilter{
+				BaseDomain: core.IPv6Available, Nameserver: core.IPv4Only, MXRecord: core.NoRecordsFound,
+				Sort: "-ts_updated",
+			},
+		},
+		{
+			name:  "country and continent are normalized",
+			input: DomainFilterInput{Country: "dk", Continent: "north america"},
+			want:  core.DomainFilter{CountryCode: "DK", Continent: "North America"},
+		},
+		{
+			name:  "rank range and asn",
+			input: DomainFilterInput{RankMin: 10, RankMax: 10, ASN: 3292},
+			want:  core.DomainFilter{RankMin: 10, RankMax: 10, ASN: 3292},
+		},
+		{
+			name:  "rank_min without rank_max",
+			input: DomainFilterInput{RankMin: 500},
+			want:  core.DomainFilter{RankMin: 500},
+		},
+		{
+			name:  "dates",
+			input: DomainFilterInput{UpdatedAfter: "2024-01-02", UpdatedBefore: "2024-02-03T04:05:06+01:00"},
+			want: core.DomainFilter{
+				UpdatedAfter:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				UpdatedBefore: time.Date(2024, 2, 3, 3, 5, 6, 0, time.UTC),
+			},
+		},
+		{
+			name:  "campaign",
+			input: DomainFilterInput{Campaign: shortuuid.DefaultEncoder.Encode(campaignID)},
+			want:  core.DomainFilter{CampaignID: campaignID},
+		},
+		{
+			name:    "unknown check status",
+			input:   DomainFilterInput{WwwDomain: "yes"},
+			wantErr: "www must be one of supported, unsupported, no_record",
+		},
+		{
+			name:    "unknown v6_only status",
+			input:   DomainFilterInput{V6Only: "maybe"},
+			wantErr: "v6_only must be one of supported, unsupported, no_record",
+		},
+		{
+			name:    "country name",
+			input:   DomainFilterInput{Country: "Denmark"},
+			wantErr: "country must be a two-letter country code",
+		},
+		{
+			name:    "unknown continent",
+			input:   DomainFilterInput{Continent: "Atlantis"},
+			wantErr: "continent must be one of Africa, Antarctica, Asia, Europe, Oceania, North America, South America",
+		},
+		{
+			name:    "negative asn",
+			input:   DomainFilterInput{ASN: -1},
+			wantErr: "asn must not be negative",
+		},
+		{
+			name:    "negative rank",
+			input:   DomainFilterInput{RankMin: -1},
+			wantErr: "rank_min and rank_max must not be negative",
+		},
+		{
+			name:    "empty rank range",
+			input:   DomainFilterInput{RankMin: 11, RankMax: 10},
+			wantErr: "rank_min must not be greater than rank_max",
+		},
+		{
+			name:    "invalid updated_after",
+			input:   DomainFilterInput{UpdatedAfter: "yesterday"},
+			wantErr: "updated_after must be a date or an RFC 3339 timestamp",
+		},
+		{
+			name:    "invalid updated_before",
+			input:   DomainFilterInput{UpdatedBefore: "2024-13-01"},
+			wantErr: "updated_before must be a date or an RFC 3339 timestamp",
+		},
+		{
+			name:    "invalid campaign",
+			input:   DomainFilterInput{Campaign: "not-a-uuid"},
+			wantErr: "invalid campaign uuid",
+		},
+		{
+			name:    "unknown sort order",
+			input:   DomainFilterInput{Sort: "-asn"},
+			wantErr: "sort must be one of rank, -rank, site, -site, ts_updated, -ts_updated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.input.filter()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("filter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filter() error = %v", err)
+			}
+			if !got.UpdatedAfter.Equal(tt.want.UpdatedAfter) || !got.UpdatedBefore.Equal(tt.want.UpdatedBefore) {
+				t.Errorf("filter() dates = %v, %v, want %v, %v",
+					got.UpdatedAfter, got.UpdatedBefore, tt.want.UpdatedAfter, tt.want.UpdatedBefore)
+			}
+			got.UpdatedAfter, got.UpdatedBefore = tt.want.UpdatedAfter, tt.want.UpdatedBefore
+			if got != tt.want {
+				t.Errorf("filter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainFilterInputIsSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		input DomainFilterInput
+		want  bool
+	}{
+		{"empty", DomainFilterInput{}, false},
+		{"filter", DomainFilterInput{Country: "DK"}, true},
+		{"sort only", DomainFilterInput{Sort: "site"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.isSet(); got != tt.want {
+				t.Errorf("isSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
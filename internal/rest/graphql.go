@@ -0,0 +1,190 @@
+package rest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed graphql.graphql
+var graphQLSchema string
+
+// Limits of the GraphQL queries, if the config does not set them.
+const (
+	defaultGraphQLMaxDepth      = 8
+	defaultGraphQLMaxComplexity = 2000
+)
+
+// maxGraphQLBody is the largest GraphQL request body that is read.
+const maxGraphQLBody = 64 << 10
+
+// GraphQLRepos is the services the GraphQL API reads from.
+type GraphQLRepos struct {
+	Domains   *core.DomainService
+	Countries *core.CountryService
+	Campaigns *core.CampaignService
+	ASNs      *core.ASNService
+	Changelog *core.ChangelogService
+	Metrics   *core.MetricService
+}
+
+// GraphQLLimits is how deep and how complex a GraphQL query may be.
+type GraphQLLimits struct {
+	MaxDepth      int // Most levels of nested fields
+	MaxComplexity int // Most objects a query may return, see graphQLComplexity
+}
+
+// GraphQLHandler serves the read-only GraphQL API.
+type GraphQLHandler struct {
+	repos  GraphQLRepos
+	limits GraphQLLimits
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance, with the default for
+// every limit that is not set.
+func NewGraphQLHandler(repos GraphQLRepos, limits GraphQLLimits) (*GraphQLHandler, error) {
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = defaultGraphQLMaxDepth
+	}
+	if limits.MaxComplexity <= 0 {
+		limits.MaxComplexity = defaultGraphQLMaxComplexity
+	}
+	schema, err := graphql.ParseSchema(graphQLSchema, &graphQLResolver{repos: repos},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(limits.MaxDepth),
+		// Resolve a whole page at once, so the loads of its objects are batched.
+		graphql.MaxParallelism(graphQLMaxLimit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("parse GraphQL schema: %w", err)
+	}
+	return &GraphQLHandler{repos: repos, limits: limits, schema: schema}, nil
+}
+
+// GraphQLRequest is a GraphQL query, in the body of a POST request or in the
+// query parameters of a GET request.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLResponse is the result of a GraphQL query.
+type GraphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError is an error of a GraphQL query.
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Routes returns a router with the GraphQL endpoint mounted.
+func (rs *GraphQLHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /graphql - run a query given in the query parameters
+	r.Get("/", rs.Query)
+	// POST /graphql - run a query given in the body
+	r.Post("/", rs.Query)
+
+	return r
+}
+
+// graphQLDescription documents the GraphQL endpoint.
+const graphQLDescription = "Runs a read-only GraphQL query over the domains, campaigns, countries, ASNs, changelogs " +
+	"and metrics. The schema can be fetched with an introspection query. A list takes an offset and a limit of at most 100. " +
+	"By default, a query may nest fields 8 levels deep and return about 2000 objects, where a list counts as many objects as its limit."
+
+// Operations returns the documentation of the GraphQL endpoint.
+func (rs *GraphQLHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:      "GET",
+			Path:        "/",
+			Summary:     "Run a GraphQL query",
+			Description: graphQLDescription,
+			Query: []QueryParam{
+				{Name: "query", Description: "The GraphQL query"},
+				{Name: "operationName", Description: "The operation to run, if the query has several"},
+				{Name: "variables", Description: "The variables of the query, as a JSON object"},
+			},
+			Response: GraphQLResponse{},
+		},
+		{
+			Method:      "POST",
+			Path:        "/",
+			Summary:     "Run a GraphQL query",
+			Description: graphQLDescription,
+			Body:        GraphQLRequest{},
+			Response:    GraphQLResponse{},
+		},
+	}
+}
+
+// Query runs a GraphQL query.
+func (rs *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	request, err := readGraphQLRequest(w, r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	complexity, err := graphQLComplexity(request.Query, request.Variables, rs.limits.MaxComplexity)
+	if err != nil {
+		render.JSON(w, r, &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}})
+		return
+	}
+	if complexity > rs.limits.MaxComplexity {
+		render.JSON(w, r, &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf(
+			"query is too complex, it may return more than %d objects, use smaller limits or fewer nested lists",
+			rs.limits.MaxComplexity,
+		)}})
+		return
+	}
+
+	ctx := withGraphQLLoaders(r.Context(), rs.repos)
+	response := rs.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+	if len(response.Errors) > 0 {
+		// Errors are returned with a 200, they must not be cached as a result.
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	render.JSON(w, r, response)
+}
+
+// readGraphQLRequest reads a GraphQL request from the query parameters of a GET
+// request, or the JSON body of a POST request.
+func readGraphQLRequest(w http.ResponseWriter, r *http.Request) (GraphQLRequest, error) {
+	var request GraphQLRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return request, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else {
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody))
+		if err := decoder.Decode(&request); err != nil {
+			return request, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	}
+	if request.Query == "" {
+		return request, errors.New("a query is required")
+	}
+	return request, nil
+}
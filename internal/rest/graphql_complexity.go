@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"strconv"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// graphQLListSizes is the number of objects a list returns if the query does
+// not set its limit: the default limit, or about the most a list without a
+// limit argument returns.
+var graphQLListSizes = map[string]int{
+	"domains":           graphQLDefaultLimit,
+	"heroes":            graphQLDefaultLimit,
+	"search":            graphQLDefaultLimit,
+	"asns":              graphQLDefaultLimit,
+	"changelog":         graphQLDefaultLimit,
+	"campaignChangelog": graphQLDefaultLimit,
+	"countries":         250,
+	"campaigns":         100,
+	"log":               90,
+	"metrics":           100,
+}
+
+// graphQLComplexity estimates how many objects a query returns. Every field
+// with a selection counts once for every object of its parent, and a list
+// counts as many times as its limit. Fragments count where they are spread, and
+// a document with several operations counts as its most complex one. A limit
+// that is not known before the query runs counts as graphQLMaxLimit.
+// The estimate stops counting once it is past maxComplexity.
+func graphQLComplexity(query string, variables map[string]any, maxComplexity int) (int, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, op := range doc.Operations {
+		c := complexity{doc: doc, variables: variables, definitions: op.VariableDefinitions, max: maxComplexity}
+		total = max(total, c.selectionSet(op.SelectionSet, 0))
+	}
+	return total, nil
+}
+
+// complexity is the state of a complexity estimate.
+type complexity struct {
+	doc         *ast.QueryDocument
+	variables   map[string]any
+	definitions ast.VariableDefinitionList // Of the operation, with the defaults of its variables
+	max         int
+}
+
+// maxFragmentNesting is how deep fragments are followed. Fragments that spread
+// themselves are rejected by the validation of the query later on.
+const maxFragmentNesting = 32
+
+// selectionSet returns the number of objects a selection set returns, for a
+// single parent object.
+func (c complexity) selectionSet(set ast.SelectionSet, depth int) int {
+	if depth > maxFragmentNesting {
+		return c.max + 1
+	}
+	total := 0
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if len(selection.SelectionSet) == 0 {
+				continue
+			}
+			total += c.cap(c.listSize(selection) * (1 + c.selectionSet(selection.SelectionSet, depth+1)))
+		case *ast.InlineFragment:
+			total += c.selectionSet(selection.SelectionSet, depth+1)
+		case *ast.FragmentSpread:
+			if fragment := c.doc.Fragments.ForName(selection.Name); fragment != nil {
+				total += c.selectionSet(fragment.SelectionSet, depth+1)
+			}
+		}
+		total = c.cap(total)
+	}
+	return total
+}
+
+// listSize returns the number of objects a field returns: its limit, the size
+// of a list without a limit, or one.
+func (c complexity) listSize(field *ast.Field) int {
+	if arg := field.Arguments.ForName("limit"); arg != nil {
+		if limit, ok := c.intValue(arg.Value); ok {
+			return graphQLLimit(&limit)
+		}
+		return graphQLMaxLimit
+	}
+	if size, ok := graphQLListSizes[field.Name]; ok {
+		return size
+	}
+	return 1
+}
+
+// intValue returns the value of an integer argument, or of a variable with an
+// integer value or default. It returns false for other values, which the
+// validation rejects, and for variables that are null or not set.
+func (c complexity) intValue(value *ast.Value) (int32, bool) {
+	switch value.Kind {
+	case ast.IntValue:
+		n, err := strconv.ParseInt(value.Raw, 10, 32)
+		if err != nil {
+			return 0, false
+		}
+		return int32(n), true
+	case ast.Variable:
+		if v, ok := c.variables[value.Raw]; ok {
+			n, ok := v.(float64)
+			return int32(min(max(n, 0), graphQLMaxLimit+1)), ok
+		}
+		if definition := c.definitions.ForName(value.Raw); definition != nil && definition.DefaultValue != nil {
+			return c.intValue(definition.DefaultValue)
+		}
+	}
+	return 0, false
+}
+
+// cap limits a count to just past the maximum, so it can not overflow.
+func (c complexity) cap(n int) int {
+	return min(n, c.max+1)
+}
@@ -0,0 +1,620 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// Page sizes of the GraphQL lists.
+const (
+	graphQLDefaultLimit = 20
+	graphQLMaxLimit     = 100
+)
+
+// errGraphQLInternal is the error of a field that could not be resolved. The
+// cause is logged, not returned to the client.
+var errGraphQLInternal = errors.New("internal server error")
+
+// graphQLFailed logs why a field could not be resolved.
+func graphQLFailed(field string, err error) error {
+	log.Printf("GraphQL: error resolving %s: %v", field, err)
+	return errGraphQLInternal
+}
+
+// graphQLLimit returns the limit of a list, between 1 and graphQLMaxLimit, or
+// the default if it is not set.
+func graphQLLimit(limit *int32) int {
+	if limit == nil {
+		return graphQLDefaultLimit
+	}
+	return min(max(int(*limit), 1), graphQLMaxLimit)
+}
+
+// graphQLTime returns a timestamp, or null if it is not set.
+func graphQLTime(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+// pageArgs is the arguments of a list with an offset and a limit.
+type pageArgs struct {
+	Offset int32
+	Limit  int32
+}
+
+// page returns the offset and the limit of the list.
+func (a pageArgs) page() (int64, int64) {
+	return int64(max(a.Offset, 0)), int64(graphQLLimit(&a.Limit))
+}
+
+// limitArgs is the arguments of a list with only a limit.
+type limitArgs struct {
+	Limit int32
+}
+
+// graphQLLoaders is the batched loads of a GraphQL request.
+type graphQLLoaders struct {
+	countries *loader[int64, core.CountryModel]
+	asns      *loader[int64, core.ASNModel]
+	changelog *loader[domainChangelogKey, []core.ChangelogModel]
+}
+
+// domainChangelogKey is the changelog of a domain, up to a limit.
+type domainChangelogKey struct {
+	domainID int64
+	limit    int64
+}
+
+// graphQLLoadersKey is the context key of the loaders of a GraphQL request.
+type graphQLLoadersKey struct{}
+
+// withGraphQLLoaders returns the context with new loaders for a GraphQL request.
+func withGraphQLLoaders(ctx context.Context, repos GraphQLRepos) context.Context {
+	loaders := &graphQLLoaders{
+		countries: newLoader(ctx, func(ctx context.Context, ids []int64) (map[int64]core.CountryModel, error) {
+			countries, err := repos.Countries.GetCountriesByID(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[int64]core.CountryModel, len(countries))
+			for _, country := range countries {
+				values[country.ID] = country
+			}
+			return values, nil
+		}),
+		asns: newLoader(ctx, func(ctx context.Context, ids []int64) (map[int64]core.ASNModel, error) {
+			asns, err := repos.ASNs.GetASNsByID(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[int64]core.ASNModel, len(asns))
+			for _, asn := range asns {
+				values[asn.ID] = asn
+			}
+			return values, nil
+		}),
+		changelog: newLoader(ctx, func(
+			ctx context.Context,
+			keys []domainChangelogKey,
+		) (map[domainChangelogKey][]core.ChangelogModel, error) {
+			// The domains are fetched together if they ask for as many entries.
+			byLimit := make(map[int64][]int64)
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key.domainID)
+			}
+			values := make(map[domainChangelogKey][]core.ChangelogModel, len(keys))
+			for limit, domainIDs := range byLimit {
+				entries, err := repos.Changelog.GetChangelogByDomainIDs(ctx, domainIDs, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, entry := range entries {
+					key := domainChangelogKey{domainID: entry.DomainID, limit: limit}
+					values[key] = append(values[key], entry)
+				}
+			}
+			return values, nil
+		}),
+	}
+	return context.WithValue(ctx, graphQLLoadersKey{}, loaders)
+}
+
+// graphQLLoadersFrom returns the loaders of the GraphQL request of the context.
+func graphQLLoadersFrom(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// graphQLResolver resolves the Query type.
+type graphQLResolver struct {
+	repos GraphQLRepos
+}
+
+func (q *graphQLResolver) Domain(ctx context.Context, args struct{ Site string }) (*graphQLDomain, error) {
+	domain, err := q.repos.Domains.ViewDomain(ctx, strings.ToLower(args.Site))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLFailed("domain", err)
+	}
+	return newGraphQLDomain(q.repos, domain), nil
+}
+
+func (q *graphQLResolver) Domains(ctx context.Context, args pageArgs) ([]*graphQLDomain, error) {
+	offset, limit := args.page()
+	domains, err := q.repos.Domains.ListDomain(ctx, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("domains", err)
+	}
+	return newGraphQLDomains(q.repos, domains), nil
+}
+
+func (q *graphQLResolver) Heroes(ctx context.Context, args pageArgs) ([]*graphQLDomain, error) {
+	offset, limit := args.page()
+	domains, err := q.repos.Domains.ListDomainHeroes(ctx, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("heroes", err)
+	}
+	return newGraphQLDomains(q.repos, domains), nil
+}
+
+func (q *graphQLResolver) Search(ctx context.Context, args struct {
+	Query string
+	pageArgs
+}) ([]*graphQLDomain, error) {
+	offset, limit := args.page()
+	domains, err := q.repos.Domains.GetDomainsByName(ctx, args.Query, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("search", err)
+	}
+	return newGraphQLDomains(q.repos, domains), nil
+}
+
+func (q *graphQLResolver) Country(ctx context.Context, args struct{ Code string }) (*graphQLCountry, error) {
+	country, err := q.repos.Countries.GetCountryCode(ctx, strings.ToUpper(args.Code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLFailed("country", err)
+	}
+	return &graphQLCountry{repos: q.repos, country: country}, nil
+}
+
+func (q *graphQLResolver) Countries(ctx context.Context) ([]*graphQLCountry, error) {
+	countries, err := q.repos.Countries.List(ctx)
+	if err != nil {
+		return nil, graphQLFailed("countries", err)
+	}
+	list := make([]*graphQLCountry, 0, len(countries))
+	for _, country := range countries {
+		list = append(list, &graphQLCountry{repos: q.repos, country: country})
+	}
+	return list, nil
+}
+
+func (q *graphQLResolver) Campaign(ctx context.Context, args struct{ ID graphql.ID }) (*graphQLCampaign, error) {
+	id, err := decodeUUID(string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("invalid campaign id %q", args.ID)
+	}
+	campaign, err := q.repos.Campaigns.GetCampaign(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLFailed("campaign", err)
+	}
+	return &graphQLCampaign{repos: q.repos, campaign: campaign}, nil
+}
+
+func (q *graphQLResolver) Campaigns(ctx context.Context) ([]*graphQLCampaign, error) {
+	campaigns, err := q.repos.Campaigns.ListCampaign(ctx)
+	if err != nil {
+		return nil, graphQLFailed("campaigns", err)
+	}
+	list := make([]*graphQLCampaign, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		list = append(list, &graphQLCampaign{repos: q.repos, campaign: campaign})
+	}
+	return list, nil
+}
+
+func (q *graphQLResolver) Asn(ctx context.Context, args struct{ Number int32 }) (*graphQLASN, error) {
+	asn, err := q.repos.ASNs.GetASByNumber(ctx, args.Number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLFailed("asn", err)
+	}
+	// The lookup by number has no statistics, the lookup by ID does.
+	return loadGraphQLASN(ctx, asn.ID)
+}
+
+func (q *graphQLResolver) Asns(ctx context.Context, args struct {
+	Order string
+	pageArgs
+}) ([]*graphQLASN, error) {
+	order := "ipv4"
+	if args.Order == "IPV6" {
+		order = "ipv6"
+	}
+	offset, limit := args.page()
+	asns, err := q.repos.Metrics.AsnList(ctx, offset, limit, order)
+	if err != nil {
+		return nil, graphQLFailed("asns", err)
+	}
+	list := make([]*graphQLASN, 0, len(asns))
+	for _, asn := range asns {
+		list = append(list, &graphQLASN{asn: asn})
+	}
+	return list, nil
+}
+
+func (q *graphQLResolver) Changelog(ctx context.Context, args pageArgs) ([]*graphQLChange, error) {
+	offset, limit := args.page()
+	entries, err := q.repos.Changelog.List(ctx, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("changelog", err)
+	}
+	return newGraphQLChanges(entries), nil
+}
+
+func (q *graphQLResolver) CampaignChangelog(ctx context.Context, args pageArgs) ([]*graphQLChange, error) {
+	offset, limit := args.page()
+	entries, err := q.repos.Changelog.CampaignList(ctx, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("campaignChangelog", err)
+	}
+	return newGraphQLChanges(entries), nil
+}
+
+func (q *graphQLResolver) Metrics(ctx context.Context) ([]*graphQLMetric, error) {
+	metrics, err := q.repos.Metrics.DomainStats(ctx)
+	if err != nil {
+		return nil, graphQLFailed("metrics", err)
+	}
+	list := make([]*graphQLMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		list = append(list, &graphQLMetric{metric: metric})
+	}
+	return list, nil
+}
+
+// graphQLChecks resolves the check results of a domain or a campaign domain.
+type graphQLChecks struct {
+	repos        GraphQLRepos
+	site         string
+	baseDomain   string
+	wwwDomain    string
+	nameserver   string
+	mxRecord     string
+	v6Only       string
+	tsBaseDomain time.Time
+	tsWwwDomain  time.Time
+	tsNameserver time.Time
+	tsMXRecord   time.Time
+	tsV6Only     time.Time
+	tsCheck      time.Time
+	tsUpdated    time.Time
+	countryID    int64
+	asnID        int64
+}
+
+func (c graphQLChecks) Site() string                { return c.site }
+func (c graphQLChecks) BaseDomain() string          { return c.baseDomain }
+func (c graphQLChecks) WwwDomain() string           { return c.wwwDomain }
+func (c graphQLChecks) Nameserver() string          { return c.nameserver }
+func (c graphQLChecks) MxRecord() string            { return c.mxRecord }
+func (c graphQLChecks) V6Only() string              { return c.v6Only }
+func (c graphQLChecks) TsBaseDomain() *graphql.Time { return graphQLTime(c.tsBaseDomain) }
+func (c graphQLChecks) TsWwwDomain() *graphql.Time  { return graphQLTime(c.tsWwwDomain) }
+func (c graphQLChecks) TsNameserver() *graphql.Time { return graphQLTime(c.tsNameserver) }
+func (c graphQLChecks) TsMxRecord() *graphql.Time   { return graphQLTime(c.tsMXRecord) }
+func (c graphQLChecks) TsV6Only() *graphql.Time     { return graphQLTime(c.tsV6Only) }
+func (c graphQLChecks) TsCheck() *graphql.Time      { return graphQLTime(c.tsCheck) }
+func (c graphQLChecks) TsUpdated() *graphql.Time    { return graphQLTime(c.tsUpdated) }
+
+// Country is loaded together with the countries of the other domains of the request.
+func (c graphQLChecks) Country(ctx context.Context) (*graphQLCountry, error) {
+	if c.countryID == 0 {
+		return nil, nil
+	}
+	country, ok, err := graphQLLoadersFrom(ctx).countries.load(ctx, c.countryID)
+	if err != nil {
+		return nil, graphQLFailed("country", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &graphQLCountry{repos: c.repos, country: country}, nil
+}
+
+// Asn is loaded together with the ASNs of the other domains of the request.
+func (c graphQLChecks) Asn(ctx context.Context) (*graphQLASN, error) {
+	if c.asnID == 0 {
+		return nil, nil
+	}
+	return loadGraphQLASN(ctx, c.asnID)
+}
+
+// loadGraphQLASN loads an ASN by its ID, or returns nil if there is none.
+func loadGraphQLASN(ctx context.Context, id int64) (*graphQLASN, error) {
+	asn, ok, err := graphQLLoadersFrom(ctx).asns.load(ctx, id)
+	if err != nil {
+		return nil, graphQLFailed("asn", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &graphQLASN{asn: asn}, nil
+}
+
+// graphQLDomain resolves the Domain type.
+type graphQLDomain struct {
+	graphQLChecks
+	domain core.DomainModel
+}
+
+// newGraphQLDomain maps a domain to its resolver.
+func newGraphQLDomain(repos GraphQLRepos, domain core.DomainModel) *graphQLDomain {
+	return &graphQLDomain{
+		graphQLChecks: graphQLChecks{
+			repos:        repos,
+			site:         domain.Site,
+			baseDomain:   domain.BaseDomain,
+			wwwDomain:    domain.WwwDomain,
+			nameserver:   domain.Nameserver,
+			mxRecord:     domain.MXRecord,
+			v6Only:       domain.V6Only,
+			tsBaseDomain: domain.TsBaseDomain,
+			tsWwwDomain:  domain.TsWwwDomain,
+			tsNameserver: domain.TsNameserver,
+			tsMXRecord:   domain.TsMXRecord,
+			tsV6Only:     domain.TsV6Only,
+			tsCheck:      domain.TsCheck,
+			tsUpdated:    domain.TsUpdated,
+			countryID:    domain.CountryID,
+			asnID:        domain.AsnID,
+		},
+		domain: domain,
+	}
+}
+
+// newGraphQLDomains maps a list of domains to their resolvers.
+func newGraphQLDomains(repos GraphQLRepos, domains []core.DomainModel) []*graphQLDomain {
+	list := make([]*graphQLDomain, 0, len(domains))
+	for _, domain := range domains {
+		list = append(list, newGraphQLDomain(repos, domain))
+	}
+	return list
+}
+
+func (d *graphQLDomain) Rank() int32 { return int32(d.domain.Rank) }
+
+// Changelog is loaded together with the changelogs of the other domains of the request.
+func (d *graphQLDomain) Changelog(ctx context.Context, args limitArgs) ([]*graphQLChange, error) {
+	key := domainChangelogKey{domainID: d.domain.ID, limit: int64(graphQLLimit(&args.Limit))}
+	entries, _, err := graphQLLoadersFrom(ctx).changelog.load(ctx, key)
+	if err != nil {
+		return nil, graphQLFailed("changelog", err)
+	}
+	return newGraphQLChanges(entries), nil
+}
+
+func (d *graphQLDomain) Log(ctx context.Context) ([]*graphQLDomainLog, error) {
+	logs, err := d.repos.Domains.GetDomainLog(ctx, d.domain.Site)
+	if err != nil {
+		return nil, graphQLFailed("log", err)
+	}
+	list := make([]*graphQLDomainLog, 0, len(logs))
+	for _, entry := range logs {
+		response, err := newDomainLogResponse(entry.ID, entry.Time, entry.Data)
+		if err != nil {
+			return nil, graphQLFailed("log", err)
+		}
+		list = append(list, &graphQLDomainLog{log: response})
+	}
+	return list, nil
+}
+
+// graphQLCountry resolves the Country type.
+type graphQLCountry struct {
+	repos   GraphQLRepos
+	country core.CountryModel
+}
+
+func (c *graphQLCountry) Code() string   { return c.country.CountryCode }
+func (c *graphQLCountry) Name() string   { return c.country.Country }
+func (c *graphQLCountry) Tld() string    { return c.country.CountryTld }
+func (c *graphQLCountry) Sites() int32   { return c.country.Sites }
+func (c *graphQLCountry) V6Sites() int32 { return c.country.V6sites }
+
+func (c *graphQLCountry) Percent() (float64, error) {
+	response, err := newCountryResponse(c.country)
+	if err != nil {
+		return 0, graphQLFailed("percent", err)
+	}
+	return response.Percent, nil
+}
+
+func (c *graphQLCountry) Domains(ctx context.Context, args pageArgs) ([]*graphQLDomain, error) {
+	offset, limit := args.page()
+	domains, err := c.repos.Countries.ListDomainsByCountry(ctx, c.country.ID, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("domains", err)
+	}
+	return newGraphQLDomains(c.repos, domains), nil
+}
+
+func (c *graphQLCountry) Heroes(ctx context.Context, args pageArgs) ([]*graphQLDomain, error) {
+	offset, limit := args.page()
+	domains, err := c.repos.Countries.ListDomainHeroesByCountry(ctx, c.country.ID, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("heroes", err)
+	}
+	return newGraphQLDomains(c.repos, domains), nil
+}
+
+func (c *graphQLCountry) Changelog(ctx context.Context, args limitArgs) ([]*graphQLChange, error) {
+	entries, err := c.repos.Changelog.GetChangelogByCountry(ctx, c.country.ID, int64(graphQLLimit(&args.Limit)))
+	if err != nil {
+		return nil, graphQLFailed("changelog", err)
+	}
+	return newGraphQLChanges(entries), nil
+}
+
+// graphQLCampaign resolves the Campaign type.
+type graphQLCampaign struct {
+	repos    GraphQLRepos
+	campaign core.CampaignModel
+}
+
+func (c *graphQLCampaign) ID() graphql.ID          { return graphql.ID(encodeUUID(c.campaign.UUID)) }
+func (c *graphQLCampaign) Name() string            { return c.campaign.Name }
+func (c *graphQLCampaign) Description() string     { return c.campaign.Description }
+func (c *graphQLCampaign) CreatedAt() graphql.Time { return graphql.Time{Time: c.campaign.CreatedAt} }
+func (c *graphQLCampaign) DomainCount() int32      { return int32(c.campaign.Count) }
+func (c *graphQLCampaign) V6Ready() int32          { return int32(c.campaign.V6Ready) }
+
+func (c *graphQLCampaign) Domains(ctx context.Context, args pageArgs) ([]*graphQLCampaignDomain, error) {
+	offset, limit := args.page()
+	domains, err := c.repos.Campaigns.ListCampaignDomain(ctx, c.campaign.UUID, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("domains", err)
+	}
+	list := make([]*graphQLCampaignDomain, 0, len(domains))
+	for _, domain := range domains {
+		list = append(list, newGraphQLCampaignDomain(c.repos, domain))
+	}
+	return list, nil
+}
+
+func (c *graphQLCampaign) Domain(ctx context.Context, args struct{ Site string }) (*graphQLCampaignDomain, error) {
+	domain, err := c.repos.Campaigns.ViewCampaignDomain(ctx, c.campaign.UUID, strings.ToLower(args.Site))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLFailed("domain", err)
+	}
+	return newGraphQLCampaignDomain(c.repos, domain), nil
+}
+
+func (c *graphQLCampaign) Changelog(ctx context.Context, args pageArgs) ([]*graphQLChange, error) {
+	offset, limit := args.page()
+	entries, err := c.repos.Changelog.GetChangelogByCampaign(ctx, c.campaign.UUID, offset, limit)
+	if err != nil {
+		return nil, graphQLFailed("changelog", err)
+	}
+	return newGraphQLChanges(entries), nil
+}
+
+// graphQLCampaignDomain resolves the CampaignDomain type.
+type graphQLCampaignDomain struct {
+	graphQLChecks
+}
+
+// newGraphQLCampaignDomain maps a campaign domain to its resolver.
+func newGraphQLCampaignDomain(repos GraphQLRepos, domain core.CampaignDomainModel) *graphQLCampaignDomain {
+	return &graphQLCampaignDomain{
+		graphQLChecks: graphQLChecks{
+			repos:        repos,
+			site:         domain.Site,
+			baseDomain:   domain.BaseDomain,
+			wwwDomain:    domain.WwwDomain,
+			nameserver:   domain.Nameserver,
+			mxRecord:     domain.MXRecord,
+			v6Only:       domain.V6Only,
+			tsBaseDomain: domain.TsBaseDomain,
+			tsWwwDomain:  domain.TsWwwDomain,
+			tsNameserver: domain.TsNameserver,
+			tsMXRecord:   domain.TsMXRecord,
+			tsV6Only:     domain.TsV6Only,
+			tsCheck:      domain.TsCheck,
+			tsUpdated:    domain.TsUpdated,
+			countryID:    domain.CountryID,
+			asnID:        domain.AsnID,
+		},
+	}
+}
+
+// graphQLASN resolves the ASN type.
+type graphQLASN struct {
+	asn core.ASNModel
+}
+
+func (a *graphQLASN) Number() int32      { return a.asn.Number }
+func (a *graphQLASN) Name() string       { return a.asn.Name }
+func (a *graphQLASN) CountV4() int32     { return a.asn.CountV4 }
+func (a *graphQLASN) CountV6() int32     { return a.asn.CountV6 }
+func (a *graphQLASN) PercentV4() float64 { return a.asn.PercentV4 }
+func (a *graphQLASN) PercentV6() float64 { return a.asn.PercentV6 }
+
+// graphQLChange resolves the Change type.
+type graphQLChange struct {
+	entry core.ChangelogModel
+}
+
+// newGraphQLChanges maps changelog entries to their resolvers.
+func newGraphQLChanges(entries []core.ChangelogModel) []*graphQLChange {
+	list := make([]*graphQLChange, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, &graphQLChange{entry: entry})
+	}
+	return list
+}
+
+func (c *graphQLChange) ID() graphql.ID     { return graphql.ID(strconv.FormatInt(c.entry.ID, 10)) }
+func (c *graphQLChange) Time() graphql.Time { return graphql.Time{Time: c.entry.Ts} }
+func (c *graphQLChange) Site() string       { return c.entry.Site }
+func (c *graphQLChange) Message() string    { return c.entry.Message }
+func (c *graphQLChange) Ipv6Status() string { return c.entry.IPv6Status }
+
+// graphQLDomainLog resolves the DomainLog type.
+type graphQLDomainLog struct {
+	log DomainLogResponse
+}
+
+func (l *graphQLDomainLog) Time() graphql.Time { return graphql.Time{Time: l.log.Time} }
+func (l *graphQLDomainLog) BaseDomain() string { return l.log.BaseDomain }
+func (l *graphQLDomainLog) WwwDomain() string  { return l.log.WwwDomain }
+func (l *graphQLDomainLog) Nameserver() string { return l.log.Nameserver }
+func (l *graphQLDomainLog) MxRecord() string   { return l.log.MXRecord }
+
+// graphQLMetric resolves the Metric type.
+type graphQLMetric struct {
+	metric core.Metric
+}
+
+func (m *graphQLMetric) Time() graphql.Time { return graphql.Time{Time: m.metric.Time} }
+func (m *graphQLMetric) Data() graphQLJSON  { return graphQLJSON{value: m.metric.Data} }
+
+// graphQLJSON is the JSON scalar, which is only returned.
+type graphQLJSON struct {
+	value pgtype.JSONB
+}
+
+// ImplementsGraphQLType implements graphql-go's scalar interface.
+func (graphQLJSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL implements graphql-go's scalar interface. The schema takes
+// no JSON arguments.
+func (*graphQLJSON) UnmarshalGraphQL(any) error { return errors.New("JSON is not an input type") }
+
+// MarshalJSON returns the value as is.
+func (j graphQLJSON) MarshalJSON() ([]byte, error) { return j.value.MarshalJSON() }
@@ -0,0 +1,174 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLComplexity(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      int
+		wantErr   bool
+	}{
+		{"scalars only", `{ __typename }`, nil, 0, false},
+		{"one object", `{ domain(site: "example.com") { site rank } }`, nil, 1, false},
+		{"default limit", `{ domains { site } }`, nil, graphQLDefaultLimit, false},
+		{"list without a limit", `{ countries { code } }`, nil, 250, false},
+		{"limit", `{ domains(limit: 5) { site } }`, nil, 5, false},
+		{"limit above the maximum", `{ domains(limit: 500) { site } }`, nil, graphQLMaxLimit, false},
+		{"limit below one", `{ domains(limit: 0) { site } }`, nil, 1, false},
+		{"limit out of range", `{ domains(limit: 99999999999) { site } }`, nil, graphQLMaxLimit, false},
+		{"null limit", `{ domains(limit: null) { site } }`, nil, graphQLMaxLimit, false},
+		{"variable limit", `query($n: Int) { domains(limit: $n) { site } }`, map[string]any{"n": float64(7)}, 7, false},
+		{"large variable limit", `query($n: Int) { domains(limit: $n) { site } }`, map[string]any{"n": float64(1e12)}, graphQLMaxLimit, false},
+		{"missing variable", `query($n: Int) { domains(limit: $n) { site } }`, nil, graphQLMaxLimit, false},
+		{"null variable", `query($n: Int) { domains(limit: $n) { site } }`, map[string]any{"n": nil}, graphQLMaxLimit, false},
+		{"defaulted variable", `query($n: Int = 50) { domains(limit: $n) { site } }`, nil, 50, false},
+		{"variable over its default", `query($n: Int = 50) { domains(limit: $n) { site } }`, map[string]any{"n": float64(3)}, 3, false},
+		{
+			"defaulted variable in nested lists",
+			`query($n: Int = 100) { domains(limit: $n) { country { domains(limit: $n) { site } } } }`,
+			nil, 1001, false,
+		},
+		{
+			"defaulted variable in a fragment",
+			`query($n: Int = 30) { domains(limit: 2) { ...d } } fragment d on Domain { changelog(limit: $n) { site } }`,
+			nil, 2 * (1 + 30), false,
+		},
+		{
+			"defaults of each operation",
+			`query a($n: Int = 5) { domains(limit: $n) { site } } query b($n: Int = 7) { heroes(limit: $n) { site } }`,
+			nil, 7, false,
+		},
+		{"nested object", `{ domains(limit: 5) { country { name } } }`, nil, 5 * (1 + 1), false},
+		{"nested list", `{ domains(limit: 5) { changelog(limit: 3) { site } } }`, nil, 5 * (1 + 3), false},
+		{"sibling fields add up", `{ domain(site: "a") { site } country(code: "NO") { name } }`, nil, 2, false},
+		{"aliases count apart", `{ a: domains(limit: 5) { site } b: domains(limit: 5) { site } }`, nil, 10, false},
+		{"fragment spread", `{ domains(limit: 2) { ...d } } fragment d on Domain { country { name } }`, nil, 2 * (1 + 1), false},
+		{"inline fragment", `{ domains(limit: 2) { ... on Domain { asn { name } } } }`, nil, 2 * (1 + 1), false},
+		{"most complex operation", `query a { domain(site: "a") { site } } query b { countries { name } }`, nil, 250, false},
+		{"stops past the maximum", `{ domains(limit: 100) { changelog(limit: 100) { site } } }`, nil, 1001, false},
+		{"fragment spreading itself", `{ domain(site: "a") { ...f } } fragment f on Domain { ...f }`, nil, 1001, false},
+		{"invalid query", `{ domains(`, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := graphQLComplexity(tt.query, tt.variables, 1000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("graphQLComplexity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("graphQLComplexity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGraphQLHandlerDefaults(t *testing.T) {
+	h, err := NewGraphQLHandler(GraphQLRepos{}, GraphQLLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.limits.MaxDepth != defaultGraphQLMaxDepth || h.limits.MaxComplexity != defaultGraphQLMaxComplexity {
+		t.Errorf("limits = %+v, want the defaults", h.limits)
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	// The handler has no services, so the queries that pass the limits must not
+	// resolve any of them.
+	h, err := NewGraphQLHandler(GraphQLRepos{}, GraphQLLimits{MaxDepth: 3, MaxComplexity: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		variables string
+		wantData  bool
+		wantError string
+	}{
+		{"within the limits", `{ __typename }`, "", true, ""},
+		{"at the depth limit", `{ __schema { queryType { name } } }`, "", true, ""},
+		{"too deep", `{ __schema { queryType { fields { name } } } }`, "", false, "exceeds max depth 3"},
+		{"too deep in a fragment", `{ __schema { ...s } } fragment s on __Schema { queryType { fields { name } } }`, "", false, "exceeds max depth 3"},
+		{"too complex", `{ domains(limit: 51) { site } }`, "", false, "query is too complex, it may return more than 50 objects"},
+		{"too complex with nesting", `{ domains(limit: 10) { changelog(limit: 5) { site } } }`, "", false, "query is too complex"},
+		{"too complex with a variable", `query($n: Int) { domains(limit: $n) { site } }`, `{"n": 60}`, false, "query is too complex"},
+		{"too complex with a defaulted variable", `query($n: Int = 60) { domains(limit: $n) { site } }`, "", false, "query is too complex"},
+		{"invalid query", `{ domains(`, "", false, "Expected Name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"query": {tt.query}}
+			if tt.variables != "" {
+				query.Set("variables", tt.variables)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			h.Query(rec, r)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			var response struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []GraphQLError  `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			hasData := len(response.Data) > 0 && string(response.Data) != "null"
+			if hasData != tt.wantData {
+				t.Errorf("data = %s, want data: %v", response.Data, tt.wantData)
+			}
+			if tt.wantError == "" {
+				if len(response.Errors) > 0 {
+					t.Errorf("errors = %+v, want none", response.Errors)
+				}
+				return
+			}
+			if len(response.Errors) == 0 || !strings.Contains(response.Errors[0].Message, tt.wantError) {
+				t.Errorf("errors = %+v, want %q", response.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestGraphQLRequest(t *testing.T) {
+	h, err := NewGraphQLHandler(GraphQLRepos{}, GraphQLLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"GET", http.MethodGet, "/?query=%7B__typename%7D", "", http.StatusOK},
+		{"POST", http.MethodPost, "/", `{"query": "{ __typename }"}`, http.StatusOK},
+		{"missing query", http.MethodGet, "/", "", http.StatusBadRequest},
+		{"invalid variables", http.MethodGet, "/?query=%7B__typename%7D&variables=%5B", "", http.StatusBadRequest},
+		{"invalid body", http.MethodPost, "/", `{"query":`, http.StatusBadRequest},
+		{"body too large", http.MethodPost, "/", `{"query": "` + strings.Repeat(" ", maxGraphQLBody) + `{ __typename }"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.Query(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
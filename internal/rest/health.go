@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"whynoipv6/internal/buildinfo"
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// readyCheckTimeout is how long the readiness checks may take together.
+const readyCheckTimeout = 2 * time.Second
+
+// Status of a health or readiness check.
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// HealthHandler is a handler for the liveness, readiness and build info
+// endpoints, for load balancers and monitoring.
+type HealthHandler struct {
+	DB         *pgxpool.Pool
+	Metrics    *core.MetricService
+	MaxDataAge time.Duration // The API is not ready if the last crawl finished longer ago, zero to not check it
+}
+
+// HealthResponse is the response structure for the liveness endpoint.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadyResponse is the response structure for the readiness endpoint.
+type ReadyResponse struct {
+	Status   string             `json:"status"`
+	Database CheckResponse      `json:"database"`
+	Data     *DataCheckResponse `json:"data,omitempty"` // Only if the data age is checked
+}
+
+// CheckResponse is the result of a readiness check.
+type CheckResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DataCheckResponse is the result of the data age check.
+type DataCheckResponse struct {
+	CheckResponse
+	LastCrawl  *time.Time `json:"last_crawl,omitempty"`
+	AgeSeconds int64      `json:"age_seconds"`
+	MaxSeconds int64      `json:"max_seconds"`
+}
+
+// Operations documents the endpoints, which are mounted on the root of the router.
+func (rs HealthHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:   "GET",
+			Path:     "/healthz",
+			Summary:  "Liveness check",
+			Response: HealthResponse{},
+		},
+		{
+			Method: "GET",
+			Path:   "/readyz",
+			Summary: "Readiness check, of the database and optionally of the age of the data. " +
+				"Returns 503 if a check fails",
+			Response: ReadyResponse{},
+			Error:    ReadyResponse{},
+		},
+		{
+			Method:   "GET",
+			Path:     "/version",
+			Summary:  "Build information of the API server",
+			Response: buildinfo.Info{},
+		},
+	}
+}
+
+// Healthz reports that the server is running. It does not check its
+// dependencies, so a database outage does not get the server restarted.
+func (rs HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, HealthResponse{Status: checkOK})
+}
+
+// Readyz reports whether the server can serve requests: the database is
+// reachable and, if MaxDataAge is set, the last crawl is recent enough.
+func (rs HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	resp := ReadyResponse{Status: checkOK, Database: CheckResponse{Status: checkOK}}
+	if err := rs.DB.Ping(ctx); err != nil {
+		log.Println("Readiness check: database:", err)
+		resp.Database = CheckResponse{Status: checkFail, Error: "database unreachable"}
+	} else if rs.MaxDataAge > 0 {
+		resp.Data = rs.checkDataAge(ctx)
+	}
+
+	if resp.Database.Status != checkOK || (resp.Data != nil && resp.Data.Status != checkOK) {
+		resp.Status = checkFail
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, resp)
+}
+
+// checkDataAge checks that the last crawl finished within MaxDataAge.
+func (rs HealthHandler) checkDataAge(ctx context.Context) *DataCheckResponse {
+	check := &DataCheckResponse{
+		CheckResponse: CheckResponse{Status: checkOK},
+		MaxSeconds:    int64(rs.MaxDataAge.Seconds()),
+	}
+	lastCrawl, err := rs.Metrics.LatestMetricTime(ctx, "crawler")
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		check.CheckResponse = CheckResponse{Status: checkFail, Error: "no crawl has finished"}
+		return check
+	case err != nil:
+		log.Println("Readiness check: data age:", err)
+		check.CheckResponse = CheckResponse{Status: checkFail, Error: "could not read the last crawl"}
+		return check
+	}
+
+	age := time.Since(lastCrawl)
+	check.LastCrawl = &lastCrawl
+	check.AgeSeconds = int64(age.Seconds())
+	if age > rs.MaxDataAge {
+		check.CheckResponse = CheckResponse{Status: checkFail, Error: "the last crawl is too old"}
+	}
+	return check
+}
+
+// Version returns the build information of the server.
+func (rs HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, buildinfo.Read())
+}
@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// defaultHistoryRange is the period of a history without a from date.
+	defaultHistoryRange = 90 * 24 * time.Hour
+	// maxHistoryRange is the longest period of a history.
+	maxHistoryRange = 5 * 366 * 24 * time.Hour
+)
+
+// DomainHistoryInput is the query parameters of a domain history.
+type DomainHistoryInput struct {
+	From   string `in:"query=from"`
+	To     string `in:"query=to"`
+	Bucket string `in:"query=bucket"`
+}
+
+// DomainHistoryResponse is the response structure for the history of a domain.
+type DomainHistoryResponse struct {
+	Domain    string                    `json:"domain"`
+	From      time.Time                 `json:"from"`
+	To        time.Time                 `json:"to"`
+	Entries   int                       `json:"entries"`
+	Uptime    HistoryUptimeResponse     `json:"uptime"`
+	Intervals []HistoryIntervalResponse `json:"intervals"`
+	Buckets   []HistoryBucketResponse   `json:"buckets,omitempty"`
+}
+
+// HistoryUptimeResponse is the percent of the time each check was supported.
+// A check without a known status in the period is null.
+type HistoryUptimeResponse struct {
+	BaseDomain *float64 `json:"base_domain"`
+	WwwDomain  *float64 `json:"www_domain"`
+	Nameserver *float64 `json:"nameserver"`
+	MXRecord   *float64 `json:"mx_record"`
+}
+
+// HistoryIntervalResponse is a period in which a check had the same status.
+type HistoryIntervalResponse struct {
+	Check  string    `json:"check"`
+	Status string    `json:"status"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// HistoryBucketResponse is the uptime in a part of the history.
+type HistoryBucketResponse struct {
+	From    time.Time             `json:"from"`
+	To      time.Time             `json:"to"`
+	Entries int                   `json:"entries"`
+	Uptime  HistoryUptimeResponse `json:"uptime"`
+}
+
+// domainHistoryDescription documents the domain history endpoint.
+const domainHistoryDescription = "The IPv6 history of a domain, built from the crawler log: the intervals in which " +
+	"each check (base_domain, www_domain, nameserver and mx_record) had the same status, and the percent of the time " +
+	"each check was supported. from and to are a date or an RFC 3339 timestamp, the last 90 days by default. " +
+	"bucket is day, week or month, and adds the uptime per bucket for charting."
+
+// input validates the query parameters and returns the period and bucket of the history.
+func (in DomainHistoryInput) input() (time.Time, time.Time, string, error) {
+	from, err := core.ParseDate(in.From)
+	if err != nil {
+		return from, from, "", errors.New("from must be a date or an RFC 3339 timestamp")
+	}
+	to, err := core.ParseDate(in.To)
+	if err != nil {
+		return from, to, "", errors.New("to must be a date or an RFC 3339 timestamp")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultHistoryRange)
+	}
+	if !from.Before(to) {
+		return from, to, "", errors.New("from must be before to")
+	}
+	if to.Sub(from) > maxHistoryRange {
+		return from, to, "", errors.New("the period must be at most 5 years")
+	}
+	bucket := strings.ToLower(in.Bucket)
+	if bucket != "" && !slices.Contains(core.HistoryBuckets, bucket) {
+		return from, to, "", errors.New("bucket must be one of " + strings.Join(core.HistoryBuckets, ", "))
+	}
+	return from, to, bucket, nil
+}
+
+// GetDomainHistory returns the IPv6 history of a domain.
+func (rs DomainHandler) GetDomainHistory(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*DomainHistoryInput)
+	from, to, bucket, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	site := chi.URLParam(r, "domain")
+	history, err := rs.Repo.GetDomainHistory(r.Context(), site, from, to, bucket)
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "domain not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error building domain history:", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+	render.JSON(w, r, newDomainHistoryResponse(site, history))
+}
+
+// GetDomainHistoryV2 returns the IPv6 history of a domain.
+func (rs DomainHandler) GetDomainHistoryV2(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*DomainHistoryInput)
+	from, to, bucket, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	site := chi.URLParam(r, "domain")
+	history, err := rs.Repo.GetDomainHistory(r.Context(), site, from, to, bucket)
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "domain not found"))
+		return
+	}
+	renderData(w, r, newDomainHistoryResponse(site, history))
+}
+
+// newDomainHistoryResponse maps a domain history to its response structure.
+func newDomainHistoryResponse(site string, history core.DomainHistory) DomainHistoryResponse {
+	response := DomainHistoryResponse{
+		Domain:    site,
+		From:      history.From,
+		To:        history.To,
+		Entries:   history.Entries,
+		Uptime:    newHistoryUptimeResponse(history.Uptime),
+		Intervals: []HistoryIntervalResponse{},
+	}
+	for _, interval := range history.Intervals {
+		response.Intervals = append(response.Intervals, HistoryIntervalResponse{
+			Check:  interval.Check,
+			Status: interval.Status,
+			From:   interval.From,
+			To:     interval.To,
+		})
+	}
+	for _, bucket := range history.Buckets {
+		response.Buckets = append(response.Buckets, HistoryBucketResponse{
+			From:    bucket.From,
+			To:      bucket.To,
+			Entries: bucket.Entries,
+			Uptime:  newHistoryUptimeResponse(bucket.Uptime),
+		})
+	}
+	return response
+}
+
+// newHistoryUptimeResponse maps the uptime of each check to its response structure.
+func newHistoryUptimeResponse(uptime map[string]float64) HistoryUptimeResponse {
+	check := func(name string) *float64 {
+		if value, ok := uptime[name]; ok {
+			return &value
+		}
+		return nil
+	}
+	return HistoryUptimeResponse{
+		BaseDomain: check(core.CheckBaseDomain),
+		WwwDomain:  check(core.CheckWwwDomain),
+		Nameserver: check(core.CheckNameserver),
+		MXRecord:   check(core.CheckMXRecord),
+	}
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"whynoipv6/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute is the route label of the requests that did not match a
+// route, or were rejected before routing, e.g. by the rate limiter.
+const unmatchedRoute = "unmatched"
+
+// Instrument is a middleware that records the status and latency of every
+// request in the Prometheus metrics, by the route pattern it matched.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		metrics.HTTPStarted()
+		defer func() {
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			metrics.HTTPFinished(r.Method, route, status, time.Since(start))
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Timeouts of the API server, if the config does not set them.
+const (
+	defaultReadTimeout     = 10 * time.Second
+	defaultWriteTimeout    = 30 * time.Second
+	defaultIdleTimeout     = 2 * time.Minute
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// ServerConfig is where and how the API server listens.
+type ServerConfig struct {
+	Addrs           []string      // Addresses to listen on, e.g. [::1]:9001. [::]:9001 listens on IPv4 too
+	ReadTimeout     time.Duration // How long a client may take to send a request
+	WriteTimeout    time.Duration // How long a handler may take to write a response, see LongLived
+	IdleTimeout     time.Duration // How long a kept-alive connection waits for the next request
+	ShutdownTimeout time.Duration // How long the requests in flight may take to finish at shutdown
+	TLSCert         string        // Certificate file, TLS is served if it and the key are set
+	TLSKey          string        // Key file of the certificate
+}
+
+// Server is the HTTP server of the API.
+type Server struct {
+	http   *http.Server
+	cfg    ServerConfig
+	logger zerolog.Logger
+}
+
+// NewServer creates a new Server instance, with the default for every timeout
+// the config does not set.
+func NewServer(handler http.Handler, cfg ServerConfig, logger zerolog.Logger) *Server {
+	cfg.ReadTimeout = orDefault(cfg.ReadTimeout, defaultReadTimeout)
+	cfg.WriteTimeout = orDefault(cfg.WriteTimeout, defaultWriteTimeout)
+	cfg.IdleTimeout = orDefault(cfg.IdleTimeout, defaultIdleTimeout)
+	cfg.ShutdownTimeout = orDefault(cfg.ShutdownTimeout, defaultShutdownTimeout)
+
+	return &Server{
+		http: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// RegisterOnShutdown registers a function to call when the server starts to
+// shut down, e.g. to end the responses that never finish on their own.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.http.RegisterOnShutdown(f)
+}
+
+// Run listens on every address of the config and serves requests until the
+// context is cancelled. The server then stops accepting connections and waits
+// for the requests in flight, up to the shutdown timeout, before it closes the
+// remaining connections. A listener that fails stops the server.
+func (s *Server) Run(ctx context.Context) error {
+	if len(s.cfg.Addrs) == 0 {
+		return errors.New("no listen address")
+	}
+	useTLS := s.cfg.TLSCert != "" || s.cfg.TLSKey != ""
+	if useTLS {
+		cert, err := tls.LoadX509KeyPair(s.cfg.TLSCert, s.cfg.TLSKey)
+		if err != nil {
+			return fmt.Errorf("load TLS certificate: %w", err)
+		}
+		s.http.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	// Listen on every address before serving any, so a taken address fails fast.
+	var listeners []net.Listener
+	for _, addr := range s.cfg.Addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range listeners {
+				_ = ln.Close()
+			}
+			return fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		s.logger.Info().Str("addr", ln.Addr().String()).Bool("tls", useTLS).Msg("Listening")
+		go func() {
+			var err error
+			if useTLS {
+				err = s.http.ServeTLS(ln, "", "")
+			} else {
+				err = s.http.Serve(ln)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			errs <- err
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		s.logger.Info().Dur("timeout", s.cfg.ShutdownTimeout).Msg("Shutting down, waiting for requests in flight")
+	case err = <-errs:
+		s.logger.Error().Err(err).Msg("Listener failed, shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if shutdownErr := s.http.Shutdown(shutdownCtx); shutdownErr != nil {
+		s.logger.Warn().Err(shutdownErr).Msg("Requests still in flight, closing their connections")
+		_ = s.http.Close()
+	}
+	return err
+}
+
+// LongLived is a middleware that lifts the read and write timeouts of the
+// server for a route whose responses can take longer, e.g. a stream or a large
+// export.
+func LongLived(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Println("Error lifting the read timeout:", err)
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Println("Error lifting the write timeout:", err)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// orDefault returns d, or def if d is not set.
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// loaderWait is how long a loader collects keys before it fetches them. The
+// resolvers of the objects of a list run at the same time, so their loads end
+// up in one batch.
+const loaderWait = 2 * time.Millisecond
+
+// loader batches the loads of the resolvers of a request into one fetch, and
+// remembers the results for the rest of the request.
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *loaderBatch[K, V]       // Batch that collects keys
+	batches map[K]*loaderBatch[K, V] // Batch of every key loaded before
+}
+
+// loaderBatch is the keys fetched at once, and their results.
+type loaderBatch[K comparable, V any] struct {
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// newLoader creates a loader for the request of ctx, which fetches the values
+// of a batch of keys with fetch. A key that fetch leaves out has no value.
+func newLoader[K comparable, V any](
+	ctx context.Context,
+	fetch func(ctx context.Context, keys []K) (map[K]V, error),
+) *loader[K, V] {
+	return &loader[K, V]{
+		ctx:     ctx,
+		fetch:   fetch,
+		batches: make(map[K]*loaderBatch[K, V]),
+	}
+}
+
+// load returns the value of a key, and reports whether it has one.
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	batch, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &loaderBatch[K, V]{done: make(chan struct{})}
+			time.AfterFunc(loaderWait, l.run)
+		}
+		batch = l.pending
+		batch.keys = append(batch.keys, key)
+		l.batches[key] = batch
+	}
+	l.mu.Unlock()
+
+	var zero V
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return zero, false, ctx.Err()
+	}
+	if batch.err != nil {
+		return zero, false, batch.err
+	}
+	value, ok := batch.values[key]
+	return value, ok, nil
+}
+
+// run fetches the pending batch.
+func (l *loader[K, V]) run() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	batch.values, batch.err = l.fetch(l.ctx, batch.keys)
+	close(batch.done)
+}
@@ -6,6 +6,7 @@ import (
 
 	"whynoipv6/internal/core"
 
+	"github.com/ggicci/httpin"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/jackc/pgtype"
@@ -35,9 +36,35 @@ func (rs MetricHandler) Routes() chi.Router {
 	// GET /metrics/asn/search/{query}
 	r.Get("/asn/search/{query}", rs.SearchAsn)
 
+	// GET /metrics/asn/{asn}/history
+	r.With(httpin.NewInput(StatsHistoryInput{})).Get("/asn/{asn}/history", rs.AsnHistory)
+
 	return r
 }
 
+// Operations returns the documentation of all metric endpoints.
+func (rs MetricHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/overview", Summary: "Aggregated metrics for all crawled domains", Response: []MetricResponse{}},
+		{
+			Method:   "GET",
+			Path:     "/asn",
+			Summary:  "Aggregated metrics per ASN",
+			Query:    []QueryParam{{Name: "order", Description: "Sort by the number of IPv4 or IPv6 domains", Enum: []string{"ipv4", "ipv6"}}},
+			Response: []ASNResponse{},
+		},
+		{Method: "GET", Path: "/asn/search/{query}", Summary: "Search for an ASN by number or name", Response: []ASNResponse{}},
+		{
+			Method:      "GET",
+			Path:        "/asn/{asn}/history",
+			Summary:     "Retrieve the statistics of an ASN over time, e.g. /metric/asn/AS15169/history",
+			Description: statsHistoryDescription,
+			Input:       StatsHistoryInput{},
+			Response:    ASNHistoryResponse{},
+		},
+	}
+}
+
 // Totals returns the aggregated metrics for all crawled domains.
 func (rs MetricHandler) Totals(w http.ResponseWriter, r *http.Request) {
 	metrics, err := rs.Repo.GetMetrics(r.Context(), "domains")
@@ -155,3 +182,16 @@ func (rs MetricHandler) SearchAsn(w http.ResponseWriter, r *http.Request) {
 
 	render.JSON(w, r, asnList)
 }
+
+// newASNResponse maps an ASN to its response structure.
+func newASNResponse(asn core.ASNModel) ASNResponse {
+	return ASNResponse{
+		ID:        asn.ID,
+		Number:    asn.Number,
+		Name:      asn.Name,
+		CountV4:   asn.CountV4,
+		CountV6:   asn.CountV6,
+		PercentV4: asn.PercentV4,
+		PercentV6: asn.PercentV6,
+	}
+}
@@ -0,0 +1,474 @@
+package rest
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgtype"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Operation documents a single API endpoint in the OpenAPI document.
+// The schemas are generated from the Input and Response types, so they always
+// match what the handler sends.
+type Operation struct {
+	Method      string       // HTTP method, e.g. GET
+	Path        string       // Route pattern relative to the mount point, e.g. /{domain}
+	Summary     string       // Short description of the endpoint
+	Description string       // Longer description of the endpoint, optional
+	Input       any          // httpin input struct, used for the query parameters
+	Query       []QueryParam // Query parameters that are not part of an httpin input struct
+	Body        any          // Value of the JSON request body type, optional
+	Response    any          // Value of the response type
+	Error       any          // Value of the error response type, ErrorResponse if nil
+	Admin       bool         // Needs an admin API key
+}
+
+// QueryParam documents a query parameter that is read directly from the URL.
+type QueryParam struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+// ErrorResponse is the body of an error response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// OpenAPI collects the operations of all handlers and builds an OpenAPI 3 document from them.
+type OpenAPI struct {
+	title      string
+	version    string
+	operations map[string][]Operation // Operations by mount point
+}
+
+// NewOpenAPI creates a new OpenAPI document.
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		title:      title,
+		version:    version,
+		operations: map[string][]Operation{},
+	}
+}
+
+// Mount adds the operations of a handler mounted at the given prefix.
+func (o *OpenAPI) Mount(prefix string, operations []Operation) {
+	o.operations[prefix] = append(o.operations[prefix], operations...)
+}
+
+// ServeDocument serves the OpenAPI document.
+func (o *OpenAPI) ServeDocument(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, o.Document())
+}
+
+// ServeDocs serves the API documentation page, which renders the OpenAPI document.
+func (o *OpenAPI) ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(docsPage)
+}
+
+// Operations returns the documentation of the OpenAPI document and docs page.
+func (o *OpenAPI) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/openapi.json", Summary: "The OpenAPI document for this API"},
+		{Method: "GET", Path: "/docs", Summary: "The API documentation"},
+	}
+}
+
+// Check compares the documented operations with the routes registered on the router.
+// It returns a list of routes that are not documented, and operations that have no route.
+func (o *OpenAPI) Check(router chi.Routes) []string {
+	// Collect the methods registered for every route.
+	registered := map[string][]string{}
+	err := chi.Walk(
+		router,
+		func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+			route = normalizePath(route)
+			registered[route] = append(registered[route], method)
+			return nil
+		},
+	)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	documented := map[string][]string{}
+	for prefix, operations := range o.operations {
+		for _, op := range operations {
+			route := normalizePath(prefix + op.Path)
+			documented[route] = append(documented[route], op.Method)
+		}
+	}
+
+	var problems []string
+	for route, methods := range registered {
+		// Routes registered with HandleFunc answer every method, one documented method is enough.
+		if len(methods) >= len(anyMethods) && len(documented[route]) > 0 {
+			continue
+		}
+		for _, method := range methods {
+			if method == http.MethodHead || method == http.MethodOptions {
+				continue
+			}
+			if !slices.Contains(documented[route], method) {
+				problems = append(problems, "route is not documented: "+method+" "+route)
+			}
+		}
+	}
+	for route, methods := range documented {
+		for _, method := range methods {
+			if !slices.Contains(registered[route], method) && len(registered[route]) < len(anyMethods) {
+				problems = append(problems, "documented route does not exist: "+method+" "+route)
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// Document builds the OpenAPI document.
+func (o *OpenAPI) Document() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	// Sort the mount points, so the document is stable.
+	prefixes := make([]string, 0, len(o.operations))
+	for prefix := range o.operations {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		tag := strings.Trim(prefix, "/")
+		for _, op := range o.operations[prefix] {
+			path := normalizePath(prefix + op.Path)
+			if paths[path] == nil {
+				paths[path] = map[string]any{}
+			}
+			paths[path][strings.ToLower(op.Method)] = o.operation(tag, path, op, schemas)
+		}
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   o.title,
+			"version": o.version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        apiKeyHeader,
+					"description": "Optional, raises the rate limit. Also accepted as a bearer token.",
+				},
+			},
+		},
+		// Every endpoint can be used with or without an API key.
+		"security": []any{map[string]any{}, map[string]any{"apiKey": []string{}}},
+	}
+}
+
+// operation builds the OpenAPI operation object for a single endpoint.
+func (o *OpenAPI) operation(tag, path string, op Operation, schemas map[string]any) map[string]any {
+	parameters := []any{}
+
+	// Path parameters
+	for _, match := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+
+	// Query parameters from the httpin input struct
+	if op.Input != nil {
+		parameters = append(parameters, inputParameters(reflect.TypeOf(op.Input))...)
+	}
+	for _, q := range op.Query {
+		schema := map[string]any{"type": "string"}
+		if len(q.Enum) > 0 {
+			schema["enum"] = q.Enum
+		}
+		parameters = append(parameters, map[string]any{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      schema,
+		})
+	}
+
+	var errorResponse any = ErrorResponse{}
+	if op.Error != nil {
+		errorResponse = op.Error
+	}
+	responses := map[string]any{
+		"default": map[string]any{
+			"description": "Error",
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": schemaFor(reflect.TypeOf(errorResponse), schemas),
+				},
+			},
+		},
+	}
+	if op.Response != nil {
+		responses["200"] = map[string]any{
+			"description": "OK",
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": schemaFor(reflect.TypeOf(op.Response), schemas),
+				},
+			},
+		}
+	}
+
+	operation := map[string]any{
+		"tags":        []string{tag},
+		"summary":     op.Summary,
+		"operationId": operationID(op.Method, path),
+		"parameters":  parameters,
+		"responses":   responses,
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	if op.Body != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": schemaFor(reflect.TypeOf(op.Body), schemas),
+				},
+			},
+		}
+	}
+	if op.Admin {
+		operation["security"] = []any{map[string]any{"apiKey": []string{}}}
+	}
+	return operation
+}
+
+var (
+	// anyMethods are the methods chi registers for a route added with Handle or HandleFunc.
+	anyMethods = []string{
+		http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPatch, http.MethodPost, http.MethodPut, http.MethodTrace,
+	}
+	pathParamRegex = regexp.MustCompile(`\{([^}/]+)\}`)
+	timeType       = reflect.TypeOf(time.Time{})
+	jsonbType      = reflect.TypeOf(pgtype.JSONB{})
+)
+
+// normalizePath removes the trailing slash chi adds to mounted root routes.
+func normalizePath(path string) string {
+	path = strings.ReplaceAll(path, "/*/", "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// operationID builds a unique id for an operation from its method and path, e.g. getDomainByDomainLog.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		if part == "" {
+			continue
+		}
+		if match := pathParamRegex.FindStringSubmatch(part); match != nil {
+			b.WriteString("By")
+			part = match[1]
+		}
+		part = strings.NewReplacer(".", "", "-", "", "_", "").Replace(part)
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// inputParameters builds the query parameters from the `in` tags of an httpin
+// input struct, including the structs it embeds.
+func inputParameters(t reflect.Type) []any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var parameters []any
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			parameters = append(parameters, inputParameters(field.Type)...)
+			continue
+		}
+		tag := field.Tag.Get("in")
+		if tag == "" {
+			continue
+		}
+
+		var name, def string
+		for _, directive := range strings.Split(tag, ";") {
+			key, value, _ := strings.Cut(directive, "=")
+			switch key {
+			case "query":
+				name = value
+			case "default":
+				def = value
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		schema := schemaFor(field.Type, nil)
+		if def != "" {
+			schema["default"] = defaultValue(field.Type, def)
+		}
+		parameters = append(parameters, map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": schema,
+		})
+	}
+	return parameters
+}
+
+// defaultValue converts the default value of an httpin directive to the type of the field.
+func defaultValue(t reflect.Type, value string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// schemaFor builds the JSON schema for a Go type. Named structs are added to the
+// schemas map and referenced, if the map is not nil.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == jsonbType:
+		return map[string]any{"type": "object", "additionalProperties": true}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if name := schemaName(t); name != "" && schemas != nil {
+			if _, ok := schemas[name]; !ok {
+				schemas[name] = map[string]any{} // Placeholder for recursive types
+				schemas[name] = structSchema(t, schemas)
+			}
+			return map[string]any{"$ref": "#/components/schemas/" + name}
+		}
+		return structSchema(t, schemas)
+	}
+	panic(fmt.Sprintf("openapi: unsupported type %s", t))
+}
+
+// schemaName returns the component name of a named type. The type arguments of
+// generic types are added to the name, e.g. Envelope[[]rest.DomainResponse]
+// becomes EnvelopeDomainResponseList.
+func schemaName(t reflect.Type) string {
+	name, args, ok := strings.Cut(t.Name(), "[")
+	if !ok {
+		return name
+	}
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		list := strings.HasPrefix(arg, "[]")
+		arg = strings.TrimPrefix(arg, "[]")
+		if i := strings.LastIndex(arg, "."); i >= 0 {
+			arg = arg[i+1:]
+		}
+		name += strings.ToUpper(arg[:1]) + arg[1:]
+		if list {
+			name += "List"
+		}
+	}
+	return name
+}
+
+// structSchema builds the JSON schema for a struct from its json tags.
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// Fields of embedded structs are promoted to the parent object.
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := structSchema(field.Type, schemas)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			if r, ok := embedded["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,331 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// apiKeyHeader is the request header with the API key. The key is also
+	// accepted as a bearer token in the Authorization header.
+	apiKeyHeader = "X-API-Key"
+	// apiKeyCacheTTL is how long a looked up API key is cached, and how long it
+	// takes for a revoked key to stop working.
+	apiKeyCacheTTL = time.Minute
+	// rateLimitFlushInterval is how often the usage counters are written to the
+	// database, and idle rate limits are forgotten.
+	rateLimitFlushInterval = time.Minute
+)
+
+// unlimitedPaths are not rate limited, so load balancers and monitoring can
+// check and scrape the server as often as they need to.
+var unlimitedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// errRateLimited is returned by lookup if the client made too many requests.
+var errRateLimited = errors.New("rate limited")
+
+// RateLimits are the request rate limits of the API, in requests per minute.
+// A client can make a minute of requests at once, after which the limit
+// refills gradually.
+type RateLimits struct {
+	Anonymous int // Requests per minute of a client without an API key, by IP address
+	Key       int // Requests per minute of an API key without its own limit
+}
+
+// DefaultRateLimits are the limits used for the values that are not configured.
+var DefaultRateLimits = RateLimits{
+	Anonymous: 60,
+	Key:       600,
+}
+
+// withDefaults returns the limits, with the default for every value that is not set.
+func (l RateLimits) withDefaults() RateLimits {
+	if l.Anonymous <= 0 {
+		l.Anonymous = DefaultRateLimits.Anonymous
+	}
+	if l.Key <= 0 {
+		l.Key = DefaultRateLimits.Key
+	}
+	return l
+}
+
+// RateLimiter limits the requests of every API key, and of every client
+// without one, with a token bucket each. It counts the requests of every key.
+type RateLimiter struct {
+	keys   *core.APIKeyService
+	limits RateLimits
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // By API key id or client
+	cache   map[string]cachedAPIKey // By API key
+	usage   map[int64]*apiKeyUsage  // By API key id, since the last flush
+}
+
+// cachedAPIKey is a looked up API key. Unknown keys are not cached, so the
+// cache holds at most the keys that exist, however many keys clients make up.
+type cachedAPIKey struct {
+	key     core.APIKeyModel
+	expires time.Time
+}
+
+// apiKeyUsage is the requests made with an API key.
+type apiKeyUsage struct {
+	requests int64
+	lastUsed time.Time
+}
+
+// apiKeyContextKey is the context key of the API key of a request.
+type apiKeyContextKey struct{}
+
+// NewRateLimiter creates a rate limiter that looks up the API keys of the requests.
+func NewRateLimiter(keys *core.APIKeyService, limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		keys:    keys,
+		limits:  limits.withDefaults(),
+		buckets: map[string]*tokenBucket{},
+		cache:   map[string]cachedAPIKey{},
+		usage:   map[int64]*apiKeyUsage{},
+	}
+}
+
+// APIKeyFromContext returns the API key the request was made with, if any.
+func APIKeyFromContext(ctx context.Context) (core.APIKeyModel, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(core.APIKeyModel)
+	return key, ok
+}
+
+// Handler is a middleware that rate limits the requests, and sets the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers.
+// Requests with an invalid or revoked API key are rejected. The health and
+// readiness checks and the metrics are not limited.
+func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unlimitedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := "client:" + clientKey(r)
+		bucket, limit := client, rl.limits.Anonymous
+		var apiKey core.APIKeyModel
+		if key := requestAPIKey(r); key != "" {
+			var err error
+			apiKey, err = rl.lookup(r.Context(), key, client)
+			switch {
+			case errors.Is(err, errRateLimited):
+				// Looking up keys that are not cached takes from the limit of the
+				// client, so guessing keys does not flood the database. The
+				// request is rejected by the empty bucket of the client.
+			case errors.Is(err, pgx.ErrNoRows):
+				renderLimitError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid or revoked API key")
+				return
+			case err != nil:
+				log.Println("Error looking up API key:", err)
+				renderLimitError(w, r, http.StatusInternalServerError, ErrCodeInternal, "internal server error")
+				return
+			default:
+				bucket, limit = "key:"+strconv.FormatInt(apiKey.ID, 10), rl.limits.Key
+				if apiKey.RateLimit > 0 {
+					limit = int(apiKey.RateLimit)
+				}
+				r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey))
+			}
+		}
+
+		now := time.Now()
+		rl.mu.Lock()
+		allowed, remaining, refill, wait := rl.take(bucket, limit, now)
+		if allowed && apiKey.ID != 0 {
+			usage, ok := rl.usage[apiKey.ID]
+			if !ok {
+				usage = &apiKeyUsage{}
+				rl.usage[apiKey.ID] = usage
+			}
+			usage.requests++
+			usage.lastUsed = now
+		}
+		rl.mu.Unlock()
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(refill)))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds(wait), 1)))
+			renderLimitError(w, r, http.StatusTooManyRequests, ErrCodeRateLimited,
+				"at most "+strconv.Itoa(limit)+" requests per minute")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Run writes the usage counters to the database, and forgets the rate limits
+// of idle clients, until the context is cancelled.
+func (rl *RateLimiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			rl.flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			rl.flush(ctx)
+		}
+	}
+}
+
+// flush writes the usage counters to the database, and removes the full
+// buckets and expired keys, which are the same as new ones.
+func (rl *RateLimiter) flush(ctx context.Context) {
+	now := time.Now()
+	rl.mu.Lock()
+	usage := rl.usage
+	rl.usage = map[int64]*apiKeyUsage{}
+	for name, b := range rl.buckets {
+		if _, full, _ := b.state(b.limit, now); full <= 0 {
+			delete(rl.buckets, name)
+		}
+	}
+	for key, cached := range rl.cache {
+		if now.After(cached.expires) {
+			delete(rl.cache, key)
+		}
+	}
+	rl.mu.Unlock()
+
+	for id, u := range usage {
+		if err := rl.keys.AddAPIKeyUsage(ctx, id, u.requests, u.lastUsed); err != nil {
+			log.Println("Error storing API key usage:", err)
+		}
+	}
+}
+
+// lookup returns an API key, from the cache if it was looked up recently.
+// Looking up a key that is not cached takes a token from the bucket of the
+// client, and returns errRateLimited if it is empty, so unknown keys are
+// looked up at most at the rate limit of the client.
+// Returns pgx.ErrNoRows if the key is unknown or revoked.
+func (rl *RateLimiter) lookup(ctx context.Context, key, client string) (core.APIKeyModel, error) {
+	now := time.Now()
+	rl.mu.Lock()
+	if cached, ok := rl.cache[key]; ok && now.Before(cached.expires) {
+		rl.mu.Unlock()
+		return cached.key, nil
+	}
+	allowed, _, _, _ := rl.take(client, rl.limits.Anonymous, now)
+	rl.mu.Unlock()
+	if !allowed {
+		return core.APIKeyModel{}, errRateLimited
+	}
+
+	apiKey, err := rl.keys.GetAPIKey(ctx, key)
+	if err != nil {
+		return apiKey, err
+	}
+	rl.mu.Lock()
+	rl.cache[key] = cachedAPIKey{key: apiKey, expires: time.Now().Add(apiKeyCacheTTL)}
+	rl.mu.Unlock()
+	return apiKey, nil
+}
+
+// take takes a token from a bucket, creating it if needed. It returns whether
+// there was a token, the tokens left, the time until the bucket is full and
+// the time until the next token. The caller must hold rl.mu.
+func (rl *RateLimiter) take(name string, limit int, now time.Time) (bool, int, time.Duration, time.Duration) {
+	b, ok := rl.buckets[name]
+	if !ok {
+		b = newTokenBucket(limit, now)
+		rl.buckets[name] = b
+	}
+	allowed := b.take(limit, now)
+	remaining, refill, wait := b.state(limit, now)
+	return allowed, remaining, refill, wait
+}
+
+// requestAPIKey returns the API key of a request, from the X-API-Key header or
+// a bearer token.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// renderLimitError renders an error of the rate limiter, in the /v2 format
+// for /v2 requests.
+func renderLimitError(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string) {
+	if strings.HasPrefix(r.URL.Path, "/v2/") {
+		_ = render.Render(w, r, &ErrorEnvelope{HTTPStatusCode: status, Error: APIError{Code: code, Message: message}})
+		return
+	}
+	switch status {
+	case http.StatusUnauthorized:
+		_ = render.Render(w, r, ErrUnauthorized(errors.New(message)))
+	case http.StatusTooManyRequests:
+		_ = render.Render(w, r, ErrTooManyRequests(errors.New(message)))
+	default:
+		render.Status(r, status)
+		render.JSON(w, r, render.M{"error": message})
+	}
+}
+
+// tokenBucket holds up to limit tokens, and refills at limit tokens per minute.
+// Every request takes a token.
+type tokenBucket struct {
+	limit   int
+	tokens  float64
+	updated time.Time
+}
+
+// newTokenBucket returns a full bucket.
+func newTokenBucket(limit int, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit), updated: now}
+}
+
+// take refills the bucket and takes a token, if there is one. The limit of an
+// API key can change while its bucket exists.
+func (b *tokenBucket) take(limit int, now time.Time) bool {
+	b.limit = limit
+	b.tokens = min(float64(limit), b.tokens+now.Sub(b.updated).Minutes()*float64(limit))
+	b.updated = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// state returns the tokens left in the bucket, the time until it is full, and
+// the time until the next token.
+func (b *tokenBucket) state(limit int, now time.Time) (int, time.Duration, time.Duration) {
+	tokens := min(float64(limit), b.tokens+now.Sub(b.updated).Minutes()*float64(limit))
+	perToken := time.Minute / time.Duration(limit)
+	full := time.Duration((float64(limit) - tokens) * float64(perToken))
+	next := time.Duration(max(1-tokens, 0) * float64(perToken))
+	return int(tokens), full, next
+}
+
+// seconds rounds a duration up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	type take struct {
+		after time.Duration // Since start
+		limit int
+		want  bool
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		takes         []take
+		wantRemaining int
+		wantFull      time.Duration
+		wantNext      time.Duration
+	}{
+		{
+			name:  "a full bucket allows a minute of requests at once",
+			limit: 3,
+			takes: []take{{0, 3, true}, {0, 3, true}, {0, 3, true}, {0, 3, false}},
+			// Empty, a token every 20 seconds.
+			wantRemaining: 0, wantFull: time.Minute, wantNext: 20 * time.Second,
+		},
+		{
+			name:  "refills gradually",
+			limit: 3,
+			takes: []take{{0, 3, true}, {0, 3, true}, {0, 3, true}, {10 * time.Second, 3, false}, {20 * time.Second, 3, true}},
+			// Taken at 20s, empty again.
+			wantRemaining: 0, wantFull: time.Minute, wantNext: 20 * time.Second,
+		},
+		{
+			name:          "never more than full",
+			limit:         3,
+			takes:         []take{{time.Hour, 3, true}},
+			wantRemaining: 2, wantFull: 20 * time.Second, wantNext: 0,
+		},
+		{
+			name:          "a rejected request takes nothing",
+			limit:         1,
+			takes:         []take{{0, 1, true}, {0, 1, false}, {0, 1, false}, {time.Minute, 1, true}},
+			wantRemaining: 0, wantFull: time.Minute, wantNext: time.Minute,
+		},
+		{
+			name:          "the limit can change",
+			limit:         2,
+			takes:         []take{{0, 2, true}, {0, 2, true}, {0, 60, false}, {time.Second, 60, true}},
+			wantRemaining: 0, wantFull: time.Minute, wantNext: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.limit, start)
+			var now time.Time
+			for i, take := range tt.takes {
+				now = start.Add(take.after)
+				if got := b.take(take.limit, now); got != take.want {
+					t.Errorf("take %d = %v, want %v", i, got, take.want)
+				}
+			}
+			remaining, full, next := b.state(b.limit, now)
+			if remaining != tt.wantRemaining || full != tt.wantFull || next != tt.wantNext {
+				t.Errorf("state() = %d, %v, %v, want %d, %v, %v",
+					remaining, full, next, tt.wantRemaining, tt.wantFull, tt.wantNext)
+			}
+		})
+	}
+}
+
+// noKeys is a database without API keys.
+type noKeys struct {
+	queries int
+}
+
+func (d *noKeys) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return nil, nil
+}
+
+func (d *noKeys) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *noKeys) QueryRow(context.Context, string, ...any) pgx.Row {
+	d.queries++
+	return noRow{}
+}
+
+type noRow struct{}
+
+func (noRow) Scan(...any) error {
+	return pgx.ErrNoRows
+}
+
+func TestLookupUnknownKeys(t *testing.T) {
+	db := &noKeys{}
+	rl := NewRateLimiter(core.NewAPIKeyService(db), RateLimits{Anonymous: 3})
+
+	for i, want := range []error{pgx.ErrNoRows, pgx.ErrNoRows, pgx.ErrNoRows, errRateLimited} {
+		_, err := rl.lookup(context.Background(), "made-up-key-"+string(rune('a'+i)), "client:192.0.2.1")
+		if !errors.Is(err, want) {
+			t.Errorf("lookup %d error = %v, want %v", i, err, want)
+		}
+	}
+	if db.queries != 3 {
+		t.Errorf("%d queries, want 3, the rest are rate limited", db.queries)
+	}
+	if len(rl.cache) != 0 {
+		t.Errorf("%d keys cached, want none", len(rl.cache))
+	}
+
+	// Another client has its own limit, and an unknown key is looked up again.
+	if _, err := rl.lookup(context.Background(), "made-up-key-a", "client:192.0.2.2"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("lookup error = %v, want %v", err, pgx.ErrNoRows)
+	}
+	if db.queries != 4 {
+		t.Errorf("%d queries, want 4", db.queries)
+	}
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DefaultTrustedProxies are the proxies trusted if none are configured, a
+// proxy on the same host such as nginx.
+var DefaultTrustedProxies = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// ParseTrustedProxies parses a list of addresses and CIDR prefixes of trusted
+// proxies, e.g. 10.0.0.0/8 or 2001:db8::1. An empty list is DefaultTrustedProxies.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	if len(prefixes) == 0 {
+		return DefaultTrustedProxies, nil
+	}
+	return prefixes, nil
+}
+
+// RealIP returns a middleware that sets the RemoteAddr of a request to the
+// address of the client, from the X-Forwarded-For or X-Real-IP header, if the
+// request comes from a trusted proxy. The headers of other requests are
+// ignored, so a client can not pick the address it is logged and rate limited by.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if addr, ok := realIP(r, trusted); ok {
+				r.RemoteAddr = addr.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIP returns the address of the client of a request that comes from a
+// trusted proxy. Every proxy appends the address it got the request from to
+// X-Forwarded-For, so the client is the last address that is not a trusted
+// proxy, the ones before it may be made up by the client.
+func realIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseRemoteAddr(r.RemoteAddr)
+	if !ok || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap()
+			if !isTrusted(client, trusted) {
+				break
+			}
+		}
+		if client.IsValid() {
+			return client, true
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// parseRemoteAddr parses the RemoteAddr of a request, with or without a port.
+func parseRemoteAddr(remoteAddr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// isTrusted reports whether an address is a trusted proxy.
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    []string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{"empty is loopback", nil, DefaultTrustedProxies, false},
+		{"blank entries are skipped", []string{" ", ""}, DefaultTrustedProxies, false},
+		{
+			"addresses and prefixes",
+			[]string{"10.0.0.0/8", " 2001:db8::1 ", "192.0.2.7/24", "::ffff:192.0.2.1"},
+			[]netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("2001:db8::1/128"),
+				netip.MustParsePrefix("192.0.2.0/24"),
+				netip.MustParsePrefix("192.0.2.1/32"),
+			},
+			false,
+		},
+		{"invalid address", []string{"proxy.example"}, nil, true},
+		{"invalid prefix", []string{"10.0.0.0/33"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrustedProxies(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTrustedProxies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseTrustedProxies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"no proxy", "192.0.2.1:1234", nil, "", "192.0.2.1:1234"},
+		{"untrusted peer with made up headers", "192.0.2.1:1234", []string{"198.51.100.1"}, "198.51.100.2", "192.0.2.1:1234"},
+		{"trusted proxy", "127.0.0.1:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"trusted proxy over IPv6", "[::1]:1234", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"made up hops before the client", "127.0.0.1:1234", []string{"203.0.113.9, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chain of trusted proxies", "127.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"}, "", "198.51.100.1"},
+		{"only trusted proxies", "127.0.0.1:1234", []string{"10.0.0.2, 10.0.0.3"}, "", "10.0.0.2"},
+		{"invalid hop stops the walk", "127.0.0.1:1234", []string{"198.51.100.1, unknown, 10.0.0.3"}, "", "10.0.0.3"},
+		{"invalid last hop", "127.0.0.1:1234", []string{"198.51.100.1, unknown"}, "198.51.100.2", "198.51.100.2"},
+		{"X-Real-IP", "127.0.0.1:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"X-Forwarded-For before X-Real-IP", "127.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.2", "198.51.100.1"},
+		{"IPv4-mapped addresses", "[::ffff:127.0.0.1]:1234", []string{"::ffff:198.51.100.1"}, "", "198.51.100.1"},
+		{"invalid headers", "127.0.0.1:1234", []string{"unknown"}, "unknown", "127.0.0.1:1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			var got string
+			RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1"},
+		{"192.0.2.1", "192.0.2.1"},
+		{"[2001:db8:1:2:3:4:5:6]:1234", "2001:db8:1:2::/64"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64"},
+		{"[::ffff:192.0.2.1]:1234", "192.0.2.1"},
+		{"@", "@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if got := clientKey(r); got != tt.want {
+				t.Errorf("clientKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
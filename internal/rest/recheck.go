@@ -0,0 +1,241 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+	"whynoipv6/internal/postgres/db"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+// RecheckLimits are the rate limits of the recheck endpoint. Every recheck
+// resolves a domain from scratch, so they keep the endpoint from being used to
+// flood the resolvers.
+type RecheckLimits struct {
+	ClientLimit    int           // Rechecks a client can request per window
+	ClientWindow   time.Duration // Window of the client limit
+	DomainInterval time.Duration // Time between two rechecks of the same domain
+}
+
+// DefaultRecheckLimits are the limits used for the values that are not configured.
+var DefaultRecheckLimits = RecheckLimits{
+	ClientLimit:    10,
+	ClientWindow:   time.Hour,
+	DomainInterval: 15 * time.Minute,
+}
+
+// withDefaults returns the limits, with the default for every value that is not set.
+func (l RecheckLimits) withDefaults() RecheckLimits {
+	if l.ClientLimit <= 0 {
+		l.ClientLimit = DefaultRecheckLimits.ClientLimit
+	}
+	if l.ClientWindow <= 0 {
+		l.ClientWindow = DefaultRecheckLimits.ClientWindow
+	}
+	if l.DomainInterval <= 0 {
+		l.DomainInterval = DefaultRecheckLimits.DomainInterval
+	}
+	return l
+}
+
+// errClientLimit is returned if a client has requested all the rechecks of its window.
+var errClientLimit = errors.New("client limit reached")
+
+// JobHandler is a handler for the recheck jobs.
+type JobHandler struct {
+	Repo *core.RecheckJobService
+}
+
+// JobResponse is the response structure for a recheck job.
+type JobResponse struct {
+	ID         string             `json:"id"`
+	Domain     string             `json:"domain"`
+	Status     string             `json:"status"`
+	Result     *JobResultResponse `json:"result,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// JobResultResponse is the response structure for the result of a finished recheck.
+type JobResultResponse struct {
+	BaseDomain string `json:"base_domain"`
+	WwwDomain  string `json:"www_domain"`
+	Nameserver string `json:"nameserver"`
+	MXRecord   string `json:"mx_record"`
+}
+
+// Routes returns a router with all job endpoints mounted.
+func (rs JobHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /jobs/{id} - poll the status and result of a recheck job
+	r.Get("/{id}", rs.GetJob)
+
+	return r
+}
+
+// Operations returns the documentation of all job endpoints.
+func (rs JobHandler) Operations() []Operation {
+	return []Operation{
+		{Method: "GET", Path: "/{id}", Summary: "Retrieve the status and result of a recheck job", Response: JobResponse{}},
+	}
+}
+
+// GetJob returns a recheck job by its ID.
+func (rs JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := decodeUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = render.Render(w, r, ErrNotFound())
+		return
+	}
+
+	job, err := rs.Repo.GetJob(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = render.Render(w, r, ErrNotFound())
+		return
+	}
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+	render.JSON(w, r, newJobResponse(job))
+}
+
+// RecheckDomain queues a recheck of a domain and returns the job. If a recheck
+// of the domain is already queued or running, that job is returned instead.
+func (rs DomainHandler) RecheckDomain(w http.ResponseWriter, r *http.Request) {
+	limits := rs.RecheckLimits.withDefaults()
+
+	domain, err := rs.Repo.GetDomain(r.Context(), strings.ToLower(chi.URLParam(r, "domain")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "domain not found"})
+		return
+	}
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+
+	// Per-domain limit: one pending recheck, and a pause after each recheck.
+	latest, err := rs.Jobs.GetLatestJob(r.Context(), domain.ID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Never rechecked
+	case err != nil:
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	case latest.Status == core.JobQueued || latest.Status == core.JobRunning:
+		renderJobAccepted(w, r, latest)
+		return
+	case time.Since(latest.CreatedAt) < limits.DomainInterval:
+		retryAfter(w, latest.CreatedAt.Add(limits.DomainInterval))
+		_ = render.Render(w, r, ErrTooManyRequests(fmt.Errorf(
+			"%s was rechecked less than %s ago, see /jobs/%s", domain.Site, limits.DomainInterval, encodeUUID(latest.ID),
+		)))
+		return
+	}
+
+	// Per-client limit: a number of rechecks per window. The requests of a
+	// client are serialized, so parallel requests can not all pass the count.
+	client := clientKey(r)
+	var job core.RecheckJobModel
+	var oldest time.Time
+	err = inTx(r.Context(), rs.DB, func(tx db.DBTX) error {
+		jobs := core.NewRecheckJobService(tx)
+		if err := jobs.LockClient(r.Context(), client); err != nil {
+			return err
+		}
+		var count int64
+		count, oldest, err = jobs.ClientJobs(r.Context(), client, time.Now().Add(-limits.ClientWindow))
+		if err != nil {
+			return err
+		}
+		if count >= int64(limits.ClientLimit) {
+			return errClientLimit
+		}
+		job, _, err = jobs.CreateJob(r.Context(), domain, client, core.JobPriorityHigh)
+		return err
+	})
+	if errors.Is(err, errClientLimit) {
+		retryAfter(w, oldest.Add(limits.ClientWindow))
+		_ = render.Render(w, r, ErrTooManyRequests(fmt.Errorf(
+			"at most %d rechecks per %s", limits.ClientLimit, limits.ClientWindow,
+		)))
+		return
+	}
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+	renderJobAccepted(w, r, job)
+}
+
+// renderJobAccepted responds with a pending job and where to poll it.
+func renderJobAccepted(w http.ResponseWriter, r *http.Request, job core.RecheckJobModel) {
+	w.Header().Set("Location", "/jobs/"+encodeUUID(job.ID))
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, newJobResponse(job))
+}
+
+// retryAfter sets the Retry-After header to the number of seconds until the given time.
+func retryAfter(w http.ResponseWriter, at time.Time) {
+	seconds := int(time.Until(at).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
+
+// clientKey identifies the client of a request for rate limiting. IPv6 clients
+// are identified by their /64, as every host usually has a whole /64 to pick
+// addresses from. The address is the one RealIP has taken from a trusted proxy,
+// or the address the request came from.
+func clientKey(r *http.Request) string {
+	addr, ok := parseRemoteAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if addr.Is6() {
+		prefix, _ := addr.Prefix(64)
+		return prefix.String()
+	}
+	return addr.String()
+}
+
+// newJobResponse maps a recheck job to its response structure.
+func newJobResponse(job core.RecheckJobModel) JobResponse {
+	response := JobResponse{
+		ID:        encodeUUID(job.ID),
+		Domain:    job.Site,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+	}
+	if !job.StartedAt.IsZero() {
+		response.StartedAt = &job.StartedAt
+	}
+	if !job.FinishedAt.IsZero() {
+		response.FinishedAt = &job.FinishedAt
+	}
+	if job.Status == core.JobDone {
+		response.Result = &JobResultResponse{
+			BaseDomain: job.BaseDomain,
+			WwwDomain:  job.WwwDomain,
+			Nameserver: job.Nameserver,
+			MXRecord:   job.MXRecord,
+		}
+	}
+	return response
+}
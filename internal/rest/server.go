@@ -1,8 +1,9 @@
 package rest
 
 import (
-	"log"
 	"net/http"
+	"net/netip"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -10,18 +11,21 @@ import (
 	"github.com/google/uuid"
 	"github.com/lithammer/shortuuid/v4"
 	"github.com/rs/cors"
+	"github.com/rs/zerolog"
 )
 
-// NewRouter instantiates a new router.
-func NewRouter() (*chi.Mux, error) {
+// NewRouter instantiates a new router, which logs the requests to logger. The
+// client address is taken from the proxy headers of the requests from the
+// trusted proxies.
+func NewRouter(logger zerolog.Logger, trustedProxies []netip.Prefix) (*chi.Mux, error) {
 	r := chi.NewRouter()
 
 	corsMiddleware := cors.New(cors.Options{
 		// AllowedOrigins: []string{"https://whynoipv6.com","https://ipv6.fail"}, // Use this to allow specific origin hosts
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "HEAD", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "HEAD", "OPTIONS", "POST", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link", "Location", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	})
@@ -30,12 +34,14 @@ func NewRouter() (*chi.Mux, error) {
 		render.SetContentType(
 			render.ContentTypeJSON,
 		), // Set content-Type headers as application/json
-		middleware.RealIP,    // Logs the real ip from nginx
-		middleware.Logger,    // Log API request calls
-		middleware.Recoverer, // Recover from panics without crashing server
-		middleware.RequestID, // Injects a request ID into the context of each request
+		RealIP(trustedProxies), // Logs the real ip from nginx
+		middleware.RequestID,   // Injects a request ID into the context of each request
+		Trace,                  // Record a span of each request, with its request ID
+		AccessLog(logger),      // Log API request calls
+		Instrument,             // Record the status and latency in the metrics
+		middleware.Recoverer,   // Recover from panics without crashing server
 		// middleware.RedirectSlashes, // Redirect slashes to no slash URL versions
-		middleware.NoCache, // We dont like cache!
+		NoStore.Handler, // Routes that can be cached have their own policy
 		middleware.SetHeader("X-Content-Type-Options", "nosniff"),
 		middleware.SetHeader("X-Frame-Options", "deny"),
 		corsMiddleware.Handler,
@@ -44,20 +50,24 @@ func NewRouter() (*chi.Mux, error) {
 	return r, nil
 }
 
-// PrintRoutes prints the routes of the application.
-func PrintRoutes(r *chi.Mux) {
-	log.Println("Routes:")
+// PrintRoutes logs the routes of the application at the debug level.
+func PrintRoutes(r *chi.Mux, logger zerolog.Logger) {
 	err := chi.Walk(
 		r,
 		func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-			log.Printf("\t[%s]: '%s'\n", method, route)
+			logger.Debug().Str("method", method).Str("route", route).Msg("Route")
 			return nil
 		},
 	)
 	if err != nil {
-		log.Println("Error printing routes:", err)
+		logger.Error().Err(err).Msg("Error printing routes")
 	}
-	log.Println("")
+}
+
+// setMaxAge replaces the Cache-Control header of the cache policy of the route,
+// and lets clients and proxies cache the response for maxAge.
+func setMaxAge(w http.ResponseWriter, maxAge time.Duration) {
+	w.Header().Set("Cache-Control", CachePolicy{MaxAge: maxAge}.cacheControl())
 }
 
 // PaginationInput is the path variables from the request.
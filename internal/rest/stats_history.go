@@ -0,0 +1,267 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+// defaultStatsHistoryRange is the period of a history without a start date.
+const defaultStatsHistoryRange = 365 * 24 * time.Hour
+
+// StatsHistoryInput is the query parameters of a country or ASN history.
+type StatsHistoryInput struct {
+	Start    string `in:"query=start"`
+	End      string `in:"query=end"`
+	Interval string `in:"query=interval"`
+}
+
+// statsHistoryDescription documents the country and ASN history endpoints.
+const statsHistoryDescription = "The statistics are stored once a day, after the crawler has run. " +
+	"start and end are a date or an RFC 3339 timestamp, the last year by default. interval is day (default), " +
+	"week, month, quarter or year, and each period holds the last snapshot in it. " +
+	"change is the difference in percentage points from the previous period."
+
+// CountryHistoryResponse is the response structure for the history of a country.
+type CountryHistoryResponse struct {
+	Country     string                `json:"country"`
+	CountryCode string                `json:"country_code"`
+	Interval    string                `json:"interval"`
+	Points      []CountryHistoryPoint `json:"points"`
+}
+
+// CountryHistoryPoint is the statistics of a country in a period.
+type CountryHistoryPoint struct {
+	Period  time.Time `json:"period"`
+	Day     time.Time `json:"day"`
+	Sites   int32     `json:"sites"`
+	V6sites int32     `json:"v6sites"`
+	Percent float64   `json:"percent"`
+	Change  *float64  `json:"change"`
+}
+
+// ASNHistoryResponse is the response structure for the history of an ASN.
+type ASNHistoryResponse struct {
+	Number   int32             `json:"number"`
+	Name     string            `json:"name"`
+	Interval string            `json:"interval"`
+	Points   []ASNHistoryPoint `json:"points"`
+}
+
+// ASNHistoryPoint is the statistics of an ASN in a period.
+type ASNHistoryPoint struct {
+	Period    time.Time `json:"period"`
+	Day       time.Time `json:"day"`
+	CountV4   int32     `json:"count_v4"`
+	CountV6   int32     `json:"count_v6"`
+	PercentV4 float64   `json:"percent_v4"`
+	PercentV6 float64   `json:"percent_v6"`
+	Change    *float64  `json:"change"` // Change of percent_v6
+}
+
+// input validates the query parameters and returns the days and interval of the history.
+func (in StatsHistoryInput) input() (time.Time, time.Time, string, error) {
+	start, err := core.ParseDate(in.Start)
+	if err != nil {
+		return start, start, "", errors.New("start must be a date or an RFC 3339 timestamp")
+	}
+	end, err := core.ParseDate(in.End)
+	if err != nil {
+		return start, end, "", errors.New("end must be a date or an RFC 3339 timestamp")
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-defaultStatsHistoryRange)
+	}
+	if start.After(end) {
+		return start, end, "", errors.New("start must not be after end")
+	}
+	interval := strings.ToLower(in.Interval)
+	if interval == "" {
+		interval = core.IntervalDay
+	}
+	if !slices.Contains(core.StatsIntervals, interval) {
+		return start, end, "", errors.New("interval must be one of " + strings.Join(core.StatsIntervals, ", "))
+	}
+	return start, end, interval, nil
+}
+
+// CountryHistory returns the daily statistics of a country over time.
+func (rs CountryHandler) CountryHistory(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*StatsHistoryInput)
+	start, end, interval, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	country, err := rs.Repo.GetCountryCode(r.Context(), strings.ToUpper(chi.URLParam(r, "code")))
+	if err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "Country not found"})
+		return
+	}
+	history, err := rs.Repo.CountryHistory(r.Context(), country.ID, start, end, interval)
+	if err != nil {
+		log.Println("Error retrieving country history:", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+	render.JSON(w, r, newCountryHistoryResponse(country, interval, history))
+}
+
+// CountryHistoryV2 returns the daily statistics of a country over time.
+func (rs CountryHandler) CountryHistoryV2(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*StatsHistoryInput)
+	start, end, interval, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	country, err := rs.Repo.GetCountryCode(r.Context(), strings.ToUpper(chi.URLParam(r, "code")))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "country not found"))
+		return
+	}
+	history, err := rs.Repo.CountryHistory(r.Context(), country.ID, start, end, interval)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	renderData(w, r, newCountryHistoryResponse(country, interval, history))
+}
+
+// AsnHistory returns the daily statistics of an ASN over time.
+func (rs MetricHandler) AsnHistory(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*StatsHistoryInput)
+	start, end, interval, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	number, err := asNumber(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	asn, history, err := rs.Repo.ASNHistory(r.Context(), number, start, end, interval)
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "asn not found"})
+		return
+	}
+	if err != nil {
+		log.Println("Error retrieving ASN history:", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal server error"})
+		return
+	}
+	render.JSON(w, r, newASNHistoryResponse(asn, interval, history))
+}
+
+// AsnHistoryV2 returns the daily statistics of an ASN over time.
+func (rs MetricHandler) AsnHistoryV2(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*StatsHistoryInput)
+	start, end, interval, err := input.input()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	number, err := asNumber(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	asn, history, err := rs.Repo.ASNHistory(r.Context(), number, start, end, interval)
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "asn not found"))
+		return
+	}
+	renderData(w, r, newASNHistoryResponse(asn, interval, history))
+}
+
+// asNumber parses the AS number in the URL, with or without the AS prefix.
+func asNumber(r *http.Request) (int32, error) {
+	value := strings.TrimPrefix(strings.ToUpper(chi.URLParam(r, "asn")), "AS")
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || number < 0 {
+		return 0, errors.New("invalid AS number")
+	}
+	return int32(number), nil
+}
+
+// newCountryHistoryResponse maps the history of a country to its response structure.
+func newCountryHistoryResponse(
+	country core.CountryModel,
+	interval string,
+	history []core.CountryHistoryModel,
+) CountryHistoryResponse {
+	response := CountryHistoryResponse{
+		Country:     country.Country,
+		CountryCode: country.CountryCode,
+		Interval:    interval,
+		Points:      []CountryHistoryPoint{},
+	}
+	for i, point := range history {
+		var change *float64
+		if i > 0 {
+			change = percentChange(history[i-1].Percent, point.Percent)
+		}
+		response.Points = append(response.Points, CountryHistoryPoint{
+			Period:  point.Period,
+			Day:     point.Day,
+			Sites:   point.Sites,
+			V6sites: point.V6sites,
+			Percent: point.Percent,
+			Change:  change,
+		})
+	}
+	return response
+}
+
+// newASNHistoryResponse maps the history of an ASN to its response structure.
+func newASNHistoryResponse(asn core.ASNModel, interval string, history []core.ASNHistoryModel) ASNHistoryResponse {
+	response := ASNHistoryResponse{
+		Number:   asn.Number,
+		Name:     asn.Name,
+		Interval: interval,
+		Points:   []ASNHistoryPoint{},
+	}
+	for i, point := range history {
+		var change *float64
+		if i > 0 {
+			change = percentChange(history[i-1].PercentV6, point.PercentV6)
+		}
+		response.Points = append(response.Points, ASNHistoryPoint{
+			Period:    point.Period,
+			Day:       point.Day,
+			CountV4:   point.CountV4,
+			CountV6:   point.CountV6,
+			PercentV4: point.PercentV4,
+			PercentV6: point.PercentV6,
+			Change:    change,
+		})
+	}
+	return response
+}
+
+// percentChange returns the difference between two percentages in percentage
+// points, rounded to one decimal like the percentages.
+func percentChange(previous, current float64) *float64 {
+	change := math.Round((current-previous)*10) / 10
+	return &change
+}
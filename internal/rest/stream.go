@@ -0,0 +1,525 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamBatchSize is the number of changelog entries read per query when
+	// broadcasting or replaying entries.
+	streamBatchSize = 500
+	// streamReplayLimit is the maximum number of missed entries of each
+	// changelog replayed to a resuming client, older entries are skipped.
+	streamReplayLimit = 1000
+	// streamBuffer is the number of events a client can fall behind before it is
+	// disconnected. It resumes from where it was when it reconnects.
+	streamBuffer = 256
+	// streamHeartbeat is how often an idle stream sends a comment, so proxies
+	// keep the connection open.
+	streamHeartbeat = 30 * time.Second
+	// streamRetry is how long browsers wait before reconnecting.
+	streamRetry = 5 * time.Second
+	// streamGrace is how long entries are read again after they were first
+	// read. Ids are taken when the entries are written, but the entries show up
+	// when their transaction commits, so an entry can show up after entries
+	// with a higher id.
+	streamGrace = 30 * time.Second
+)
+
+// Event types of the changelog stream.
+const (
+	streamEventDomain   = "domain"
+	streamEventCampaign = "campaign"
+)
+
+// streamPosition is the id of the domain and campaign changelog entry up to
+// which a client has been sent every entry. It is the event id of the stream,
+// "<domain id>.<campaign id>", so a client resumes both changelogs with
+// Last-Event-ID.
+type streamPosition struct {
+	Domain, Campaign int64
+}
+
+// String encodes the position as an event id.
+func (p streamPosition) String() string {
+	return strconv.FormatInt(p.Domain, 10) + "." + strconv.FormatInt(p.Campaign, 10)
+}
+
+// parseStreamPosition decodes an event id.
+func parseStreamPosition(id string) (streamPosition, error) {
+	domain, campaign, ok := strings.Cut(id, ".")
+	if !ok {
+		return streamPosition{}, errors.New("invalid event id")
+	}
+	var p streamPosition
+	var err1, err2 error
+	p.Domain, err1 = strconv.ParseInt(domain, 10, 64)
+	p.Campaign, err2 = strconv.ParseInt(campaign, 10, 64)
+	if err1 != nil || err2 != nil || p.Domain < 0 || p.Campaign < 0 {
+		return streamPosition{}, errors.New("invalid event id")
+	}
+	return p, nil
+}
+
+// id returns the position in the domain or the campaign changelog.
+func (p streamPosition) id(campaign bool) int64 {
+	if campaign {
+		return p.Campaign
+	}
+	return p.Domain
+}
+
+// advance moves the position in the domain or the campaign changelog forward
+// to id. It never moves back.
+func (p *streamPosition) advance(campaign bool, id int64) {
+	if campaign {
+		p.Campaign = max(p.Campaign, id)
+	} else {
+		p.Domain = max(p.Domain, id)
+	}
+}
+
+// streamEvent is a changelog entry, of a domain or of a campaign domain.
+// Position is the position of the stream when the entry was broadcast.
+type streamEvent struct {
+	Campaign bool
+	Entry    core.ChangelogModel
+	Position streamPosition
+}
+
+// streamKey identifies a changelog entry.
+type streamKey struct {
+	Campaign bool
+	ID       int64
+}
+
+// streamWindow is the entries the stream has read. Every entry up to the
+// position has been read, or was never written. The entries after it are read
+// again until the grace period has passed, seen keeps them from being sent
+// twice.
+type streamWindow struct {
+	position streamPosition
+	seen     map[streamKey]time.Time // When the entries after the position were first read
+}
+
+// newStreamWindow creates a window that starts after the position.
+func newStreamWindow(position streamPosition) streamWindow {
+	return streamWindow{position: position, seen: make(map[streamKey]time.Time)}
+}
+
+// add records that the event has been read, and reports whether it is new.
+func (w *streamWindow) add(event streamEvent, now time.Time) bool {
+	key := streamKey{Campaign: event.Campaign, ID: event.Entry.ID}
+	if key.ID <= w.position.id(key.Campaign) {
+		return false
+	}
+	if _, ok := w.seen[key]; ok {
+		return false
+	}
+	w.seen[key] = now
+	return true
+}
+
+// has reports whether the event has been read.
+func (w streamWindow) has(event streamEvent) bool {
+	key := streamKey{Campaign: event.Campaign, ID: event.Entry.ID}
+	_, ok := w.seen[key]
+	return ok || key.ID <= w.position.id(key.Campaign)
+}
+
+// settle moves the position past the entries read before the grace period.
+// The entries before them that have not shown up by now are not expected to.
+func (w *streamWindow) settle(now time.Time) {
+	for key, read := range w.seen {
+		if now.Sub(read) >= streamGrace {
+			w.position.advance(key.Campaign, key.ID)
+		}
+	}
+	for key := range w.seen {
+		if key.ID <= w.position.id(key.Campaign) {
+			delete(w.seen, key)
+		}
+	}
+}
+
+// ids returns the ids of the entries read after the position in the domain or
+// the campaign changelog.
+func (w streamWindow) ids(campaign bool) []int64 {
+	var ids []int64
+	for key := range w.seen {
+		if key.Campaign == campaign {
+			ids = append(ids, key.ID)
+		}
+	}
+	return ids
+}
+
+// last returns the position of the newest entries read.
+func (w streamWindow) last() streamPosition {
+	last := w.position
+	for key := range w.seen {
+		last.advance(key.Campaign, key.ID)
+	}
+	return last
+}
+
+// clone returns a copy of the window.
+func (w streamWindow) clone() streamWindow {
+	w.seen = maps.Clone(w.seen)
+	return w
+}
+
+// ChangelogStream fans out new changelog entries to the clients of the
+// changelog stream. The database notifies it about new entries, see Notify.
+type ChangelogStream struct {
+	repo *core.ChangelogService
+
+	mu      sync.Mutex
+	ready   bool                          // The window has been loaded
+	closed  bool                          // The server is shutting down
+	window  streamWindow                  // Entries broadcast
+	clients map[chan streamEvent]struct{} // Subscribed clients
+}
+
+// NewChangelogStream creates a new ChangelogStream instance.
+func NewChangelogStream(repo *core.ChangelogService) *ChangelogStream {
+	return &ChangelogStream{
+		repo:    repo,
+		clients: make(map[chan streamEvent]struct{}),
+	}
+}
+
+// Notify broadcasts the changelog entries written since the last call, and the
+// entries of the grace period that had not shown up yet. The first call only
+// loads the newest entries, so it starts from there. Notify is the callback of
+// postgres.Listen on core.ChangelogChannel, it must not be called concurrently.
+func (s *ChangelogStream) Notify(ctx context.Context) {
+	s.mu.Lock()
+	ready, window := s.ready, s.window.clone()
+	s.mu.Unlock()
+
+	if !ready {
+		domainID, campaignID, err := s.repo.LatestChangelogIDs(ctx)
+		if err != nil {
+			log.Println("Error loading the changelog stream position:", err)
+			return
+		}
+		s.mu.Lock()
+		s.window = newStreamWindow(streamPosition{Domain: domainID, Campaign: campaignID})
+		s.ready = true
+		s.mu.Unlock()
+		return
+	}
+
+	if err := readChangelogSince(ctx, s.repo, window, nil, s.broadcast); err != nil {
+		log.Println("Error reading new changelog entries:", err)
+	}
+	s.mu.Lock()
+	s.window.settle(time.Now())
+	s.mu.Unlock()
+}
+
+// broadcast sends an event to every client, unless it has been sent before. A
+// client that has fallen too far behind is disconnected, rather than holding
+// up the others.
+func (s *ChangelogStream) broadcast(event streamEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.window.add(event, time.Now()) {
+		return nil
+	}
+	event.Position = s.window.position
+	for client := range s.clients {
+		select {
+		case client <- event:
+		default:
+			delete(s.clients, client)
+			close(client)
+		}
+	}
+	return nil
+}
+
+// subscribe registers a client, and returns its events and the entries
+// broadcast before it subscribed. It reports false until the stream has loaded
+// its window, and once it is closed.
+func (s *ChangelogStream) subscribe() (chan streamEvent, streamWindow, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ready || s.closed {
+		return nil, streamWindow{}, false
+	}
+	client := make(chan streamEvent, streamBuffer)
+	s.clients[client] = struct{}{}
+	return client, s.window.clone(), true
+}
+
+// Close disconnects every client and refuses new ones, so the server can shut
+// down. The clients resume from where they were when they reconnect.
+func (s *ChangelogStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for client := range s.clients {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+// unsubscribe removes a client, unless it was already disconnected.
+func (s *ChangelogStream) unsubscribe(client chan streamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[client]; ok {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+// readChangelogSince calls fn for the domain and campaign changelog entries
+// after the position of the window that the window has not read, oldest first.
+// If until is not nil, each changelog stops at its entry in until.
+func readChangelogSince(
+	ctx context.Context,
+	repo *core.ChangelogService,
+	window streamWindow,
+	until *streamPosition,
+	fn func(streamEvent) error,
+) error {
+	for _, changelog := range []struct {
+		campaign bool
+		list     func(ctx context.Context, afterID int64, skip []int64, limit int64) ([]core.ChangelogModel, error)
+	}{
+		{campaign: false, list: repo.ListChangelogSince},
+		{campaign: true, list: repo.ListCampaignChangelogSince},
+	} {
+		skip := window.ids(changelog.campaign)
+		for after, done := window.position.id(changelog.campaign), false; !done; {
+			entries, err := changelog.list(ctx, after, skip, streamBatchSize)
+			if err != nil {
+				return err
+			}
+			done = len(entries) < streamBatchSize
+			for _, entry := range entries {
+				if until != nil && entry.ID > until.id(changelog.campaign) {
+					done = true
+					break
+				}
+				if err := fn(streamEvent{Campaign: changelog.campaign, Entry: entry}); err != nil {
+					return err
+				}
+				after = entry.ID
+			}
+		}
+	}
+	return nil
+}
+
+// ChangelogStreamInput is the query parameters of the changelog stream.
+type ChangelogStreamInput struct {
+	Domain      string `in:"query=domain"`
+	Campaign    string `in:"query=campaign"`
+	Country     string `in:"query=country"`
+	Status      string `in:"query=status"`
+	LastEventID string `in:"query=last_event_id"`
+}
+
+// changelogStreamDescription documents the changelog stream endpoint.
+const changelogStreamDescription = "A Server-Sent Events stream (text/event-stream) of the domain and campaign " +
+	"changelog entries as they are written. Each event has the type domain or campaign, and a changelog entry as data. " +
+	"The optional filters are domain, campaign (a campaign UUID, only campaign entries), country (a two-letter " +
+	"country code) and status (supported, unsupported or no_record). A client resumes after the event in the " +
+	"Last-Event-ID header, or the last_event_id query parameter, and receives the entries it missed, " +
+	"up to the last 1000 entries of each changelog. The entries of the last 30 seconds before it reconnected " +
+	"can be sent again, their id tells them apart."
+
+// streamFilter selects the events a client receives.
+type streamFilter struct {
+	site        string
+	campaignID  uuid.UUID
+	countryCode string
+	status      string
+}
+
+// filter validates the input and converts it to a stream filter.
+func (in ChangelogStreamInput) filter() (streamFilter, error) {
+	filter := streamFilter{
+		site:        strings.ToLower(in.Domain),
+		countryCode: strings.ToUpper(in.Country),
+		status:      in.Status,
+	}
+	if filter.site != "" && !domainRegex.MatchString(filter.site) {
+		return filter, errors.New("invalid domain")
+	}
+	if in.Campaign != "" {
+		var err error
+		if filter.campaignID, err = decodeUUID(in.Campaign); err != nil {
+			return filter, errors.New("invalid campaign uuid")
+		}
+	}
+	if filter.countryCode != "" && len(filter.countryCode) != 2 {
+		return filter, errors.New("country must be a two-letter country code")
+	}
+	if filter.status != "" && !slices.Contains(core.CheckStatuses, filter.status) {
+		return filter, errors.New("status must be one of " + strings.Join(core.CheckStatuses, ", "))
+	}
+	return filter, nil
+}
+
+// match reports whether the event passes the filter.
+func (f streamFilter) match(event streamEvent) bool {
+	entry := event.Entry
+	switch {
+	case f.site != "" && entry.Site != f.site:
+		return false
+	case f.campaignID != uuid.Nil && (!event.Campaign || entry.CampaignID != f.campaignID):
+		return false
+	case f.countryCode != "" && entry.CountryCode != f.countryCode:
+		return false
+	case f.status != "" && entry.IPv6Status != f.status:
+		return false
+	}
+	return true
+}
+
+// ChangelogStream streams new changelog entries as Server-Sent Events.
+func (rs ChangelogHandler) ChangelogStream(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*ChangelogStreamInput)
+	filter, err := input.filter()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = input.LastEventID
+	}
+	var resume *streamPosition
+	if lastEventID != "" {
+		position, err := parseStreamPosition(lastEventID)
+		if err != nil {
+			_ = render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+		resume = &position
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "streaming is not supported"})
+		return
+	}
+	events, window, ok := rs.Stream.subscribe()
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(streamRetry.Seconds())))
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, render.M{"error": "the changelog stream is not available, try again shortly"})
+		return
+	}
+	defer rs.Stream.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Ask nginx not to buffer the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	// Replay the entries the client missed that were broadcast before it
+	// subscribed, the others it receives as they are broadcast. The position
+	// only moves forward, so the event ids keep growing.
+	current := window.position
+	if resume != nil {
+		current = *resume
+		from := current
+		from.Domain = max(from.Domain, window.position.Domain-streamReplayLimit)
+		from.Campaign = max(from.Campaign, window.position.Campaign-streamReplayLimit)
+		last := window.last()
+		err := readChangelogSince(r.Context(), rs.Repo, newStreamWindow(from), &last, func(event streamEvent) error {
+			if !window.has(event) {
+				return nil
+			}
+			// Entries after the position of the window can still be
+			// followed by entries with a lower id.
+			event.Position = current
+			event.Position.advance(event.Campaign, min(event.Entry.ID, window.position.id(event.Campaign)))
+			return writeStreamEvent(w, filter, &current, event)
+		})
+		if err != nil {
+			return
+		}
+		// The client has been sent every entry up to the position of the
+		// window, or the filter skipped it.
+		current.advance(false, window.position.Domain)
+		current.advance(true, window.position.Campaign)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// The client fell behind or the server is shutting down, it
+				// resumes when it reconnects.
+				return
+			}
+			if err := writeStreamEvent(w, filter, &current, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent moves the client to the position of the event, and writes
+// the event if it passes the filter.
+func writeStreamEvent(w http.ResponseWriter, filter streamFilter, position *streamPosition, event streamEvent) error {
+	position.advance(false, event.Position.Domain)
+	position.advance(true, event.Position.Campaign)
+	if !filter.match(event) {
+		return nil
+	}
+
+	eventType, response := streamEventDomain, newDomainChangelogResponse(event.Entry)
+	if event.Campaign {
+		eventType, response = streamEventCampaign, newCampaignChangelogResponse(event.Entry)
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", position, eventType, data)
+	return err
+}
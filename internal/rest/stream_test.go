@@ -0,0 +1,236 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"whynoipv6/internal/core"
+)
+
+func TestParseStreamPosition(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    streamPosition
+		wantErr bool
+	}{
+		{"0.0", streamPosition{}, false},
+		{"12.34", streamPosition{Domain: 12, Campaign: 34}, false},
+		{"9223372036854775807.1", streamPosition{Domain: 9223372036854775807, Campaign: 1}, false},
+		{"", streamPosition{}, true},
+		{"12", streamPosition{}, true},
+		{"12.", streamPosition{}, true},
+		{".34", streamPosition{}, true},
+		{"12.34.56", streamPosition{}, true},
+		{"-1.0", streamPosition{}, true},
+		{"0.-1", streamPosition{}, true},
+		{"a.b", streamPosition{}, true},
+		{"9223372036854775808.0", streamPosition{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := parseStreamPosition(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStreamPosition(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseStreamPosition(%q) = %+v, want %+v", tt.id, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.id {
+				t.Errorf("String() = %q, want %q", got.String(), tt.id)
+			}
+		})
+	}
+}
+
+func TestStreamPositionAdvance(t *testing.T) {
+	tests := []struct {
+		name     string
+		campaign bool
+		id       int64
+		want     streamPosition
+	}{
+		{"domain forward", false, 15, streamPosition{Domain: 15, Campaign: 20}},
+		{"domain back", false, 5, streamPosition{Domain: 10, Campaign: 20}},
+		{"campaign forward", true, 25, streamPosition{Domain: 10, Campaign: 25}},
+		{"campaign back", true, 15, streamPosition{Domain: 10, Campaign: 20}},
+		{"same id", true, 20, streamPosition{Domain: 10, Campaign: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := streamPosition{Domain: 10, Campaign: 20}
+			p.advance(tt.campaign, tt.id)
+			if p != tt.want {
+				t.Errorf("advance() = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamWindow(t *testing.T) {
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	domain := func(id int64) streamEvent { return streamEvent{Entry: core.ChangelogModel{ID: id}} }
+	campaign := func(id int64) streamEvent { return streamEvent{Campaign: true, Entry: core.ChangelogModel{ID: id}} }
+
+	// Each step reads events at a time after the start, then settles the window.
+	type step struct {
+		after    time.Duration
+		events   []streamEvent
+		wantNew  []bool
+		position streamPosition
+		seen     int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "entries in order",
+			steps: []step{
+				{0, []streamEvent{domain(11), domain(12), campaign(21)}, []bool{true, true, true}, streamPosition{Domain: 10, Campaign: 20}, 3},
+				{streamGrace, nil, nil, streamPosition{Domain: 12, Campaign: 21}, 0},
+			},
+		},
+		{
+			name: "entries read again are not new",
+			steps: []step{
+				{0, []streamEvent{domain(11), domain(13)}, []bool{true, true}, streamPosition{Domain: 10, Campaign: 20}, 2},
+				{time.Second, []streamEvent{domain(11), domain(13), domain(14)}, []bool{false, false, true}, streamPosition{Domain: 10, Campaign: 20}, 3},
+			},
+		},
+		{
+			name: "a late entry within the grace period",
+			steps: []step{
+				{0, []streamEvent{domain(11), domain(13)}, []bool{true, true}, streamPosition{Domain: 10, Campaign: 20}, 2},
+				{streamGrace / 2, []streamEvent{domain(12)}, []bool{true}, streamPosition{Domain: 10, Campaign: 20}, 3},
+				{streamGrace, nil, nil, streamPosition{Domain: 13, Campaign: 20}, 0},
+				{2 * streamGrace, []streamEvent{domain(12), domain(13)}, []bool{false, false}, streamPosition{Domain: 13, Campaign: 20}, 0},
+			},
+		},
+		{
+			name: "entries at or before the position are not new",
+			steps: []step{
+				{0, []streamEvent{domain(9), domain(10), campaign(20)}, []bool{false, false, false}, streamPosition{Domain: 10, Campaign: 20}, 0},
+			},
+		},
+		{
+			name: "newer entries keep the window open",
+			steps: []step{
+				{0, []streamEvent{domain(11)}, []bool{true}, streamPosition{Domain: 10, Campaign: 20}, 1},
+				{streamGrace - time.Second, []streamEvent{domain(12)}, []bool{true}, streamPosition{Domain: 10, Campaign: 20}, 2},
+				{streamGrace, nil, nil, streamPosition{Domain: 11, Campaign: 20}, 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newStreamWindow(streamPosition{Domain: 10, Campaign: 20})
+			for i, step := range tt.steps {
+				now := start.Add(step.after)
+				for j, event := range step.events {
+					if got := w.add(event, now); got != step.wantNew[j] {
+						t.Errorf("step %d: add(%+v) = %v, want %v", i, event.Entry.ID, got, step.wantNew[j])
+					}
+					if !w.has(event) {
+						t.Errorf("step %d: has(%d) = false after add", i, event.Entry.ID)
+					}
+				}
+				w.settle(now)
+				if w.position != step.position {
+					t.Errorf("step %d: position = %+v, want %+v", i, w.position, step.position)
+				}
+				if len(w.seen) != step.seen {
+					t.Errorf("step %d: %d entries after the position, want %d", i, len(w.seen), step.seen)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWindowLast(t *testing.T) {
+	w := newStreamWindow(streamPosition{Domain: 10, Campaign: 20})
+	now := time.Now()
+	w.add(streamEvent{Entry: core.ChangelogModel{ID: 14}}, now)
+	w.add(streamEvent{Entry: core.ChangelogModel{ID: 12}}, now)
+	w.add(streamEvent{Campaign: true, Entry: core.ChangelogModel{ID: 22}}, now)
+
+	if got, want := w.last(), (streamPosition{Domain: 14, Campaign: 22}); got != want {
+		t.Errorf("last() = %+v, want %+v", got, want)
+	}
+	ids := w.ids(false)
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{12, 14}) {
+		t.Errorf("ids(false) = %v, want [12 14]", ids)
+	}
+	if ids := w.ids(true); !slices.Equal(ids, []int64{22}) {
+		t.Errorf("ids(true) = %v, want [22]", ids)
+	}
+	if w.has(streamEvent{Entry: core.ChangelogModel{ID: 13}}) {
+		t.Error("has(13) = true, want false")
+	}
+
+	clone := w.clone()
+	clone.add(streamEvent{Entry: core.ChangelogModel{ID: 13}}, now)
+	if w.has(streamEvent{Entry: core.ChangelogModel{ID: 13}}) {
+		t.Error("adding to a clone changed the window")
+	}
+}
+
+func TestWriteStreamEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   streamFilter
+		event    streamEvent
+		want     streamPosition
+		wantBody string
+	}{
+		{
+			name:     "domain entry",
+			event:    streamEvent{Entry: core.ChangelogModel{ID: 15, Site: "example.com"}, Position: streamPosition{Domain: 12, Campaign: 20}},
+			want:     streamPosition{Domain: 12, Campaign: 20},
+			wantBody: "id: 12.20\nevent: domain\n",
+		},
+		{
+			name:     "campaign entry",
+			event:    streamEvent{Campaign: true, Entry: core.ChangelogModel{ID: 25, Site: "example.com"}, Position: streamPosition{Domain: 12, Campaign: 24}},
+			want:     streamPosition{Domain: 12, Campaign: 24},
+			wantBody: "id: 12.24\nevent: campaign\n",
+		},
+		{
+			name:   "filtered entry moves the position",
+			filter: streamFilter{site: "example.org"},
+			event:  streamEvent{Entry: core.ChangelogModel{ID: 15, Site: "example.com"}, Position: streamPosition{Domain: 14, Campaign: 20}},
+			want:   streamPosition{Domain: 14, Campaign: 20},
+		},
+		{
+			name:     "the position never moves back",
+			event:    streamEvent{Entry: core.ChangelogModel{ID: 11, Site: "example.com"}, Position: streamPosition{Domain: 5, Campaign: 5}},
+			want:     streamPosition{Domain: 10, Campaign: 20},
+			wantBody: "id: 10.20\nevent: domain\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			position := streamPosition{Domain: 10, Campaign: 20}
+			if err := writeStreamEvent(w, tt.filter, &position, tt.event); err != nil {
+				t.Fatal(err)
+			}
+			if position != tt.want {
+				t.Errorf("position = %+v, want %+v", position, tt.want)
+			}
+			body := w.Body.String()
+			if tt.wantBody == "" {
+				if body != "" {
+					t.Errorf("body = %q, want nothing", body)
+				}
+				return
+			}
+			if !strings.HasPrefix(body, tt.wantBody) || !strings.Contains(body, `"domain":"example.com"`) {
+				t.Errorf("body = %q, want it to start with %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ggicci/httpin"
+	httpincore "github.com/ggicci/httpin/core"
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v4"
+)
+
+// The /v2 API wraps every successful response in an Envelope, and every failed
+// response in an ErrorEnvelope with a typed error code:
+//
+//	{"data": [...], "meta": {"total": 1000, "offset": 0, "limit": 50}, "links": {"self": "...", "next": "..."}}
+//	{"error": {"code": "not_found", "message": "domain not found"}}
+
+// maxLimit is the maximum number of items a paginated /v2 endpoint returns.
+const maxLimit = 100
+
+// Envelope is the body of a successful /v2 response.
+type Envelope[T any] struct {
+	Data  T      `json:"data"`
+	Meta  *Meta  `json:"meta,omitempty"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// Meta holds the pagination metadata of a list response. Total is left out
+// of pages fetched by cursor, unless the request asks for it.
+type Meta struct {
+	Total      *int64 `json:"total,omitempty"`
+	Offset     int64  `json:"offset"`
+	Limit      int64  `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Links holds the links to the current, next and previous page of a list response.
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// ErrorCode is a machine-readable error code.
+type ErrorCode string
+
+// Error codes returned by the /v2 API.
+const (
+	ErrCodeInvalidRequest ErrorCode = "invalid_request" // The request has invalid parameters (400)
+	ErrCodeUnauthorized   ErrorCode = "unauthorized"    // The API key is invalid or revoked (401)
+	ErrCodeNotFound       ErrorCode = "not_found"       // The resource does not exist (404)
+	ErrCodeRateLimited    ErrorCode = "rate_limited"    // The client made too many requests (429)
+	ErrCodeInternal       ErrorCode = "internal_error"  // Something went wrong on our side (500)
+)
+
+// APIError describes why a /v2 request failed.
+type APIError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// ErrorEnvelope is the body of a failed /v2 response.
+type ErrorEnvelope struct {
+	HTTPStatusCode int      `json:"-"`
+	Error          APIError `json:"error"`
+}
+
+// Render sets the HTTP status code of the error response.
+func (e *ErrorEnvelope) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.HTTPStatusCode)
+	return nil
+}
+
+// ErrInvalidRequestV2 returns a /v2 error response for requests with invalid parameters.
+func ErrInvalidRequestV2(message string) render.Renderer {
+	return &ErrorEnvelope{
+		HTTPStatusCode: http.StatusBadRequest,
+		Error:          APIError{Code: ErrCodeInvalidRequest, Message: message},
+	}
+}
+
+// ErrNotFoundV2 returns a /v2 error response for resources that do not exist.
+func ErrNotFoundV2(message string) render.Renderer {
+	return &ErrorEnvelope{
+		HTTPStatusCode: http.StatusNotFound,
+		Error:          APIError{Code: ErrCodeNotFound, Message: message},
+	}
+}
+
+// ErrInternalV2 returns a /v2 error response for unexpected errors. The error is
+// logged, but not sent to the client.
+func ErrInternalV2(err error) render.Renderer {
+	log.Println("Internal server error:", err)
+	return &ErrorEnvelope{
+		HTTPStatusCode: http.StatusInternalServerError,
+		Error:          APIError{Code: ErrCodeInternal, Message: "internal server error"},
+	}
+}
+
+// errLookupV2 returns a not found response if the lookup found no rows, and an
+// internal error response otherwise.
+func errLookupV2(err error, message string) render.Renderer {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFoundV2(message)
+	}
+	return ErrInternalV2(err)
+}
+
+// paginationV2 decodes the pagination query parameters of a /v2 list endpoint
+// into the input struct, PaginationInput or CursorPaginationInput.
+func paginationV2(input any) func(http.Handler) http.Handler {
+	return httpin.NewInput(input, httpin.Option.WithErrorHandler(
+		func(w http.ResponseWriter, r *http.Request, err error) {
+			message := "invalid query parameters"
+			var fieldErr *httpincore.InvalidFieldError
+			if errors.As(err, &fieldErr) && fieldErr.Key != "" {
+				message = "invalid value for query parameter " + fieldErr.Key
+			}
+			_ = render.Render(w, r, ErrInvalidRequestV2(message))
+		},
+	))
+}
+
+// paginationInputV2 returns the validated pagination input of the request.
+func paginationInputV2(r *http.Request) (*PaginationInput, error) {
+	input := r.Context().Value(httpin.Input).(*PaginationInput)
+	if err := validatePagination(input.Offset, input.Limit); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// validatePagination checks the offset and limit of a /v2 list request.
+func validatePagination(offset, limit int64) error {
+	if offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	if limit < 1 || limit > maxLimit {
+		return errors.New("limit must be between 1 and " + strconv.Itoa(maxLimit))
+	}
+	return nil
+}
+
+// renderData renders a /v2 response without pagination.
+func renderData[T any](w http.ResponseWriter, r *http.Request, data T) {
+	render.JSON(w, r, Envelope[T]{Data: data})
+}
+
+// renderPage renders a page of a /v2 list response, with the total count and
+// links to the next and previous page.
+func renderPage[T any](w http.ResponseWriter, r *http.Request, data []T, total int64, page *PaginationInput) {
+	if data == nil {
+		data = []T{}
+	}
+
+	links := &Links{Self: pageURL(r.URL, page.Offset, page.Limit)}
+	if page.Offset+page.Limit < total {
+		links.Next = pageURL(r.URL, page.Offset+page.Limit, page.Limit)
+	}
+	if page.Offset > 0 {
+		links.Prev = pageURL(r.URL, max(page.Offset-page.Limit, 0), page.Limit)
+	}
+
+	render.JSON(w, r, Envelope[[]T]{
+		Data:  data,
+		Meta:  &Meta{Total: &total, Offset: page.Offset, Limit: page.Limit},
+		Links: links,
+	})
+}
+
+// pageURL returns the request URL with the given offset and limit.
+func pageURL(u *url.URL, offset, limit int64) string {
+	query := u.Query()
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	return u.Path + "?" + query.Encode()
+}
+
+// NotFoundV2 responds to requests for /v2 routes that do not exist.
+func NotFoundV2(w http.ResponseWriter, r *http.Request) {
+	_ = render.Render(w, r, ErrNotFoundV2("route not found"))
+}
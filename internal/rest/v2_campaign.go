@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// RoutesV2 returns a router with all /v2 campaign endpoints mounted.
+func (rs CampaignHandler) RoutesV2() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /v2/campaign - List all campaigns
+	r.Get("/", rs.CampaignListV2)
+	// GET /v2/campaign/{uuid} - Retrieve a campaign by its UUID
+	r.Get("/{uuid}", rs.RetrieveCampaignV2)
+	// GET /v2/campaign/{uuid}/domains - List the domains of a campaign
+	r.With(paginationV2(CursorPaginationInput{})).Get("/{uuid}/domains", rs.CampaignDomainsV2)
+	// GET /v2/campaign/{uuid}/{domain} - View details of a single domain in a campaign
+	r.Get("/{uuid}/{domain}", rs.ViewCampaignDomainV2)
+	// GET /v2/campaign/{uuid}/{domain}/log - View the crawler log of a single domain in a campaign
+	r.Get("/{uuid}/{domain}/log", rs.GetCampaignDomainLogV2)
+	// GET /v2/campaign/search/{domain} - search for a campaign domain by its name
+	r.With(paginationV2(PaginationInput{})).Get("/search/{domain}", rs.SearchDomainV2)
+
+	return r
+}
+
+// OperationsV2 returns the documentation of all /v2 campaign endpoints.
+func (rs CampaignHandler) OperationsV2() []Operation {
+	errorResponse := ErrorEnvelope{}
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all campaigns", Response: Envelope[[]CampaignListResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{uuid}", Summary: "Retrieve a campaign by its UUID", Response: Envelope[CampaignListResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{uuid}/domains", Summary: "List the domains of a campaign", Input: CursorPaginationInput{}, Response: Envelope[[]CampaignResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{uuid}/{domain}", Summary: "View a single domain in a campaign", Response: Envelope[CampaignResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{uuid}/{domain}/log", Summary: "Retrieve the crawler log for a domain in a campaign", Response: Envelope[[]DomainLogResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/search/{domain}", Summary: "Search for a campaign domain by its name", Input: PaginationInput{}, Response: Envelope[[]CampaignResponse]{}, Error: errorResponse},
+	}
+}
+
+// CampaignListV2 returns all campaigns.
+func (rs CampaignHandler) CampaignListV2(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := rs.Repo.ListCampaign(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	campaignList := []CampaignListResponse{}
+	for _, campaign := range campaigns {
+		campaignList = append(campaignList, newCampaignListResponse(campaign))
+	}
+	renderData(w, r, campaignList)
+}
+
+// RetrieveCampaignV2 returns a campaign by its UUID.
+func (rs CampaignHandler) RetrieveCampaignV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+
+	campaign, err := rs.Repo.GetCampaign(r.Context(), campaignID)
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "campaign not found"))
+		return
+	}
+	renderData(w, r, newCampaignListResponse(campaign))
+}
+
+// CampaignDomainsV2 returns a page of the domains in a campaign.
+func (rs CampaignHandler) CampaignDomainsV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+
+	// Make sure the campaign exists, so an unknown campaign is not an empty list.
+	if _, err := rs.Repo.GetCampaign(r.Context(), campaignID); err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "campaign not found"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.CampaignDomainModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountCampaignDomain(ctx, campaignID)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.CampaignDomainModel, error) {
+			return rs.Repo.ListCampaignDomain(ctx, campaignID, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.CampaignDomainModel, error) {
+			afterID, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListCampaignDomainAfter(ctx, campaignID, afterID, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.CampaignDomainModel, error) {
+			beforeID, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListCampaignDomainBefore(ctx, campaignID, beforeID, limit)
+		},
+		cursor: func(domain core.CampaignDomainModel) string {
+			return idCursor(domain.ID)
+		},
+	}, newCampaignResponse)
+}
+
+// ViewCampaignDomainV2 returns a single domain in a campaign.
+func (rs CampaignHandler) ViewCampaignDomainV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+	site := chi.URLParam(r, "domain")
+	if !domainRegex.MatchString(site) {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid domain"))
+		return
+	}
+
+	domain, err := rs.Repo.ViewCampaignDomain(r.Context(), campaignID, site)
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "domain not found"))
+		return
+	}
+	renderData(w, r, newCampaignResponse(domain))
+}
+
+// GetCampaignDomainLogV2 returns the crawler log for a domain in a campaign.
+func (rs CampaignHandler) GetCampaignDomainLogV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+
+	logs, err := rs.Repo.GetCampaignDomainLog(r.Context(), campaignID, chi.URLParam(r, "domain"))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "domain not found"))
+		return
+	}
+
+	logList := []DomainLogResponse{}
+	for _, log := range logs {
+		response, err := newDomainLogResponse(log.ID, log.Time, log.Data)
+		if err != nil {
+			_ = render.Render(w, r, ErrInternalV2(err))
+			return
+		}
+		logList = append(logList, response)
+	}
+	renderData(w, r, logList)
+}
+
+// SearchDomainV2 returns a page of the campaign domains matching the provided name.
+func (rs CampaignHandler) SearchDomainV2(w http.ResponseWriter, r *http.Request) {
+	page, err := paginationInputV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	search := strings.ToLower(chi.URLParam(r, "domain"))
+
+	domains, err := rs.Repo.GetCampaignDomainsByName(r.Context(), search, page.Offset, page.Limit)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	total, err := rs.Repo.CountCampaignDomainsByName(r.Context(), search)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	var domainList []CampaignResponse
+	for _, domain := range domains {
+		response := newCampaignResponse(domain)
+		response.CampaignUUID = encodeUUID(domain.CampaignID)
+		domainList = append(domainList, response)
+	}
+	renderPage(w, r, domainList, total, page)
+}
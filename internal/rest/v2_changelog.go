@@ -0,0 +1,213 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// domainRegex matches a valid domain name.
+var domainRegex = regexp.MustCompile(`^([a-z0-9]+(-[a-z0-9]+)*\.)+[a-z]{2,}$`)
+
+// RoutesV2 returns a router with all /v2 changelog endpoints mounted.
+func (rs ChangelogHandler) RoutesV2() chi.Router {
+	r := chi.NewRouter()
+	r.Use(paginationV2(CursorPaginationInput{}))
+
+	// GET /v2/changelog - List all changelog entries
+	r.Get("/", rs.ChangelogListV2)
+	// GET /v2/changelog/campaign - List all campaign changelog entries
+	r.Get("/campaign", rs.CampaignChangelogListV2)
+	// GET /v2/changelog/{domain} - List all changelog entries for a specific domain
+	r.Get("/{domain}", rs.ChangelogByDomainV2)
+	// GET /v2/changelog/campaign/{uuid} - List all changelog entries for a specific campaign UUID
+	r.Get("/campaign/{uuid}", rs.ChangelogByCampaignV2)
+	// GET /v2/changelog/campaign/{uuid}/{domain} - List all changelog entries for a specific domain within a campaign UUID
+	r.Get("/campaign/{uuid}/{domain}", rs.ChangelogByCampaignDomainV2)
+
+	return r
+}
+
+// OperationsV2 returns the documentation of all /v2 changelog endpoints.
+func (rs ChangelogHandler) OperationsV2() []Operation {
+	errorResponse := ErrorEnvelope{}
+	response := Envelope[[]ChangelogResponse]{}
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all changelog entries", Input: CursorPaginationInput{}, Response: response, Error: errorResponse},
+		{Method: "GET", Path: "/campaign", Summary: "List all campaign changelog entries", Input: CursorPaginationInput{}, Response: response, Error: errorResponse},
+		{Method: "GET", Path: "/{domain}", Summary: "List the changelog entries for a domain", Input: CursorPaginationInput{}, Response: response, Error: errorResponse},
+		{Method: "GET", Path: "/campaign/{uuid}", Summary: "List the changelog entries for a campaign", Input: CursorPaginationInput{}, Response: response, Error: errorResponse},
+		{Method: "GET", Path: "/campaign/{uuid}/{domain}", Summary: "List the changelog entries for a domain in a campaign", Input: CursorPaginationInput{}, Response: response, Error: errorResponse},
+	}
+}
+
+// ChangelogListV2 returns a page of all changelog entries.
+func (rs ChangelogHandler) ChangelogListV2(w http.ResponseWriter, r *http.Request) {
+	renderCursorList(w, r, cursorList[core.ChangelogModel]{
+		count:    rs.Repo.Count,
+		byOffset: rs.Repo.List,
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			after, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListChangelogAfter(ctx, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			before, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListChangelogBefore(ctx, before, limit)
+		},
+		cursor: timeCursor,
+	}, newDomainChangelogResponse)
+}
+
+// CampaignChangelogListV2 returns a page of all campaign changelog entries.
+func (rs ChangelogHandler) CampaignChangelogListV2(w http.ResponseWriter, r *http.Request) {
+	renderCursorList(w, r, cursorList[core.ChangelogModel]{
+		count:    rs.Repo.CampaignCount,
+		byOffset: rs.Repo.CampaignList,
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			after, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListCampaignChangelogAfter(ctx, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			before, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListCampaignChangelogBefore(ctx, before, limit)
+		},
+		cursor: timeCursor,
+	}, newCampaignChangelogResponse)
+}
+
+// ChangelogByDomainV2 returns a page of the changelog entries for a domain.
+func (rs ChangelogHandler) ChangelogByDomainV2(w http.ResponseWriter, r *http.Request) {
+	site := chi.URLParam(r, "domain")
+	if !domainRegex.MatchString(site) {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid domain"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.ChangelogModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountChangelogByDomain(ctx, site)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.ChangelogModel, error) {
+			return rs.Repo.GetChangelogByDomain(ctx, site, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			after, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByDomainAfter(ctx, site, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			before, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByDomainBefore(ctx, site, before, limit)
+		},
+		cursor: timeCursor,
+	}, newDomainChangelogResponse)
+}
+
+// ChangelogByCampaignV2 returns a page of the changelog entries for a campaign.
+func (rs ChangelogHandler) ChangelogByCampaignV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.ChangelogModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountChangelogByCampaign(ctx, campaignID)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.ChangelogModel, error) {
+			return rs.Repo.GetChangelogByCampaign(ctx, campaignID, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			after, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByCampaignAfter(ctx, campaignID, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			before, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByCampaignBefore(ctx, campaignID, before, limit)
+		},
+		cursor: timeCursor,
+	}, newCampaignChangelogResponse)
+}
+
+// ChangelogByCampaignDomainV2 returns a page of the changelog entries for a domain in a campaign.
+func (rs ChangelogHandler) ChangelogByCampaignDomainV2(w http.ResponseWriter, r *http.Request) {
+	campaignID, err := campaignUUIDV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid campaign uuid"))
+		return
+	}
+	site := chi.URLParam(r, "domain")
+	if !domainRegex.MatchString(site) {
+		_ = render.Render(w, r, ErrInvalidRequestV2("invalid domain"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.ChangelogModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountChangelogByCampaignDomain(ctx, campaignID, site)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.ChangelogModel, error) {
+			return rs.Repo.GetChangelogByCampaignDomain(ctx, campaignID, site, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			after, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByCampaignDomainAfter(ctx, campaignID, site, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.ChangelogModel, error) {
+			before, err := decodeTimeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.GetChangelogByCampaignDomainBefore(ctx, campaignID, site, before, limit)
+		},
+		cursor: timeCursor,
+	}, newCampaignChangelogResponse)
+}
+
+// newDomainChangelogResponse maps a changelog entry to its response structure, linking to the domain.
+func newDomainChangelogResponse(changelog core.ChangelogModel) ChangelogResponse {
+	return newChangelogResponse(changelog, "/domain/"+changelog.Site)
+}
+
+// newCampaignChangelogResponse maps a campaign changelog entry to its response structure, linking to the campaign domain.
+func newCampaignChangelogResponse(changelog core.ChangelogModel) ChangelogResponse {
+	return newChangelogResponse(changelog, "/campaign/"+encodeUUID(changelog.CampaignID)+"/"+changelog.Site)
+}
+
+// campaignUUIDV2 decodes the short campaign UUID in the URL.
+func campaignUUIDV2(r *http.Request) (uuid.UUID, error) {
+	return decodeUUID(chi.URLParam(r, "uuid"))
+}
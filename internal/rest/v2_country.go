@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"whynoipv6/internal/core"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// RoutesV2 returns a router with all /v2 country-related endpoints mounted.
+func (rs CountryHandler) RoutesV2() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /v2/country - Retrieve a list of all countries
+	r.Get("/", rs.CountryListV2)
+	// GET /v2/country/{code} - Retrieve information about a specific country
+	r.Get("/{code}", rs.CountryInfoV2)
+	// GET /v2/country/{code}/sinners - Retrieve the domains without IPv6 for a specific country
+	r.With(paginationV2(CursorPaginationInput{})).Get("/{code}/sinners", rs.CountrySinnersV2)
+	// GET /v2/country/{code}/heroes - Retrieve the domains with IPv6 for a specific country
+	r.With(paginationV2(CursorPaginationInput{})).Get("/{code}/heroes", rs.CountryHeroesV2)
+	// GET /v2/country/{code}/history - Retrieve the daily statistics of a specific country over time
+	r.With(paginationV2(StatsHistoryInput{})).Get("/{code}/history", rs.CountryHistoryV2)
+
+	return r
+}
+
+// OperationsV2 returns the documentation of all /v2 country-related endpoints.
+func (rs CountryHandler) OperationsV2() []Operation {
+	errorResponse := ErrorEnvelope{}
+	return []Operation{
+		{Method: "GET", Path: "/", Summary: "List all countries", Response: Envelope[[]CountryResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{code}", Summary: "Retrieve a country by its country code", Response: Envelope[CountryResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{code}/sinners", Summary: "List the domains without IPv6 in a country", Input: CursorPaginationInput{}, Response: Envelope[[]DomainResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{code}/heroes", Summary: "List the domains with IPv6 in a country", Input: CursorPaginationInput{}, Response: Envelope[[]DomainResponse]{}, Error: errorResponse},
+		{
+			Method:      "GET",
+			Path:        "/{code}/history",
+			Summary:     "Retrieve the statistics of a country over time",
+			Description: statsHistoryDescription,
+			Input:       StatsHistoryInput{},
+			Response:    Envelope[CountryHistoryResponse]{},
+			Error:       errorResponse,
+		},
+	}
+}
+
+// CountryListV2 returns all countries.
+func (rs CountryHandler) CountryListV2(w http.ResponseWriter, r *http.Request) {
+	countries, err := rs.Repo.List(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	countryList := []CountryResponse{}
+	for _, country := range countries {
+		response, err := newCountryResponse(country)
+		if err != nil {
+			_ = render.Render(w, r, ErrInternalV2(err))
+			return
+		}
+		countryList = append(countryList, response)
+	}
+	renderData(w, r, countryList)
+}
+
+// CountryInfoV2 returns a country by its country code.
+func (rs CountryHandler) CountryInfoV2(w http.ResponseWriter, r *http.Request) {
+	country, err := rs.Repo.GetCountryCode(r.Context(), strings.ToUpper(chi.URLParam(r, "code")))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "country not found"))
+		return
+	}
+
+	response, err := newCountryResponse(country)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	renderData(w, r, response)
+}
+
+// CountrySinnersV2 returns a page of the domains without IPv6 support in a country.
+func (rs CountryHandler) CountrySinnersV2(w http.ResponseWriter, r *http.Request) {
+	country, err := rs.Repo.GetCountryCode(r.Context(), strings.ToUpper(chi.URLParam(r, "code")))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "country not found"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.DomainModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountDomainsByCountry(ctx, country.ID)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.DomainModel, error) {
+			return rs.Repo.ListDomainsByCountry(ctx, country.ID, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			afterID, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainsByCountryAfter(ctx, country.ID, afterID, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			beforeID, err := decodeIDCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainsByCountryBefore(ctx, country.ID, beforeID, limit)
+		},
+		cursor: func(domain core.DomainModel) string {
+			return idCursor(domain.ID)
+		},
+	}, newDomainResponse)
+}
+
+// CountryHeroesV2 returns a page of the domains with IPv6 support in a country.
+func (rs CountryHandler) CountryHeroesV2(w http.ResponseWriter, r *http.Request) {
+	country, err := rs.Repo.GetCountryCode(r.Context(), strings.ToUpper(chi.URLParam(r, "code")))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "country not found"))
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.DomainModel]{
+		count: func(ctx context.Context) (int64, error) {
+			return rs.Repo.CountDomainHeroesByCountry(ctx, country.ID)
+		},
+		byOffset: func(ctx context.Context, offset, limit int64) ([]core.DomainModel, error) {
+			return rs.Repo.ListDomainHeroesByCountry(ctx, country.ID, offset, limit)
+		},
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			after, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainHeroesByCountryAfter(ctx, country.ID, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			before, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainHeroesByCountryBefore(ctx, country.ID, before, limit)
+		},
+		cursor: rankCursor,
+	}, newDomainResponse)
+}
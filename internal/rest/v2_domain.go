@@ -0,0 +1,223 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"whynoipv6/internal/core"
+
+	"github.com/ggicci/httpin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// RoutesV2 returns a router with all /v2 domain-related endpoints mounted.
+func (rs DomainHandler) RoutesV2() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /v2/domain - list the domains without IPv6, or the domains matching the filters
+	r.With(paginationV2(DomainListInputV2{})).Get("/", rs.DomainListV2)
+	// GET /v2/domain/heroes - list the domains with IPv6
+	r.With(paginationV2(CursorPaginationInput{})).Get("/heroes", rs.DomainHeroesV2)
+	// GET /v2/domain/topsinner - list the top 10-ish domains without IPv6
+	r.Get("/topsinner", rs.TopSinnerV2)
+	// GET /v2/domain/{domain} - retrieve a domain by its name
+	r.Get("/{domain}", rs.RetrieveDomainV2)
+	// GET /v2/domain/{domain}/log - retrieve the crawler log for a domain
+	r.Get("/{domain}/log", rs.GetDomainLogV2)
+	// GET /v2/domain/{domain}/history - retrieve the IPv6 history of a domain
+	r.With(paginationV2(DomainHistoryInput{})).Get("/{domain}/history", rs.GetDomainHistoryV2)
+	// GET /v2/domain/search/{domain} - search for a domain by its name
+	r.With(paginationV2(PaginationInput{})).Get("/search/{domain}", rs.SearchDomainV2)
+
+	return r
+}
+
+// OperationsV2 returns the documentation of all /v2 domain-related endpoints.
+func (rs DomainHandler) OperationsV2() []Operation {
+	errorResponse := ErrorEnvelope{}
+	return []Operation{
+		{
+			Method:      "GET",
+			Path:        "/",
+			Summary:     "List the domains without IPv6",
+			Description: domainFilterDescription,
+			Input:       DomainListInputV2{},
+			Response:    Envelope[[]DomainResponse]{},
+			Error:       errorResponse,
+		},
+		{Method: "GET", Path: "/heroes", Summary: "List the domains with IPv6", Input: CursorPaginationInput{}, Response: Envelope[[]DomainResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/topsinner", Summary: "List the top 10-ish domains without IPv6", Response: Envelope[[]DomainResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{domain}", Summary: "Retrieve a domain by its name", Response: Envelope[DomainResponse]{}, Error: errorResponse},
+		{Method: "GET", Path: "/{domain}/log", Summary: "Retrieve the crawler log for a domain", Response: Envelope[[]DomainLogResponse]{}, Error: errorResponse},
+		{
+			Method:      "GET",
+			Path:        "/{domain}/history",
+			Summary:     "Retrieve the IPv6 history of a domain",
+			Description: domainHistoryDescription,
+			Input:       DomainHistoryInput{},
+			Response:    Envelope[DomainHistoryResponse]{},
+			Error:       errorResponse,
+		},
+		{Method: "GET", Path: "/search/{domain}", Summary: "Search for a domain by its name", Input: PaginationInput{}, Response: Envelope[[]DomainResponse]{}, Error: errorResponse},
+	}
+}
+
+// DomainListV2 returns a page of the domains without IPv6 support, or of the
+// domains matching the filters if the request has any.
+func (rs DomainHandler) DomainListV2(w http.ResponseWriter, r *http.Request) {
+	input := r.Context().Value(httpin.Input).(*DomainListInputV2)
+	if input.DomainFilterInput.isSet() {
+		rs.filteredDomainListV2(w, r, input)
+		return
+	}
+
+	renderCursorList(w, r, cursorList[core.DomainModel]{
+		count:    rs.Repo.CountDomain,
+		byOffset: rs.Repo.ListDomain,
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			after, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainAfter(ctx, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			before, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainBefore(ctx, before, limit)
+		},
+		cursor: rankCursor,
+	}, newDomainResponse)
+}
+
+// filteredDomainListV2 returns a page of the domains matching the filters.
+// Filtered lists are sorted on request, so they are paginated by offset.
+func (rs DomainHandler) filteredDomainListV2(w http.ResponseWriter, r *http.Request, input *DomainListInputV2) {
+	if input.Cursor != "" || input.Before != "" {
+		_ = render.Render(w, r, ErrInvalidRequestV2("cursor and before can not be combined with filters, use offset"))
+		return
+	}
+	if err := validatePagination(input.Offset, input.Limit); err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	filter, err := input.filter()
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+
+	domains, err := rs.Repo.ListDomainFiltered(r.Context(), filter, input.Offset, input.Limit)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	total, err := rs.Repo.CountDomainFiltered(r.Context(), filter)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	renderPage(w, r, mapItems(domains, newDomainResponse), total, &PaginationInput{Offset: input.Offset, Limit: input.Limit})
+}
+
+// DomainHeroesV2 returns a page of the domains with IPv6 support.
+func (rs DomainHandler) DomainHeroesV2(w http.ResponseWriter, r *http.Request) {
+	renderCursorList(w, r, cursorList[core.DomainModel]{
+		count:    rs.Repo.CountDomainHeroes,
+		byOffset: rs.Repo.ListDomainHeroes,
+		byCursor: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			after, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainHeroesAfter(ctx, after, limit)
+		},
+		byCursorBefore: func(ctx context.Context, cursor string, limit int64) ([]core.DomainModel, error) {
+			before, err := decodeRankCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			return rs.Repo.ListDomainHeroesBefore(ctx, before, limit)
+		},
+		cursor: rankCursor,
+	}, newDomainResponse)
+}
+
+// TopSinnerV2 returns the top 10-ish domains without IPv6 support.
+func (rs DomainHandler) TopSinnerV2(w http.ResponseWriter, r *http.Request) {
+	domains, err := rs.Repo.ListDomainShamers(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	domainList := []DomainResponse{}
+	for _, domain := range domains {
+		response := newDomainResponse(domain)
+		response.Rank = domain.ID
+		domainList = append(domainList, response)
+	}
+	renderData(w, r, domainList)
+}
+
+// RetrieveDomainV2 returns a domain based on the provided domain name.
+func (rs DomainHandler) RetrieveDomainV2(w http.ResponseWriter, r *http.Request) {
+	domain, err := rs.Repo.ViewDomain(r.Context(), chi.URLParam(r, "domain"))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "domain not found"))
+		return
+	}
+	setLastModified(w, domain.TsCheck, domain.TsUpdated)
+	renderData(w, r, newDomainResponse(domain))
+}
+
+// GetDomainLogV2 returns the crawler log for a domain.
+func (rs DomainHandler) GetDomainLogV2(w http.ResponseWriter, r *http.Request) {
+	logs, err := rs.Repo.GetDomainLog(r.Context(), chi.URLParam(r, "domain"))
+	if err != nil {
+		_ = render.Render(w, r, errLookupV2(err, "domain not found"))
+		return
+	}
+
+	logList := []DomainLogResponse{}
+	for _, log := range logs {
+		response, err := newDomainLogResponse(log.ID, log.Time, log.Data)
+		if err != nil {
+			_ = render.Render(w, r, ErrInternalV2(err))
+			return
+		}
+		logList = append(logList, response)
+	}
+	renderData(w, r, logList)
+}
+
+// SearchDomainV2 returns a page of the domains matching the provided name.
+func (rs DomainHandler) SearchDomainV2(w http.ResponseWriter, r *http.Request) {
+	page, err := paginationInputV2(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequestV2(err.Error()))
+		return
+	}
+	search := strings.ToLower(chi.URLParam(r, "domain"))
+
+	domains, err := rs.Repo.GetDomainsByName(r.Context(), search, page.Offset, page.Limit)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+	total, err := rs.Repo.CountDomainsByName(r.Context(), search)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	var domainList []DomainResponse
+	for _, domain := range domains {
+		domainList = append(domainList, newDomainResponse(domain))
+	}
+	renderPage(w, r, domainList, total, page)
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// RoutesV2 returns a router with all /v2 metric endpoints mounted.
+func (rs MetricHandler) RoutesV2() chi.Router {
+	r := chi.NewRouter()
+
+	// GET /v2/metric/overview
+	r.Get("/overview", rs.OverviewV2)
+	// GET /v2/metric/asn
+	r.Get("/asn", rs.AsnMetricsV2)
+	// GET /v2/metric/asn/search/{query}
+	r.Get("/asn/search/{query}", rs.SearchAsnV2)
+	// GET /v2/metric/asn/{asn}/history
+	r.With(paginationV2(StatsHistoryInput{})).Get("/asn/{asn}/history", rs.AsnHistoryV2)
+
+	return r
+}
+
+// OperationsV2 returns the documentation of all /v2 metric endpoints.
+func (rs MetricHandler) OperationsV2() []Operation {
+	errorResponse := ErrorEnvelope{}
+	return []Operation{
+		{Method: "GET", Path: "/overview", Summary: "Aggregated metrics for all crawled domains", Response: Envelope[[]MetricResponse]{}, Error: errorResponse},
+		{
+			Method:   "GET",
+			Path:     "/asn",
+			Summary:  "Aggregated metrics per ASN",
+			Query:    []QueryParam{{Name: "order", Description: "Sort by the number of IPv4 or IPv6 domains", Enum: []string{"ipv4", "ipv6"}}},
+			Response: Envelope[[]ASNResponse]{},
+			Error:    errorResponse,
+		},
+		{Method: "GET", Path: "/asn/search/{query}", Summary: "Search for an ASN by number or name", Response: Envelope[[]ASNResponse]{}, Error: errorResponse},
+		{
+			Method:      "GET",
+			Path:        "/asn/{asn}/history",
+			Summary:     "Retrieve the statistics of an ASN over time, e.g. /v2/metric/asn/AS15169/history",
+			Description: statsHistoryDescription,
+			Input:       StatsHistoryInput{},
+			Response:    Envelope[ASNHistoryResponse]{},
+			Error:       errorResponse,
+		},
+	}
+}
+
+// OverviewV2 returns the aggregated metrics for all crawled domains.
+func (rs MetricHandler) OverviewV2(w http.ResponseWriter, r *http.Request) {
+	metrics, err := rs.Repo.DomainStats(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	metricList := []MetricResponse{}
+	for _, metric := range metrics {
+		metricList = append(metricList, MetricResponse{
+			Time: metric.Time,
+			Data: metric.Data,
+		})
+	}
+	renderData(w, r, metricList)
+}
+
+// AsnMetricsV2 returns the aggregated metrics per ASN.
+func (rs MetricHandler) AsnMetricsV2(w http.ResponseWriter, r *http.Request) {
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = "ipv4"
+	}
+	if order != "ipv4" && order != "ipv6" {
+		_ = render.Render(w, r, ErrInvalidRequestV2("order must be ipv4 or ipv6"))
+		return
+	}
+
+	asns, err := rs.Repo.AsnList(r.Context(), 0, 50, order)
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	asnList := []ASNResponse{}
+	for _, asn := range asns {
+		asnList = append(asnList, newASNResponse(asn))
+	}
+	renderData(w, r, asnList)
+}
+
+// SearchAsnV2 returns the metrics for the ASNs matching the query.
+func (rs MetricHandler) SearchAsnV2(w http.ResponseWriter, r *http.Request) {
+	asns, err := rs.Repo.SearchAsn(r.Context(), chi.URLParam(r, "query"))
+	if err != nil {
+		_ = render.Render(w, r, ErrInternalV2(err))
+		return
+	}
+
+	asnList := []ASNResponse{}
+	for _, asn := range asns {
+		asnList = append(asnList, newASNResponse(asn))
+	}
+	renderData(w, r, asnList)
+}
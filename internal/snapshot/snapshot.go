@@ -0,0 +1,225 @@
+// Package snapshot publishes dated, checksummed archives of the dataset, and
+// keeps a manifest of the published snapshots.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"whynoipv6/internal/export"
+)
+
+// ManifestFile is the name of the manifest, in the snapshot directory and in
+// every archive.
+const ManifestFile = "manifest.json"
+
+// Snapshot is a published snapshot of the dataset.
+type Snapshot struct {
+	Name      string    `json:"name"` // e.g. whynoipv6-2024-05-06
+	Date      string    `json:"date"` // Day of the snapshot, e.g. 2024-05-06
+	Created   time.Time `json:"created"`
+	Archive   string    `json:"archive,omitempty"` // File name of the archive
+	SHA256    string    `json:"sha256,omitempty"`  // Checksum of the archive
+	Size      int64     `json:"size,omitempty"`    // Size of the archive in bytes
+	Domains   int       `json:"domains"`
+	Countries int       `json:"countries"`
+	ASNs      int       `json:"asns"`
+	Changelog Changelog `json:"changelog"`
+}
+
+// Changelog is the range of changelog entries in a snapshot, the ones written
+// since the previous snapshot.
+type Changelog struct {
+	AfterID int64 `json:"after_id"` // Last entry of the previous snapshot, exclusive
+	LastID  int64 `json:"last_id"`  // Last entry of this snapshot, inclusive
+	Entries int   `json:"entries"`
+}
+
+// Manifest lists the published snapshots, oldest first.
+type Manifest struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+// Name returns the name of the snapshot of a day.
+func Name(day time.Time) string {
+	return "whynoipv6-" + day.UTC().Format(time.DateOnly)
+}
+
+// ReadManifest reads the manifest in a snapshot directory. A directory without
+// a manifest has no snapshots.
+func ReadManifest(dir string) (Manifest, error) {
+	manifest := Manifest{Snapshots: []Snapshot{}}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Previous returns the newest snapshot with another name, the one a snapshot
+// with the given name follows. It returns false if there is none.
+func (m Manifest) Previous(name string) (Snapshot, bool) {
+	for i := len(m.Snapshots) - 1; i >= 0; i-- {
+		if m.Snapshots[i].Name != name {
+			return m.Snapshots[i], true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// Add adds a snapshot, replacing an earlier snapshot with the same name.
+func (m *Manifest) Add(snapshot Snapshot) {
+	for i, s := range m.Snapshots {
+		if s.Name == snapshot.Name {
+			m.Snapshots = append(m.Snapshots[:i], m.Snapshots[i+1:]...)
+			break
+		}
+	}
+	m.Snapshots = append(m.Snapshots, snapshot)
+}
+
+// Write writes the manifest to a snapshot directory, replacing the old one at once.
+func (m Manifest) Write(dir string) error {
+	return WriteJSON(filepath.Join(dir, ManifestFile), m)
+}
+
+// File is a file in an archive.
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// WriteArchive packs the files in the staging directory, in order, into a
+// gzipped tar archive at path, and writes its checksum next to it in the
+// format of sha256sum. The archive only depends on the files and the creation
+// time, so the same data gives the same checksum. It returns the checksum and
+// size of the archive.
+func WriteArchive(path, staging string, files []string, created time.Time) (string, int64, error) {
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	hash := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(out, hash)}
+	gz := gzip.NewWriter(counter)
+	gz.ModTime = created
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if err := addFile(tw, filepath.Join(staging, name), name, created); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := out.Close(); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return "", 0, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	line := sum + "  " + filepath.Base(path) + "\n"
+	return sum, counter.n, os.WriteFile(path+".sha256", []byte(line), 0o644)
+}
+
+// addFile adds a file to an archive.
+func addFile(tw *tar.Writer, path, name string, modTime time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size(),
+		Mode:     0o644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// HashFile returns the checksum and size of a file.
+func HashFile(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: filepath.Base(path), SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size}, nil
+}
+
+// WriteJSON writes a value as indented JSON to a file, replacing it at once.
+func WriteJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// countWriter counts the bytes written to a writer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// ArchiveManifest is the manifest in an archive. It describes the files in the
+// archive, and lists the snapshots before it.
+type ArchiveManifest struct {
+	Snapshot      Snapshot        `json:"snapshot"`
+	Files         []File          `json:"files"`
+	DomainColumns []export.Column `json:"domain_columns"` // Schema of the domains file
+	Previous      []Snapshot      `json:"previous"`
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"whynoipv6/internal/config"
@@ -19,20 +20,10 @@ type apiMessage struct {
 }
 
 var (
-	httpClient *http.Client
-	cfg        *config.Config
-	err        error
-)
-
-func init() {
 	httpClient = createHTTPClient()
-
-	// Read the configuration.
-	cfg, err = config.Read()
-	if err != nil {
-		log.Fatal("Failed to read config: ", err)
-	}
-}
+	// readConfig reads the configuration the first time a message is sent.
+	readConfig = sync.OnceValues(config.Read)
+)
 
 // createHTTPClient initializes an http.Client with better default settings.
 func createHTTPClient() *http.Client {
@@ -54,6 +45,12 @@ func createHTTPClient() *http.Client {
 // NotifyIrc sends message to irc
 // This is a private setup, please don't use this
 func NotifyIrc(m string) {
+	cfg, err := readConfig()
+	if err != nil {
+		log.Println("Failed to read config:", err)
+		return
+	}
+
 	// New message
 	message := apiMessage{
 		Channel: "legz",
@@ -0,0 +1,104 @@
+// Package tracing exports OpenTelemetry traces of the API server and the
+// crawlers to an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"whynoipv6/internal/buildinfo"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shutdownTimeout is how long Setup's shutdown function waits for the
+// remaining spans to be exported.
+const shutdownTimeout = 5 * time.Second
+
+// Config is where and how much to trace.
+type Config struct {
+	Endpoint    string  // URL of the OTLP/HTTP collector, e.g. http://localhost:4318. Tracing is disabled if empty
+	SampleRatio float64 // Share of the traces that are recorded, all of them if not between 0 and 1
+}
+
+// Setup exports the traces of the process, named service, to the collector of
+// the config. Incoming and outgoing trace context is read and written in the
+// W3C Trace Context format. The returned function exports the remaining spans
+// and must be called before the process exits. If no collector is configured,
+// nothing is recorded and the function does nothing.
+func Setup(ctx context.Context, service string, cfg Config) (func(), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if cfg.Endpoint == "" {
+		return func() {}, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(service),
+		semconv.ServiceVersion(buildinfo.Version),
+	))
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, err
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = provider.Shutdown(ctx)
+	}, nil
+}
+
+// Tracer returns the tracer of a package, e.g. whynoipv6/internal/core.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Logger returns the logger with the trace_id and span_id fields of the span
+// in the context, so log lines can be found from a trace and the other way
+// around. The logger is returned as is if the context has no recorded span.
+func Logger(ctx context.Context, logger zerolog.Logger) zerolog.Logger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return logger
+	}
+	return logger.With().
+		Str("trace_id", spanCtx.TraceID().String()).
+		Str("span_id", spanCtx.SpanID().String()).
+		Logger()
+}
+
+// RecordError marks the span failed with the error, if it is not nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}